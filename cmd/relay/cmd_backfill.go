@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/scottbrown/relay/internal/backfill"
+	"github.com/scottbrown/relay/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var backfillCmd = &cobra.Command{
+	Use:   "backfill",
+	Short: "Forward stored log files for a date range to Splunk HEC",
+	Long: `Forward already-stored NDJSON files (plain or .ndjson.gz) to Splunk HEC through the
+listener's configured forwarder.
+
+Files are selected by the UTC day in their name and sent in order, in batches, at
+no more than --rate-limit lines per second. Progress is saved after every batch, so
+an interrupted backfill resumes where it stopped when run again. Use --dry-run to
+count what would be sent.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig(configFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := performBackfill(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// performBackfill forwards stored files for the listener selected by --listener.
+func performBackfill(cfg *config.Config) error {
+	if backfillListener == "" {
+		return fmt.Errorf("--listener is required")
+	}
+	if backfillSince == "" {
+		return fmt.Errorf("--since is required")
+	}
+	listenerCfg, err := findListener(cfg, backfillListener)
+	if err != nil {
+		return err
+	}
+
+	since, err := parseTimeFlag(backfillSince, false)
+	if err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	until, err := parseTimeFlag(backfillUntil, true)
+	if err != nil {
+		return fmt.Errorf("invalid --until: %w", err)
+	}
+
+	files, err := backfill.Files(listenerCfg.OutputDir, listenerCfg.FilePrefix, since, until)
+	if err != nil {
+		return fmt.Errorf("failed to list stored files: %w", err)
+	}
+	if len(files) == 0 {
+		fmt.Printf("No stored files in range for listener %s\n", listenerCfg.Name)
+		return nil
+	}
+
+	progressPath := backfillProgressFile
+	if progressPath == "" {
+		progressPath = filepath.Join(listenerCfg.OutputDir, listenerCfg.FilePrefix+".backfill-progress.json")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if backfillDryRun {
+		b, err := backfill.New(nil, backfill.Config{
+			BatchMaxLines: backfillBatchLines,
+			ProgressPath:  progressPath,
+		})
+		if err != nil {
+			return err
+		}
+		stats, err := b.Count(ctx, files)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Dry run for listener %s (nothing sent)\n", listenerCfg.Name)
+		printBackfillStats(stats)
+		return nil
+	}
+
+	if !hecConfigured(cfg, listenerCfg) {
+		return fmt.Errorf("listener %s has no Splunk HEC configured", listenerCfg.Name)
+	}
+
+	// Batching is done by the backfiller and no DLQ is attached, so each Forward
+	// reports whether the batch was delivered and failures stop the run.
+	fwd, err := buildForwarder(cfg, listenerCfg, forwarderOptions{synchronous: true})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = fwd.Shutdown(ctx)
+	}()
+
+	b, err := backfill.New(fwd, backfill.Config{
+		BatchMaxLines: backfillBatchLines,
+		RateLimit:     backfillRateLimit,
+		ProgressPath:  progressPath,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Backfilling %d file(s) for listener %s\n", len(files), listenerCfg.Name)
+	stats, err := b.Run(ctx, files)
+	printBackfillStats(stats)
+	if err != nil {
+		return fmt.Errorf("backfill stopped (progress saved in %s, re-run to resume): %w", progressPath, err)
+	}
+	return nil
+}
+
+func printBackfillStats(stats backfill.Stats) {
+	fmt.Printf("  Files:   %d\n", stats.Files)
+	fmt.Printf("  Skipped: %d (completed by a previous run)\n", stats.Skipped)
+	fmt.Printf("  Lines:   %d\n", stats.Lines)
+	fmt.Printf("  Bytes:   %d\n", stats.Bytes)
+	fmt.Printf("  Batches: %d\n", stats.Batches)
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/scottbrown/relay/internal/circuitbreaker"
+	"github.com/scottbrown/relay/internal/config"
+	"github.com/scottbrown/relay/internal/forwarder"
+	"github.com/scottbrown/relay/internal/quarantine"
+	"github.com/scottbrown/relay/internal/queue"
+	"github.com/scottbrown/relay/internal/tailer"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// redactedSecret replaces HEC tokens and HMAC keys in configuration output.
+const redactedSecret = "[REDACTED]"
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Check and inspect the configuration file",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the configuration file without starting the relay",
+	Long: `Validate the configuration file without starting the relay.
+
+Runs every check the relay makes at startup, including secret references, TLS
+certificates, output directories and listen address availability, and reports
+every problem found rather than only the first.
+
+Use --no-bind to skip the listen address check, for example to validate a new
+configuration on a host where the relay is already running.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.ValidateConfig(configFile, !validateNoBind)
+		if err != nil {
+			printValidationErrors(os.Stderr, configFile, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Configuration %s is valid (%d listeners)\n", configFile, len(cfg.Listeners))
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration of each listener",
+	Long: `Print the effective configuration of each listener as YAML.
+
+Global splunk settings are merged into each listener's splunk section and
+defaults are filled in, so the output shows what each listener actually uses.
+HEC tokens and redaction keys are redacted. Listen addresses are not checked for
+availability, so the configuration of a running relay can be shown on its host.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.ValidateConfig(configFile, false)
+		if err != nil {
+			printValidationErrors(os.Stderr, configFile, err)
+			os.Exit(1)
+		}
+
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(effectiveConfig(cfg)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		_ = enc.Close()
+	},
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Output a JSON Schema for the configuration file",
+	Long: `Output a JSON Schema (draft 2020-12) for the configuration file and exit.
+
+The schema is generated from the relay's configuration structures, so editors
+and CI can check configuration files, for example with the YAML language server:
+
+  # yaml-language-server: $schema=relay-config.schema.json
+
+Like the relay, the schema rejects unknown keys. Rules that span settings, such
+as HEC URL and token being set together, are only checked by "relay config validate".`,
+	Run: func(cmd *cobra.Command, args []string) {
+		schema, err := config.JSONSchema()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(schema))
+	},
+}
+
+// printValidationErrors prints each problem in a configuration error on its own line.
+func printValidationErrors(w io.Writer, path string, err error) {
+	var errs []error
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	} else {
+		errs = []error{err}
+	}
+
+	if len(errs) == 1 {
+		fmt.Fprintf(w, "Configuration %s is invalid: %v\n", path, errs[0])
+		return
+	}
+	fmt.Fprintf(w, "Configuration %s has %d problems:\n", path, len(errs))
+	for _, e := range errs {
+		fmt.Fprintf(w, "  - %v\n", e)
+	}
+}
+
+// effectiveConfig returns a copy of cfg with the global Splunk section merged into each
+// listener, defaults filled in and HEC tokens and redaction keys redacted.
+func effectiveConfig(cfg *config.Config) config.Config {
+	out := *cfg
+	out.Include = nil
+	out.Splunk = nil
+	out.Listeners = make([]config.ListenerConfig, len(cfg.Listeners))
+	for i, listenerCfg := range cfg.Listeners {
+		out.Listeners[i] = effectiveListener(cfg, listenerCfg)
+	}
+	return out
+}
+
+// effectiveListener returns the listener's configuration as the relay applies it.
+func effectiveListener(cfg *config.Config, listenerCfg config.ListenerConfig) config.ListenerConfig {
+	if listenerCfg.DLQ != nil {
+		dlqCfg := *listenerCfg.DLQ
+		dlqCfg.Dir = dlqDir(listenerCfg)
+		listenerCfg.DLQ = &dlqCfg
+	}
+	if listenerCfg.Queue != nil && listenerCfg.Queue.Enabled {
+		queueCfg := *listenerCfg.Queue
+		queueCfg.Dir = queueDir(listenerCfg)
+		if queueCfg.MaxBytes == 0 {
+			queueCfg.MaxBytes = queue.DefaultMaxBytes
+		}
+		if queueCfg.SegmentBytes == 0 {
+			queueCfg.SegmentBytes = queue.DefaultSegmentBytes
+		}
+		listenerCfg.Queue = &queueCfg
+	}
+	if listenerCfg.Quarantine != nil && listenerCfg.Quarantine.Enabled {
+		quarantineCfg := *listenerCfg.Quarantine
+		quarantineCfg.Dir = quarantineDir(listenerCfg)
+		if quarantineCfg.MaxDataBytes == 0 {
+			quarantineCfg.MaxDataBytes = quarantine.DefaultMaxDataBytes
+		}
+		listenerCfg.Quarantine = &quarantineCfg
+	}
+	if listenerCfg.Redaction != nil && listenerCfg.Redaction.HMACKey != "" {
+		redactionCfg := *listenerCfg.Redaction
+		redactionCfg.HMACKey = redactedSecret
+		listenerCfg.Redaction = &redactionCfg
+	}
+	if tailEnabled(listenerCfg) {
+		tailCfg := *listenerCfg.Tail
+		if tailCfg.CheckpointFile == "" {
+			tailCfg.CheckpointFile = filepath.Join(listenerCfg.OutputDir, listenerCfg.FilePrefix+".tail-checkpoint.json")
+		}
+		if tailCfg.BatchMaxLines == 0 {
+			tailCfg.BatchMaxLines = tailer.DefaultBatchMaxLines
+		}
+		if tailCfg.BatchMaxBytes == 0 {
+			tailCfg.BatchMaxBytes = tailer.DefaultBatchMaxBytes
+		}
+		if tailCfg.PollInterval == 0 {
+			tailCfg.PollInterval = int(tailer.DefaultPollInterval / time.Second)
+		}
+		listenerCfg.Tail = &tailCfg
+	}
+	listenerCfg.Splunk = effectiveSplunk(cfg, listenerCfg)
+	return listenerCfg
+}
+
+// effectiveSplunk merges the global and per-listener Splunk sections the way the listener's
+// forwarder is built. It returns nil if the listener does not forward to HEC.
+func effectiveSplunk(cfg *config.Config, listenerCfg config.ListenerConfig) *config.SplunkConfig {
+	if usesMultiTarget(cfg.Splunk, listenerCfg.Splunk) {
+		targets, routing := getHECTargetsAndRouting(cfg.Splunk, listenerCfg.Splunk)
+		targets = withDefaultSource(targets, listenerCfg.Name)
+		out := &config.SplunkConfig{Routing: &routing}
+		for _, target := range targets {
+			out.HECTargets = append(out.HECTargets, effectiveTarget(target))
+		}
+		return out
+	}
+	if !hecConfigured(cfg, listenerCfg) {
+		return nil
+	}
+
+	hecCfg := mergeHECConfig(cfg.Splunk, listenerCfg.Splunk, nil)
+	if hecCfg.Endpoint == "" {
+		hecCfg.Endpoint = forwarder.EndpointRaw
+	}
+	if hecCfg.Endpoint == forwarder.EndpointEvent && hecCfg.Event.Source == "" {
+		hecCfg.Event.Source = listenerCfg.Name
+	}
+	if hecCfg.ClientTimeout == 0 {
+		hecCfg.ClientTimeout = forwarder.DefaultClientTimeout
+	}
+	return &config.SplunkConfig{
+		HECURL:         hecCfg.URL,
+		HECToken:       redactedSecret,
+		Gzip:           &hecCfg.UseGzip,
+		SourceType:     hecCfg.SourceType,
+		Endpoint:       hecCfg.Endpoint,
+		Index:          hecCfg.Event.Index,
+		Source:         hecCfg.Event.Source,
+		Fields:         hecCfg.Event.Fields,
+		ClientTimeout:  int(hecCfg.ClientTimeout / time.Second),
+		Batch:          batchSettings(hecCfg.Batch),
+		CircuitBreaker: circuitBreakerSettings(hecCfg.CircuitBreaker),
+		Retry:          retrySettings(hecCfg.Retry),
+		Ack:            ackSettings(hecCfg.Ack),
+		Transport:      transportSettings(hecCfg.Transport),
+	}
+}
+
+// effectiveTarget fills in the defaults of a HEC target, as forwarder.NewMulti applies them.
+func effectiveTarget(target config.HECTarget) config.HECTarget {
+	gzip := target.Gzip != nil && *target.Gzip
+	target.HECToken = redactedSecret
+	target.Gzip = &gzip
+	if target.Endpoint == "" {
+		target.Endpoint = forwarder.EndpointRaw
+	}
+	if target.ClientTimeout == 0 {
+		target.ClientTimeout = int(forwarder.DefaultClientTimeout / time.Second)
+	}
+	target.Batch = batchSettings(mergeBatchConfig(nil, target.Batch))
+	target.CircuitBreaker = circuitBreakerSettings(mergeCircuitBreakerConfig(nil, target.CircuitBreaker))
+	target.Retry = retrySettings(mergeRetryConfig(nil, target.Retry))
+	target.Ack = ackSettings(mergeAckConfig(nil, target.Ack))
+	target.Transport = transportSettings(mergeTransportConfig(nil, target.Transport))
+	return target
+}
+
+// batchSettings converts merged batch settings back to their configuration form.
+func batchSettings(b forwarder.BatchConfig) *config.BatchConfig {
+	return &config.BatchConfig{
+		Enabled:       &b.Enabled,
+		MaxSize:       b.MaxSize,
+		MaxBytes:      b.MaxBytes,
+		FlushInterval: int(b.FlushInterval / time.Second),
+	}
+}
+
+// circuitBreakerSettings converts merged circuit breaker settings back to their configuration form.
+// A failure threshold of 0 disables the circuit breaker.
+func circuitBreakerSettings(cb circuitbreaker.Config) *config.CircuitBreakerConfig {
+	enabled := cb.FailureThreshold > 0
+	return &config.CircuitBreakerConfig{
+		Enabled:          &enabled,
+		FailureThreshold: cb.FailureThreshold,
+		SuccessThreshold: cb.SuccessThreshold,
+		Timeout:          int(cb.Timeout / time.Second),
+		HalfOpenMaxCalls: cb.HalfOpenMaxCalls,
+	}
+}
+
+// retrySettings converts merged retry settings back to their configuration form.
+func retrySettings(r forwarder.RetryConfig) *config.RetryConfig {
+	return &config.RetryConfig{
+		MaxAttempts:       r.MaxAttempts,
+		InitialBackoffMS:  int(r.InitialBackoff / time.Millisecond),
+		BackoffMultiplier: r.BackoffMultiplier,
+		MaxBackoffSeconds: int(r.MaxBackoff / time.Second),
+	}
+}
+
+// ackSettings converts merged indexer acknowledgement settings back to their configuration form.
+func ackSettings(a forwarder.AckConfig) *config.AckConfig {
+	return &config.AckConfig{
+		Enabled:      &a.Enabled,
+		Timeout:      int(a.Timeout / time.Second),
+		PollInterval: int(a.PollInterval / time.Second),
+	}
+}
+
+// transportSettings converts merged transport settings back to their configuration form.
+func transportSettings(t forwarder.TransportConfig) *config.TransportConfig {
+	return &config.TransportConfig{
+		MaxIdleConns:        t.MaxIdleConns,
+		MaxIdleConnsPerHost: t.MaxIdleConnsPerHost,
+		MaxConnsPerHost:     t.MaxConnsPerHost,
+		IdleConnTimeout:     int(t.IdleConnTimeout / time.Second),
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/scottbrown/relay/internal/admin"
+	"github.com/spf13/cobra"
+)
+
+var ctlCmd = &cobra.Command{
+	Use:   "ctl",
+	Short: "Control a running relay through its admin socket",
+	Long: `Control a running relay through the admin API (admin.enabled in the configuration).
+
+Commands are sent over the relay's Unix socket, so they must be run on the same
+host by a user who can access the socket. Without --listener a command applies to
+every listener; without --target it applies to every HEC target.`,
+}
+
+var ctlStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show pause and circuit breaker state of each HEC target",
+	Run: func(cmd *cobra.Command, args []string) {
+		runCtl(func(c *admin.Client) error {
+			statuses, err := c.Status(ctlListener)
+			if err != nil {
+				return err
+			}
+			printStatus(os.Stdout, statuses)
+			return nil
+		})
+	},
+}
+
+var ctlPauseCmd = &cobra.Command{
+	Use:   "pause",
+	Short: "Pause forwarding to Splunk HEC (storage continues)",
+	Long: `Pause forwarding to Splunk HEC, for example during Splunk maintenance.
+
+Lines are still stored locally while forwarding is paused. With the forward
+queue enabled, lines received while paused wait in the queue and are forwarded
+after resume; otherwise they go to the DLQ if it is enabled. The DLQ drain
+worker waits until forwarding is resumed. In tail mode the tailer stops at its checkpoint and
+catches up after resume. A paused target in a multi-target listener is skipped
+by the routing mode.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runCtl(func(c *admin.Client) error {
+			result, err := c.Pause(ctlListener, ctlTarget)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Paused forwarding%s for listener(s): %s\n", targetSuffix(ctlTarget), strings.Join(result.Listeners, ", "))
+			return nil
+		})
+	},
+}
+
+var ctlResumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume forwarding to Splunk HEC",
+	Run: func(cmd *cobra.Command, args []string) {
+		runCtl(func(c *admin.Client) error {
+			result, err := c.Resume(ctlListener, ctlTarget)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Resumed forwarding%s for listener(s): %s\n", targetSuffix(ctlTarget), strings.Join(result.Listeners, ", "))
+			return nil
+		})
+	},
+}
+
+var ctlResetCircuitCmd = &cobra.Command{
+	Use:   "reset-circuit",
+	Short: "Close HEC circuit breakers without waiting for their timeout",
+	Run: func(cmd *cobra.Command, args []string) {
+		runCtl(func(c *admin.Client) error {
+			result, err := c.ResetCircuit(ctlListener, ctlTarget)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Reset circuit breakers%s for listener(s): %s\n", targetSuffix(ctlTarget), strings.Join(result.Listeners, ", "))
+			return nil
+		})
+	},
+}
+
+var ctlFlushCmd = &cobra.Command{
+	Use:   "flush",
+	Short: "Send batched lines to Splunk HEC now",
+	Run: func(cmd *cobra.Command, args []string) {
+		runCtl(func(c *admin.Client) error {
+			result, err := c.Flush(ctlListener)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Flushed batches for listener(s): %s\n", strings.Join(result.Listeners, ", "))
+			return nil
+		})
+	},
+}
+
+var ctlReloadCmd = &cobra.Command{
+	Use:   "reload",
+	Short: "Reload the configuration file (same as SIGHUP)",
+	Run: func(cmd *cobra.Command, args []string) {
+		runCtl(func(c *admin.Client) error {
+			if err := c.Reload(); err != nil {
+				return err
+			}
+			fmt.Println("Configuration reloaded")
+			return nil
+		})
+	},
+}
+
+var ctlConnectionsCmd = &cobra.Command{
+	Use:   "connections",
+	Short: "List active client connections",
+	Run: func(cmd *cobra.Command, args []string) {
+		runCtl(func(c *admin.Client) error {
+			conns, err := c.Connections(ctlListener)
+			if err != nil {
+				return err
+			}
+			printConnections(os.Stdout, conns)
+			return nil
+		})
+	},
+}
+
+// runCtl runs a command against the admin socket selected by --socket.
+func runCtl(f func(c *admin.Client) error) {
+	if err := f(admin.NewClient(ctlSocket)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// targetSuffix describes the --target flag in command output.
+func targetSuffix(target string) string {
+	if target == "" {
+		return ""
+	}
+	return " to target " + target
+}
+
+// printStatus writes one line per HEC target.
+func printStatus(w io.Writer, statuses []admin.ListenerStatus) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LISTENER\tCONNECTIONS\tTARGET\tFORWARDING\tCIRCUIT\tLAST SUCCESS")
+	for _, l := range statuses {
+		if len(l.Targets) == 0 {
+			fmt.Fprintf(tw, "%s\t%d\t-\t-\t-\t-\n", l.Name, l.Connections)
+			continue
+		}
+		for _, t := range l.Targets {
+			forwarding := "active"
+			if t.Paused {
+				forwarding = "paused"
+			}
+			lastSuccess := "never"
+			if t.LastSuccess != nil {
+				lastSuccess = t.LastSuccess.UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\n", l.Name, l.Connections, t.Name, forwarding, t.CircuitState, lastSuccess)
+		}
+	}
+	_ = tw.Flush()
+}
+
+// printConnections writes one line per active connection.
+func printConnections(w io.Writer, conns []admin.Connection) {
+	if len(conns) == 0 {
+		fmt.Fprintln(w, "No active connections")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LISTENER\tCONN ID\tCLIENT\tIDENTITY\tSTARTED\tBYTES\tLINES")
+	for _, c := range conns {
+		identity := c.Identity
+		if identity == "" {
+			identity = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%d\n",
+			c.Listener, c.ConnID, c.ClientAddr, identity, c.Started.UTC().Format(time.RFC3339), c.Bytes, c.Lines)
+	}
+	_ = tw.Flush()
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/scottbrown/relay/internal/config"
+	"github.com/scottbrown/relay/internal/dlq"
+	"github.com/spf13/cobra"
+)
+
+var dlqCmd = &cobra.Command{
+	Use:   "dlq",
+	Short: "Manage dead letter queue files",
+	Long:  "Inspect and replay dead letter queue (DLQ) files written for failed HEC forwards",
+}
+
+var dlqReplayCmd = &cobra.Command{
+	Use:   "replay [files...]",
+	Short: "Re-send dead-lettered events to Splunk HEC",
+	Long: `Re-send dead-lettered events through the listener's configured HEC forwarder.
+
+Without file arguments, every DLQ file in the listener's DLQ directory is replayed,
+except today's file which may still be written to by a running relay (use
+--include-today to override). Delivered entries are moved to the archive directory
+and removed from their DLQ file. Progress is saved after every entry so an
+interrupted replay resumes where it stopped.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig(configFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := performDLQReplay(cfg, args); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// performDLQReplay replays DLQ files for the listener selected by --listener.
+func performDLQReplay(cfg *config.Config, files []string) error {
+	if replayListener == "" {
+		return fmt.Errorf("--listener is required")
+	}
+	listenerCfg, err := findListener(cfg, replayListener)
+	if err != nil {
+		return err
+	}
+	if !hecConfigured(cfg, listenerCfg) {
+		return fmt.Errorf("listener %s has no Splunk HEC configured", listenerCfg.Name)
+	}
+
+	filter := dlq.Filter{
+		ConnID:        replayConnID,
+		ErrorContains: replayErrorContains,
+	}
+	if filter.Since, err = parseTimeFlag(replaySince, false); err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	if filter.Until, err = parseTimeFlag(replayUntil, true); err != nil {
+		return fmt.Errorf("invalid --until: %w", err)
+	}
+
+	dir := dlqDir(listenerCfg)
+	if len(files) == 0 {
+		files, err = dlq.Files(dir)
+		if err != nil {
+			return fmt.Errorf("failed to list DLQ files: %w", err)
+		}
+		if !replayIncludeToday {
+			files = excludeDay(files, time.Now().UTC())
+		}
+	}
+	if len(files) == 0 {
+		fmt.Printf("No DLQ files to replay for listener %s\n", listenerCfg.Name)
+		return nil
+	}
+
+	// Batching is disabled and no DLQ is attached so each Forward reports
+	// whether the entry was delivered; failed entries stay in their DLQ file.
+	// Entries were redacted before they were dead-lettered.
+	fwd, err := buildForwarder(cfg, listenerCfg, forwarderOptions{synchronous: true, redacted: true})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = fwd.Shutdown(ctx)
+	}()
+
+	archiveDir := replayArchiveDir
+	if archiveDir == "" {
+		archiveDir = filepath.Join(dir, "replayed")
+	}
+	replayer, err := dlq.NewReplayer(fwd, filter, archiveDir)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("Replaying %d DLQ file(s) for listener %s\n", len(files), listenerCfg.Name)
+	stats, err := replayer.ReplayFiles(ctx, files)
+	fmt.Printf("  Replayed:  %d\n", stats.Replayed)
+	fmt.Printf("  Failed:    %d\n", stats.Failed)
+	fmt.Printf("  Skipped:   %d\n", stats.Skipped)
+	fmt.Printf("  Malformed: %d\n", stats.Malformed)
+	if err != nil {
+		return fmt.Errorf("replay interrupted (progress saved, re-run to resume): %w", err)
+	}
+	if stats.Failed > 0 {
+		return fmt.Errorf("%d entries could not be delivered and remain in the DLQ", stats.Failed)
+	}
+	return nil
+}
+
+// excludeDay removes files dated on the given UTC day.
+func excludeDay(files []string, day time.Time) []string {
+	today := day.Format("2006-01-02")
+	out := files[:0]
+	for _, f := range files {
+		if d, ok := dlq.FileDate(f); ok && d.Format("2006-01-02") == today {
+			continue
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+// parseTimeFlag parses a YYYY-MM-DD date or RFC 3339 timestamp in UTC.
+// When endOfDay is set, a bare date refers to the end of that day so the range is inclusive.
+func parseTimeFlag(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected YYYY-MM-DD or RFC 3339 timestamp, got %q", value)
+	}
+	if endOfDay {
+		return day.AddDate(0, 0, 1), nil
+	}
+	return day, nil
+}
@@ -8,13 +8,13 @@ import (
 	"net"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/scottbrown/relay"
-	"github.com/scottbrown/relay/internal/acl"
+	"github.com/scottbrown/relay/internal/admin"
 	"github.com/scottbrown/relay/internal/audit"
 	"github.com/scottbrown/relay/internal/circuitbreaker"
 	"github.com/scottbrown/relay/internal/config"
@@ -22,7 +22,6 @@ import (
 	"github.com/scottbrown/relay/internal/forwarder"
 	"github.com/scottbrown/relay/internal/healthcheck"
 	"github.com/scottbrown/relay/internal/metrics"
-	"github.com/scottbrown/relay/internal/server"
 	"github.com/scottbrown/relay/internal/storage"
 
 	"github.com/spf13/cobra"
@@ -36,95 +35,14 @@ var rootCmd = &cobra.Command{
 	Run:     handleRootCmd,
 }
 
-// reloadConfig reloads the configuration file and updates all servers with reloadable settings
-func reloadConfig(configPath string, oldCfg *config.Config, servers []*server.Server) error {
-	// Load new configuration
-	newCfg, err := config.LoadConfig(configPath)
+// reloadConfig reloads the configuration file and reconciles the running listeners with it.
+// Listeners are added, removed or rebuilt as needed; unchanged listeners keep their connections.
+func reloadConfig(configPath string, listeners *listenerSet) error {
+	newCfg, err := config.ReloadConfig(configPath, listeners.listenAddrs())
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
-
-	// Verify listener count matches (cannot add/remove listeners without restart)
-	if len(newCfg.Listeners) != len(oldCfg.Listeners) {
-		return fmt.Errorf("cannot change number of listeners (requires restart): had %d, now %d", len(oldCfg.Listeners), len(newCfg.Listeners))
-	}
-
-	// Update each server with reloadable configuration
-	for i := range servers {
-		oldListener := oldCfg.Listeners[i]
-		newListener := newCfg.Listeners[i]
-
-		// Verify non-reloadable parameters haven't changed
-		if oldListener.Name != newListener.Name {
-			return fmt.Errorf("listener name changed (requires restart): %s -> %s", oldListener.Name, newListener.Name)
-		}
-		if oldListener.ListenAddr != newListener.ListenAddr {
-			return fmt.Errorf("listener %s: listen address changed (requires restart)", oldListener.Name)
-		}
-		if oldListener.OutputDir != newListener.OutputDir {
-			return fmt.Errorf("listener %s: output directory changed (requires restart)", oldListener.Name)
-		}
-		if oldListener.MaxLineBytes != newListener.MaxLineBytes {
-			return fmt.Errorf("listener %s: max line bytes changed (requires restart)", oldListener.Name)
-		}
-
-		// Check TLS changes
-		oldTLS := oldListener.TLS != nil
-		newTLS := newListener.TLS != nil
-		if oldTLS != newTLS {
-			return fmt.Errorf("listener %s: TLS configuration changed (requires restart)", oldListener.Name)
-		}
-		if oldTLS && newTLS {
-			if oldListener.TLS.CertFile != newListener.TLS.CertFile || oldListener.TLS.KeyFile != newListener.TLS.KeyFile {
-				return fmt.Errorf("listener %s: TLS certificate/key changed (requires restart)", oldListener.Name)
-			}
-		}
-
-		// Build reloadable server config
-		serverCfg := server.ReloadableConfig{
-			AllowedCIDRs: newListener.AllowedCIDRs,
-		}
-
-		// Determine forwarder config (handle both single and multi-target)
-		hasMultiTarget := false
-		if newCfg.Splunk != nil && len(newCfg.Splunk.HECTargets) > 0 {
-			hasMultiTarget = true
-		}
-		if newListener.Splunk != nil && len(newListener.Splunk.HECTargets) > 0 {
-			hasMultiTarget = true
-		}
-
-		if hasMultiTarget {
-			// Multi-target mode: extract first target's config as representative
-			// (UpdateConfig will apply to all targets in MultiHEC)
-			targets, _ := getHECTargetsAndRouting(newCfg.Splunk, newListener.Splunk)
-			if len(targets) > 0 {
-				serverCfg.ForwarderConfig.Token = targets[0].HECToken
-				serverCfg.ForwarderConfig.SourceType = targets[0].SourceType
-				if targets[0].Gzip != nil {
-					serverCfg.ForwarderConfig.UseGzip = *targets[0].Gzip
-				}
-			}
-		} else {
-			// Legacy single-target mode
-			hecCfg := mergeHECConfig(newCfg.Splunk, newListener.Splunk, nil) // DLQ not reloadable
-			serverCfg.ForwarderConfig.Token = hecCfg.Token
-			serverCfg.ForwarderConfig.SourceType = hecCfg.SourceType
-			serverCfg.ForwarderConfig.UseGzip = hecCfg.UseGzip
-		}
-
-		// Apply reloadable configuration to server
-		if err := servers[i].UpdateConfig(serverCfg); err != nil {
-			return fmt.Errorf("listener %s: failed to update config: %w", oldListener.Name, err)
-		}
-
-		slog.Info("reloaded configuration for listener", "listener", oldListener.Name)
-	}
-
-	// Update the old config reference with new config (for next reload)
-	*oldCfg = *newCfg
-
-	return nil
+	return listeners.reconcile(newCfg)
 }
 
 func handleRootCmd(cmd *cobra.Command, args []string) {
@@ -218,139 +136,45 @@ func handleRootCmd(cmd *cobra.Command, args []string) {
 		slog.Info("audit logging enabled", "log_file", auditCfg.LogFile, "format", auditCfg.Format)
 	}
 
-	// Create servers for each listener
-	servers := make([]*server.Server, 0, len(cfg.Listeners))
-	storageManagers := make([]*storage.Manager, 0, len(cfg.Listeners))
-	forwarders := make([]forwarder.Forwarder, 0, len(cfg.Listeners))
-	retentionDirs := make([]string, 0, len(cfg.Listeners)*2) // Track directories for retention policy
-
-	for _, listenerCfg := range cfg.Listeners {
-		// Initialize ACL
-		aclList, err := acl.New(listenerCfg.AllowedCIDRs)
-		if err != nil {
-			slog.Error("failed to initialize ACL", "listener", listenerCfg.Name, "error", err)
-			os.Exit(1)
-		}
+	// Create the listeners; nothing accepts connections until they are started
+	listeners, err := newListenerSet(cfg, auditLogger)
+	if err != nil {
+		slog.Error("failed to initialize listeners", "error", err)
+		os.Exit(1)
+	}
 
-		// Initialize storage with file prefix
-		storageMgr, err := storage.New(listenerCfg.OutputDir, listenerCfg.FilePrefix)
-		if err != nil {
-			slog.Error("failed to initialize storage", "listener", listenerCfg.Name, "error", err)
+	// Start the HTTP health and readiness server if enabled
+	if cfg.Health != nil && cfg.Health.Enabled {
+		httpHealthSrv := healthcheck.NewHTTP(cfg.Health.Addr, listeners.healthListeners(), readiness(cfg.Health.Readiness))
+		if err := httpHealthSrv.Start(); err != nil {
+			slog.Error("failed to start HTTP health server", "error", err)
 			os.Exit(1)
 		}
-		storageManagers = append(storageManagers, storageMgr)
-		retentionDirs = append(retentionDirs, listenerCfg.OutputDir)
-
-		// Initialize DLQ if configured
-		var dlqWriter *dlq.Writer
-		if listenerCfg.DLQ != nil && listenerCfg.DLQ.Enabled {
-			dlqDir := listenerCfg.DLQ.Dir
-			if dlqDir == "" {
-				dlqDir = filepath.Join(listenerCfg.OutputDir, "dlq")
-			}
-			dlqWriter, err = dlq.New(dlqDir)
-			if err != nil {
-				slog.Error("failed to initialize DLQ", "listener", listenerCfg.Name, "error", err)
-				os.Exit(1)
-			}
-			retentionDirs = append(retentionDirs, dlqDir)
-			slog.Info("initialized DLQ", "listener", listenerCfg.Name, "dir", dlqDir)
-		}
-
-		// Initialize HEC forwarder (single or multi-target)
-		var fwd forwarder.Forwarder
-		hasMultiTarget := false
-
-		// Check for multi-target configuration
-		if cfg.Splunk != nil && len(cfg.Splunk.HECTargets) > 0 {
-			hasMultiTarget = true
-		}
-		if listenerCfg.Splunk != nil && len(listenerCfg.Splunk.HECTargets) > 0 {
-			hasMultiTarget = true
-		}
-
-		if hasMultiTarget {
-			// Initialize multi-target forwarder
-			targets, routingMode := getHECTargetsAndRouting(cfg.Splunk, listenerCfg.Splunk)
-			multiFwd, err := forwarder.NewMulti(targets, routingMode)
-			if err != nil {
-				slog.Error("failed to initialize multi-target HEC forwarder", "listener", listenerCfg.Name, "error", err)
-				os.Exit(1)
-			}
-			fwd = multiFwd
-			slog.Info("initialized multi-target HEC forwarder",
-				"listener", listenerCfg.Name,
-				"targets", len(targets),
-				"mode", routingMode)
-		} else {
-			// Initialize single-target forwarder (legacy mode)
-			hecCfg := mergeHECConfig(cfg.Splunk, listenerCfg.Splunk, dlqWriter)
-			fwd = forwarder.New(hecCfg)
-			if hecCfg.URL != "" {
-				slog.Info("initialized single-target HEC forwarder", "listener", listenerCfg.Name)
-			}
-		}
-
-		forwarders = append(forwarders, fwd)
-
-		// Health check for HEC if configured
-		if fwd != nil {
-			slog.Info("testing Splunk HEC connectivity", "listener", listenerCfg.Name)
-			if err := fwd.HealthCheck(); err != nil {
-				// Only fail if HEC is actually configured (URL not empty)
-				if hasMultiTarget || (listenerCfg.Splunk != nil && listenerCfg.Splunk.HECURL != "") || (cfg.Splunk != nil && cfg.Splunk.HECURL != "") {
-					slog.Error("Splunk HEC health check failed", "listener", listenerCfg.Name, "error", err)
-					os.Exit(1)
-				}
-			} else {
-				slog.Info("Splunk HEC connectivity verified", "listener", listenerCfg.Name)
-			}
-		}
-
-		// Initialize server
-		var tlsCertFile, tlsKeyFile string
-		if listenerCfg.TLS != nil {
-			tlsCertFile = listenerCfg.TLS.CertFile
-			tlsKeyFile = listenerCfg.TLS.KeyFile
-		}
-
-		// Build server config with timeouts
-		serverCfg := server.Config{
-			ListenAddr:   listenerCfg.ListenAddr,
-			TLSCertFile:  tlsCertFile,
-			TLSKeyFile:   tlsKeyFile,
-			MaxLineBytes: listenerCfg.MaxLineBytes,
-		}
+		defer httpHealthSrv.Stop()
+		listeners.health = httpHealthSrv
+		slog.Info("HTTP health server listening", "addr", cfg.Health.Addr)
+	}
 
-		// Apply connection timeouts if configured
-		if listenerCfg.Timeout != nil {
-			if listenerCfg.Timeout.ReadSeconds > 0 {
-				serverCfg.ReadTimeout = time.Duration(listenerCfg.Timeout.ReadSeconds) * time.Second
-			}
-			if listenerCfg.Timeout.IdleSeconds > 0 {
-				serverCfg.IdleTimeout = time.Duration(listenerCfg.Timeout.IdleSeconds) * time.Second
-			}
-		}
+	// Reloads come from SIGHUP and the admin API; only one runs at a time
+	var reloadMu sync.Mutex
+	reload := func() error {
+		reloadMu.Lock()
+		defer reloadMu.Unlock()
+		return reloadConfig(configFile, listeners)
+	}
 
-		srv, err := server.New(serverCfg, aclList, storageMgr, fwd, auditLogger)
-		if err != nil {
-			slog.Error("failed to create server", "listener", listenerCfg.Name, "error", err)
+	// Start the admin API if enabled
+	if cfg.Admin != nil && cfg.Admin.Enabled {
+		adminSrv := admin.New(cfg.Admin.Socket, listeners.adminListeners(), reload, auditLogger)
+		if err := adminSrv.Start(); err != nil {
+			slog.Error("failed to start admin server", "error", err)
 			os.Exit(1)
 		}
-
-		servers = append(servers, srv)
-		slog.Info("initialized listener", "listener", listenerCfg.Name, "log_type", listenerCfg.LogType, "addr", listenerCfg.ListenAddr)
+		defer adminSrv.Stop()
+		listeners.admin = adminSrv
+		slog.Info("admin server listening", "socket", cfg.Admin.Socket)
 	}
 
-	// Cleanup storage managers on exit
-	defer func() {
-		for _, mgr := range storageManagers {
-			if err := mgr.Close(); err != nil {
-				slog.Warn("failed to close storage manager", "error", err)
-			}
-		}
-	}()
-
 	// Initialize and start retention worker if enabled
 	retentionCtx, cancelRetention := context.WithCancel(context.Background())
 	defer cancelRetention()
@@ -362,22 +186,15 @@ func handleRootCmd(cmd *cobra.Command, args []string) {
 			CheckInterval: time.Duration(cfg.Retention.CheckInterval) * time.Second,
 			CompressAge:   cfg.Retention.CompressAge,
 		}
-		retentionWorker := storage.NewRetentionWorker(retentionPolicy, retentionDirs...)
+		retentionWorker := storage.NewRetentionWorker(retentionPolicy, listeners.retentionDirs()...)
 		retentionWorker.Start(retentionCtx)
+		listeners.retention = retentionWorker
 	}
 
-	// Start all servers
-	serverErrCh := make(chan error, len(servers))
-	for i, srv := range servers {
-		name := cfg.Listeners[i].Name
-		go func(s *server.Server, n string) {
-			slog.Info("starting listener", "listener", n)
-			if err := s.Start(); err != nil {
-				serverErrCh <- fmt.Errorf("[%s] %w", n, err)
-			} else {
-				serverErrCh <- nil
-			}
-		}(srv, name)
+	// Start all listeners with their DLQ drain workers and storage tailers
+	if err := listeners.start(); err != nil {
+		slog.Error("failed to start listener", "error", err)
+		os.Exit(1)
 	}
 
 	// Signal handling for shutdown and reload
@@ -388,57 +205,32 @@ func handleRootCmd(cmd *cobra.Command, args []string) {
 	// Main event loop for signals and server errors
 	for {
 		select {
-		case err := <-serverErrCh:
-			if err != nil && !errors.Is(err, net.ErrClosed) {
-				slog.Error("server error", "error", err)
+		case exit := <-listeners.exits:
+			if !listeners.running(exit.listener) {
+				continue // Stopped by a reload
 			}
-			// If any server fails, gracefully shutdown all
-			slog.Info("initiating graceful shutdown of all servers")
-			shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-			defer cancel()
-
-			for i, srv := range servers {
-				if err := srv.Shutdown(shutdownCtx); err != nil {
-					slog.Warn("server shutdown error", "listener", cfg.Listeners[i].Name, "error", err)
-				}
+			if exit.err != nil && !errors.Is(exit.err, net.ErrClosed) {
+				slog.Error("server error", "listener", exit.listener.cfg.Name, "error", exit.err)
 			}
-			goto shutdown
+			// If any server fails, gracefully shutdown all
+			listeners.shutdown()
+			return
 		case sig := <-sigCh:
 			switch sig {
 			case syscall.SIGHUP:
 				slog.Info("received SIGHUP, reloading configuration")
-				if err := reloadConfig(configFile, cfg, servers); err != nil {
+				if err := reload(); err != nil {
 					slog.Error("failed to reload configuration", "error", err)
 				} else {
 					slog.Info("configuration reloaded successfully")
 				}
 			case syscall.SIGINT, syscall.SIGTERM:
 				slog.Info("received signal, initiating graceful shutdown", "signal", sig.String())
-				shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-				defer cancel()
-
-				for i, srv := range servers {
-					if err := srv.Shutdown(shutdownCtx); err != nil {
-						slog.Warn("server shutdown error", "listener", cfg.Listeners[i].Name, "error", err)
-					}
-				}
-				goto shutdown
+				listeners.shutdown()
+				return
 			}
 		}
 	}
-
-shutdown:
-
-	// Shutdown forwarders with timeout
-	slog.Info("shutting down forwarders")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	for _, fwd := range forwarders {
-		if err := fwd.Shutdown(ctx); err != nil {
-			slog.Warn("failed to shutdown forwarder", "error", err)
-		}
-	}
 }
 
 func mergeHECConfig(global, perListener *config.SplunkConfig, dlqWriter *dlq.Writer) forwarder.Config {
@@ -453,12 +245,19 @@ func mergeHECConfig(global, perListener *config.SplunkConfig, dlqWriter *dlq.Wri
 		if global.Gzip != nil {
 			cfg.UseGzip = *global.Gzip
 		}
+		cfg.Endpoint = global.Endpoint
+		cfg.Event = forwarder.EventConfig{
+			Index:  global.Index,
+			Source: global.Source,
+			Fields: global.Fields,
+		}
 		if global.ClientTimeout > 0 {
 			cfg.ClientTimeout = time.Duration(global.ClientTimeout) * time.Second
 		}
 		cfg.CircuitBreaker = mergeCircuitBreakerConfig(global.CircuitBreaker, nil)
 		cfg.Batch = mergeBatchConfig(global.Batch, nil)
 		cfg.Retry = mergeRetryConfig(global.Retry, nil)
+		cfg.Ack = mergeAckConfig(global.Ack, nil)
 		cfg.Transport = mergeTransportConfig(global.Transport, nil)
 	}
 
@@ -476,6 +275,18 @@ func mergeHECConfig(global, perListener *config.SplunkConfig, dlqWriter *dlq.Wri
 		if perListener.Gzip != nil {
 			cfg.UseGzip = *perListener.Gzip
 		}
+		if perListener.Endpoint != "" {
+			cfg.Endpoint = perListener.Endpoint
+		}
+		if perListener.Index != "" {
+			cfg.Event.Index = perListener.Index
+		}
+		if perListener.Source != "" {
+			cfg.Event.Source = perListener.Source
+		}
+		if len(perListener.Fields) > 0 {
+			cfg.Event.Fields = perListener.Fields
+		}
 		if perListener.ClientTimeout > 0 {
 			cfg.ClientTimeout = time.Duration(perListener.ClientTimeout) * time.Second
 		}
@@ -484,11 +295,13 @@ func mergeHECConfig(global, perListener *config.SplunkConfig, dlqWriter *dlq.Wri
 			cfg.CircuitBreaker = mergeCircuitBreakerConfig(global.CircuitBreaker, perListener.CircuitBreaker)
 			cfg.Batch = mergeBatchConfig(global.Batch, perListener.Batch)
 			cfg.Retry = mergeRetryConfig(global.Retry, perListener.Retry)
+			cfg.Ack = mergeAckConfig(global.Ack, perListener.Ack)
 			cfg.Transport = mergeTransportConfig(global.Transport, perListener.Transport)
 		} else {
 			cfg.CircuitBreaker = mergeCircuitBreakerConfig(nil, perListener.CircuitBreaker)
 			cfg.Batch = mergeBatchConfig(nil, perListener.Batch)
 			cfg.Retry = mergeRetryConfig(nil, perListener.Retry)
+			cfg.Ack = mergeAckConfig(nil, perListener.Ack)
 			cfg.Transport = mergeTransportConfig(nil, perListener.Transport)
 		}
 	}
@@ -630,6 +443,32 @@ func mergeRetryConfig(global, perListener *config.RetryConfig) forwarder.RetryCo
 	return retryCfg
 }
 
+func mergeAckConfig(global, perListener *config.AckConfig) forwarder.AckConfig {
+	// Start with defaults
+	ackCfg := forwarder.AckConfig{
+		Enabled:      false,
+		Timeout:      60 * time.Second,
+		PollInterval: 1 * time.Second,
+	}
+
+	for _, cfg := range []*config.AckConfig{global, perListener} {
+		if cfg == nil {
+			continue
+		}
+		if cfg.Enabled != nil {
+			ackCfg.Enabled = *cfg.Enabled
+		}
+		if cfg.Timeout > 0 {
+			ackCfg.Timeout = time.Duration(cfg.Timeout) * time.Second
+		}
+		if cfg.PollInterval > 0 {
+			ackCfg.PollInterval = time.Duration(cfg.PollInterval) * time.Second
+		}
+	}
+
+	return ackCfg
+}
+
 func mergeTransportConfig(global, perListener *config.TransportConfig) forwarder.TransportConfig {
 	// Start with defaults
 	transportCfg := forwarder.TransportConfig{
@@ -674,9 +513,9 @@ func mergeTransportConfig(global, perListener *config.TransportConfig) forwarder
 	return transportCfg
 }
 
-func getHECTargetsAndRouting(global, perListener *config.SplunkConfig) ([]config.HECTarget, config.RoutingMode) {
+func getHECTargetsAndRouting(global, perListener *config.SplunkConfig) ([]config.HECTarget, config.RoutingConfig) {
 	var targets []config.HECTarget
-	var routingMode config.RoutingMode
+	var routing config.RoutingConfig
 
 	// Per-listener targets override global targets
 	if perListener != nil && len(perListener.HECTargets) > 0 {
@@ -685,15 +524,15 @@ func getHECTargetsAndRouting(global, perListener *config.SplunkConfig) ([]config
 		targets = global.HECTargets
 	}
 
-	// Get routing mode (per-listener overrides global)
+	// Get routing (per-listener overrides global)
 	if perListener != nil && perListener.Routing != nil {
-		routingMode = perListener.Routing.Mode
+		routing = *perListener.Routing
 	} else if global != nil && global.Routing != nil {
-		routingMode = global.Routing.Mode
+		routing = *global.Routing
 	} else {
 		// Default to "all" mode
-		routingMode = config.RoutingModeAll
+		routing.Mode = config.RoutingModeAll
 	}
 
-	return targets, routingMode
+	return targets, routing
 }
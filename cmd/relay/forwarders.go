@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/scottbrown/relay/internal/config"
+	"github.com/scottbrown/relay/internal/dlq"
+	"github.com/scottbrown/relay/internal/forwarder"
+	"github.com/scottbrown/relay/internal/redact"
+	"github.com/scottbrown/relay/internal/tailer"
+)
+
+// forwarderOptions adjusts how a listener's forwarder is built.
+type forwarderOptions struct {
+	dlq *dlq.Writer // Dead letter queue for failed forwards (single-target only)
+	// synchronous disables batching so Forward reports the real delivery result.
+	// Used by tools that must know whether each payload reached HEC.
+	synchronous bool
+	// redacted skips forward redaction for lines that were redacted before they were
+	// dead-lettered; pseudonymising a pseudonym again would change it.
+	redacted bool
+}
+
+// usesMultiTarget reports whether a listener forwards to hec_targets rather than a single HEC.
+func usesMultiTarget(global, perListener *config.SplunkConfig) bool {
+	if global != nil && len(global.HECTargets) > 0 {
+		return true
+	}
+	return perListener != nil && len(perListener.HECTargets) > 0
+}
+
+// hecConfigured reports whether HEC forwarding is configured for the listener.
+func hecConfigured(cfg *config.Config, listenerCfg config.ListenerConfig) bool {
+	if usesMultiTarget(cfg.Splunk, listenerCfg.Splunk) {
+		return true
+	}
+	return mergeHECConfig(cfg.Splunk, listenerCfg.Splunk, nil).URL != ""
+}
+
+// buildForwarder creates the single or multi-target forwarder for a listener.
+func buildForwarder(cfg *config.Config, listenerCfg config.ListenerConfig, opts forwarderOptions) (forwarder.Forwarder, error) {
+	if usesMultiTarget(cfg.Splunk, listenerCfg.Splunk) {
+		targets, routing := getHECTargetsAndRouting(cfg.Splunk, listenerCfg.Splunk)
+		targets = withDefaultSource(targets, listenerCfg.Name)
+		if opts.synchronous {
+			targets = withoutBatching(targets)
+		}
+		redactors := make(map[string]*redact.Redactor)
+		if !opts.redacted {
+			for _, target := range targets {
+				r, err := forwardRedactor(listenerCfg, target.Redact)
+				if err != nil {
+					return nil, fmt.Errorf("target %s: %w", target.Name, err)
+				}
+				redactors[target.Name] = r
+			}
+		}
+		multiFwd, err := forwarder.NewMultiWithRouting(listenerCfg.Name, targets, routing)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize multi-target HEC forwarder: %w", err)
+		}
+		for name, r := range redactors {
+			if r == nil {
+				continue
+			}
+			if err := multiFwd.SetRedactor(name, r); err != nil {
+				return nil, err
+			}
+		}
+		return multiFwd, nil
+	}
+
+	hecCfg := mergeHECConfig(cfg.Splunk, listenerCfg.Splunk, opts.dlq)
+	hecCfg.Listener = listenerCfg.Name
+	if hecCfg.Endpoint == forwarder.EndpointEvent && hecCfg.Event.Source == "" {
+		hecCfg.Event.Source = listenerCfg.Name
+	}
+	if opts.synchronous {
+		hecCfg.Batch.Enabled = false
+	}
+	if !opts.redacted {
+		r, err := forwardRedactor(listenerCfg, nil)
+		if err != nil {
+			return nil, err
+		}
+		hecCfg.Redactor = r
+	}
+	return forwarder.New(hecCfg), nil
+}
+
+// forwardRedactor returns the redactor applied to lines as they are sent to a target: the
+// listener's forward_rules followed by the target's own rules. It returns nil if there are none.
+func forwardRedactor(listenerCfg config.ListenerConfig, targetRules []config.RedactionRule) (*redact.Redactor, error) {
+	rd := listenerCfg.Redaction
+	if rd == nil {
+		rd = &config.RedactionConfig{}
+	}
+	r, err := redact.New(config.RedactRules(rd.ForwardRules, targetRules), []byte(rd.HMACKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize forward redaction: %w", err)
+	}
+	return r, nil
+}
+
+// withoutBatching returns a copy of targets with batching disabled.
+func withoutBatching(targets []config.HECTarget) []config.HECTarget {
+	disabled := false
+	out := make([]config.HECTarget, len(targets))
+	for i, target := range targets {
+		batch := config.BatchConfig{}
+		if target.Batch != nil {
+			batch = *target.Batch
+		}
+		batch.Enabled = &disabled
+		target.Batch = &batch
+		out[i] = target
+	}
+	return out
+}
+
+// withDefaultSource returns a copy of targets with event sources defaulting to the listener name.
+func withDefaultSource(targets []config.HECTarget, listenerName string) []config.HECTarget {
+	out := make([]config.HECTarget, len(targets))
+	for i, target := range targets {
+		if target.Endpoint == forwarder.EndpointEvent && target.Source == "" {
+			target.Source = listenerName
+		}
+		out[i] = target
+	}
+	return out
+}
+
+// newDLQDrainer creates the DLQ drain worker for a listener.
+// Entries are re-sent through a separate synchronous forwarder without a DLQ so failures are
+// not dead-lettered twice; live is the listener's forwarder whose circuit breaker is watched.
+// The drain forwarder is returned so it can be shut down with the others.
+func newDLQDrainer(cfg *config.Config, listenerCfg config.ListenerConfig, live forwarder.Forwarder) (*dlq.Drainer, forwarder.Forwarder, error) {
+	stater, ok := live.(forwarder.CircuitStater)
+	if !ok {
+		return nil, nil, fmt.Errorf("forwarder does not expose circuit breaker state")
+	}
+
+	drainFwd, err := buildForwarder(cfg, listenerCfg, forwarderOptions{synchronous: true, redacted: true})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	drainCfg := listenerCfg.DLQ.Drain
+	drainConfig := dlq.DrainConfig{
+		Name:          listenerCfg.Name,
+		Dir:           dlqDir(listenerCfg),
+		RateLimit:     drainCfg.RateLimit,
+		CheckInterval: time.Duration(drainCfg.CheckInterval) * time.Second,
+		State:         stater.CircuitState,
+	}
+	if pauser, ok := live.(forwarder.Pauser); ok {
+		drainConfig.Paused = pauser.Paused
+	}
+	drainer, err := dlq.NewDrainer(drainFwd, drainConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	return drainer, drainFwd, nil
+}
+
+// tailEnabled reports whether a listener forwards from its stored files instead of directly.
+func tailEnabled(listenerCfg config.ListenerConfig) bool {
+	return listenerCfg.Tail != nil && listenerCfg.Tail.Enabled
+}
+
+// newTailer creates the storage tailer for a listener in tail mode.
+// fwd should be synchronous so a batch is only checkpointed once HEC has accepted it.
+func newTailer(listenerCfg config.ListenerConfig, fwd forwarder.Forwarder) (*tailer.Tailer, error) {
+	tailCfg := listenerCfg.Tail
+	return tailer.New(fwd, tailer.Config{
+		Name:           listenerCfg.Name,
+		Dir:            listenerCfg.OutputDir,
+		FilePrefix:     listenerCfg.FilePrefix,
+		CheckpointPath: tailCfg.CheckpointFile,
+		BatchMaxLines:  tailCfg.BatchMaxLines,
+		BatchMaxBytes:  tailCfg.BatchMaxBytes,
+		PollInterval:   time.Duration(tailCfg.PollInterval) * time.Second,
+	})
+}
+
+// findListener returns the listener with the given name.
+func findListener(cfg *config.Config, name string) (config.ListenerConfig, error) {
+	for _, listenerCfg := range cfg.Listeners {
+		if listenerCfg.Name == name {
+			return listenerCfg, nil
+		}
+	}
+	return config.ListenerConfig{}, fmt.Errorf("listener %q not found in configuration", name)
+}
+
+// queueDir returns the forward queue directory for a listener, applying the {output_dir}/queue default.
+func queueDir(listenerCfg config.ListenerConfig) string {
+	if listenerCfg.Queue != nil && listenerCfg.Queue.Dir != "" {
+		return listenerCfg.Queue.Dir
+	}
+	return filepath.Join(listenerCfg.OutputDir, "queue")
+}
+
+// dlqDir returns the DLQ directory for a listener, applying the {output_dir}/dlq default.
+func dlqDir(listenerCfg config.ListenerConfig) string {
+	if listenerCfg.DLQ != nil && listenerCfg.DLQ.Dir != "" {
+		return listenerCfg.DLQ.Dir
+	}
+	return filepath.Join(listenerCfg.OutputDir, "dlq")
+}
+
+// quarantineDir returns the quarantine directory for a listener, applying the {output_dir}/quarantine default.
+func quarantineDir(listenerCfg config.ListenerConfig) string {
+	if listenerCfg.Quarantine != nil && listenerCfg.Quarantine.Dir != "" {
+		return listenerCfg.Quarantine.Dir
+	}
+	return filepath.Join(listenerCfg.OutputDir, "quarantine")
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/scottbrown/relay/internal/config"
+	"github.com/scottbrown/relay/internal/dlq"
+	"github.com/scottbrown/relay/internal/forwarder"
+	"github.com/scottbrown/relay/internal/healthcheck"
+	"github.com/scottbrown/relay/internal/server"
+	"github.com/scottbrown/relay/internal/storage"
+)
+
+// healthListener builds the status sources the HTTP health server reports for a listener.
+func healthListener(cfg *config.Config, listenerCfg config.ListenerConfig, srv *server.Server, storageMgr *storage.Manager, fwd forwarder.Forwarder) healthcheck.Listener {
+	l := healthcheck.Listener{
+		Name:      listenerCfg.Name,
+		LogType:   listenerCfg.LogType,
+		Accepting: srv.Accepting,
+		LastEvent: srv.LastEvent,
+		Storage:   storageMgr.CheckWritable,
+	}
+
+	if listenerCfg.DLQ != nil && listenerCfg.DLQ.Enabled {
+		dir := dlqDir(listenerCfg)
+		l.DLQBacklog = func() int64 {
+			size, err := dlq.Size(dir)
+			if err != nil {
+				slog.Warn("failed to measure DLQ backlog", "listener", listenerCfg.Name, "error", err)
+			}
+			return size
+		}
+	}
+
+	if reporter, ok := fwd.(forwarder.TargetReporter); ok && hecConfigured(cfg, listenerCfg) {
+		l.Targets = reporter.Targets
+	}
+
+	return l
+}
+
+// readiness converts the configured readiness conditions, applying defaults.
+func readiness(cfg *config.ReadinessConfig) healthcheck.Readiness {
+	r := healthcheck.Readiness{
+		CircuitOpen:       true,
+		StorageUnwritable: true,
+	}
+	if cfg == nil {
+		return r
+	}
+	if cfg.CircuitOpen != nil {
+		r.CircuitOpen = *cfg.CircuitOpen
+	}
+	if cfg.StorageUnwritable != nil {
+		r.StorageUnwritable = *cfg.StorageUnwritable
+	}
+	r.MaxDLQBacklogBytes = cfg.MaxDLQBacklogBytes
+	r.MaxEventAge = time.Duration(cfg.MaxEventAge) * time.Second
+	return r
+}
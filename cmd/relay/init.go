@@ -1,12 +1,57 @@
 package main
 
+import "github.com/scottbrown/relay/internal/config"
+
 func init() {
 	// Add subcommands
 	rootCmd.AddCommand(templateCmd)
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configSchemaCmd)
 	rootCmd.AddCommand(smokeTestCmd)
+	rootCmd.AddCommand(dlqCmd)
+	dlqCmd.AddCommand(dlqReplayCmd)
+	rootCmd.AddCommand(backfillCmd)
+	rootCmd.AddCommand(ctlCmd)
+	ctlCmd.AddCommand(ctlStatusCmd)
+	ctlCmd.AddCommand(ctlPauseCmd)
+	ctlCmd.AddCommand(ctlResumeCmd)
+	ctlCmd.AddCommand(ctlResetCircuitCmd)
+	ctlCmd.AddCommand(ctlFlushCmd)
+	ctlCmd.AddCommand(ctlReloadCmd)
+	ctlCmd.AddCommand(ctlConnectionsCmd)
 
 	// Root command flags
 	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "f", "", "Path to configuration file")
 	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Log level (debug, info, warn, error)")
 	rootCmd.PersistentFlags().StringVar(&metricsAddr, "metrics-addr", ":9017", "Metrics server address (empty to disable)")
+
+	// config validate flags
+	configValidateCmd.Flags().BoolVar(&validateNoBind, "no-bind", false, "Do not check that listen addresses are available")
+
+	// dlq replay flags
+	dlqReplayCmd.Flags().StringVarP(&replayListener, "listener", "l", "", "Listener whose DLQ and HEC forwarder are used (required)")
+	dlqReplayCmd.Flags().StringVar(&replaySince, "since", "", "Only replay entries at or after this date (YYYY-MM-DD or RFC 3339)")
+	dlqReplayCmd.Flags().StringVar(&replayUntil, "until", "", "Only replay entries up to this date, inclusive (YYYY-MM-DD or RFC 3339)")
+	dlqReplayCmd.Flags().StringVar(&replayConnID, "conn-id", "", "Only replay entries with this connection ID")
+	dlqReplayCmd.Flags().StringVar(&replayErrorContains, "error-contains", "", "Only replay entries whose error contains this text")
+	dlqReplayCmd.Flags().StringVar(&replayArchiveDir, "archive-dir", "", "Directory for delivered entries (default: {dlq_dir}/replayed)")
+	dlqReplayCmd.Flags().BoolVar(&replayIncludeToday, "include-today", false, "Also replay today's DLQ file (stop the relay first)")
+
+	// backfill flags
+	backfillCmd.Flags().StringVarP(&backfillListener, "listener", "l", "", "Listener whose stored files and HEC forwarder are used (required)")
+	backfillCmd.Flags().StringVar(&backfillSince, "since", "", "First day to send (YYYY-MM-DD or RFC 3339, required)")
+	backfillCmd.Flags().StringVar(&backfillUntil, "until", "", "Last day to send, inclusive (YYYY-MM-DD or RFC 3339, default: newest file)")
+	backfillCmd.Flags().BoolVar(&backfillDryRun, "dry-run", false, "Count the files, lines and bytes that would be sent without sending")
+	backfillCmd.Flags().IntVar(&backfillRateLimit, "rate-limit", 1000, "Maximum lines sent per second (0 = unlimited)")
+	backfillCmd.Flags().IntVar(&backfillBatchLines, "batch-lines", 500, "Maximum lines per HEC request")
+	backfillCmd.Flags().StringVar(&backfillProgressFile, "progress-file", "", "Progress file for resuming (default: {output_dir}/{file_prefix}.backfill-progress.json)")
+
+	// ctl flags
+	ctlCmd.PersistentFlags().StringVar(&ctlSocket, "socket", config.DefaultAdminSocket, "Admin socket of the running relay")
+	ctlCmd.PersistentFlags().StringVarP(&ctlListener, "listener", "l", "", "Listener to control (default: all listeners)")
+	ctlPauseCmd.Flags().StringVarP(&ctlTarget, "target", "t", "", "HEC target to pause (default: all targets)")
+	ctlResumeCmd.Flags().StringVarP(&ctlTarget, "target", "t", "", "HEC target to resume (default: all targets)")
+	ctlResetCircuitCmd.Flags().StringVarP(&ctlTarget, "target", "t", "", "HEC target whose circuit breaker is reset (default: all targets)")
 }
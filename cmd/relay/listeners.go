@@ -0,0 +1,757 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/scottbrown/relay/internal/acl"
+	"github.com/scottbrown/relay/internal/admin"
+	"github.com/scottbrown/relay/internal/audit"
+	"github.com/scottbrown/relay/internal/config"
+	"github.com/scottbrown/relay/internal/dlq"
+	"github.com/scottbrown/relay/internal/filter"
+	"github.com/scottbrown/relay/internal/forwarder"
+	"github.com/scottbrown/relay/internal/healthcheck"
+	"github.com/scottbrown/relay/internal/logschema"
+	"github.com/scottbrown/relay/internal/logtypes"
+	"github.com/scottbrown/relay/internal/metrics"
+	"github.com/scottbrown/relay/internal/quarantine"
+	"github.com/scottbrown/relay/internal/queue"
+	"github.com/scottbrown/relay/internal/redact"
+	"github.com/scottbrown/relay/internal/server"
+	"github.com/scottbrown/relay/internal/storage"
+	"github.com/scottbrown/relay/internal/tailer"
+)
+
+// listenerDrainTimeout is how long a stopping listener waits for its connections to close
+// before closing them.
+const listenerDrainTimeout = 30 * time.Second
+
+// listener is everything running for one configured listener. A reload that changes
+// more than the reloadable and HEC settings replaces the listener as a whole.
+type listener struct {
+	cfg        config.ListenerConfig
+	relayCfg   *config.Config // Configuration the listener was last built or updated from
+	srv        *server.Server
+	storage    *storage.Manager
+	dlq        *dlq.Writer           // nil without a DLQ
+	quarantine *quarantine.Writer    // nil without a quarantine store
+	fwd        forwarder.Forwarder   // Forwarder the server sends to
+	forwarders []forwarder.Forwarder // Every forwarder to shut down, including the DLQ drain's
+	drainer    *dlq.Drainer          // nil without DLQ drain
+	tailer     *tailer.Tailer        // nil without tail mode
+	cancel     context.CancelFunc    // Stops the drainer and tailer
+	dirs       []string              // Directories covered by the retention policy
+}
+
+// listenerExit reports that a listener's server stopped accepting connections.
+type listenerExit struct {
+	listener *listener
+	err      error
+}
+
+// newListener creates a listener's storage, DLQ, forwarders, workers and server.
+// Nothing listens or runs until start is called. Anything created before an error is released.
+func newListener(cfg *config.Config, listenerCfg config.ListenerConfig, auditLogger *audit.Logger) (_ *listener, err error) {
+	l := &listener{cfg: listenerCfg, relayCfg: cfg}
+	defer func() {
+		if err != nil {
+			l.release()
+		}
+	}()
+
+	// Initialize ACL
+	aclList, err := acl.New(listenerCfg.AllowedCIDRs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize ACL: %w", err)
+	}
+
+	// Initialize storage with file prefix
+	l.storage, err = storage.New(listenerCfg.OutputDir, listenerCfg.FilePrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	l.storage.SetName(listenerCfg.Name)
+	l.dirs = append(l.dirs, listenerCfg.OutputDir)
+
+	// Initialize DLQ if configured
+	if listenerCfg.DLQ != nil && listenerCfg.DLQ.Enabled {
+		dir := dlqDir(listenerCfg)
+		l.dlq, err = dlq.New(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize DLQ: %w", err)
+		}
+		l.dlq.SetName(listenerCfg.Name)
+		l.dirs = append(l.dirs, dir, dlq.DrainedDir(dir))
+		slog.Info("initialized DLQ", "listener", listenerCfg.Name, "dir", dir)
+	}
+
+	// Initialize quarantine store if configured
+	if listenerCfg.Quarantine != nil && listenerCfg.Quarantine.Enabled {
+		dir := quarantineDir(listenerCfg)
+		l.quarantine, err = quarantine.New(dir, listenerCfg.Quarantine.MaxDataBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize quarantine: %w", err)
+		}
+		l.quarantine.SetName(listenerCfg.Name)
+		l.dirs = append(l.dirs, dir)
+		slog.Info("initialized quarantine", "listener", listenerCfg.Name, "dir", dir)
+	}
+
+	// Initialize HEC forwarder (single or multi-target)
+	hasMultiTarget := usesMultiTarget(cfg.Splunk, listenerCfg.Splunk)
+	// In tail mode the forwarder is synchronous so the tailer checkpoints only delivered batches
+	l.fwd, err = buildForwarder(cfg, listenerCfg, forwarderOptions{dlq: l.dlq, synchronous: tailEnabled(listenerCfg)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize HEC forwarder: %w", err)
+	}
+	l.forwarders = append(l.forwarders, l.fwd)
+	if hasMultiTarget {
+		targets, routing := getHECTargetsAndRouting(cfg.Splunk, listenerCfg.Splunk)
+		slog.Info("initialized multi-target HEC forwarder",
+			"listener", listenerCfg.Name,
+			"targets", len(targets),
+			"mode", routing.Mode)
+	} else if hecConfigured(cfg, listenerCfg) {
+		slog.Info("initialized single-target HEC forwarder", "listener", listenerCfg.Name)
+	}
+
+	// Initialize DLQ drain worker if configured
+	if l.dlq != nil && listenerCfg.DLQ.Drain != nil && listenerCfg.DLQ.Drain.Enabled {
+		drainer, drainFwd, err := newDLQDrainer(cfg, listenerCfg, l.fwd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize DLQ drain: %w", err)
+		}
+		l.drainer = drainer
+		l.forwarders = append(l.forwarders, drainFwd)
+	}
+
+	// Initialize storage tailer if tail mode is enabled
+	if tailEnabled(listenerCfg) {
+		l.tailer, err = newTailer(listenerCfg, l.fwd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize storage tailer: %w", err)
+		}
+	}
+
+	// Health check for HEC if configured
+	if hecConfigured(cfg, listenerCfg) {
+		slog.Info("testing Splunk HEC connectivity", "listener", listenerCfg.Name)
+		if err := l.fwd.HealthCheck(); err != nil {
+			return nil, fmt.Errorf("HEC health check failed: %w", err)
+		}
+		slog.Info("Splunk HEC connectivity verified", "listener", listenerCfg.Name)
+	}
+
+	// Build server config with timeouts
+	serverCfg := server.Config{
+		Name:         listenerCfg.Name,
+		ListenAddr:   listenerCfg.ListenAddr,
+		MaxLineBytes: listenerCfg.MaxLineBytes,
+		StoreOnly:    tailEnabled(listenerCfg),
+		Quarantine:   l.quarantine,
+	}
+	if listenerCfg.TLS != nil {
+		serverCfg.TLSCertFile = listenerCfg.TLS.CertFile
+		serverCfg.TLSKeyFile = listenerCfg.TLS.KeyFile
+		serverCfg.TLSClientCAFile = listenerCfg.TLS.ClientCAFile
+		serverCfg.TLSClientAuth = listenerCfg.TLS.ClientAuth
+		serverCfg.TLSAllowedClients = listenerCfg.TLS.AllowedClients
+	}
+
+	// Check lines against the log type's schema if configured
+	if sv := listenerCfg.SchemaValidation; sv != nil && sv.Enabled {
+		serverCfg.Schema, _ = logschema.For(logtypes.LogType(listenerCfg.LogType)) // Checked by config validation
+		serverCfg.SchemaLogEvery = sv.LogEvery
+		serverCfg.SchemaQuarantine = sv.Action == config.SchemaActionQuarantine // Checked to have a quarantine store
+		slog.Info("enabled schema validation", "listener", listenerCfg.Name, "action", sv.Action)
+	}
+
+	// Redact fields before lines are stored if configured
+	if rd := listenerCfg.Redaction; rd != nil && len(rd.Rules) > 0 {
+		serverCfg.Redactor, err = redact.New(config.RedactRules(rd.Rules), []byte(rd.HMACKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize redaction: %w", err)
+		}
+		slog.Info("enabled redaction", "listener", listenerCfg.Name, "rules", len(rd.Rules))
+	}
+
+	// Drop, sample or route lines by their field values if configured
+	if len(listenerCfg.Filters) > 0 {
+		serverCfg.Filter, err = filter.New(config.FilterRules(listenerCfg.Filters))
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize filters: %w", err)
+		}
+		slog.Info("enabled filters", "listener", listenerCfg.Name, "rules", len(listenerCfg.Filters))
+	}
+
+	// Apply connection timeouts if configured
+	if listenerCfg.Timeout != nil {
+		if listenerCfg.Timeout.ReadSeconds > 0 {
+			serverCfg.ReadTimeout = time.Duration(listenerCfg.Timeout.ReadSeconds) * time.Second
+		}
+		if listenerCfg.Timeout.IdleSeconds > 0 {
+			serverCfg.IdleTimeout = time.Duration(listenerCfg.Timeout.IdleSeconds) * time.Second
+		}
+	}
+
+	// Open the durable forward queue if configured (closed by the server on shutdown)
+	if listenerCfg.Queue != nil && listenerCfg.Queue.Enabled {
+		serverCfg.Queue, err = queue.Open(queue.Config{
+			Name:         listenerCfg.Name,
+			Dir:          queueDir(listenerCfg),
+			MaxBytes:     listenerCfg.Queue.MaxBytes,
+			SegmentBytes: listenerCfg.Queue.SegmentBytes,
+			MaxLineBytes: listenerCfg.MaxLineBytes,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to open forward queue: %w", err)
+		}
+		slog.Info("initialized forward queue", "listener", listenerCfg.Name, "dir", queueDir(listenerCfg))
+	}
+
+	l.srv, err = server.New(serverCfg, aclList, l.storage, l.fwd, auditLogger)
+	if err != nil {
+		if serverCfg.Queue != nil {
+			_ = serverCfg.Queue.Close()
+		}
+		return nil, fmt.Errorf("failed to create server: %w", err)
+	}
+
+	metrics.RegisterListener(listenerCfg.Name, listenerCfg.LogType)
+	slog.Info("initialized listener", "listener", listenerCfg.Name, "log_type", listenerCfg.LogType, "addr", listenerCfg.ListenAddr)
+	return l, nil
+}
+
+// start listens on the listener's address, then starts its workers and accepts connections
+// in the background. exits receives the result when the server stops accepting.
+func (l *listener) start(exits chan<- listenerExit) error {
+	if err := l.srv.Listen(); err != nil {
+		return err
+	}
+
+	l.startWorkers()
+
+	go func() {
+		slog.Info("starting listener", "listener", l.cfg.Name)
+		exits <- listenerExit{listener: l, err: l.srv.Start()}
+	}()
+	return nil
+}
+
+// shutdown stops accepting connections and waits for active ones until ctx expires.
+func (l *listener) shutdown(ctx context.Context) {
+	if err := l.srv.Shutdown(ctx); err != nil {
+		slog.Warn("server shutdown error", "listener", l.cfg.Name, "error", err)
+	}
+}
+
+// release stops the listener's workers, then shuts down its forwarders and closes its files.
+// Workers stop first so they do not send through forwarders that are shutting down.
+// Unsent DLQ entries and tailed lines stay on disk for the next start.
+func (l *listener) release() {
+	l.stopWorkers()
+	shutdownForwarders(l.cfg.Name, l.forwarders)
+
+	if l.storage != nil {
+		if err := l.storage.Close(); err != nil {
+			slog.Warn("failed to close storage manager", "listener", l.cfg.Name, "error", err)
+		}
+	}
+	if l.dlq != nil {
+		if err := l.dlq.Close(); err != nil {
+			slog.Warn("failed to close DLQ", "listener", l.cfg.Name, "error", err)
+		}
+	}
+	if l.quarantine != nil {
+		if err := l.quarantine.Close(); err != nil {
+			slog.Warn("failed to close quarantine", "listener", l.cfg.Name, "error", err)
+		}
+	}
+}
+
+// startWorkers starts the DLQ drain worker and tailer, if the listener has them.
+func (l *listener) startWorkers() {
+	ctx, cancel := context.WithCancel(context.Background())
+	l.cancel = cancel
+	if l.drainer != nil {
+		l.drainer.Start(ctx)
+	}
+	if l.tailer != nil {
+		l.tailer.Start(ctx)
+	}
+}
+
+// stopWorkers stops the DLQ drain worker and tailer and waits for them to exit.
+// It does nothing if startWorkers has not been called.
+func (l *listener) stopWorkers() {
+	if l.cancel == nil {
+		return
+	}
+	l.cancel()
+	l.cancel = nil
+	if l.drainer != nil {
+		l.drainer.Wait()
+	}
+	if l.tailer != nil {
+		l.tailer.Wait()
+	}
+}
+
+// replaceForwarder builds the forwarders for listenerCfg and swaps them into the running
+// listener, so HEC changes apply without closing connections. The new forwarder must pass
+// its health check; otherwise the listener keeps the previous one. The DLQ drain worker and
+// tailer are restarted with the new forwarder and resume from their checkpoints. The previous
+// forwarders are flushed and shut down, and targets that were paused stay paused.
+func (l *listener) replaceForwarder(cfg *config.Config, listenerCfg config.ListenerConfig) (err error) {
+	fwd, err := buildForwarder(cfg, listenerCfg, forwarderOptions{dlq: l.dlq, synchronous: tailEnabled(listenerCfg)})
+	if err != nil {
+		return fmt.Errorf("failed to initialize HEC forwarder: %w", err)
+	}
+	forwarders := []forwarder.Forwarder{fwd}
+	defer func() {
+		if err != nil {
+			shutdownForwarders(listenerCfg.Name, forwarders)
+		}
+	}()
+
+	if hecConfigured(cfg, listenerCfg) {
+		if err := fwd.HealthCheck(); err != nil {
+			return fmt.Errorf("HEC health check failed: %w", err)
+		}
+	}
+
+	var drainer *dlq.Drainer
+	if l.drainer != nil {
+		var drainFwd forwarder.Forwarder
+		drainer, drainFwd, err = newDLQDrainer(cfg, listenerCfg, fwd)
+		if err != nil {
+			return fmt.Errorf("failed to initialize DLQ drain: %w", err)
+		}
+		forwarders = append(forwarders, drainFwd)
+	}
+
+	// The tailer reads its checkpoint when created, so the previous one must stop first
+	l.stopWorkers()
+	tail := l.tailer
+	if tail != nil {
+		tail, err = newTailer(listenerCfg, fwd)
+		if err != nil {
+			l.startWorkers()
+			return fmt.Errorf("failed to initialize storage tailer: %w", err)
+		}
+	}
+
+	copyPauseState(l.fwd, fwd)
+	l.srv.SetForwarder(fwd)
+	previous := l.forwarders
+	l.fwd, l.forwarders, l.drainer, l.tailer = fwd, forwarders, drainer, tail
+	l.relayCfg = cfg
+	l.startWorkers()
+
+	shutdownForwarders(listenerCfg.Name, previous)
+	slog.Info("replaced HEC forwarder", "listener", listenerCfg.Name)
+	return nil
+}
+
+// copyPauseState pauses the targets of fwd that are paused in previous.
+// Targets that no longer exist are ignored.
+func copyPauseState(previous, fwd forwarder.Forwarder) {
+	reporter, ok := previous.(forwarder.TargetReporter)
+	if !ok {
+		return
+	}
+	pauser, ok := fwd.(forwarder.Pauser)
+	if !ok {
+		return
+	}
+	for _, target := range reporter.Targets() {
+		if target.Paused {
+			_ = pauser.Pause(target.Name) // The target may have been removed
+		}
+	}
+}
+
+// shutdownForwarders shuts down forwarders, flushing their batches, within a 5 second timeout.
+func shutdownForwarders(name string, forwarders []forwarder.Forwarder) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, fwd := range forwarders {
+		if err := fwd.Shutdown(ctx); err != nil {
+			slog.Warn("failed to shutdown forwarder", "listener", name, "error", err)
+		}
+	}
+}
+
+// healthListener returns the status sources the HTTP health server reports for the listener.
+func (l *listener) healthListener() healthcheck.Listener {
+	return healthListener(l.relayCfg, l.cfg, l.srv, l.storage, l.fwd)
+}
+
+// adminListener returns the controls the admin API has over the listener.
+func (l *listener) adminListener() admin.Listener {
+	a := admin.Listener{Name: l.cfg.Name, Connections: l.srv.Connections}
+	if hecConfigured(l.relayCfg, l.cfg) {
+		a.Forwarder = l.fwd
+	}
+	return a
+}
+
+// listenerSet holds the running listeners and reconciles them with the configuration
+// on reload. The health server, admin API and retention worker are kept in step with it.
+type listenerSet struct {
+	mu          sync.Mutex
+	listeners   []*listener // In configuration order
+	auditLogger *audit.Logger
+	exits       chan listenerExit
+
+	health    *healthcheck.HTTPServer  // nil when disabled
+	admin     *admin.Server            // nil when disabled
+	retention *storage.RetentionWorker // nil when disabled
+}
+
+// newListenerSet creates the listeners of cfg without starting them.
+func newListenerSet(cfg *config.Config, auditLogger *audit.Logger) (*listenerSet, error) {
+	set := &listenerSet{
+		auditLogger: auditLogger,
+		exits:       make(chan listenerExit, len(cfg.Listeners)),
+	}
+	for _, listenerCfg := range cfg.Listeners {
+		l, err := newListener(cfg, listenerCfg, auditLogger)
+		if err != nil {
+			set.release()
+			return nil, fmt.Errorf("listener %s: %w", listenerCfg.Name, err)
+		}
+		set.listeners = append(set.listeners, l)
+	}
+	return set, nil
+}
+
+// start starts every listener.
+func (s *listenerSet) start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, l := range s.listeners {
+		if err := l.start(s.exits); err != nil {
+			return fmt.Errorf("listener %s: %w", l.cfg.Name, err)
+		}
+	}
+	return nil
+}
+
+// running reports whether l is one of the current listeners. A listener stopped by a
+// reload is no longer running, so its exit is expected.
+func (s *listenerSet) running(l *listener) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, current := range s.listeners {
+		if current == l {
+			return true
+		}
+	}
+	return false
+}
+
+// shutdown stops every listener, waiting up to listenerDrainTimeout for connections to close.
+func (s *listenerSet) shutdown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	slog.Info("initiating graceful shutdown of all servers")
+	ctx, cancel := context.WithTimeout(context.Background(), listenerDrainTimeout)
+	defer cancel()
+	for _, l := range s.listeners {
+		l.shutdown(ctx)
+	}
+
+	slog.Info("shutting down forwarders")
+	for _, l := range s.listeners {
+		l.release()
+	}
+}
+
+// release releases listeners that were never started.
+func (s *listenerSet) release() {
+	for _, l := range s.listeners {
+		l.release()
+	}
+}
+
+// listenAddrs returns the addresses the running listeners are bound to.
+func (s *listenerSet) listenAddrs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	addrs := make([]string, 0, len(s.listeners))
+	for _, l := range s.listeners {
+		addrs = append(addrs, l.cfg.ListenAddr)
+	}
+	return addrs
+}
+
+// healthListeners returns the health status sources of every listener.
+func (s *listenerSet) healthListeners() []healthcheck.Listener {
+	out := make([]healthcheck.Listener, 0, len(s.listeners))
+	for _, l := range s.listeners {
+		out = append(out, l.healthListener())
+	}
+	return out
+}
+
+// adminListeners returns the admin API controls of every listener.
+func (s *listenerSet) adminListeners() []admin.Listener {
+	out := make([]admin.Listener, 0, len(s.listeners))
+	for _, l := range s.listeners {
+		out = append(out, l.adminListener())
+	}
+	return out
+}
+
+// retentionDirs returns the directories of every listener covered by the retention policy.
+func (s *listenerSet) retentionDirs() []string {
+	var dirs []string
+	for _, l := range s.listeners {
+		dirs = append(dirs, l.dirs...)
+	}
+	return dirs
+}
+
+// reconcile brings the running listeners in line with cfg:
+//   - listeners no longer configured are drained and stopped
+//   - listeners whose non-reloadable settings changed are stopped and rebuilt
+//   - new listeners are created and started
+//   - the reloadable settings of every other listener are updated in place,
+//     without touching their connections; changed HEC settings get a new forwarder
+//
+// Removed and changed listeners are stopped before anything starts, so a listener can take
+// over an address another one gave up. A rebuilt listener that fails to start is restored
+// with its previous settings. Errors are collected per listener; the others are still applied.
+func (s *listenerSet) reconcile(cfg *config.Config) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := make(map[string]*listener, len(s.listeners))
+	for _, l := range s.listeners {
+		current[l.cfg.Name] = l
+	}
+	wanted := make(map[string]config.ListenerConfig, len(cfg.Listeners))
+	for _, listenerCfg := range cfg.Listeners {
+		wanted[listenerCfg.Name] = listenerCfg
+	}
+
+	// Stop removed and changed listeners together, so connections drain in parallel
+	var stopping []*listener
+	for _, l := range s.listeners {
+		listenerCfg, ok := wanted[l.cfg.Name]
+		switch {
+		case !ok:
+			slog.Info("stopping removed listener", "listener", l.cfg.Name)
+		case requiresRestart(l, listenerCfg):
+			slog.Info("stopping listener to apply changed settings", "listener", l.cfg.Name)
+		default:
+			continue
+		}
+		stopping = append(stopping, l)
+	}
+	s.stop(stopping)
+	for _, l := range stopping {
+		if _, ok := wanted[l.cfg.Name]; !ok {
+			metrics.UnregisterListener(l.cfg.Name)
+			slog.Info("removed listener", "listener", l.cfg.Name)
+		}
+	}
+
+	var errs []error
+	next := make([]*listener, 0, len(cfg.Listeners))
+	for _, listenerCfg := range cfg.Listeners {
+		old, exists := current[listenerCfg.Name]
+
+		if exists && !requiresRestart(old, listenerCfg) {
+			if forwarderChanged(old, cfg, listenerCfg) {
+				// A listener whose forwarder cannot be replaced keeps all of its previous settings
+				if err := old.replaceForwarder(cfg, listenerCfg); err != nil {
+					errs = append(errs, fmt.Errorf("listener %s: %w", listenerCfg.Name, err))
+					next = append(next, old)
+					continue
+				}
+			}
+			if err := updateListener(cfg, old, listenerCfg); err != nil {
+				errs = append(errs, fmt.Errorf("listener %s: failed to update config: %w", listenerCfg.Name, err))
+			}
+			next = append(next, old)
+			slog.Info("reloaded configuration for listener", "listener", listenerCfg.Name)
+			continue
+		}
+
+		l, err := s.startListener(cfg, listenerCfg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("listener %s: %w", listenerCfg.Name, err))
+			if !exists {
+				continue
+			}
+			// Bring the listener back as it was rather than leave it stopped
+			l, err = s.startListener(old.relayCfg, old.cfg)
+			if err != nil {
+				slog.Error("failed to restore listener", "listener", listenerCfg.Name, "error", err)
+				metrics.UnregisterListener(listenerCfg.Name)
+				continue
+			}
+			slog.Warn("restored listener with its previous settings", "listener", listenerCfg.Name)
+		} else if exists {
+			slog.Info("restarted listener with changed settings", "listener", listenerCfg.Name)
+		} else {
+			slog.Info("added listener", "listener", listenerCfg.Name)
+		}
+		next = append(next, l)
+	}
+
+	s.listeners = next
+	s.publish()
+	return errors.Join(errs...)
+}
+
+// startListener creates and starts a listener, releasing it if it cannot start.
+func (s *listenerSet) startListener(cfg *config.Config, listenerCfg config.ListenerConfig) (*listener, error) {
+	l, err := newListener(cfg, listenerCfg, s.auditLogger)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.start(s.exits); err != nil {
+		l.release()
+		return nil, err
+	}
+	return l, nil
+}
+
+// stop drains and stops listeners, shutting their servers down in parallel.
+func (s *listenerSet) stop(listeners []*listener) {
+	ctx, cancel := context.WithTimeout(context.Background(), listenerDrainTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, l := range listeners {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.shutdown(ctx)
+			l.release()
+		}()
+	}
+	wg.Wait()
+}
+
+// publish passes the current listeners to the health server, admin API and retention worker.
+func (s *listenerSet) publish() {
+	if s.health != nil {
+		s.health.SetListeners(s.healthListeners())
+	}
+	if s.admin != nil {
+		s.admin.SetListeners(s.adminListeners())
+	}
+	if s.retention != nil {
+		s.retention.SetDirectories(s.retentionDirs()...)
+	}
+}
+
+// requiresRestart reports whether l must be rebuilt to apply listenerCfg.
+// The ACL, TLS certificate and HEC settings can be changed on a running listener.
+func requiresRestart(l *listener, listenerCfg config.ListenerConfig) bool {
+	oldCfg := l.cfg
+	if (oldCfg.TLS != nil && oldCfg.TLS.CertFile != "") != (listenerCfg.TLS != nil && listenerCfg.TLS.CertFile != "") {
+		return true
+	}
+	return !reflect.DeepEqual(withoutReloadable(oldCfg), withoutReloadable(listenerCfg))
+}
+
+// forwarderChanged reports whether l needs a new forwarder to apply listenerCfg from cfg,
+// including the HEC settings inherited from cfg. The token, source type and gzip setting
+// are updated on the running forwarder instead.
+func forwarderChanged(l *listener, cfg *config.Config, listenerCfg config.ListenerConfig) bool {
+	return !reflect.DeepEqual(withoutReloadableSplunk(l.relayCfg.Splunk), withoutReloadableSplunk(cfg.Splunk)) ||
+		!reflect.DeepEqual(withoutReloadableSplunk(l.cfg.Splunk), withoutReloadableSplunk(listenerCfg.Splunk))
+}
+
+// withoutReloadable returns a copy of listenerCfg with its reloadable settings cleared.
+// The Splunk section is compared separately by forwarderChanged.
+func withoutReloadable(listenerCfg config.ListenerConfig) config.ListenerConfig {
+	listenerCfg.AllowedCIDRs = ""
+	listenerCfg.Splunk = nil
+	if listenerCfg.TLS != nil {
+		tls := *listenerCfg.TLS
+		tls.CertFile = ""
+		tls.KeyFile = ""
+		listenerCfg.TLS = &tls
+	}
+	return listenerCfg
+}
+
+// withoutReloadableSplunk returns a copy of splunkCfg with the token, source type and
+// gzip setting cleared, for the legacy target and each HEC target.
+func withoutReloadableSplunk(splunkCfg *config.SplunkConfig) *config.SplunkConfig {
+	if splunkCfg == nil {
+		return nil
+	}
+	c := *splunkCfg
+	c.HECToken = ""
+	c.SourceType = ""
+	c.Gzip = nil
+	c.HECTargets = make([]config.HECTarget, len(splunkCfg.HECTargets))
+	for i, target := range splunkCfg.HECTargets {
+		target.HECToken = ""
+		target.SourceType = ""
+		target.Gzip = nil
+		c.HECTargets[i] = target
+	}
+	return &c
+}
+
+// updateListener applies the reloadable settings of listenerCfg to a running listener:
+// its ACL, TLS certificate, and HEC token, source type and gzip. The HEC settings are applied
+// to every forwarder of the listener, including the DLQ drain's.
+func updateListener(cfg *config.Config, l *listener, listenerCfg config.ListenerConfig) error {
+	// Build reloadable server config
+	serverCfg := server.ReloadableConfig{
+		AllowedCIDRs: listenerCfg.AllowedCIDRs,
+	}
+	if listenerCfg.TLS != nil {
+		serverCfg.TLSCertFile = listenerCfg.TLS.CertFile
+		serverCfg.TLSKeyFile = listenerCfg.TLS.KeyFile
+	}
+
+	// Determine forwarder config (handle both single and multi-target)
+	if usesMultiTarget(cfg.Splunk, listenerCfg.Splunk) {
+		// Multi-target mode: each target keeps its own token, source type and gzip setting
+		targets, _ := getHECTargetsAndRouting(cfg.Splunk, listenerCfg.Splunk)
+		serverCfg.ForwarderConfig.Targets = make(map[string]forwarder.ReloadableConfig, len(targets))
+		for _, target := range targets {
+			targetCfg := forwarder.ReloadableConfig{Token: target.HECToken, SourceType: target.SourceType}
+			if target.Gzip != nil {
+				targetCfg.UseGzip = *target.Gzip
+			}
+			serverCfg.ForwarderConfig.Targets[target.Name] = targetCfg
+		}
+	} else {
+		// Legacy single-target mode
+		hecCfg := mergeHECConfig(cfg.Splunk, listenerCfg.Splunk, nil) // DLQ not reloadable
+		serverCfg.ForwarderConfig.Token = hecCfg.Token
+		serverCfg.ForwarderConfig.SourceType = hecCfg.SourceType
+		serverCfg.ForwarderConfig.UseGzip = hecCfg.UseGzip
+	}
+
+	// Apply reloadable configuration to server
+	if err := l.srv.UpdateConfig(serverCfg); err != nil {
+		return err
+	}
+	for _, fwd := range l.forwarders {
+		if fwd != l.fwd {
+			fwd.UpdateConfig(serverCfg.ForwarderConfig)
+		}
+	}
+	l.cfg = listenerCfg
+	l.relayCfg = cfg
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/scottbrown/relay/internal/config"
+	"github.com/scottbrown/relay/internal/forwarder"
+	"github.com/scottbrown/relay/internal/server"
+)
+
+func TestUpdateListener_ReloadsDrainToken(t *testing.T) {
+	var mu sync.Mutex
+	var auth string
+	hec := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		auth = r.Header.Get("Authorization")
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer hec.Close()
+
+	listenerCfg := config.ListenerConfig{
+		Name:      "test",
+		OutputDir: t.TempDir(),
+		DLQ:       &config.DLQConfig{Enabled: true, Drain: &config.DLQDrainConfig{Enabled: true, RateLimit: 100, CheckInterval: 10}},
+	}
+	cfg := &config.Config{Splunk: &config.SplunkConfig{HECURL: hec.URL, HECToken: "old-token"}}
+
+	fwd, err := buildForwarder(cfg, listenerCfg, forwarderOptions{})
+	if err != nil {
+		t.Fatalf("buildForwarder() error = %v", err)
+	}
+	_, drainFwd, err := newDLQDrainer(cfg, listenerCfg, fwd)
+	if err != nil {
+		t.Fatalf("newDLQDrainer() error = %v", err)
+	}
+	srv, err := server.New(server.Config{ListenAddr: "127.0.0.1:0"}, nil, nil, fwd, nil)
+	if err != nil {
+		t.Fatalf("server.New() error = %v", err)
+	}
+	l := &listener{cfg: listenerCfg, relayCfg: cfg, srv: srv, fwd: fwd, forwarders: []forwarder.Forwarder{fwd, drainFwd}}
+	defer shutdownForwarders(listenerCfg.Name, l.forwarders)
+
+	// A token-only change is applied to the running forwarders, including the drain's
+	reloaded := &config.Config{Splunk: &config.SplunkConfig{HECURL: hec.URL, HECToken: "new-token"}}
+	if forwarderChanged(l, reloaded, listenerCfg) {
+		t.Fatal("a token change should not rebuild the forwarder")
+	}
+	if err := updateListener(reloaded, l, listenerCfg); err != nil {
+		t.Fatalf("updateListener() error = %v", err)
+	}
+
+	if err := drainFwd.Forward("drain", []byte("{\"n\":1}")); err != nil {
+		t.Fatalf("drain Forward() error = %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if auth != "Splunk new-token" {
+		t.Errorf("drain Authorization = %q, want %q", auth, "Splunk new-token")
+	}
+}
@@ -4,4 +4,30 @@ var (
 	configFile  string
 	logLevel    string
 	metricsAddr string
+
+	// config validate flags
+	validateNoBind bool
+
+	// dlq replay flags
+	replayListener      string
+	replaySince         string
+	replayUntil         string
+	replayConnID        string
+	replayErrorContains string
+	replayArchiveDir    string
+	replayIncludeToday  bool
+
+	// backfill flags
+	backfillListener     string
+	backfillSince        string
+	backfillUntil        string
+	backfillDryRun       bool
+	backfillRateLimit    int
+	backfillBatchLines   int
+	backfillProgressFile string
+
+	// ctl flags
+	ctlSocket   string
+	ctlListener string
+	ctlTarget   string
 )
@@ -0,0 +1,329 @@
+// Package admin provides a local HTTP API for controlling a running relay.
+// It is served over a Unix socket, so only users with access to the socket file
+// can pause forwarding, reset circuit breakers, flush batches, reload the
+// configuration, or list active connections. relay ctl is its client.
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/scottbrown/relay/internal/audit"
+	"github.com/scottbrown/relay/internal/forwarder"
+	"github.com/scottbrown/relay/internal/server"
+)
+
+// errUnknownListener is returned when a request names a listener that does not exist.
+var errUnknownListener = errors.New("unknown listener")
+
+// Listener gives the admin API control of one listener.
+type Listener struct {
+	Name        string
+	Forwarder   forwarder.Forwarder // nil without HEC forwarding
+	Connections func() []server.ConnectionInfo
+}
+
+// Server serves the admin API on a Unix socket.
+type Server struct {
+	socket      string
+	listeners   []Listener
+	mu          sync.RWMutex // Protects listeners
+	reload      func() error
+	auditLogger *audit.Logger
+	server      *http.Server
+	listener    net.Listener
+}
+
+// New creates an admin server for the given listeners. reload is called to reload the
+// configuration, and auditLogger (which may be nil) records every command.
+// The server is not started until Start is called.
+func New(socket string, listeners []Listener, reload func() error, auditLogger *audit.Logger) *Server {
+	s := &Server{
+		socket:      socket,
+		listeners:   listeners,
+		reload:      reload,
+		auditLogger: auditLogger,
+	}
+	s.server = &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+		IdleTimeout:       30 * time.Second,
+	}
+	return s
+}
+
+// Handler returns the HTTP handler serving the admin API.
+//
+// Commands are POST requests that take optional listener and target query parameters.
+// Without a listener they apply to every listener; without a target, to every HEC target.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/status", s.handleStatus)
+	mux.HandleFunc("GET /v1/connections", s.handleConnections)
+	mux.HandleFunc("POST /v1/pause", s.command("pause", func(fwd forwarder.Forwarder, target string) error {
+		p, ok := fwd.(forwarder.Pauser)
+		if !ok {
+			return errors.New("forwarder cannot be paused")
+		}
+		return p.Pause(target)
+	}))
+	mux.HandleFunc("POST /v1/resume", s.command("resume", func(fwd forwarder.Forwarder, target string) error {
+		p, ok := fwd.(forwarder.Pauser)
+		if !ok {
+			return errors.New("forwarder cannot be paused")
+		}
+		return p.Resume(target)
+	}))
+	mux.HandleFunc("POST /v1/circuit/reset", s.command("reset_circuit", func(fwd forwarder.Forwarder, target string) error {
+		r, ok := fwd.(forwarder.CircuitResetter)
+		if !ok {
+			return errors.New("forwarder has no circuit breaker")
+		}
+		return r.ResetCircuit(target)
+	}))
+	mux.HandleFunc("POST /v1/flush", s.command("flush", func(fwd forwarder.Forwarder, _ string) error {
+		if f, ok := fwd.(forwarder.Flusher); ok {
+			f.Flush()
+		}
+		return nil
+	}))
+	mux.HandleFunc("POST /v1/reload", s.handleReload)
+	return mux
+}
+
+// Start removes a stale socket file, then serves on the socket in a background goroutine.
+// The socket is only accessible to the relay's user.
+// Returns an error if another process is serving on the socket or it cannot be created.
+func (s *Server) Start() error {
+	if conn, err := net.Dial("unix", s.socket); err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("admin socket %s is in use by another process", s.socket)
+	}
+	if err := os.Remove(s.socket); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove stale admin socket: %w", err)
+	}
+
+	var err error
+	s.listener, err = net.Listen("unix", s.socket)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(s.socket, 0600); err != nil {
+		_ = s.listener.Close()
+		return fmt.Errorf("failed to restrict admin socket permissions: %w", err)
+	}
+
+	go func() {
+		if err := s.server.Serve(s.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("admin server failed", "error", err)
+		}
+	}()
+	return nil
+}
+
+// Stop closes the server and removes the socket file. It is safe to call Stop without Start.
+func (s *Server) Stop() error {
+	err := s.server.Close()
+	if s.listener != nil {
+		// Serve may not have taken the listener yet. Closing it removes the socket file.
+		_ = s.listener.Close()
+	}
+	return err
+}
+
+// SetListeners replaces the listeners under control, for example after a configuration reload.
+func (s *Server) SetListeners(listeners []Listener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = listeners
+}
+
+// command returns a handler that applies op to the forwarders of the selected listeners.
+// Without a listener every listener with HEC forwarding is selected, and a target only
+// needs to exist on one of them.
+func (s *Server) command(action string, op func(fwd forwarder.Forwarder, target string) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		listener := r.URL.Query().Get("listener")
+		target := r.URL.Query().Get("target")
+
+		applied, err := s.apply(listener, target, op)
+		s.audit(action, listener, target, err)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		slog.Info("admin command applied", "action", action, "listeners", applied, "target", target)
+		writeJSON(w, http.StatusOK, Result{Listeners: applied})
+	}
+}
+
+// apply runs op for the selected listeners and returns the names it succeeded for.
+func (s *Server) apply(listener, target string, op func(forwarder.Forwarder, string) error) ([]string, error) {
+	selected, err := s.selectListeners(listener)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []string
+	var lastErr error
+	for _, l := range selected {
+		if l.Forwarder == nil {
+			lastErr = fmt.Errorf("listener %s has no HEC forwarding", l.Name)
+			if listener != "" {
+				return nil, lastErr
+			}
+			continue
+		}
+		if err := op(l.Forwarder, target); err != nil {
+			lastErr = fmt.Errorf("listener %s: %w", l.Name, err)
+			if listener != "" {
+				return nil, lastErr
+			}
+			continue
+		}
+		applied = append(applied, l.Name)
+	}
+	if len(applied) == 0 {
+		if lastErr == nil {
+			lastErr = errors.New("no listeners")
+		}
+		return nil, lastErr
+	}
+	return applied, nil
+}
+
+// selectListeners returns the named listener, or every listener if name is empty.
+func (s *Server) selectListeners(name string) ([]Listener, error) {
+	s.mu.RLock()
+	listeners := s.listeners
+	s.mu.RUnlock()
+
+	if name == "" {
+		return listeners, nil
+	}
+	for _, l := range listeners {
+		if l.Name == name {
+			return []Listener{l}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %q", errUnknownListener, name)
+}
+
+// handleStatus reports the forwarding state of the selected listeners.
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	selected, err := s.selectListeners(r.URL.Query().Get("listener"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	statuses := make([]ListenerStatus, 0, len(selected))
+	for _, l := range selected {
+		status := ListenerStatus{Name: l.Name}
+		if reporter, ok := l.Forwarder.(forwarder.TargetReporter); ok {
+			for _, t := range reporter.Targets() {
+				ts := TargetStatus{Name: t.Name, Paused: t.Paused, CircuitState: t.CircuitState.String()}
+				if !t.LastSuccess.IsZero() {
+					last := t.LastSuccess
+					ts.LastSuccess = &last
+				}
+				status.Targets = append(status.Targets, ts)
+			}
+		}
+		if l.Connections != nil {
+			status.Connections = len(l.Connections())
+		}
+		statuses = append(statuses, status)
+	}
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+// handleConnections lists the active connections of the selected listeners.
+func (s *Server) handleConnections(w http.ResponseWriter, r *http.Request) {
+	selected, err := s.selectListeners(r.URL.Query().Get("listener"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	conns := make([]Connection, 0)
+	for _, l := range selected {
+		if l.Connections == nil {
+			continue
+		}
+		for _, c := range l.Connections() {
+			conns = append(conns, Connection{
+				Listener:   l.Name,
+				ConnID:     c.ConnID,
+				ClientAddr: c.ClientAddr,
+				Identity:   c.Identity,
+				Started:    c.Started.UTC(),
+				Bytes:      c.Bytes,
+				Lines:      c.Lines,
+			})
+		}
+	}
+	writeJSON(w, http.StatusOK, conns)
+}
+
+// handleReload reloads the configuration, as SIGHUP does.
+func (s *Server) handleReload(w http.ResponseWriter, _ *http.Request) {
+	err := s.reload()
+	s.audit("reload", "", "", err)
+	if err != nil {
+		slog.Error("failed to reload configuration", "source", "admin", "error", err)
+		writeJSON(w, http.StatusInternalServerError, Result{Error: err.Error()})
+		return
+	}
+	slog.Info("configuration reloaded successfully", "source", "admin")
+	writeJSON(w, http.StatusOK, Result{})
+}
+
+// audit records an admin command in the audit log.
+func (s *Server) audit(action, listener, target string, err error) {
+	if s.auditLogger == nil {
+		return
+	}
+	event := audit.Event{
+		EventType: audit.EventAdminCommand,
+		Success:   err == nil,
+		Actor:     "admin_socket",
+		Resource:  listener,
+		Action:    action,
+		Result:    "applied",
+	}
+	if target != "" {
+		event.Details = map[string]interface{}{"target": target}
+	}
+	if err != nil {
+		event.Result = "failed"
+		if event.Details == nil {
+			event.Details = make(map[string]interface{})
+		}
+		event.Details["error"] = err.Error()
+	}
+	_ = s.auditLogger.Log(event)
+}
+
+// writeError writes err with 404 for unknown listeners and 400 otherwise.
+func writeError(w http.ResponseWriter, err error) {
+	code := http.StatusBadRequest
+	if errors.Is(err, errUnknownListener) {
+		code = http.StatusNotFound
+	}
+	writeJSON(w, code, Result{Error: err.Error()})
+}
+
+// writeJSON writes v as JSON with the given status code.
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v) // Client disconnects are not actionable
+}
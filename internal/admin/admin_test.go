@@ -0,0 +1,230 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/scottbrown/relay/internal/forwarder"
+	"github.com/scottbrown/relay/internal/server"
+)
+
+// newTestServer returns an admin server with a "users" listener forwarding to a
+// "primary" HEC target and a "files" listener without HEC forwarding.
+func newTestServer(t *testing.T, reload func() error) (*Server, *forwarder.HEC) {
+	t.Helper()
+	hec := forwarder.New(forwarder.Config{Name: "primary", URL: "http://127.0.0.1:1", Token: "test-token"})
+	t.Cleanup(func() { _ = hec.Shutdown(t.Context()) })
+
+	started := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	listeners := []Listener{
+		{
+			Name:      "users",
+			Forwarder: hec,
+			Connections: func() []server.ConnectionInfo {
+				return []server.ConnectionInfo{{ConnID: "conn-1", ClientAddr: "10.0.0.1:5000", Started: started, Bytes: 42, Lines: 2}}
+			},
+		},
+		{Name: "files"},
+	}
+	return New(filepath.Join(t.TempDir(), "admin.sock"), listeners, reload, nil), hec
+}
+
+// request sends a request to srv and decodes the response into v.
+func request(t *testing.T, srv *Server, method, target string, v interface{}) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("%s %s returned invalid JSON: %v", method, target, err)
+	}
+	return rec.Code
+}
+
+func TestServer_PauseResume(t *testing.T) {
+	srv, hec := newTestServer(t, nil)
+
+	var result Result
+	if code := request(t, srv, http.MethodPost, "/v1/pause?listener=users&target=primary", &result); code != http.StatusOK {
+		t.Fatalf("pause returned status %d: %s", code, result.Error)
+	}
+	if len(result.Listeners) != 1 || result.Listeners[0] != "users" {
+		t.Errorf("pause applied to %v, want [users]", result.Listeners)
+	}
+	if !hec.Paused() {
+		t.Error("forwarder should be paused")
+	}
+
+	var statuses []ListenerStatus
+	request(t, srv, http.MethodGet, "/v1/status?listener=users", &statuses)
+	if len(statuses) != 1 || len(statuses[0].Targets) != 1 || !statuses[0].Targets[0].Paused {
+		t.Errorf("status should report the paused target: %+v", statuses)
+	}
+
+	// Without a listener the command skips listeners without HEC forwarding
+	result = Result{}
+	if code := request(t, srv, http.MethodPost, "/v1/resume", &result); code != http.StatusOK {
+		t.Fatalf("resume returned status %d: %s", code, result.Error)
+	}
+	if hec.Paused() {
+		t.Error("forwarder should be resumed")
+	}
+}
+
+func TestServer_CommandErrors(t *testing.T) {
+	srv, _ := newTestServer(t, nil)
+
+	tests := []struct {
+		name     string
+		target   string
+		expected int
+	}{
+		{name: "unknown listener", target: "/v1/pause?listener=orders", expected: http.StatusNotFound},
+		{name: "unknown target", target: "/v1/pause?listener=users&target=secondary", expected: http.StatusBadRequest},
+		{name: "unknown target on every listener", target: "/v1/circuit/reset?target=secondary", expected: http.StatusBadRequest},
+		{name: "listener without HEC", target: "/v1/flush?listener=files", expected: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result Result
+			code := request(t, srv, http.MethodPost, tt.target, &result)
+			if code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, code)
+			}
+			if result.Error == "" {
+				t.Error("expected an error message")
+			}
+		})
+	}
+}
+
+func TestServer_Connections(t *testing.T) {
+	srv, _ := newTestServer(t, nil)
+
+	var conns []Connection
+	if code := request(t, srv, http.MethodGet, "/v1/connections", &conns); code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", code)
+	}
+	if len(conns) != 1 {
+		t.Fatalf("expected 1 connection, got %d", len(conns))
+	}
+	c := conns[0]
+	if c.Listener != "users" || c.ConnID != "conn-1" || c.ClientAddr != "10.0.0.1:5000" || c.Bytes != 42 || c.Lines != 2 {
+		t.Errorf("unexpected connection: %+v", c)
+	}
+
+	if code := request(t, srv, http.MethodGet, "/v1/connections?listener=files", &conns); code != http.StatusOK || len(conns) != 0 {
+		t.Errorf("expected no connections for files, got status %d and %v", code, conns)
+	}
+}
+
+func TestServer_Reload(t *testing.T) {
+	reloads := 0
+	srv, _ := newTestServer(t, func() error {
+		reloads++
+		if reloads > 1 {
+			return errors.New("listener users: cannot change listen_addr during reload")
+		}
+		return nil
+	})
+
+	var result Result
+	if code := request(t, srv, http.MethodPost, "/v1/reload", &result); code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", code)
+	}
+	if code := request(t, srv, http.MethodPost, "/v1/reload", &result); code != http.StatusInternalServerError {
+		t.Errorf("expected status 500 for a failed reload, got %d", code)
+	}
+	if result.Error == "" {
+		t.Error("expected the reload error in the response")
+	}
+	if reloads != 2 {
+		t.Errorf("reload called %d times, want 2", reloads)
+	}
+}
+
+func TestServer_Client(t *testing.T) {
+	// Unix socket paths are limited to about 100 bytes, which t.TempDir can exceed
+	dir, err := os.MkdirTemp("", "relay")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "admin.sock")
+
+	srv, hec := newTestServer(t, func() error { return nil })
+	srv.socket = socket
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer srv.Stop()
+
+	info, err := os.Stat(socket)
+	if err != nil {
+		t.Fatalf("socket not created: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("socket permissions = %o, want 600", perm)
+	}
+
+	// A second server must not take over a socket in use
+	if err := New(socket, nil, nil, nil).Start(); err == nil {
+		t.Error("Start() should fail while the socket is in use")
+	}
+
+	client := NewClient(socket)
+	if _, err := client.Pause("users", ""); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if !hec.Paused() {
+		t.Error("forwarder should be paused")
+	}
+	if _, err := client.Pause("orders", ""); err == nil {
+		t.Error("Pause() should fail for an unknown listener")
+	}
+	if err := client.Reload(); err != nil {
+		t.Errorf("Reload() error = %v", err)
+	}
+	statuses, err := client.Status("")
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if len(statuses) != 2 || statuses[0].Connections != 1 {
+		t.Errorf("unexpected status: %+v", statuses)
+	}
+	conns, err := client.Connections("users")
+	if err != nil || len(conns) != 1 {
+		t.Errorf("Connections() = %v, %v, want 1 connection", conns, err)
+	}
+}
+
+func TestServer_StartRemovesStaleSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "relay")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "admin.sock")
+
+	// A socket file left behind by a relay that did not shut down cleanly
+	if err := os.WriteFile(socket, nil, 0600); err != nil {
+		t.Fatalf("failed to create stale socket: %v", err)
+	}
+
+	srv := New(socket, nil, nil, nil)
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := srv.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if _, err := os.Stat(socket); !os.IsNotExist(err) {
+		t.Errorf("socket should be removed after Stop, got %v", err)
+	}
+}
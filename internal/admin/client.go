@@ -0,0 +1,151 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Result is the response to a command. Listeners names the listeners it was applied to.
+type Result struct {
+	Listeners []string `json:"listeners,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// ListenerStatus is the forwarding state of one listener.
+type ListenerStatus struct {
+	Name        string         `json:"name"`
+	Connections int            `json:"connections"`
+	Targets     []TargetStatus `json:"targets,omitempty"`
+}
+
+// TargetStatus is the state of one HEC target.
+type TargetStatus struct {
+	Name         string     `json:"name"`
+	Paused       bool       `json:"paused"`
+	CircuitState string     `json:"circuit_state"`
+	LastSuccess  *time.Time `json:"last_success,omitempty"`
+}
+
+// Connection is an active client connection.
+type Connection struct {
+	Listener   string    `json:"listener"`
+	ConnID     string    `json:"conn_id"`
+	ClientAddr string    `json:"client_addr"`
+	Identity   string    `json:"client_identity,omitempty"`
+	Started    time.Time `json:"started"`
+	Bytes      int64     `json:"bytes"`
+	Lines      int64     `json:"lines"`
+}
+
+// Client calls the admin API of a running relay over its Unix socket.
+type Client struct {
+	http *http.Client
+}
+
+// NewClient creates a client for the admin socket at path.
+func NewClient(socket string) *Client {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		},
+	}
+	return &Client{http: &http.Client{Transport: transport, Timeout: 60 * time.Second}}
+}
+
+// Pause pauses forwarding for a listener and target; empty values select all of them.
+func (c *Client) Pause(listener, target string) (Result, error) {
+	return c.command("/v1/pause", listener, target)
+}
+
+// Resume resumes forwarding for a listener and target; empty values select all of them.
+func (c *Client) Resume(listener, target string) (Result, error) {
+	return c.command("/v1/resume", listener, target)
+}
+
+// ResetCircuit closes the circuit breakers of a listener and target; empty values select all of them.
+func (c *Client) ResetCircuit(listener, target string) (Result, error) {
+	return c.command("/v1/circuit/reset", listener, target)
+}
+
+// Flush sends the batched lines of a listener, or of every listener if listener is empty.
+func (c *Client) Flush(listener string) (Result, error) {
+	return c.command("/v1/flush", listener, "")
+}
+
+// Reload reloads the relay's configuration file.
+func (c *Client) Reload() error {
+	_, err := c.command("/v1/reload", "", "")
+	return err
+}
+
+// Status returns the forwarding state of a listener, or of every listener if listener is empty.
+func (c *Client) Status(listener string) ([]ListenerStatus, error) {
+	var statuses []ListenerStatus
+	err := c.get("/v1/status", listener, &statuses)
+	return statuses, err
+}
+
+// Connections lists the active connections of a listener, or of every listener if listener is empty.
+func (c *Client) Connections(listener string) ([]Connection, error) {
+	var conns []Connection
+	err := c.get("/v1/connections", listener, &conns)
+	return conns, err
+}
+
+// command sends a POST request and decodes the result.
+func (c *Client) command(path, listener, target string) (Result, error) {
+	var result Result
+	req, err := http.NewRequest(http.MethodPost, requestURL(path, listener, target), nil)
+	if err != nil {
+		return result, err
+	}
+	err = c.do(req, &result)
+	return result, err
+}
+
+// get sends a GET request and decodes the response into v.
+func (c *Client) get(path, listener string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, requestURL(path, listener, ""), nil)
+	if err != nil {
+		return err
+	}
+	return c.do(req, v)
+}
+
+// do sends req and decodes a successful response into v.
+// An error response is returned as an error with the server's message.
+func (c *Client) do(req *http.Request, v interface{}) error {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach relay admin socket: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var result Result
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || result.Error == "" {
+			return fmt.Errorf("admin request failed with status: %s", resp.Status)
+		}
+		return fmt.Errorf("%s", result.Error)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// requestURL builds the URL for an admin API path. The host is ignored by the Unix dialer.
+func requestURL(path, listener, target string) string {
+	q := url.Values{}
+	if listener != "" {
+		q.Set("listener", listener)
+	}
+	if target != "" {
+		q.Set("target", target)
+	}
+	u := url.URL{Scheme: "http", Host: "relay", Path: path, RawQuery: q.Encode()}
+	return u.String()
+}
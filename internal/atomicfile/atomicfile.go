@@ -0,0 +1,51 @@
+// Package atomicfile replaces small state files, such as checkpoints and cursors, so that a
+// crash or power failure leaves either the previous contents or the new ones.
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Write replaces the file at path with data. The data is written to path.tmp and synced,
+// the temporary file is renamed over path, and the directory is synced so the rename itself
+// survives a power failure.
+func Write(path string, data []byte, perm os.FileMode) error {
+	tmp := path + ".tmp"
+	// #nosec G304 -- callers pass paths built from their configured directories.
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir flushes a directory's entries to disk. Windows cannot sync a directory and makes
+// renames durable without it.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	// #nosec G304 -- dir is the directory of a path passed to Write.
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,35 @@
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	for _, data := range []string{`{"offset":1}`, `{"offset":22}`, `{}`} {
+		if err := Write(path, []byte(data), 0600); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("ReadFile() error = %v", err)
+		}
+		if string(got) != data {
+			t.Errorf("file = %q, want %q", got, data)
+		}
+	}
+
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file should be renamed away, stat error = %v", err)
+	}
+}
+
+func TestWrite_MissingDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "state.json")
+	if err := Write(path, []byte("{}"), 0600); err == nil {
+		t.Error("Write() should fail when the directory does not exist")
+	}
+}
@@ -23,6 +23,7 @@ const (
 	EventConfigChange       EventType = "config.changed"
 	EventServerStart        EventType = "server.start"
 	EventServerStop         EventType = "server.stop"
+	EventAdminCommand       EventType = "admin.command"
 )
 
 // Event represents a single audit log entry with security-relevant information.
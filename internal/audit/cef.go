@@ -46,8 +46,8 @@ func determineSeverity(event Event) int {
 	switch event.EventType {
 	case EventAuthSuccess:
 		return 5 // Medium for successful auth
-	case EventConfigChange:
-		return 6 // Medium for config changes
+	case EventConfigChange, EventAdminCommand:
+		return 6 // Medium for config changes and operator commands
 	case EventServerStart, EventServerStop:
 		return 4 // Low-medium for server lifecycle
 	default:
@@ -0,0 +1,248 @@
+// Package backfill forwards historical stored log files to HEC.
+// It streams the daily NDJSON files for a date range in batches, at a limited rate,
+// and records per-file progress so an interrupted backfill resumes where it stopped.
+package backfill
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/scottbrown/relay/internal/storage"
+)
+
+// Sender delivers a batch of newline-separated lines. forwarder.Forwarder satisfies this interface.
+type Sender interface {
+	Forward(connID string, data []byte) error
+}
+
+// Config holds configuration for a Backfiller.
+type Config struct {
+	BatchMaxLines int    // Maximum lines per HEC request (default: 500)
+	BatchMaxBytes int    // Maximum bytes per HEC request (default: 1 MiB)
+	RateLimit     int    // Maximum lines sent per second (0 = unlimited)
+	ProgressPath  string // Progress file; empty disables progress tracking
+}
+
+// Stats summarises a backfill or dry run.
+type Stats struct {
+	Files   int   // Files processed
+	Skipped int   // Files skipped because a previous run completed them
+	Lines   int   // Lines sent (or counted in a dry run)
+	Bytes   int64 // Bytes of line data sent (or counted)
+	Batches int   // HEC requests made
+}
+
+// FileProgress records how far a file has been sent.
+type FileProgress struct {
+	Offset int64 `json:"offset"`
+	Done   bool  `json:"done"`
+}
+
+// progress is persisted after every batch.
+// Files are keyed by name without the .gz suffix so they survive retention compression.
+type progress struct {
+	Files map[string]FileProgress `json:"files"`
+}
+
+// Backfiller sends stored files through a Sender.
+type Backfiller struct {
+	config   Config
+	sender   Sender
+	progress progress
+	now      func() time.Time
+}
+
+// Files returns the stored files for filePrefix in dir whose day overlaps [since, until).
+// A zero since or until leaves that end of the range open.
+func Files(dir, filePrefix string, since, until time.Time) ([]string, error) {
+	files, err := storage.DayFiles(dir, filePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	out := files[:0]
+	for _, f := range files {
+		day, ok := storage.FileDay(f)
+		if !ok {
+			continue
+		}
+		if !since.IsZero() && !day.AddDate(0, 0, 1).After(since) {
+			continue
+		}
+		if !until.IsZero() && !day.Before(until) {
+			continue
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+// New creates a Backfiller and loads its progress file, if any.
+func New(sender Sender, config Config) (*Backfiller, error) {
+	if config.BatchMaxLines <= 0 {
+		config.BatchMaxLines = 500
+	}
+	if config.BatchMaxBytes <= 0 {
+		config.BatchMaxBytes = 1 << 20
+	}
+
+	b := &Backfiller{
+		config:   config,
+		sender:   sender,
+		progress: progress{Files: make(map[string]FileProgress)},
+		now:      time.Now,
+	}
+
+	if config.ProgressPath == "" {
+		return b, nil
+	}
+	// #nosec G304 -- ProgressPath is provided by the operator or derived from the output directory.
+	data, err := os.ReadFile(config.ProgressPath)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(data, &b.progress); err != nil {
+			return nil, fmt.Errorf("failed to parse backfill progress %s: %w", config.ProgressPath, err)
+		}
+		if b.progress.Files == nil {
+			b.progress.Files = make(map[string]FileProgress)
+		}
+	}
+	return b, nil
+}
+
+// Count reports what Run would send without sending anything or touching the progress file.
+func (b *Backfiller) Count(ctx context.Context, files []string) (Stats, error) {
+	return b.process(ctx, files, true)
+}
+
+// Run sends every remaining line in files, in order.
+// It stops at the first failed batch; progress up to the last delivered batch is kept,
+// so re-running resumes there. Delivery is at-least-once.
+func (b *Backfiller) Run(ctx context.Context, files []string) (Stats, error) {
+	return b.process(ctx, files, false)
+}
+
+func (b *Backfiller) process(ctx context.Context, files []string, dryRun bool) (Stats, error) {
+	var stats Stats
+	for _, path := range files {
+		key := storage.FileKey(path)
+		fp := b.progress.Files[key]
+		if fp.Done {
+			stats.Skipped++
+			continue
+		}
+
+		if err := b.processFile(ctx, path, fp.Offset, dryRun, &stats); err != nil {
+			return stats, fmt.Errorf("%s: %w", path, err)
+		}
+		stats.Files++
+	}
+	return stats, nil
+}
+
+// processFile sends one file from offset in batches.
+func (b *Backfiller) processFile(ctx context.Context, path string, offset int64, dryRun bool, stats *Stats) error {
+	reader, err := storage.OpenLineReader(path, offset)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = reader.Close() }()
+
+	key := storage.FileKey(path)
+	var batch [][]byte
+	size := 0
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		lines := len(batch)
+		if !dryRun {
+			if err := b.sender.Forward("backfill", bytes.Join(batch, []byte("\n"))); err != nil {
+				return err
+			}
+			b.progress.Files[key] = FileProgress{Offset: reader.Offset()}
+			if err := b.saveProgress(); err != nil {
+				return err
+			}
+		}
+		stats.Lines += lines
+		stats.Bytes += int64(size)
+		stats.Batches++
+		batch, size = nil, 0
+
+		if !dryRun && b.config.RateLimit > 0 {
+			wait := time.Duration(lines) * time.Second / time.Duration(b.config.RateLimit)
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if len(line) == 0 {
+			continue
+		}
+		batch = append(batch, line)
+		size += len(line)
+		if len(batch) >= b.config.BatchMaxLines || size >= b.config.BatchMaxBytes {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	if !dryRun {
+		// Today's file may still be written to; a later run picks up from the offset
+		day, _ := storage.FileDay(path)
+		done := day.AddDate(0, 0, 1).Before(b.now().UTC())
+		b.progress.Files[key] = FileProgress{Offset: reader.Offset(), Done: done}
+		if err := b.saveProgress(); err != nil {
+			return err
+		}
+		slog.Info("backfilled file", "file", path, "complete", done)
+	}
+	return nil
+}
+
+// saveProgress writes the progress file atomically via a temporary file and rename.
+func (b *Backfiller) saveProgress() error {
+	if b.config.ProgressPath == "" {
+		return nil
+	}
+	data, err := json.Marshal(b.progress)
+	if err != nil {
+		return err
+	}
+	tmp := b.config.ProgressPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, b.config.ProgressPath)
+}
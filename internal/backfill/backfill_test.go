@@ -0,0 +1,224 @@
+package backfill
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/scottbrown/relay/internal/forwarder"
+	"github.com/scottbrown/relay/internal/redact"
+)
+
+// mockSender records payloads and fails after failAfter successful sends (if > 0)
+type mockSender struct {
+	payloads  []string
+	failAfter int
+}
+
+func (m *mockSender) Forward(connID string, data []byte) error {
+	if m.failAfter > 0 && len(m.payloads) >= m.failAfter {
+		return errors.New("hec unavailable")
+	}
+	m.payloads = append(m.payloads, string(data))
+	return nil
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeGzipFile(t *testing.T, path, data string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	if _, err := gz.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func day(s string) time.Time {
+	d, _ := time.Parse("2006-01-02", s)
+	return d
+}
+
+func TestFiles_SelectsDateRange(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"zpa-2025-01-09.ndjson", "zpa-2025-01-10.ndjson.gz", "zpa-2025-01-11.ndjson", "zpa-2025-01-12.ndjson", "other-2025-01-10.ndjson"} {
+		writeFile(t, filepath.Join(dir, name), "")
+	}
+
+	files, err := Files(dir, "zpa", day("2025-01-10"), day("2025-01-12"))
+	if err != nil {
+		t.Fatalf("Files() error = %v", err)
+	}
+	if len(files) != 2 || filepath.Base(files[0]) != "zpa-2025-01-10.ndjson.gz" || filepath.Base(files[1]) != "zpa-2025-01-11.ndjson" {
+		t.Errorf("Files() = %v, want the 10th (compressed) and 11th", files)
+	}
+}
+
+func TestBackfiller_RunBatchesPlainAndCompressed(t *testing.T) {
+	dir := t.TempDir()
+	writeGzipFile(t, filepath.Join(dir, "zpa-2025-01-10.ndjson.gz"), "{\"n\":1}\n{\"n\":2}\n{\"n\":3}\n")
+	writeFile(t, filepath.Join(dir, "zpa-2025-01-11.ndjson"), "{\"n\":4}\n")
+
+	files, _ := Files(dir, "zpa", time.Time{}, time.Time{})
+	sender := &mockSender{}
+	b, err := New(sender, Config{BatchMaxLines: 2, ProgressPath: filepath.Join(dir, "progress.json")})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	stats, err := b.Run(context.Background(), files)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if stats.Files != 2 || stats.Lines != 4 || stats.Batches != 3 {
+		t.Errorf("stats = %+v, want 2 files, 4 lines, 3 batches", stats)
+	}
+	if len(sender.payloads) != 3 || sender.payloads[0] != "{\"n\":1}\n{\"n\":2}" || sender.payloads[2] != `{"n":4}` {
+		t.Errorf("payloads = %q", sender.payloads)
+	}
+}
+
+func TestBackfiller_RedactsEveryLineOfBatch(t *testing.T) {
+	var bodies []string
+	hec := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer hec.Close()
+
+	redactor, err := redact.New([]redact.Rule{{Path: "Username", Action: redact.Mask}}, nil)
+	if err != nil {
+		t.Fatalf("redact.New() error = %v", err)
+	}
+	fwd := forwarder.New(forwarder.Config{URL: hec.URL, Token: "token", Redactor: redactor})
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "zpa-2025-01-10.ndjson"), "{\"User\":\"a\"}\n{\"Username\":\"b\"}\n{\"Username\":\"c\"}\n")
+	files, _ := Files(dir, "zpa", time.Time{}, time.Time{})
+	b, err := New(fwd, Config{BatchMaxLines: 10, ProgressPath: filepath.Join(dir, "progress.json")})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := b.Run(context.Background(), files); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	want := "{\"User\":\"a\"}\n{\"Username\":\"[REDACTED]\"}\n{\"Username\":\"[REDACTED]\"}"
+	if len(bodies) != 1 || bodies[0] != want {
+		t.Errorf("HEC bodies = %q, want one batch %q", bodies, want)
+	}
+}
+
+func TestBackfiller_ResumesFromProgress(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "zpa-2025-01-10.ndjson"), "{\"n\":1}\n{\"n\":2}\n{\"n\":3}\n")
+	writeFile(t, filepath.Join(dir, "zpa-2025-01-11.ndjson"), "{\"n\":4}\n")
+	files, _ := Files(dir, "zpa", time.Time{}, time.Time{})
+	progressPath := filepath.Join(dir, "progress.json")
+
+	failing := &mockSender{failAfter: 1}
+	b, err := New(failing, Config{BatchMaxLines: 1, ProgressPath: progressPath})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := b.Run(context.Background(), files); err == nil {
+		t.Fatal("Run() should fail when HEC rejects a batch")
+	}
+
+	sender := &mockSender{}
+	b, err = New(sender, Config{BatchMaxLines: 1, ProgressPath: progressPath})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := b.Run(context.Background(), files); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	want := []string{`{"n":2}`, `{"n":3}`, `{"n":4}`}
+	if len(sender.payloads) != len(want) {
+		t.Fatalf("payloads after resume = %q, want %q", sender.payloads, want)
+	}
+	for i := range want {
+		if sender.payloads[i] != want[i] {
+			t.Errorf("payload[%d] = %q, want %q", i, sender.payloads[i], want[i])
+		}
+	}
+
+	// A third run finds every past file complete
+	b, _ = New(sender, Config{ProgressPath: progressPath})
+	stats, err := b.Run(context.Background(), files)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if stats.Skipped != 2 || stats.Lines != 0 {
+		t.Errorf("stats on re-run = %+v, want both files skipped", stats)
+	}
+}
+
+func TestBackfiller_CountDoesNotSend(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "zpa-2025-01-10.ndjson"), "{\"n\":1}\n\n{\"n\":2}\n")
+	files, _ := Files(dir, "zpa", time.Time{}, time.Time{})
+	progressPath := filepath.Join(dir, "progress.json")
+
+	b, err := New(nil, Config{ProgressPath: progressPath})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	stats, err := b.Count(context.Background(), files)
+	if err != nil {
+		t.Fatalf("Count() error = %v", err)
+	}
+	if stats.Files != 1 || stats.Lines != 2 || stats.Bytes != 14 {
+		t.Errorf("stats = %+v, want 1 file, 2 lines, 14 bytes", stats)
+	}
+	if _, err := os.Stat(progressPath); !os.IsNotExist(err) {
+		t.Error("dry run should not write a progress file")
+	}
+}
+
+func TestBackfiller_TodayNotMarkedComplete(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "zpa-2025-01-10.ndjson"), "{\"n\":1}\n")
+	files, _ := Files(dir, "zpa", time.Time{}, time.Time{})
+	progressPath := filepath.Join(dir, "progress.json")
+
+	b, _ := New(&mockSender{}, Config{ProgressPath: progressPath})
+	b.now = func() time.Time { return day("2025-01-10").Add(12 * time.Hour) }
+	if _, err := b.Run(context.Background(), files); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// Lines appended later today are sent by the next run
+	f, _ := os.OpenFile(files[0], os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString("{\"n\":2}\n")
+	f.Close()
+
+	sender := &mockSender{}
+	b, _ = New(sender, Config{ProgressPath: progressPath})
+	if _, err := b.Run(context.Background(), files); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(sender.payloads) != 1 || sender.payloads[0] != `{"n":2}` {
+		t.Errorf("payloads = %q, want only the appended line", sender.payloads)
+	}
+}
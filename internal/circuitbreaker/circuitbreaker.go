@@ -247,6 +247,14 @@ func (cb *CircuitBreaker) GetState() State {
 	return cb.state
 }
 
+// Rejecting reports whether the circuit is open and calls are rejected. An open circuit whose
+// timeout has passed is not rejecting: the next call is let through to test recovery.
+func (cb *CircuitBreaker) Rejecting() bool {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	return cb.state == StateOpen && time.Since(cb.lastStateChange) < cb.config.Timeout
+}
+
 // GetFailures returns the current failure count
 func (cb *CircuitBreaker) GetFailures() int {
 	cb.mu.RLock()
@@ -129,6 +129,27 @@ func TestCircuitBreaker_OpenToHalfOpen(t *testing.T) {
 	}
 }
 
+func TestCircuitBreaker_Rejecting(t *testing.T) {
+	cb := New(Config{FailureThreshold: 1, Timeout: 50 * time.Millisecond})
+
+	if cb.Rejecting() {
+		t.Error("a closed circuit should not be rejecting")
+	}
+
+	cb.Call(func() error {
+		return errors.New("test error")
+	})
+	if !cb.Rejecting() {
+		t.Error("an open circuit should be rejecting")
+	}
+
+	// Once the timeout has passed the next call tests recovery
+	time.Sleep(60 * time.Millisecond)
+	if cb.Rejecting() {
+		t.Error("an open circuit past its timeout should not be rejecting")
+	}
+}
+
 func TestCircuitBreaker_HalfOpenToClosed(t *testing.T) {
 	config := Config{
 		FailureThreshold: 2,
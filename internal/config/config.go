@@ -4,17 +4,23 @@ package config
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	_ "embed"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/scottbrown/relay/internal/acl"
+	"github.com/scottbrown/relay/internal/filter"
+	"github.com/scottbrown/relay/internal/logschema"
 	"github.com/scottbrown/relay/internal/logtypes"
-	"gopkg.in/yaml.v3"
+	"github.com/scottbrown/relay/internal/redact"
 )
 
 const (
@@ -24,6 +30,10 @@ const (
 	DefaultHealthCheckAddr string = ":9099"
 	// DefaultHealthCheckEnabled indicates if health checks are enabled by default.
 	DefaultHealthCheckEnabled bool = false
+	// DefaultHealthAddr is the default address for the HTTP health and readiness server.
+	DefaultHealthAddr string = ":9098"
+	// DefaultAdminSocket is the default Unix socket path for the admin API.
+	DefaultAdminSocket string = "/run/relay/admin.sock"
 )
 
 //go:embed config.template.yml
@@ -32,7 +42,7 @@ var configTemplate string
 // BatchConfig holds configuration for batching multiple log lines before forwarding.
 // When enabled, logs are accumulated and sent together to reduce network overhead.
 type BatchConfig struct {
-	Enabled       *bool `yaml:"enabled"`
+	Enabled       *bool `yaml:"enabled,omitempty"`
 	MaxSize       int   `yaml:"max_size"`
 	MaxBytes      int   `yaml:"max_bytes"`
 	FlushInterval int   `yaml:"flush_interval_seconds"`
@@ -42,7 +52,7 @@ type BatchConfig struct {
 // The circuit breaker prevents cascading failures by temporarily stopping
 // requests to a failing service.
 type CircuitBreakerConfig struct {
-	Enabled          *bool `yaml:"enabled"`
+	Enabled          *bool `yaml:"enabled,omitempty"`
 	FailureThreshold int   `yaml:"failure_threshold"`
 	SuccessThreshold int   `yaml:"success_threshold"`
 	Timeout          int   `yaml:"timeout_seconds"`
@@ -59,6 +69,14 @@ type RetryConfig struct {
 	MaxBackoffSeconds int     `yaml:"max_backoff_seconds"`
 }
 
+// AckConfig holds configuration for HEC indexer acknowledgement (useACK).
+// The HEC token must have indexer acknowledgement enabled.
+type AckConfig struct {
+	Enabled      *bool `yaml:"enabled,omitempty"`
+	Timeout      int   `yaml:"timeout_seconds"`       // Seconds to wait for an acknowledgement before re-sending (default: 60)
+	PollInterval int   `yaml:"poll_interval_seconds"` // Seconds between ack status queries (default: 1)
+}
+
 // TransportConfig holds HTTP transport configuration for connection pooling and timeouts.
 // These settings control how HTTP connections are managed and reused for HEC forwarding.
 type TransportConfig struct {
@@ -71,16 +89,23 @@ type TransportConfig struct {
 // HECTarget represents a single Splunk HEC endpoint target.
 // Multiple targets can be configured for high availability or multi-tenancy.
 type HECTarget struct {
-	Name           string                `yaml:"name"`
-	HECURL         string                `yaml:"hec_url"`
-	HECToken       string                `yaml:"hec_token"`
-	Gzip           *bool                 `yaml:"gzip"`
-	SourceType     string                `yaml:"source_type"`
-	ClientTimeout  int                   `yaml:"client_timeout_seconds"` // HTTP client timeout for HEC requests
-	Batch          *BatchConfig          `yaml:"batch"`
-	CircuitBreaker *CircuitBreakerConfig `yaml:"circuit_breaker"`
-	Retry          *RetryConfig          `yaml:"retry"`
-	Transport      *TransportConfig      `yaml:"transport"`
+	Name           string                `yaml:"name,omitempty"`
+	HECURL         string                `yaml:"hec_url,omitempty"`
+	HECToken       string                `yaml:"hec_token,omitempty"`
+	Gzip           *bool                 `yaml:"gzip,omitempty"`
+	SourceType     string                `yaml:"source_type,omitempty"`
+	Endpoint       string                `yaml:"endpoint,omitempty"`               // HEC endpoint format: "raw" (default) or "event"
+	Index          string                `yaml:"index,omitempty"`                  // Destination index (event endpoint only)
+	Source         string                `yaml:"source,omitempty"`                 // Event source (event endpoint only, default: listener name)
+	Fields         map[string]string     `yaml:"fields,omitempty"`                 // Indexed fields added to every event (event endpoint only)
+	ClientTimeout  int                   `yaml:"client_timeout_seconds,omitempty"` // HTTP client timeout for HEC requests
+	Batch          *BatchConfig          `yaml:"batch,omitempty"`
+	CircuitBreaker *CircuitBreakerConfig `yaml:"circuit_breaker,omitempty"`
+	Retry          *RetryConfig          `yaml:"retry,omitempty"`
+	Ack            *AckConfig            `yaml:"ack,omitempty"`
+	Transport      *TransportConfig      `yaml:"transport,omitempty"`
+	Redact         []RedactionRule       `yaml:"redact,omitempty"` // Redaction applied to lines sent to this target only
+	Weight         int                   `yaml:"weight,omitempty"` // Relative share of lines (weighted and hash modes, default: 1)
 }
 
 // RoutingMode defines how logs are distributed across multiple HEC targets.
@@ -93,11 +118,30 @@ const (
 	RoutingModePrimaryFailover RoutingMode = "primary-failover"
 	// RoutingModeRoundRobin distributes logs across targets in round-robin fashion.
 	RoutingModeRoundRobin RoutingMode = "round-robin"
+	// RoutingModeRouteByField picks targets by the value of a field of each line.
+	RoutingModeRouteByField RoutingMode = "route-by-field"
+	// RoutingModeWeighted distributes logs across targets in proportion to their weights.
+	RoutingModeWeighted RoutingMode = "weighted"
+	// RoutingModeHash sends all logs with the same value of a field to the same target.
+	RoutingModeHash RoutingMode = "hash"
 )
 
+// MaxTargetWeight is the largest weight of a HEC target.
+const MaxTargetWeight = 1000
+
 // RoutingConfig holds routing configuration for multiple HEC targets.
 type RoutingConfig struct {
-	Mode RoutingMode `yaml:"mode"`
+	Mode    RoutingMode  `yaml:"mode"`
+	Field   string       `yaml:"field,omitempty"`   // Dotted JSON path of the field to route by (route-by-field, hash)
+	Routes  []FieldRoute `yaml:"routes,omitempty"`  // Targets for values of the field (route-by-field)
+	Default []string     `yaml:"default,omitempty"` // Targets for lines no route matches (route-by-field)
+}
+
+// FieldRoute sends lines whose routing field has one of Values to Targets. The targets are
+// tried in order, each failing over to the next.
+type FieldRoute struct {
+	Values  []string `yaml:"values"`
+	Targets []string `yaml:"targets"`
 }
 
 // SplunkConfig holds Splunk HEC (HTTP Event Collector) configuration.
@@ -105,26 +149,35 @@ type RoutingConfig struct {
 // Supports both legacy single target configuration and new multi-target configuration.
 type SplunkConfig struct {
 	// Legacy single target configuration
-	HECURL         string                `yaml:"hec_url"`
-	HECToken       string                `yaml:"hec_token"`
-	Gzip           *bool                 `yaml:"gzip"`
-	SourceType     string                `yaml:"source_type"`
-	ClientTimeout  int                   `yaml:"client_timeout_seconds"` // HTTP client timeout for HEC requests
-	Batch          *BatchConfig          `yaml:"batch"`
-	CircuitBreaker *CircuitBreakerConfig `yaml:"circuit_breaker"`
-	Retry          *RetryConfig          `yaml:"retry"`
-	Transport      *TransportConfig      `yaml:"transport"`
+	HECURL         string                `yaml:"hec_url,omitempty"`
+	HECToken       string                `yaml:"hec_token,omitempty"`
+	Gzip           *bool                 `yaml:"gzip,omitempty"`
+	SourceType     string                `yaml:"source_type,omitempty"`
+	Endpoint       string                `yaml:"endpoint,omitempty"`               // HEC endpoint format: "raw" (default) or "event"
+	Index          string                `yaml:"index,omitempty"`                  // Destination index (event endpoint only)
+	Source         string                `yaml:"source,omitempty"`                 // Event source (event endpoint only, default: listener name)
+	Fields         map[string]string     `yaml:"fields,omitempty"`                 // Indexed fields added to every event (event endpoint only)
+	ClientTimeout  int                   `yaml:"client_timeout_seconds,omitempty"` // HTTP client timeout for HEC requests
+	Batch          *BatchConfig          `yaml:"batch,omitempty"`
+	CircuitBreaker *CircuitBreakerConfig `yaml:"circuit_breaker,omitempty"`
+	Retry          *RetryConfig          `yaml:"retry,omitempty"`
+	Ack            *AckConfig            `yaml:"ack,omitempty"`
+	Transport      *TransportConfig      `yaml:"transport,omitempty"`
 
 	// Multi-target configuration
-	HECTargets []HECTarget    `yaml:"hec_targets"`
-	Routing    *RoutingConfig `yaml:"routing"`
+	HECTargets []HECTarget    `yaml:"hec_targets,omitempty"`
+	Routing    *RoutingConfig `yaml:"routing,omitempty"`
 }
 
 // TLSConfig holds TLS certificate configuration for encrypted connections.
 // Both CertFile and KeyFile must be specified together.
+// ClientAuth enables mutual TLS, verifying client certificates against ClientCAFile.
 type TLSConfig struct {
-	CertFile string `yaml:"cert_file"`
-	KeyFile  string `yaml:"key_file"`
+	CertFile       string   `yaml:"cert_file,omitempty"`
+	KeyFile        string   `yaml:"key_file,omitempty"`
+	ClientCAFile   string   `yaml:"client_ca_file,omitempty"`  // CA bundle for verifying client certificates
+	ClientAuth     string   `yaml:"client_auth,omitempty"`     // Client certificate mode: none (default), optional, required
+	AllowedClients []string `yaml:"allowed_clients,omitempty"` // Allowed client certificate CNs/SANs (empty = any verified client)
 }
 
 // TimeoutConfig holds timeout configuration for TCP connections.
@@ -137,8 +190,137 @@ type TimeoutConfig struct {
 // DLQConfig holds dead letter queue configuration for failed HEC forwards.
 // Failed messages are written to NDJSON files for later analysis or replay.
 type DLQConfig struct {
-	Enabled bool   `yaml:"enabled"`   // Enable/disable DLQ (default: false)
-	Dir     string `yaml:"directory"` // Directory for DLQ files
+	Enabled bool            `yaml:"enabled"`             // Enable/disable DLQ (default: false)
+	Dir     string          `yaml:"directory,omitempty"` // Directory for DLQ files
+	Drain   *DLQDrainConfig `yaml:"drain,omitempty"`     // Automatic re-forwarding once HEC recovers
+}
+
+// DLQDrainConfig holds configuration for automatically draining the DLQ.
+// When enabled, entries are re-forwarded at a limited rate while the HEC circuit breaker is closed.
+type DLQDrainConfig struct {
+	Enabled       bool `yaml:"enabled"`                // Enable/disable automatic drain (default: false)
+	RateLimit     int  `yaml:"rate_limit"`             // Maximum entries re-forwarded per second (default: 100)
+	CheckInterval int  `yaml:"check_interval_seconds"` // How often to check breaker state and backlog (default: 10)
+}
+
+// QueueConfig holds configuration for the durable forward queue.
+// Accepted lines are written to a disk-backed queue and removed only after reaching HEC or the DLQ.
+type QueueConfig struct {
+	Enabled      bool   `yaml:"enabled"`             // Enable/disable the forward queue (default: false)
+	Dir          string `yaml:"directory,omitempty"` // Directory for queue segments (default: {output_dir}/queue)
+	MaxBytes     int64  `yaml:"max_bytes"`           // Unforwarded bytes before clients are slowed down (default: 268435456)
+	SegmentBytes int64  `yaml:"segment_bytes"`       // Size of each segment file (default: 67108864)
+}
+
+// TailConfig holds configuration for tail-the-store forwarding.
+// When enabled, lines are only written to storage and a tailer forwards them from the daily files,
+// checkpointing a byte offset after each successful HEC batch.
+type TailConfig struct {
+	Enabled        bool   `yaml:"enabled"`                   // Enable/disable tail mode (default: false)
+	CheckpointFile string `yaml:"checkpoint_file,omitempty"` // Checkpoint path (default: {output_dir}/{file_prefix}.tail-checkpoint.json)
+	BatchMaxLines  int    `yaml:"batch_max_lines"`           // Maximum lines per HEC request (default: 500)
+	BatchMaxBytes  int    `yaml:"batch_max_bytes"`           // Maximum bytes per HEC request (default: 1048576)
+	PollInterval   int    `yaml:"poll_interval_seconds"`     // How often to check for new lines when caught up (default: 1)
+}
+
+// SchemaValidationConfig holds configuration for checking lines against their log type's schema.
+// Lines with fields that are missing, unexpected or of the wrong type count as schema drift.
+type SchemaValidationConfig struct {
+	Enabled  bool   `yaml:"enabled"`          // Enable/disable schema validation (default: false)
+	Action   string `yaml:"action,omitempty"` // What to do with drifted lines: forward or quarantine (default: forward)
+	LogEvery int    `yaml:"log_every"`        // Log the first drifted line and then one in every N (default: 100)
+}
+
+// Schema validation actions.
+const (
+	SchemaActionForward    = "forward"    // Count and log drifted lines, then store and forward them as usual
+	SchemaActionQuarantine = "quarantine" // Write drifted lines to the quarantine store instead (requires quarantine.enabled)
+)
+
+// QuarantineConfig holds configuration for the quarantine store.
+// Lines that are not valid JSON or exceed max_line_bytes are written to NDJSON files with
+// their origin instead of being dropped.
+type QuarantineConfig struct {
+	Enabled      bool   `yaml:"enabled"`             // Enable/disable the quarantine store (default: false)
+	Dir          string `yaml:"directory,omitempty"` // Directory for quarantine files (default: {output_dir}/quarantine)
+	MaxDataBytes int    `yaml:"max_data_bytes"`      // Bytes of each line to keep; the hash covers the whole line (default: 65536)
+}
+
+// RedactionConfig holds configuration for removing or pseudonymising fields of a listener's lines.
+// Rules apply to stored and forwarded lines; forward rules apply to forwarded lines only,
+// so the stored copy can keep fields that Splunk must not receive.
+type RedactionConfig struct {
+	HMACKey      string          `yaml:"hmac_key,omitempty"`      // Key for hmac pseudonyms; accepts secret references
+	Rules        []RedactionRule `yaml:"rules,omitempty"`         // Applied before lines are stored and forwarded
+	ForwardRules []RedactionRule `yaml:"forward_rules,omitempty"` // Applied to forwarded lines only
+}
+
+// RedactionRule redacts the field at a JSON path.
+type RedactionRule struct {
+	Path   string `yaml:"path"`           // Dotted path of object keys, such as Username or Policy.Name
+	Action string `yaml:"action"`         // drop, mask or hmac
+	Mask   string `yaml:"mask,omitempty"` // Replacement for mask (default: [REDACTED])
+}
+
+// RedactRules converts redaction rules to the rules of a redact.Redactor, in order.
+func RedactRules(rules ...[]RedactionRule) []redact.Rule {
+	var out []redact.Rule
+	for _, set := range rules {
+		for _, rule := range set {
+			out = append(out, redact.Rule{Path: rule.Path, Action: rule.Action, Mask: rule.Mask})
+		}
+	}
+	return out
+}
+
+// FilterRule drops, samples or routes the lines that meet all of its conditions.
+// A listener's rules are checked in order and the first rule a line matches applies.
+type FilterRule struct {
+	Name       string            `yaml:"name"`                  // Identifies the rule in metrics
+	Match      []FilterCondition `yaml:"match"`                 // Conditions that must all hold
+	Action     string            `yaml:"action"`                // drop, drop_forward, sample or route
+	SampleRate int               `yaml:"sample_rate,omitempty"` // Forward one in every N matching lines (sample)
+	Target     string            `yaml:"target,omitempty"`      // HEC target to forward matching lines to (route)
+}
+
+// FilterCondition tests the value of the field at a JSON path. Every test that is set must hold.
+type FilterCondition struct {
+	Field  string   `yaml:"field"`            // Dotted path of object keys, such as Status or Policy.Name
+	Equals *string  `yaml:"equals,omitempty"` // Value, compared as text
+	Regex  string   `yaml:"regex,omitempty"`  // Regular expression the value must match
+	GT     *float64 `yaml:"gt,omitempty"`     // Numeric comparisons; numbers given as strings are also compared
+	GTE    *float64 `yaml:"gte,omitempty"`
+	LT     *float64 `yaml:"lt,omitempty"`
+	LTE    *float64 `yaml:"lte,omitempty"`
+	Exists *bool    `yaml:"exists,omitempty"` // Whether the field must be present
+}
+
+// FilterRules converts filter rules to the rules of a filter.Filter, in order.
+func FilterRules(rules []FilterRule) []filter.Rule {
+	out := make([]filter.Rule, 0, len(rules))
+	for _, rule := range rules {
+		match := make([]filter.Condition, 0, len(rule.Match))
+		for _, c := range rule.Match {
+			match = append(match, filter.Condition{
+				Field:          c.Field,
+				Equals:         c.Equals,
+				Regex:          c.Regex,
+				GreaterThan:    c.GT,
+				GreaterOrEqual: c.GTE,
+				LessThan:       c.LT,
+				LessOrEqual:    c.LTE,
+				Exists:         c.Exists,
+			})
+		}
+		out = append(out, filter.Rule{
+			Name:       rule.Name,
+			Match:      match,
+			Action:     rule.Action,
+			SampleRate: rule.SampleRate,
+			Target:     rule.Target,
+		})
+	}
+	return out
 }
 
 // RetentionConfig holds configuration for automatic cleanup of old log files.
@@ -153,43 +335,102 @@ type RetentionConfig struct {
 // AuditConfig holds configuration for audit logging.
 // Audit logs provide a tamper-evident trail of security-relevant events for compliance.
 type AuditConfig struct {
-	Enabled     bool   `yaml:"enabled"`      // Enable/disable audit logging (default: false)
-	LogFile     string `yaml:"log_file"`     // Path to audit log file (default: ./audit.log)
-	Format      string `yaml:"format"`       // Output format: "json" or "cef" (default: json)
-	IncludeData bool   `yaml:"include_data"` // Include line data in audit (default: false, PII concern)
+	Enabled     bool   `yaml:"enabled"`            // Enable/disable audit logging (default: false)
+	LogFile     string `yaml:"log_file,omitempty"` // Path to audit log file (default: ./audit.log)
+	Format      string `yaml:"format,omitempty"`   // Output format: "json" or "cef" (default: json)
+	IncludeData bool   `yaml:"include_data"`       // Include line data in audit (default: false, PII concern)
+}
+
+// HealthConfig holds configuration for the HTTP health (/healthz) and readiness (/readyz) endpoints.
+// Unlike the TCP health check, these report the status of each listener and its HEC targets.
+type HealthConfig struct {
+	Enabled   bool             `yaml:"enabled"`             // Enable/disable the HTTP health server (default: false)
+	Addr      string           `yaml:"addr,omitempty"`      // Address for the HTTP health server (default: :9098)
+	Readiness *ReadinessConfig `yaml:"readiness,omitempty"` // Conditions that fail /readyz
+}
+
+// ReadinessConfig holds the conditions under which /readyz reports the relay as not ready.
+// A listener that is not accepting connections always fails readiness.
+type ReadinessConfig struct {
+	CircuitOpen        *bool `yaml:"circuit_open,omitempty"`       // Fail while any HEC circuit breaker is open (default: true)
+	StorageUnwritable  *bool `yaml:"storage_unwritable,omitempty"` // Fail while any storage directory is not writable (default: true)
+	MaxDLQBacklogBytes int64 `yaml:"max_dlq_backlog_bytes"`        // Fail when a listener's DLQ backlog exceeds this size (0 = disabled)
+	MaxEventAge        int   `yaml:"max_event_age_seconds"`        // Fail when a listener has received nothing for this long (0 = disabled)
+}
+
+// AdminConfig holds configuration for the local admin API used by relay ctl.
+// The API is served over a Unix socket so access is controlled by file permissions.
+type AdminConfig struct {
+	Enabled bool   `yaml:"enabled"`          // Enable/disable the admin API (default: false)
+	Socket  string `yaml:"socket,omitempty"` // Unix socket path (default: /run/relay/admin.sock)
 }
 
 // ListenerConfig holds configuration for a single TCP listener.
 // Each listener can accept ZPA logs on a specific port and handle a specific log type.
 type ListenerConfig struct {
-	Name         string         `yaml:"name"`
-	ListenAddr   string         `yaml:"listen_addr"`
-	LogType      string         `yaml:"log_type"`
-	OutputDir    string         `yaml:"output_dir"`
-	FilePrefix   string         `yaml:"file_prefix"`
-	TLS          *TLSConfig     `yaml:"tls"`
-	AllowedCIDRs string         `yaml:"allowed_cidrs"`
-	MaxLineBytes int            `yaml:"max_line_bytes"`
-	Timeout      *TimeoutConfig `yaml:"timeout"`
-	DLQ          *DLQConfig     `yaml:"dlq"`
-	Splunk       *SplunkConfig  `yaml:"splunk"`
+	Name             string                  `yaml:"name,omitempty"`
+	ListenAddr       string                  `yaml:"listen_addr,omitempty"`
+	LogType          string                  `yaml:"log_type,omitempty"`
+	OutputDir        string                  `yaml:"output_dir,omitempty"`
+	FilePrefix       string                  `yaml:"file_prefix,omitempty"`
+	TLS              *TLSConfig              `yaml:"tls,omitempty"`
+	AllowedCIDRs     string                  `yaml:"allowed_cidrs,omitempty"`
+	MaxLineBytes     int                     `yaml:"max_line_bytes"`
+	Timeout          *TimeoutConfig          `yaml:"timeout,omitempty"`
+	DLQ              *DLQConfig              `yaml:"dlq,omitempty"`
+	Queue            *QueueConfig            `yaml:"queue,omitempty"`
+	Tail             *TailConfig             `yaml:"tail,omitempty"`
+	SchemaValidation *SchemaValidationConfig `yaml:"schema_validation,omitempty"`
+	Quarantine       *QuarantineConfig       `yaml:"quarantine,omitempty"`
+	Redaction        *RedactionConfig        `yaml:"redaction,omitempty"`
+	Filters          []FilterRule            `yaml:"filters,omitempty"`
+	Splunk           *SplunkConfig           `yaml:"splunk,omitempty"`
 }
 
 // Config represents the complete application configuration.
 // It supports multiple listeners, each with independent settings for storage and forwarding.
 type Config struct {
-	Splunk             *SplunkConfig    `yaml:"splunk"`
+	Include            []string         `yaml:"include,omitempty"` // Configuration fragments merged into this file (main file only)
+	Splunk             *SplunkConfig    `yaml:"splunk,omitempty"`
 	HealthCheckEnabled bool             `yaml:"health_check_enabled"`
-	HealthCheckAddr    string           `yaml:"health_check_addr"`
-	Retention          *RetentionConfig `yaml:"retention"`
-	Audit              *AuditConfig     `yaml:"audit"`
-	Listeners          []ListenerConfig `yaml:"listeners"`
+	HealthCheckAddr    string           `yaml:"health_check_addr,omitempty"`
+	Health             *HealthConfig    `yaml:"health,omitempty"`
+	Admin              *AdminConfig     `yaml:"admin,omitempty"`
+	Retention          *RetentionConfig `yaml:"retention,omitempty"`
+	Audit              *AuditConfig     `yaml:"audit,omitempty"`
+	Listeners          []ListenerConfig `yaml:"listeners,omitempty"`
+}
+
+// loadOptions adjusts the checks loadConfig makes against the running system.
+type loadOptions struct {
+	bound  []string // Listen addresses held by the running relay, not checked for availability
+	noBind bool     // Skip the availability check of every listen address
 }
 
 // LoadConfig reads and validates configuration from the specified YAML file.
 // It returns an error if the file cannot be read, parsed, or contains invalid settings.
 // All listener addresses, TLS certificates, and storage directories are validated during load.
+// Invalid settings are reported together, as an error joined with errors.Join.
 func LoadConfig(configFile string) (*Config, error) {
+	return loadConfig(configFile, loadOptions{})
+}
+
+// ReloadConfig reads and validates configuration like LoadConfig, for a relay that is already
+// running. Listen addresses in bound are held by the running relay's listeners, so they are
+// not checked for availability.
+func ReloadConfig(configFile string, bound []string) (*Config, error) {
+	return loadConfig(configFile, loadOptions{bound: bound})
+}
+
+// ValidateConfig reads and validates configuration like LoadConfig, for checking a file
+// without starting the relay. With bind false, listen addresses are not checked for
+// availability, so the configuration of a running relay can be checked on its host.
+func ValidateConfig(configFile string, bind bool) (*Config, error) {
+	return loadConfig(configFile, loadOptions{noBind: !bind})
+}
+
+// loadConfig reads, resolves and validates configuration.
+func loadConfig(configFile string, opts loadOptions) (*Config, error) {
 	// Config file is now required
 	if configFile == "" {
 		return nil, fmt.Errorf("configuration file is required")
@@ -208,16 +449,27 @@ func LoadConfig(configFile string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %v", err)
 	}
 
-	// Parse YAML
-	config := &Config{}
-	if err := yaml.Unmarshal(data, config); err != nil {
-		return nil, fmt.Errorf("failed to parse YAML config: %v", err)
+	// Parse YAML, merging in included files
+	config, includes, err := parseConfig(configFile, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve secret references (${ENV}, file:, credential:) before validation
+	if err := resolveSecrets(config); err != nil {
+		return nil, fmt.Errorf("failed to resolve secret: %w", err)
 	}
 
 	// Apply defaults
 	if config.HealthCheckAddr == "" {
 		config.HealthCheckAddr = DefaultHealthCheckAddr
 	}
+	if config.Health != nil && config.Health.Enabled && config.Health.Addr == "" {
+		config.Health.Addr = DefaultHealthAddr
+	}
+	if config.Admin != nil && config.Admin.Enabled && config.Admin.Socket == "" {
+		config.Admin.Socket = DefaultAdminSocket
+	}
 
 	// Apply retention defaults if retention is enabled
 	if config.Retention != nil && config.Retention.Enabled {
@@ -230,106 +482,162 @@ func LoadConfig(configFile string) (*Config, error) {
 		// CompressAge defaults to 0 (disabled) if not specified
 	}
 
+	// Apply DLQ drain defaults if drain is enabled
+	for i := range config.Listeners {
+		dlqCfg := config.Listeners[i].DLQ
+		if dlqCfg == nil || dlqCfg.Drain == nil || !dlqCfg.Drain.Enabled {
+			continue
+		}
+		if dlqCfg.Drain.RateLimit == 0 {
+			dlqCfg.Drain.RateLimit = 100 // Default: 100 entries per second
+		}
+		if dlqCfg.Drain.CheckInterval == 0 {
+			dlqCfg.Drain.CheckInterval = 10 // Default: 10 seconds
+		}
+	}
+
+	// Apply schema validation defaults if schema validation is enabled
+	for i := range config.Listeners {
+		svCfg := config.Listeners[i].SchemaValidation
+		if svCfg == nil || !svCfg.Enabled {
+			continue
+		}
+		if svCfg.Action == "" {
+			svCfg.Action = SchemaActionForward
+		}
+		if svCfg.LogEvery == 0 {
+			svCfg.LogEvery = 100 // Default: log one in every 100 drifted lines
+		}
+	}
+
 	// Validate configuration
-	if err := validateConfig(config); err != nil {
+	if err := validateConfig(config, opts); err != nil {
 		return nil, err
 	}
 
-	slog.Info("loaded configuration", "file", configFile)
+	if len(includes) > 0 {
+		slog.Info("loaded configuration", "file", configFile, "includes", includes)
+	} else {
+		slog.Info("loaded configuration", "file", configFile)
+	}
 	return config, nil
 }
 
-func validateConfig(cfg *Config) error {
+// validateConfig checks the configuration and returns every problem found, joined into one error.
+// Checks that depend on a setting that is already invalid are skipped.
+func validateConfig(cfg *Config, opts loadOptions) error {
+	var errs []error
+
 	// Require at least one listener
 	if len(cfg.Listeners) == 0 {
-		return fmt.Errorf("at least one listener is required")
+		errs = append(errs, fmt.Errorf("at least one listener is required"))
 	}
 
 	// Validate retention configuration if enabled
 	if cfg.Retention != nil && cfg.Retention.Enabled {
 		if cfg.Retention.MaxAge <= 0 {
-			return fmt.Errorf("retention.max_age_days must be greater than 0")
+			errs = append(errs, fmt.Errorf("retention.max_age_days must be greater than 0"))
 		}
 		if cfg.Retention.CheckInterval <= 0 {
-			return fmt.Errorf("retention.check_interval_seconds must be greater than 0")
+			errs = append(errs, fmt.Errorf("retention.check_interval_seconds must be greater than 0"))
 		}
 		if cfg.Retention.CompressAge < 0 {
-			return fmt.Errorf("retention.compress_age_days cannot be negative")
+			errs = append(errs, fmt.Errorf("retention.compress_age_days cannot be negative"))
 		}
 		if cfg.Retention.CompressAge > 0 && cfg.Retention.CompressAge >= cfg.Retention.MaxAge {
-			return fmt.Errorf("retention.compress_age_days (%d) must be less than max_age_days (%d)",
-				cfg.Retention.CompressAge, cfg.Retention.MaxAge)
+			errs = append(errs, fmt.Errorf("retention.compress_age_days (%d) must be less than max_age_days (%d)",
+				cfg.Retention.CompressAge, cfg.Retention.MaxAge))
+		}
+	}
+
+	// Validate HTTP health configuration if enabled
+	if cfg.Health != nil && cfg.Health.Enabled {
+		if cfg.HealthCheckEnabled && cfg.Health.Addr == cfg.HealthCheckAddr {
+			errs = append(errs, fmt.Errorf("health.addr '%s' conflicts with health_check_addr", cfg.Health.Addr))
+		}
+		if r := cfg.Health.Readiness; r != nil {
+			if r.MaxDLQBacklogBytes < 0 {
+				errs = append(errs, fmt.Errorf("health.readiness.max_dlq_backlog_bytes cannot be negative"))
+			}
+			if r.MaxEventAge < 0 {
+				errs = append(errs, fmt.Errorf("health.readiness.max_event_age_seconds cannot be negative"))
+			}
 		}
 	}
 
-	// Track unique listen addresses
+	// Track unique listener names and listen addresses
+	names := make(map[string]bool)
 	listenAddrs := make(map[string]bool)
 
 	for i, listener := range cfg.Listeners {
-		// Validate required fields
+		// Validate required fields; the remaining checks need them
 		if listener.Name == "" {
-			return fmt.Errorf("listener %d: name is required", i)
+			errs = append(errs, fmt.Errorf("listener %d: name is required", i))
+			continue
+		}
+		if names[listener.Name] {
+			errs = append(errs, fmt.Errorf("listener %s: duplicate name", listener.Name))
 		}
+		names[listener.Name] = true
+
+		var missing []error
 		if listener.ListenAddr == "" {
-			return fmt.Errorf("listener %s: listen_addr is required", listener.Name)
+			missing = append(missing, fmt.Errorf("listener %s: listen_addr is required", listener.Name))
 		}
 		if listener.LogType == "" {
-			return fmt.Errorf("listener %s: log_type is required", listener.Name)
+			missing = append(missing, fmt.Errorf("listener %s: log_type is required", listener.Name))
 		}
 		if listener.OutputDir == "" {
-			return fmt.Errorf("listener %s: output_dir is required", listener.Name)
+			missing = append(missing, fmt.Errorf("listener %s: output_dir is required", listener.Name))
 		}
 		if listener.FilePrefix == "" {
-			return fmt.Errorf("listener %s: file_prefix is required", listener.Name)
+			missing = append(missing, fmt.Errorf("listener %s: file_prefix is required", listener.Name))
+		}
+		if len(missing) > 0 {
+			errs = append(errs, missing...)
+			continue
 		}
 
 		// Validate log type
 		lt := logtypes.LogType(listener.LogType)
 		if !lt.IsValid() {
-			return fmt.Errorf("listener %s: invalid log_type '%s'", listener.Name, listener.LogType)
+			errs = append(errs, fmt.Errorf("listener %s: invalid log_type '%s'", listener.Name, listener.LogType))
 		}
 
 		// Check for duplicate listen addresses
 		if listenAddrs[listener.ListenAddr] {
-			return fmt.Errorf("listener %s: duplicate listen_addr '%s'", listener.Name, listener.ListenAddr)
+			errs = append(errs, fmt.Errorf("listener %s: duplicate listen_addr '%s'", listener.Name, listener.ListenAddr))
+		} else if !opts.noBind && !slices.Contains(opts.bound, listener.ListenAddr) {
+			// Validate listen address availability (addresses the running relay holds are not free)
+			if err := validateListenAddr(listener.ListenAddr); err != nil {
+				errs = append(errs, fmt.Errorf("listener %s: cannot bind to listen address: %w", listener.Name, err))
+			}
 		}
 		listenAddrs[listener.ListenAddr] = true
 
-		// Validate listen address availability
-		if err := validateListenAddr(listener.ListenAddr); err != nil {
-			return fmt.Errorf("listener %s: cannot bind to listen address: %w", listener.Name, err)
-		}
-
 		// Validate TLS configuration
 		if listener.TLS != nil {
 			if (listener.TLS.CertFile == "") != (listener.TLS.KeyFile == "") {
-				return fmt.Errorf("listener %s: both tls.cert_file and tls.key_file must be specified or both omitted", listener.Name)
-			}
-			if listener.TLS.CertFile != "" {
-				// Check if cert file exists and is readable
-				if _, err := os.Stat(listener.TLS.CertFile); err != nil {
-					return fmt.Errorf("listener %s: TLS cert file not accessible: %w", listener.Name, err)
-				}
-				// Check if key file exists and is readable
-				if _, err := os.Stat(listener.TLS.KeyFile); err != nil {
-					return fmt.Errorf("listener %s: TLS key file not accessible: %w", listener.Name, err)
-				}
-				// Validate certificate by loading it
-				if _, err := tls.LoadX509KeyPair(listener.TLS.CertFile, listener.TLS.KeyFile); err != nil {
-					return fmt.Errorf("listener %s: failed to load TLS certificate: %w", listener.Name, err)
+				errs = append(errs, fmt.Errorf("listener %s: both tls.cert_file and tls.key_file must be specified or both omitted", listener.Name))
+			} else if listener.TLS.CertFile != "" {
+				if err := validateCertificate(listener.TLS); err != nil {
+					errs = append(errs, fmt.Errorf("listener %s: %w", listener.Name, err))
 				}
 			}
+			if err := validateClientAuth(listener.TLS); err != nil {
+				errs = append(errs, fmt.Errorf("listener %s: %w", listener.Name, err))
+			}
 		}
 
 		// Validate storage directory (create if needed and test writability)
 		if err := validateStorageDir(listener.OutputDir); err != nil {
-			return fmt.Errorf("listener %s: %w", listener.Name, err)
+			errs = append(errs, fmt.Errorf("listener %s: %w", listener.Name, err))
 		}
 
 		// Validate CIDR list
 		if listener.AllowedCIDRs != "" {
 			if _, err := acl.New(listener.AllowedCIDRs); err != nil {
-				return fmt.Errorf("listener %s: invalid CIDR list: %w", listener.Name, err)
+				errs = append(errs, fmt.Errorf("listener %s: invalid CIDR list: %w", listener.Name, err))
 			}
 		}
 
@@ -367,36 +675,180 @@ func validateConfig(cfg *Config) error {
 		// Validate single vs multi-target configuration
 		if hasMultiTarget {
 			// Validate multi-target configuration
-			if err := validateMultiTargetConfig(cfg.Splunk, listener.Splunk, listener.Name); err != nil {
-				return err
-			}
+			errs = append(errs, validateMultiTargetConfig(cfg.Splunk, listener.Splunk, listener.Name, listener.Redaction)...)
 		} else {
 			// Validate legacy single HEC configuration
 			if hecURL != "" || hecToken != "" {
 				// Both URL and token must be specified
 				if hecURL == "" {
-					return fmt.Errorf("listener %s: HEC URL required when HEC token is specified", listener.Name)
+					errs = append(errs, fmt.Errorf("listener %s: HEC URL required when HEC token is specified", listener.Name))
 				}
 				if hecToken == "" {
-					return fmt.Errorf("listener %s: HEC token required when HEC URL is specified", listener.Name)
+					errs = append(errs, fmt.Errorf("listener %s: HEC token required when HEC URL is specified", listener.Name))
 				}
 				if sourceType == "" {
-					return fmt.Errorf("listener %s: splunk.source_type is required when HEC is configured", listener.Name)
+					errs = append(errs, fmt.Errorf("listener %s: splunk.source_type is required when HEC is configured", listener.Name))
 				}
 
 				// Validate HEC URL format
-				if err := validateHECURL(hecURL); err != nil {
-					return fmt.Errorf("listener %s: invalid HEC URL: %w", listener.Name, err)
+				if hecURL != "" {
+					if err := validateHECURL(hecURL); err != nil {
+						errs = append(errs, fmt.Errorf("listener %s: invalid HEC URL: %w", listener.Name, err))
+					}
+				}
+			}
+
+			// Validate endpoint format, with per-listener settings overriding global ones
+			var endpoint, index, source string
+			var fields map[string]string
+			for _, sc := range []*SplunkConfig{cfg.Splunk, listener.Splunk} {
+				if sc == nil {
+					continue
+				}
+				if sc.Endpoint != "" {
+					endpoint = sc.Endpoint
+				}
+				if sc.Index != "" {
+					index = sc.Index
+				}
+				if sc.Source != "" {
+					source = sc.Source
+				}
+				if len(sc.Fields) > 0 {
+					fields = sc.Fields
+				}
+			}
+			if err := validateEndpoint(endpoint, index, source, fields); err != nil {
+				errs = append(errs, fmt.Errorf("listener %s: %w", listener.Name, err))
+			}
+			for _, sc := range []*SplunkConfig{cfg.Splunk, listener.Splunk} {
+				if sc == nil {
+					continue
+				}
+				if err := validateAck(sc.Ack); err != nil {
+					errs = append(errs, fmt.Errorf("listener %s: %w", listener.Name, err))
 				}
 			}
 		}
 
+		// Validate DLQ drain configuration
+		if listener.DLQ != nil && listener.DLQ.Drain != nil && listener.DLQ.Drain.Enabled {
+			if !listener.DLQ.Enabled {
+				errs = append(errs, fmt.Errorf("listener %s: dlq.drain requires dlq.enabled", listener.Name))
+			}
+			if hasMultiTarget || hecURL == "" {
+				errs = append(errs, fmt.Errorf("listener %s: dlq.drain requires a single-target HEC (hec_url)", listener.Name))
+			}
+			if listener.DLQ.Drain.RateLimit <= 0 {
+				errs = append(errs, fmt.Errorf("listener %s: dlq.drain.rate_limit must be greater than 0", listener.Name))
+			}
+			if listener.DLQ.Drain.CheckInterval <= 0 {
+				errs = append(errs, fmt.Errorf("listener %s: dlq.drain.check_interval_seconds must be greater than 0", listener.Name))
+			}
+		}
+
+		// Validate forward queue configuration
+		if listener.Queue != nil && listener.Queue.Enabled {
+			if listener.Queue.MaxBytes < 0 {
+				errs = append(errs, fmt.Errorf("listener %s: queue.max_bytes cannot be negative", listener.Name))
+			}
+			if listener.Queue.SegmentBytes < 0 {
+				errs = append(errs, fmt.Errorf("listener %s: queue.segment_bytes cannot be negative", listener.Name))
+			}
+		}
+
+		// Validate tail mode configuration
+		if listener.Tail != nil && listener.Tail.Enabled {
+			if !hasMultiTarget && hecURL == "" {
+				errs = append(errs, fmt.Errorf("listener %s: tail requires HEC to be configured", listener.Name))
+			}
+			if listener.Queue != nil && listener.Queue.Enabled {
+				errs = append(errs, fmt.Errorf("listener %s: tail cannot be combined with queue", listener.Name))
+			}
+			if listener.DLQ != nil && listener.DLQ.Enabled {
+				errs = append(errs, fmt.Errorf("listener %s: tail cannot be combined with dlq; failed batches are retried from storage", listener.Name))
+			}
+			if listener.Tail.BatchMaxLines < 0 {
+				errs = append(errs, fmt.Errorf("listener %s: tail.batch_max_lines cannot be negative", listener.Name))
+			}
+			if listener.Tail.BatchMaxBytes < 0 {
+				errs = append(errs, fmt.Errorf("listener %s: tail.batch_max_bytes cannot be negative", listener.Name))
+			}
+			if listener.Tail.PollInterval < 0 {
+				errs = append(errs, fmt.Errorf("listener %s: tail.poll_interval_seconds cannot be negative", listener.Name))
+			}
+		}
+
+		// Validate schema validation configuration
+		if sv := listener.SchemaValidation; sv != nil && sv.Enabled {
+			if _, ok := logschema.For(lt); lt.IsValid() && !ok {
+				errs = append(errs, fmt.Errorf("listener %s: schema_validation is not available for log_type '%s'", listener.Name, listener.LogType))
+			}
+			if sv.Action != SchemaActionForward && sv.Action != SchemaActionQuarantine {
+				errs = append(errs, fmt.Errorf("listener %s: schema_validation.action must be '%s' or '%s'", listener.Name, SchemaActionForward, SchemaActionQuarantine))
+			}
+			if sv.LogEvery < 0 {
+				errs = append(errs, fmt.Errorf("listener %s: schema_validation.log_every cannot be negative", listener.Name))
+			}
+			if sv.Action == SchemaActionQuarantine && (listener.Quarantine == nil || !listener.Quarantine.Enabled) {
+				errs = append(errs, fmt.Errorf("listener %s: schema_validation.action 'quarantine' requires quarantine.enabled", listener.Name))
+			}
+		}
+
+		// Validate redaction rules
+		if rd := listener.Redaction; rd != nil {
+			if _, err := redact.New(RedactRules(rd.Rules), []byte(rd.HMACKey)); err != nil {
+				errs = append(errs, fmt.Errorf("listener %s: redaction.rules: %w", listener.Name, err))
+			}
+			if _, err := redact.New(RedactRules(rd.ForwardRules), []byte(rd.HMACKey)); err != nil {
+				errs = append(errs, fmt.Errorf("listener %s: redaction.forward_rules: %w", listener.Name, err))
+			}
+		}
+
+		// Validate filter rules
+		if len(listener.Filters) > 0 {
+			if _, err := filter.New(FilterRules(listener.Filters)); err != nil {
+				errs = append(errs, fmt.Errorf("listener %s: filters: %w", listener.Name, err))
+			}
+			targets := hecTargetNames(cfg.Splunk, listener.Splunk)
+			for _, rule := range listener.Filters {
+				if rule.Action == filter.Route && rule.Target != "" && !targets[rule.Target] {
+					errs = append(errs, fmt.Errorf("listener %s: filters: rule %s: target '%s' is not one of the listener's hec_targets", listener.Name, rule.Name, rule.Target))
+				}
+				if rule.Action != filter.Drop && listener.Tail != nil && listener.Tail.Enabled {
+					errs = append(errs, fmt.Errorf("listener %s: filters: rule %s: action '%s' cannot be used with tail, which forwards every stored line", listener.Name, rule.Name, rule.Action))
+				}
+			}
+		}
+
+		// Validate quarantine configuration
+		if listener.Quarantine != nil && listener.Quarantine.Enabled && listener.Quarantine.MaxDataBytes < 0 {
+			errs = append(errs, fmt.Errorf("listener %s: quarantine.max_data_bytes cannot be negative", listener.Name))
+		}
+
 		// Apply default max line bytes if not specified
 		if listener.MaxLineBytes == 0 {
 			cfg.Listeners[i].MaxLineBytes = DefaultMaxLineBytes
 		}
 	}
 
+	return errors.Join(errs...)
+}
+
+// validateCertificate checks that the listener's certificate and key can be loaded.
+func validateCertificate(t *TLSConfig) error {
+	// Check if cert file exists and is readable
+	if _, err := os.Stat(t.CertFile); err != nil {
+		return fmt.Errorf("TLS cert file not accessible: %w", err)
+	}
+	// Check if key file exists and is readable
+	if _, err := os.Stat(t.KeyFile); err != nil {
+		return fmt.Errorf("TLS key file not accessible: %w", err)
+	}
+	// Validate certificate by loading it
+	if _, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile); err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
 	return nil
 }
 
@@ -453,15 +905,32 @@ func GetTemplate() string {
 	return configTemplate
 }
 
-// validateMultiTargetConfig validates multi-target HEC configuration
-func validateMultiTargetConfig(global, perListener *SplunkConfig, listenerName string) error {
+// hecTargetNames returns the names of the HEC targets a listener forwards to.
+// Per-listener targets replace the global ones.
+func hecTargetNames(global, perListener *SplunkConfig) map[string]bool {
+	var targets []HECTarget
+	if global != nil {
+		targets = global.HECTargets
+	}
+	if perListener != nil && len(perListener.HECTargets) > 0 {
+		targets = perListener.HECTargets
+	}
+	names := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		names[target.Name] = true
+	}
+	return names
+}
+
+// validateMultiTargetConfig validates multi-target HEC configuration, returning every problem found
+func validateMultiTargetConfig(global, perListener *SplunkConfig, listenerName string, redaction *RedactionConfig) []error {
 	// Collect targets from both global and per-listener config
 	var targets []HECTarget
 
 	if global != nil && len(global.HECTargets) > 0 {
 		// Cannot mix single and multi-target config at global level
 		if global.HECURL != "" || global.HECToken != "" {
-			return fmt.Errorf("listener %s: cannot specify both legacy HEC config (hec_url/hec_token) and hec_targets", listenerName)
+			return []error{fmt.Errorf("listener %s: cannot specify both legacy HEC config (hec_url/hec_token) and hec_targets", listenerName)}
 		}
 		targets = append(targets, global.HECTargets...)
 	}
@@ -469,7 +938,7 @@ func validateMultiTargetConfig(global, perListener *SplunkConfig, listenerName s
 	if perListener != nil && len(perListener.HECTargets) > 0 {
 		// Cannot mix single and multi-target config at listener level
 		if perListener.HECURL != "" || perListener.HECToken != "" {
-			return fmt.Errorf("listener %s: cannot specify both legacy HEC config (hec_url/hec_token) and hec_targets", listenerName)
+			return []error{fmt.Errorf("listener %s: cannot specify both legacy HEC config (hec_url/hec_token) and hec_targets", listenerName)}
 		}
 		// Per-listener targets override global targets
 		targets = perListener.HECTargets
@@ -477,63 +946,216 @@ func validateMultiTargetConfig(global, perListener *SplunkConfig, listenerName s
 
 	// Require at least one target
 	if len(targets) == 0 {
-		return fmt.Errorf("listener %s: at least one HEC target required when using multi-target configuration", listenerName)
+		return []error{fmt.Errorf("listener %s: at least one HEC target required when using multi-target configuration", listenerName)}
 	}
 
 	// Track unique target names
 	targetNames := make(map[string]bool)
 
+	var errs []error
+
 	// Validate each target
 	for i, target := range targets {
 		if target.Name == "" {
-			return fmt.Errorf("listener %s: target %d: name is required", listenerName, i)
+			errs = append(errs, fmt.Errorf("listener %s: target %d: name is required", listenerName, i))
+			continue
 		}
 
 		// Check for duplicate names
 		if targetNames[target.Name] {
-			return fmt.Errorf("listener %s: duplicate target name '%s'", listenerName, target.Name)
+			errs = append(errs, fmt.Errorf("listener %s: duplicate target name '%s'", listenerName, target.Name))
 		}
 		targetNames[target.Name] = true
 
 		if target.HECURL == "" {
-			return fmt.Errorf("listener %s: target '%s': hec_url is required", listenerName, target.Name)
+			errs = append(errs, fmt.Errorf("listener %s: target '%s': hec_url is required", listenerName, target.Name))
+		} else if err := validateHECURL(target.HECURL); err != nil {
+			// Validate HEC URL format
+			errs = append(errs, fmt.Errorf("listener %s: target '%s': invalid HEC URL: %w", listenerName, target.Name, err))
 		}
 		if target.HECToken == "" {
-			return fmt.Errorf("listener %s: target '%s': hec_token is required", listenerName, target.Name)
+			errs = append(errs, fmt.Errorf("listener %s: target '%s': hec_token is required", listenerName, target.Name))
 		}
 		if target.SourceType == "" {
-			return fmt.Errorf("listener %s: target '%s': source_type is required", listenerName, target.Name)
+			errs = append(errs, fmt.Errorf("listener %s: target '%s': source_type is required", listenerName, target.Name))
 		}
 
-		// Validate HEC URL format
-		if err := validateHECURL(target.HECURL); err != nil {
-			return fmt.Errorf("listener %s: target '%s': invalid HEC URL: %w", listenerName, target.Name, err)
+		if err := validateEndpoint(target.Endpoint, target.Index, target.Source, target.Fields); err != nil {
+			errs = append(errs, fmt.Errorf("listener %s: target '%s': %w", listenerName, target.Name, err))
+		}
+		if err := validateAck(target.Ack); err != nil {
+			errs = append(errs, fmt.Errorf("listener %s: target '%s': %w", listenerName, target.Name, err))
+		}
+		if target.Weight < 0 || target.Weight > MaxTargetWeight {
+			errs = append(errs, fmt.Errorf("listener %s: target '%s': weight must be between 0 (default 1) and %d", listenerName, target.Name, MaxTargetWeight))
+		}
+		if len(target.Redact) > 0 {
+			// Target rules are keyed with the listener's hmac_key
+			var key string
+			if redaction != nil {
+				key = redaction.HMACKey
+			}
+			if _, err := redact.New(RedactRules(target.Redact), []byte(key)); err != nil {
+				errs = append(errs, fmt.Errorf("listener %s: target '%s': redact: %w", listenerName, target.Name, err))
+			}
 		}
 	}
 
 	// Validate routing configuration
-	var routingMode RoutingMode
+	var routing RoutingConfig
 	if perListener != nil && perListener.Routing != nil {
-		routingMode = perListener.Routing.Mode
+		routing = *perListener.Routing
 	} else if global != nil && global.Routing != nil {
-		routingMode = global.Routing.Mode
+		routing = *global.Routing
 	} else {
 		// Default routing mode
-		routingMode = RoutingModeAll
+		routing.Mode = RoutingModeAll
 	}
 
 	// Validate routing mode
-	if !isValidRoutingMode(routingMode) {
-		return fmt.Errorf("listener %s: invalid routing mode '%s' (must be one of: all, primary-failover, round-robin)", listenerName, routingMode)
+	if !isValidRoutingMode(routing.Mode) {
+		errs = append(errs, fmt.Errorf("listener %s: invalid routing mode '%s' (must be one of: all, primary-failover, round-robin, route-by-field, weighted, hash)", listenerName, routing.Mode))
+	}
+	if routing.Mode != RoutingModeWeighted && routing.Mode != RoutingModeHash {
+		for _, target := range targets {
+			if target.Weight != 0 {
+				errs = append(errs, fmt.Errorf("listener %s: target '%s': weight requires routing mode '%s' or '%s'", listenerName, target.Name, RoutingModeWeighted, RoutingModeHash))
+			}
+		}
+	}
+	for _, err := range validateFieldRoutes(routing, targets) {
+		errs = append(errs, fmt.Errorf("listener %s: %w", listenerName, err))
+	}
+
+	return errs
+}
+
+// validateFieldRoutes checks the field of route-by-field and hash routing, and the routes and
+// default targets of route-by-field routing.
+func validateFieldRoutes(routing RoutingConfig, targets []HECTarget) []error {
+	var errs []error
+	switch routing.Mode {
+	case RoutingModeRouteByField, RoutingModeHash:
+		if routing.Field == "" {
+			errs = append(errs, fmt.Errorf("routing.field is required for mode '%s'", routing.Mode))
+		} else if slices.Contains(strings.Split(strings.TrimPrefix(routing.Field, "$."), "."), "") {
+			errs = append(errs, fmt.Errorf("routing.field '%s' has an empty key", routing.Field))
+		}
+	default:
+		if routing.Field != "" {
+			errs = append(errs, fmt.Errorf("routing.field requires mode '%s' or '%s'", RoutingModeRouteByField, RoutingModeHash))
+		}
+	}
+	if routing.Mode != RoutingModeRouteByField {
+		if len(routing.Routes) > 0 || len(routing.Default) > 0 {
+			errs = append(errs, fmt.Errorf("routing.routes and default require mode '%s'", RoutingModeRouteByField))
+		}
+		return errs
 	}
 
+	if len(routing.Default) == 0 {
+		errs = append(errs, fmt.Errorf("routing.default is required for mode '%s'", RoutingModeRouteByField))
+	}
+
+	names := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		names[target.Name] = true
+	}
+	checkTargets := func(setting string, list []string) {
+		for _, name := range list {
+			if !names[name] {
+				errs = append(errs, fmt.Errorf("%s: unknown target '%s'", setting, name))
+			}
+		}
+	}
+	checkTargets("routing.default", routing.Default)
+
+	routed := make(map[string]bool)
+	for i, route := range routing.Routes {
+		setting := fmt.Sprintf("routing.routes[%d]", i)
+		if len(route.Values) == 0 {
+			errs = append(errs, fmt.Errorf("%s: values is required", setting))
+		}
+		if len(route.Targets) == 0 {
+			errs = append(errs, fmt.Errorf("%s: targets is required", setting))
+		}
+		checkTargets(setting, route.Targets)
+		for _, value := range route.Values {
+			if routed[value] {
+				errs = append(errs, fmt.Errorf("%s: value '%s' is already routed", setting, value))
+			}
+			routed[value] = true
+		}
+	}
+	return errs
+}
+
+// validateEndpoint checks the HEC endpoint format and the event metadata that depends on it.
+func validateEndpoint(endpoint, index, source string, fields map[string]string) error {
+	switch endpoint {
+	case "", "raw":
+		if index != "" || source != "" || len(fields) > 0 {
+			return fmt.Errorf("index, source and fields require endpoint \"event\"")
+		}
+	case "event":
+	default:
+		return fmt.Errorf("invalid endpoint '%s' (must be one of: raw, event)", endpoint)
+	}
+	return nil
+}
+
+// validateClientAuth checks the mutual TLS settings of a listener.
+func validateClientAuth(t *TLSConfig) error {
+	switch t.ClientAuth {
+	case "", "none":
+		if t.ClientCAFile != "" {
+			return fmt.Errorf("tls.client_ca_file requires tls.client_auth optional or required")
+		}
+		if len(t.AllowedClients) > 0 {
+			return fmt.Errorf("tls.allowed_clients requires tls.client_auth optional or required")
+		}
+		return nil
+	case "optional", "required":
+	default:
+		return fmt.Errorf("invalid tls.client_auth '%s' (must be one of: none, optional, required)", t.ClientAuth)
+	}
+
+	if t.CertFile == "" {
+		return fmt.Errorf("tls.client_auth requires tls.cert_file and tls.key_file")
+	}
+	if t.ClientCAFile == "" {
+		return fmt.Errorf("tls.client_auth %s requires tls.client_ca_file", t.ClientAuth)
+	}
+	// #nosec G304 -- CA bundle path comes from the operator's configuration
+	caPEM, err := os.ReadFile(t.ClientCAFile)
+	if err != nil {
+		return fmt.Errorf("TLS client CA file not accessible: %w", err)
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(caPEM) {
+		return fmt.Errorf("tls.client_ca_file contains no PEM certificates")
+	}
+	return nil
+}
+
+// validateAck checks the indexer acknowledgement settings.
+func validateAck(ack *AckConfig) error {
+	if ack == nil {
+		return nil
+	}
+	if ack.Timeout < 0 {
+		return fmt.Errorf("ack.timeout_seconds cannot be negative")
+	}
+	if ack.PollInterval < 0 {
+		return fmt.Errorf("ack.poll_interval_seconds cannot be negative")
+	}
 	return nil
 }
 
 // isValidRoutingMode checks if the routing mode is valid
 func isValidRoutingMode(mode RoutingMode) bool {
 	switch mode {
-	case RoutingModeAll, RoutingModePrimaryFailover, RoutingModeRoundRobin:
+	case RoutingModeAll, RoutingModePrimaryFailover, RoutingModeRoundRobin, RoutingModeRouteByField,
+		RoutingModeWeighted, RoutingModeHash:
 		return true
 	default:
 		return false
@@ -673,6 +673,41 @@ func TestLoadConfig_ListenAddressInUse(t *testing.T) {
 	}
 }
 
+func TestReloadConfig_BoundAddress(t *testing.T) {
+	// The running relay holds the listener's address
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to create test listener: %v", err)
+	}
+	defer listener.Close()
+
+	addr := listener.Addr().String()
+
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "test.yml")
+
+	content := fmt.Sprintf(`listeners:
+  - name: "test"
+    listen_addr: "%s"
+    log_type: "user-activity"
+    output_dir: "%s/logs"
+    file_prefix: "zpa-test"
+`, addr, tmpDir)
+
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create config file: %v", err)
+	}
+
+	if _, err := ReloadConfig(configFile, []string{addr}); err != nil {
+		t.Errorf("ReloadConfig should accept an address held by the relay: %v", err)
+	}
+
+	_, err = ReloadConfig(configFile, []string{"127.0.0.1:1"})
+	if err == nil || !strings.Contains(err.Error(), "cannot bind to listen address") {
+		t.Errorf("expected error about binding to address, got %v", err)
+	}
+}
+
 // Test global and per-listener HEC config merge
 func TestLoadConfig_GlobalAndPerListenerHEC(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -710,3 +745,1126 @@ listeners:
 		t.Fatalf("LoadConfig should succeed: %v", err)
 	}
 }
+
+func TestLoadConfig_DLQDrainDefaults(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "test.yml")
+
+	content := fmt.Sprintf(`listeners:
+  - name: "test"
+    listen_addr: ":19024"
+    log_type: "user-activity"
+    output_dir: "%s/logs"
+    file_prefix: "zpa-test"
+    dlq:
+      enabled: true
+      drain:
+        enabled: true
+    splunk:
+      hec_url: "https://test.splunk.com"
+      hec_token: "test-token"
+      source_type: "zpa:user:activity"
+`, tmpDir)
+
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create config file: %v", err)
+	}
+
+	cfg, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig should succeed: %v", err)
+	}
+
+	drain := cfg.Listeners[0].DLQ.Drain
+	if drain.RateLimit != 100 {
+		t.Errorf("expected default rate_limit 100, got %d", drain.RateLimit)
+	}
+	if drain.CheckInterval != 10 {
+		t.Errorf("expected default check_interval_seconds 10, got %d", drain.CheckInterval)
+	}
+}
+
+func TestLoadConfig_DLQDrainRequiresDLQ(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "test.yml")
+
+	content := fmt.Sprintf(`listeners:
+  - name: "test"
+    listen_addr: ":19025"
+    log_type: "user-activity"
+    output_dir: "%s/logs"
+    file_prefix: "zpa-test"
+    dlq:
+      drain:
+        enabled: true
+    splunk:
+      hec_url: "https://test.splunk.com"
+      hec_token: "test-token"
+      source_type: "zpa:user:activity"
+`, tmpDir)
+
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create config file: %v", err)
+	}
+
+	_, err := LoadConfig(configFile)
+	if err == nil {
+		t.Fatal("expected error for drain without DLQ enabled")
+	}
+
+	if !strings.Contains(err.Error(), "dlq.drain requires dlq.enabled") {
+		t.Errorf("expected error about dlq.enabled, got %q", err.Error())
+	}
+}
+
+func TestLoadConfig_TailRequiresHEC(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "test.yml")
+
+	content := fmt.Sprintf(`listeners:
+  - name: "test"
+    listen_addr: ":19026"
+    log_type: "user-activity"
+    output_dir: "%s/logs"
+    file_prefix: "zpa-test"
+    tail:
+      enabled: true
+`, tmpDir)
+
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create config file: %v", err)
+	}
+
+	_, err := LoadConfig(configFile)
+	if err == nil {
+		t.Fatal("expected error for tail mode without HEC")
+	}
+
+	if !strings.Contains(err.Error(), "tail requires HEC") {
+		t.Errorf("expected error about HEC, got %q", err.Error())
+	}
+}
+
+func TestLoadConfig_TailExcludesQueue(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "test.yml")
+
+	content := fmt.Sprintf(`listeners:
+  - name: "test"
+    listen_addr: ":19027"
+    log_type: "user-activity"
+    output_dir: "%s/logs"
+    file_prefix: "zpa-test"
+    tail:
+      enabled: true
+    queue:
+      enabled: true
+    splunk:
+      hec_url: "https://test.splunk.com"
+      hec_token: "test-token"
+      source_type: "zpa:user:activity"
+`, tmpDir)
+
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create config file: %v", err)
+	}
+
+	_, err := LoadConfig(configFile)
+	if err == nil {
+		t.Fatal("expected error for tail mode combined with queue")
+	}
+
+	if !strings.Contains(err.Error(), "tail cannot be combined with queue") {
+		t.Errorf("expected error about queue, got %q", err.Error())
+	}
+}
+
+func TestLoadConfig_EndpointValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		splunk   string
+		expected string
+	}{
+		{
+			name: "unknown endpoint",
+			splunk: `      endpoint: "json"
+`,
+			expected: "invalid endpoint 'json'",
+		},
+		{
+			name: "index without event endpoint",
+			splunk: `      index: "zpa"
+`,
+			expected: `require endpoint "event"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			configFile := filepath.Join(tmpDir, "test.yml")
+
+			content := fmt.Sprintf(`listeners:
+  - name: "test"
+    listen_addr: ":19028"
+    log_type: "user-activity"
+    output_dir: "%s/logs"
+    file_prefix: "zpa-test"
+    splunk:
+      hec_url: "https://test.splunk.com"
+      hec_token: "test-token"
+      source_type: "zpa:user:activity"
+%s`, tmpDir, tt.splunk)
+
+			if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+				t.Fatalf("failed to create config file: %v", err)
+			}
+
+			_, err := LoadConfig(configFile)
+			if err == nil {
+				t.Fatal("expected validation error")
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error containing %q, got %q", tt.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestLoadConfig_EventEndpoint(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "test.yml")
+
+	content := fmt.Sprintf(`listeners:
+  - name: "test"
+    listen_addr: ":19029"
+    log_type: "user-activity"
+    output_dir: "%s/logs"
+    file_prefix: "zpa-test"
+    splunk:
+      hec_url: "https://test.splunk.com/services/collector/event"
+      hec_token: "test-token"
+      source_type: "zpa:user:activity"
+      endpoint: "event"
+      index: "zpa"
+      fields:
+        env: "prod"
+`, tmpDir)
+
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create config file: %v", err)
+	}
+
+	cfg, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	splunk := cfg.Listeners[0].Splunk
+	if splunk.Endpoint != "event" || splunk.Index != "zpa" || splunk.Fields["env"] != "prod" {
+		t.Errorf("unexpected event settings: %+v", splunk)
+	}
+}
+
+func TestLoadConfig_AckValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		splunk   string
+		expected string
+	}{
+		{
+			name: "negative timeout",
+			splunk: `      ack:
+        enabled: true
+        timeout_seconds: -1
+`,
+			expected: "ack.timeout_seconds cannot be negative",
+		},
+		{
+			name: "negative poll interval",
+			splunk: `      ack:
+        enabled: true
+        poll_interval_seconds: -1
+`,
+			expected: "ack.poll_interval_seconds cannot be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			configFile := filepath.Join(tmpDir, "test.yml")
+
+			content := fmt.Sprintf(`listeners:
+  - name: "test"
+    listen_addr: ":19030"
+    log_type: "user-activity"
+    output_dir: "%s/logs"
+    file_prefix: "zpa-test"
+    splunk:
+      hec_url: "https://test.splunk.com"
+      hec_token: "test-token"
+      source_type: "zpa:user:activity"
+%s`, tmpDir, tt.splunk)
+
+			if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+				t.Fatalf("failed to create config file: %v", err)
+			}
+
+			_, err := LoadConfig(configFile)
+			if err == nil {
+				t.Fatal("expected validation error")
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error containing %q, got %q", tt.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestLoadConfig_ClientAuthValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		tls      string
+		expected string
+	}{
+		{
+			name: "unknown mode",
+			tls: `      client_auth: "always"
+`,
+			expected: "invalid tls.client_auth 'always'",
+		},
+		{
+			name: "allow-list without client auth",
+			tls: `      allowed_clients: ["lss-1"]
+`,
+			expected: "tls.allowed_clients requires tls.client_auth",
+		},
+		{
+			name: "client auth without server certificate",
+			tls: `      client_auth: "required"
+      client_ca_file: "/etc/relay/ca.pem"
+`,
+			expected: "tls.client_auth requires tls.cert_file and tls.key_file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			configFile := filepath.Join(tmpDir, "test.yml")
+
+			content := fmt.Sprintf(`listeners:
+  - name: "test"
+    listen_addr: ":19031"
+    log_type: "user-activity"
+    output_dir: "%s/logs"
+    file_prefix: "zpa-test"
+    tls:
+%s`, tmpDir, tt.tls)
+
+			if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+				t.Fatalf("failed to create config file: %v", err)
+			}
+
+			_, err := LoadConfig(configFile)
+			if err == nil {
+				t.Fatal("expected validation error")
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error containing %q, got %q", tt.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestLoadConfig_HealthValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		health   string
+		expected string
+	}{
+		{
+			name: "address conflicts with TCP health check",
+			health: `health_check_enabled: true
+health_check_addr: ":19099"
+health:
+  enabled: true
+  addr: ":19099"
+`,
+			expected: "health.addr ':19099' conflicts with health_check_addr",
+		},
+		{
+			name: "negative DLQ backlog",
+			health: `health:
+  enabled: true
+  readiness:
+    max_dlq_backlog_bytes: -1
+`,
+			expected: "health.readiness.max_dlq_backlog_bytes cannot be negative",
+		},
+		{
+			name: "negative event age",
+			health: `health:
+  enabled: true
+  readiness:
+    max_event_age_seconds: -1
+`,
+			expected: "health.readiness.max_event_age_seconds cannot be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			configFile := filepath.Join(tmpDir, "test.yml")
+
+			content := fmt.Sprintf(`%slisteners:
+  - name: "test"
+    listen_addr: ":19032"
+    log_type: "user-activity"
+    output_dir: "%s/logs"
+    file_prefix: "zpa-test"
+`, tt.health, tmpDir)
+
+			if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+				t.Fatalf("failed to create config file: %v", err)
+			}
+
+			_, err := LoadConfig(configFile)
+			if err == nil {
+				t.Fatal("expected validation error")
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error containing %q, got %q", tt.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestLoadConfig_HealthDefaults(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "test.yml")
+
+	content := fmt.Sprintf(`health:
+  enabled: true
+listeners:
+  - name: "test"
+    listen_addr: ":19032"
+    log_type: "user-activity"
+    output_dir: "%s/logs"
+    file_prefix: "zpa-test"
+`, tmpDir)
+
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create config file: %v", err)
+	}
+
+	cfg, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig should succeed: %v", err)
+	}
+	if cfg.Health.Addr != DefaultHealthAddr {
+		t.Errorf("expected default health addr %s, got %s", DefaultHealthAddr, cfg.Health.Addr)
+	}
+}
+
+func TestLoadConfig_AdminDefaults(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "test.yml")
+
+	content := fmt.Sprintf(`admin:
+  enabled: true
+listeners:
+  - name: "test"
+    listen_addr: ":19033"
+    log_type: "user-activity"
+    output_dir: "%s/logs"
+    file_prefix: "zpa-test"
+`, tmpDir)
+
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create config file: %v", err)
+	}
+
+	cfg, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig should succeed: %v", err)
+	}
+	if cfg.Admin.Socket != DefaultAdminSocket {
+		t.Errorf("expected default admin socket %s, got %s", DefaultAdminSocket, cfg.Admin.Socket)
+	}
+}
+
+func TestValidateConfig_NoBind(t *testing.T) {
+	// The running relay holds the listener's address
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to create test listener: %v", err)
+	}
+	defer listener.Close()
+
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "test.yml")
+
+	content := fmt.Sprintf(`listeners:
+  - name: "test"
+    listen_addr: "%s"
+    log_type: "user-activity"
+    output_dir: "%s/logs"
+    file_prefix: "zpa-test"
+`, listener.Addr().String(), tmpDir)
+
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create config file: %v", err)
+	}
+
+	if _, err := ValidateConfig(configFile, true); err == nil || !strings.Contains(err.Error(), "cannot bind") {
+		t.Errorf("ValidateConfig with bind should report the address in use, got %v", err)
+	}
+	if _, err := ValidateConfig(configFile, false); err != nil {
+		t.Errorf("ValidateConfig without bind should succeed: %v", err)
+	}
+}
+
+func TestValidateConfig_ReportsEveryError(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "test.yml")
+
+	content := fmt.Sprintf(`retention:
+  enabled: true
+  max_age_days: 5
+  compress_age_days: 7
+listeners:
+  - name: "users"
+    listen_addr: ":19036"
+    log_type: "unknown"
+    output_dir: "%s/logs"
+    file_prefix: "zpa-users"
+    splunk:
+      hec_url: "ftp://splunk.example.com"
+      hec_token: "test-token"
+  - name: "status"
+    listen_addr: ":19036"
+    log_type: "user-status"
+  - name: "multi"
+    listen_addr: ":19037"
+    log_type: "user-status"
+    output_dir: "%s/logs"
+    file_prefix: "zpa-multi"
+    splunk:
+      hec_targets:
+        - name: "primary"
+          hec_url: "https://splunk1.example.com:8088/services/collector/raw"
+        - name: "primary"
+          hec_url: "https://splunk2.example.com:8088/services/collector/raw"
+          hec_token: "test-token"
+          source_type: "zpa:user:status"
+`, tmpDir, tmpDir)
+
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create config file: %v", err)
+	}
+
+	_, err := ValidateConfig(configFile, false)
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("expected a joined error, got %T", err)
+	}
+
+	expected := []string{
+		"retention.compress_age_days (7) must be less than max_age_days (5)",
+		"listener users: invalid log_type 'unknown'",
+		"listener users: splunk.source_type is required when HEC is configured",
+		"listener users: invalid HEC URL",
+		"listener status: output_dir is required",
+		"listener status: file_prefix is required",
+		"listener multi: target 'primary': hec_token is required",
+		"listener multi: target 'primary': source_type is required",
+		"listener multi: duplicate target name 'primary'",
+	}
+	errs := joined.Unwrap()
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, got %d: %v", len(expected), len(errs), err)
+	}
+	for _, want := range expected {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoadConfig_UnknownKey(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "test.yml")
+
+	content := fmt.Sprintf(`splunk:
+  batch:
+    enabled: true
+    flush_interval: 5
+listeners:
+  - name: "test"
+    listen_addr: ":19045"
+    log_type: "user-activity"
+    output_dir: "%s/logs"
+    file_prefix: "zpa-test"
+`, tmpDir)
+
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create config file: %v", err)
+	}
+
+	_, err := ValidateConfig(configFile, false)
+	if err == nil || !strings.Contains(err.Error(), "line 4: field flush_interval not found") {
+		t.Errorf("expected error naming the unknown key and its line, got %v", err)
+	}
+}
+
+func TestGetTemplate_KnownKeys(t *testing.T) {
+	var cfg Config
+	if err := decodeStrict([]byte(GetTemplate()), &cfg); err != nil {
+		t.Errorf("template should only use known keys: %v", err)
+	}
+}
+
+func TestLoadConfig_SchemaValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		logType  string
+		section  string
+		expected string
+	}{
+		{
+			name:    "defaults",
+			logType: "user-activity",
+			section: `      enabled: true
+`,
+		},
+		{
+			name:    "log type without schema",
+			logType: "pse-metrics",
+			section: `      enabled: true
+`,
+			expected: "schema_validation is not available for log_type 'pse-metrics'",
+		},
+		{
+			name:    "unknown action",
+			logType: "audit",
+			section: `      enabled: true
+      action: "drop"
+`,
+			expected: "schema_validation.action must be 'forward' or 'quarantine'",
+		},
+		{
+			name:    "negative log_every",
+			logType: "audit",
+			section: `      enabled: true
+      log_every: -1
+`,
+			expected: "schema_validation.log_every cannot be negative",
+		},
+		{
+			name:    "quarantine without quarantine store",
+			logType: "audit",
+			section: `      enabled: true
+      action: "quarantine"
+`,
+			expected: "schema_validation.action 'quarantine' requires quarantine.enabled",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			configFile := filepath.Join(tmpDir, "test.yml")
+
+			content := fmt.Sprintf(`listeners:
+  - name: "test"
+    listen_addr: ":19046"
+    log_type: "%s"
+    output_dir: "%s/logs"
+    file_prefix: "zpa-test"
+    schema_validation:
+%s`, tt.logType, tmpDir, tt.section)
+
+			if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+				t.Fatalf("failed to create config file: %v", err)
+			}
+
+			cfg, err := LoadConfig(configFile)
+			if tt.expected == "" {
+				if err != nil {
+					t.Fatalf("LoadConfig should succeed: %v", err)
+				}
+				sv := cfg.Listeners[0].SchemaValidation
+				if sv.Action != SchemaActionForward || sv.LogEvery != 100 {
+					t.Errorf("expected default action forward and log_every 100, got %+v", sv)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_Quarantine(t *testing.T) {
+	tests := []struct {
+		name     string
+		section  string
+		expected string
+	}{
+		{
+			name: "with schema quarantine",
+			section: `    schema_validation:
+      enabled: true
+      action: "quarantine"
+    quarantine:
+      enabled: true
+      directory: "/var/lib/relay/quarantine"
+`,
+		},
+		{
+			name: "negative max_data_bytes",
+			section: `    quarantine:
+      enabled: true
+      max_data_bytes: -1
+`,
+			expected: "quarantine.max_data_bytes cannot be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			configFile := filepath.Join(tmpDir, "test.yml")
+
+			content := fmt.Sprintf(`listeners:
+  - name: "test"
+    listen_addr: ":19047"
+    log_type: "audit"
+    output_dir: "%s/logs"
+    file_prefix: "zpa-test"
+%s`, tmpDir, tt.section)
+
+			if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+				t.Fatalf("failed to create config file: %v", err)
+			}
+
+			cfg, err := LoadConfig(configFile)
+			if tt.expected == "" {
+				if err != nil {
+					t.Fatalf("LoadConfig should succeed: %v", err)
+				}
+				if q := cfg.Listeners[0].Quarantine; !q.Enabled || q.Dir != "/var/lib/relay/quarantine" {
+					t.Errorf("unexpected quarantine configuration %+v", q)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_Redaction(t *testing.T) {
+	tests := []struct {
+		name     string
+		section  string
+		expected string
+	}{
+		{
+			name: "with rules",
+			section: `    redaction:
+      hmac_key: "${RELAY_TEST_HMAC_KEY}"
+      rules:
+        - path: "ClientPrivateIP"
+          action: "drop"
+      forward_rules:
+        - path: "Username"
+          action: "hmac"
+    splunk:
+      hec_targets:
+        - name: "tenant"
+          hec_url: "https://tenant.example.com:8088/services/collector/raw"
+          hec_token: "token"
+          source_type: "zpa:user:activity"
+          redact:
+            - path: "ClientPublicIP"
+              action: "mask"
+              mask: "0.0.0.0"
+`,
+		},
+		{
+			name: "hmac without key",
+			section: `    redaction:
+      rules:
+        - path: "Username"
+          action: "hmac"
+`,
+			expected: "listener test: redaction.rules: path Username: action 'hmac' requires a key of at least 16 bytes",
+		},
+		{
+			name: "bad target rule",
+			section: `    splunk:
+      hec_targets:
+        - name: "tenant"
+          hec_url: "https://tenant.example.com:8088/services/collector/raw"
+          hec_token: "token"
+          source_type: "zpa:user:activity"
+          redact:
+            - path: "Username"
+              action: "encrypt"
+`,
+			expected: "listener test: target 'tenant': redact: path Username: action must be",
+		},
+	}
+
+	t.Setenv("RELAY_TEST_HMAC_KEY", "0123456789abcdef0123456789abcdef")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			configFile := filepath.Join(tmpDir, "test.yml")
+
+			content := fmt.Sprintf(`listeners:
+  - name: "test"
+    listen_addr: ":19048"
+    log_type: "user-activity"
+    output_dir: "%s/logs"
+    file_prefix: "zpa-test"
+%s`, tmpDir, tt.section)
+
+			if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+				t.Fatalf("failed to create config file: %v", err)
+			}
+
+			cfg, err := LoadConfig(configFile)
+			if tt.expected == "" {
+				if err != nil {
+					t.Fatalf("LoadConfig should succeed: %v", err)
+				}
+				rd := cfg.Listeners[0].Redaction
+				if rd.HMACKey != "0123456789abcdef0123456789abcdef" || len(rd.Rules) != 1 || len(rd.ForwardRules) != 1 {
+					t.Errorf("unexpected redaction configuration %+v", rd)
+				}
+				if target := cfg.Listeners[0].Splunk.HECTargets[0]; len(target.Redact) != 1 || target.Redact[0].Mask != "0.0.0.0" {
+					t.Errorf("unexpected target redaction %+v", target.Redact)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_Filters(t *testing.T) {
+	targets := `    splunk:
+      hec_targets:
+        - name: "soc"
+          hec_url: "https://soc.example.com:8088/services/collector/raw"
+          hec_token: "token"
+          source_type: "zpa:app-connector:metrics"
+`
+	tests := []struct {
+		name     string
+		section  string
+		expected string
+	}{
+		{
+			name: "with rules",
+			section: `    filters:
+      - name: "drop-idle"
+        match:
+          - field: "CPUUtilization"
+            lt: 5
+          - field: "ConnectorStatus"
+            equals: 200
+        action: "drop"
+      - name: "sample-rest"
+        match:
+          - field: "CPUUtilization"
+            exists: true
+        action: "sample"
+        sample_rate: 10
+      - name: "errors-to-soc"
+        match:
+          - field: "Error"
+            regex: "^timeout"
+        action: "route"
+        target: "soc"
+` + targets,
+		},
+		{
+			name: "unknown route target",
+			section: `    filters:
+      - name: "errors"
+        match:
+          - field: "Error"
+            exists: true
+        action: "route"
+        target: "noc"
+` + targets,
+			expected: "listener test: filters: rule errors: target 'noc' is not one of the listener's hec_targets",
+		},
+		{
+			name: "invalid rule",
+			section: `    filters:
+      - name: "errors"
+        match:
+          - field: "Error"
+            regex: "("
+        action: "drop"
+`,
+			expected: "listener test: filters: rule errors: field Error: invalid regex",
+		},
+		{
+			name: "forward action with tail",
+			section: `    tail:
+      enabled: true
+    filters:
+      - name: "idle"
+        match:
+          - field: "CPUUtilization"
+            lt: 5
+        action: "drop_forward"
+` + targets,
+			expected: "listener test: filters: rule idle: action 'drop_forward' cannot be used with tail",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			configFile := filepath.Join(tmpDir, "test.yml")
+
+			content := fmt.Sprintf(`listeners:
+  - name: "test"
+    listen_addr: ":19049"
+    log_type: "app-connector-metrics"
+    output_dir: "%s/logs"
+    file_prefix: "zpa-test"
+%s`, tmpDir, tt.section)
+
+			if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+				t.Fatalf("failed to create config file: %v", err)
+			}
+
+			cfg, err := LoadConfig(configFile)
+			if tt.expected == "" {
+				if err != nil {
+					t.Fatalf("LoadConfig should succeed: %v", err)
+				}
+				filters := cfg.Listeners[0].Filters
+				if len(filters) != 3 || *filters[0].Match[0].LT != 5 || *filters[0].Match[1].Equals != "200" ||
+					filters[1].SampleRate != 10 || filters[2].Target != "soc" {
+					t.Errorf("unexpected filters %+v", filters)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_RouteByField(t *testing.T) {
+	targets := `    splunk:
+      hec_targets:
+        - name: "acme"
+          hec_url: "https://acme.example.com:8088/services/collector/raw"
+          hec_token: "token"
+          source_type: "zpa:user:activity"
+        - name: "shared"
+          hec_url: "https://shared.example.com:8088/services/collector/raw"
+          hec_token: "token"
+          source_type: "zpa:user:activity"
+`
+	tests := []struct {
+		name     string
+		routing  string
+		expected string
+	}{
+		{
+			name: "with routes",
+			routing: `        mode: "route-by-field"
+        field: "Customer"
+        routes:
+          - values: ["acme", "acme-eu"]
+            targets: ["acme", "shared"]
+        default: ["shared"]
+`,
+		},
+		{
+			name: "without field and default",
+			routing: `        mode: "route-by-field"
+`,
+			expected: "listener test: routing.field is required for mode 'route-by-field'\nlistener test: routing.default is required",
+		},
+		{
+			name: "unknown target",
+			routing: `        mode: "route-by-field"
+        field: "Customer"
+        routes:
+          - values: ["acme"]
+            targets: ["acme", "acme-dr"]
+        default: ["shared"]
+`,
+			expected: "listener test: routing.routes[0]: unknown target 'acme-dr'",
+		},
+		{
+			name: "value in two routes",
+			routing: `        mode: "route-by-field"
+        field: "Customer"
+        routes:
+          - values: ["acme"]
+            targets: ["acme"]
+          - values: ["acme"]
+            targets: ["shared"]
+        default: ["shared"]
+`,
+			expected: "listener test: routing.routes[1]: value 'acme' is already routed",
+		},
+		{
+			name: "field with another mode",
+			routing: `        mode: "all"
+        field: "Customer"
+`,
+			expected: "listener test: routing.field requires mode 'route-by-field' or 'hash'",
+		},
+		{
+			name: "default with another mode",
+			routing: `        mode: "hash"
+        field: "Customer"
+        default: ["shared"]
+`,
+			expected: "listener test: routing.routes and default require mode 'route-by-field'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			configFile := filepath.Join(tmpDir, "test.yml")
+
+			content := fmt.Sprintf(`listeners:
+  - name: "test"
+    listen_addr: ":19050"
+    log_type: "user-activity"
+    output_dir: "%s/logs"
+    file_prefix: "zpa-test"
+%s      routing:
+%s`, tmpDir, targets, tt.routing)
+
+			if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+				t.Fatalf("failed to create config file: %v", err)
+			}
+
+			cfg, err := LoadConfig(configFile)
+			if tt.expected == "" {
+				if err != nil {
+					t.Fatalf("LoadConfig should succeed: %v", err)
+				}
+				routing := cfg.Listeners[0].Splunk.Routing
+				if routing.Field != "Customer" || len(routing.Routes) != 1 || len(routing.Routes[0].Values) != 2 || routing.Default[0] != "shared" {
+					t.Errorf("unexpected routing %+v", routing)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_WeightedAndHash(t *testing.T) {
+	tests := []struct {
+		name     string
+		routing  string
+		weight   string
+		expected string
+	}{
+		{
+			name:    "weighted",
+			routing: "        mode: \"weighted\"\n",
+			weight:  "9",
+		},
+		{
+			name:    "hash by field",
+			routing: "        mode: \"hash\"\n        field: \"SessionID\"\n",
+			weight:  "2",
+		},
+		{
+			name:     "hash without field",
+			routing:  "        mode: \"hash\"\n",
+			weight:   "1",
+			expected: "listener test: routing.field is required for mode 'hash'",
+		},
+		{
+			name:     "weight out of range",
+			routing:  "        mode: \"weighted\"\n",
+			weight:   "1001",
+			expected: "listener test: target 'new': weight must be between 0 (default 1) and 1000",
+		},
+		{
+			name:     "weight with another mode",
+			routing:  "        mode: \"round-robin\"\n",
+			weight:   "3",
+			expected: "listener test: target 'new': weight requires routing mode 'weighted' or 'hash'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			configFile := filepath.Join(tmpDir, "test.yml")
+
+			content := fmt.Sprintf(`listeners:
+  - name: "test"
+    listen_addr: ":19051"
+    log_type: "user-activity"
+    output_dir: "%s/logs"
+    file_prefix: "zpa-test"
+    splunk:
+      hec_targets:
+        - name: "old"
+          hec_url: "https://old.example.com:8088/services/collector/raw"
+          hec_token: "token"
+          source_type: "zpa:user:activity"
+        - name: "new"
+          hec_url: "https://new.example.com:8088/services/collector/raw"
+          hec_token: "token"
+          source_type: "zpa:user:activity"
+          weight: %s
+      routing:
+%s`, tmpDir, tt.weight, tt.routing)
+
+			if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+				t.Fatalf("failed to create config file: %v", err)
+			}
+
+			cfg, err := LoadConfig(configFile)
+			if tt.expected == "" {
+				if err != nil {
+					t.Fatalf("LoadConfig should succeed: %v", err)
+				}
+				targets := cfg.Listeners[0].Splunk.HECTargets
+				if targets[0].Weight != 0 || fmt.Sprint(targets[1].Weight) != tt.weight {
+					t.Errorf("weights = %d, %d, want 0, %s", targets[0].Weight, targets[1].Weight, tt.weight)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
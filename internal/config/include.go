@@ -0,0 +1,258 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// includeKey lists configuration fragments to merge into the main configuration file.
+// Each entry is a file path or glob, relative to the main file's directory unless absolute:
+//
+//	include:
+//	  - conf.d/*.yml
+//
+// Fragments use the same format as the main file. Their listeners are appended, and global
+// sections are merged key by key; a key set to different values in two files is an error.
+// Fragments cannot include other files. Globs are expanded on every load, so a reload picks
+// up added and removed fragments.
+const includeKey = "include"
+
+// decodeStrict decodes YAML into v, reporting keys that v has no field for with their line.
+// An empty document leaves v unchanged.
+func decodeStrict(data []byte, v interface{}) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// parseConfig decodes the main configuration file and merges in the fragments it includes.
+func parseConfig(configFile string, data []byte) (*Config, []string, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse YAML config: %v", err)
+	}
+
+	// Check each file on its own first, so type errors and unknown keys name the file they are in
+	config := &Config{}
+	if err := decodeStrict(data, config); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse YAML config: %v", err)
+	}
+	if _, ok := doc[includeKey]; !ok {
+		return config, nil, nil
+	}
+
+	files, err := includedFiles(configFile, config.Include)
+	if err != nil {
+		return nil, nil, err
+	}
+	delete(doc, includeKey)
+
+	m := newMerger(configFile, doc)
+	for _, file := range files {
+		// #nosec G304 -- included files are listed in the operator's configuration file.
+		fragmentData, err := os.ReadFile(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read included config file: %v", err)
+		}
+		if err := decodeStrict(fragmentData, &Config{}); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse included config file %s: %v", file, err)
+		}
+		var fragment map[string]interface{}
+		if err := yaml.Unmarshal(fragmentData, &fragment); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse included config file %s: %v", file, err)
+		}
+		if _, ok := fragment[includeKey]; ok {
+			return nil, nil, fmt.Errorf("included config file %s: include is only supported in the main configuration file", file)
+		}
+		if err := m.merge(file, fragment); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	merged, err := yaml.Marshal(m.doc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to merge included config files: %v", err)
+	}
+	config = &Config{}
+	if err := decodeStrict(merged, config); err != nil {
+		return nil, nil, fmt.Errorf("failed to merge included config files: %v", err)
+	}
+	return config, files, nil
+}
+
+// includedFiles expands the include patterns of configFile into a sorted list of files.
+// A path without glob characters must exist; a glob may match nothing, such as an empty conf.d.
+func includedFiles(configFile string, patterns []string) ([]string, error) {
+	self, err := filepath.Abs(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve config file path: %v", err)
+	}
+
+	var files []string
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(configFile), pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include pattern %q: %v", pattern, err)
+		}
+		if len(matches) == 0 && !strings.ContainsAny(pattern, "*?[") {
+			return nil, fmt.Errorf("included config file not found: %s", pattern)
+		}
+		for _, match := range matches {
+			abs, err := filepath.Abs(match)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve included config file path: %v", err)
+			}
+			// A glob such as *.yml may match the main file or a file matched by an earlier pattern
+			if abs == self || slices.Contains(files, match) {
+				continue
+			}
+			if info, err := os.Stat(match); err == nil && info.IsDir() {
+				continue
+			}
+			files = append(files, match)
+		}
+	}
+	return files, nil
+}
+
+// merger merges configuration fragments into one document, remembering which file set each
+// key and listener so conflicts can name both files.
+type merger struct {
+	doc       map[string]interface{}
+	sources   map[string]string // Dotted key path to the file that set it
+	listeners map[string]string // Listener name to the file that defined it
+}
+
+// newMerger returns a merger starting from the main configuration file's document.
+func newMerger(configFile string, doc map[string]interface{}) *merger {
+	if doc == nil {
+		doc = make(map[string]interface{})
+	}
+	m := &merger{
+		doc:       doc,
+		sources:   make(map[string]string),
+		listeners: make(map[string]string),
+	}
+	m.record(configFile, "", doc)
+	return m
+}
+
+// record notes file as the source of every key in section and of every listener it defines.
+func (m *merger) record(file, prefix string, section map[string]interface{}) {
+	for key, value := range section {
+		path := prefix + key
+		if path == "listeners" {
+			for _, name := range listenerNames(value) {
+				m.listeners[name] = file
+			}
+			continue
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			m.record(file, path+".", nested)
+			continue
+		}
+		m.sources[path] = file
+	}
+}
+
+// merge merges a fragment from file into the document.
+func (m *merger) merge(file string, fragment map[string]interface{}) error {
+	if listeners, ok := fragment["listeners"]; ok {
+		added, ok := listeners.([]interface{})
+		if !ok && listeners != nil {
+			return fmt.Errorf("included config file %s: listeners must be a list", file)
+		}
+		for _, name := range listenerNames(added) {
+			if other, ok := m.listeners[name]; ok {
+				return fmt.Errorf("listener %s is defined in both %s and %s", name, other, file)
+			}
+			m.listeners[name] = file
+		}
+		existing, _ := m.doc["listeners"].([]interface{})
+		m.doc["listeners"] = append(existing, added...)
+		delete(fragment, "listeners")
+	}
+	return m.mergeSection(file, "", m.doc, fragment)
+}
+
+// mergeSection merges src into dst. Nested sections are merged key by key, and any other
+// value may only be set in one file unless every file sets it to the same value.
+func (m *merger) mergeSection(file, prefix string, dst, src map[string]interface{}) error {
+	for key, value := range src {
+		path := prefix + key
+		existing, ok := dst[key]
+		if !ok || existing == nil {
+			dst[key] = value
+			if nested, isMap := value.(map[string]interface{}); isMap {
+				m.record(file, path+".", nested)
+			} else {
+				m.sources[path] = file
+			}
+			continue
+		}
+
+		dstSection, dstIsMap := existing.(map[string]interface{})
+		srcSection, srcIsMap := value.(map[string]interface{})
+		if dstIsMap && srcIsMap {
+			if err := m.mergeSection(file, path+".", dstSection, srcSection); err != nil {
+				return err
+			}
+			continue
+		}
+		if value == nil || reflect.DeepEqual(existing, value) {
+			continue
+		}
+		return fmt.Errorf("conflicting values for %s in %s and %s", path, m.sourceOf(path), file)
+	}
+	return nil
+}
+
+// sourceOf returns the file that set a key, or the first file that set a key below it.
+func (m *merger) sourceOf(path string) string {
+	if file, ok := m.sources[path]; ok {
+		return file
+	}
+	keys := make([]string, 0, len(m.sources))
+	for key := range m.sources {
+		if strings.HasPrefix(key, path+".") {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return "another file"
+	}
+	slices.Sort(keys)
+	return m.sources[keys[0]]
+}
+
+// listenerNames returns the names of the listeners in a decoded listeners list.
+// Listeners without a name are left to validation.
+func listenerNames(value interface{}) []string {
+	list, _ := value.([]interface{})
+	var names []string
+	for _, item := range list {
+		listener, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if name, ok := listener["name"].(string); ok && name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
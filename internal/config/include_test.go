@@ -0,0 +1,179 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFile writes content to path, creating its directory.
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create config file: %v", err)
+	}
+}
+
+// listenerFragment returns a configuration fragment with a single listener.
+func listenerFragment(name, addr, logType, outputDir string) string {
+	return fmt.Sprintf(`listeners:
+  - name: "%s"
+    listen_addr: "%s"
+    log_type: "%s"
+    output_dir: "%s/logs"
+    file_prefix: "zpa-%s"
+    splunk:
+      source_type: "zpa:%s"
+`, name, addr, logType, outputDir, name, logType)
+}
+
+func TestLoadConfig_Include(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yml")
+
+	writeFile(t, configFile, `include:
+  - conf.d/*.yml
+splunk:
+  hec_url: "https://splunk.example.com:8088/services/collector/raw"
+  hec_token: "test-token"
+  batch:
+    enabled: true
+`)
+	writeFile(t, filepath.Join(tmpDir, "conf.d", "20-status.yml"), listenerFragment("status", ":19039", "user-status", tmpDir))
+	writeFile(t, filepath.Join(tmpDir, "conf.d", "10-users.yml"), listenerFragment("users", ":19038", "user-activity", tmpDir)+`splunk:
+  batch:
+    max_size: 50
+`)
+
+	cfg, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig should succeed: %v", err)
+	}
+
+	// Fragments are merged in file name order
+	if len(cfg.Listeners) != 2 || cfg.Listeners[0].Name != "users" || cfg.Listeners[1].Name != "status" {
+		t.Fatalf("expected listeners [users status], got %+v", cfg.Listeners)
+	}
+	if cfg.Splunk.HECToken != "test-token" {
+		t.Errorf("global hec_token = %q, want the main file's value", cfg.Splunk.HECToken)
+	}
+	if cfg.Splunk.Batch == nil || cfg.Splunk.Batch.Enabled == nil || !*cfg.Splunk.Batch.Enabled || cfg.Splunk.Batch.MaxSize != 50 {
+		t.Errorf("global batch should merge keys from both files, got %+v", cfg.Splunk.Batch)
+	}
+
+	// A reload picks up added and removed fragments
+	if err := os.Remove(filepath.Join(tmpDir, "conf.d", "20-status.yml")); err != nil {
+		t.Fatalf("failed to remove fragment: %v", err)
+	}
+	writeFile(t, filepath.Join(tmpDir, "conf.d", "30-connectors.yml"), listenerFragment("connectors", ":19040", "app-connector-status", tmpDir))
+
+	cfg, err = ReloadConfig(configFile, []string{":19038", ":19039"})
+	if err != nil {
+		t.Fatalf("ReloadConfig should succeed: %v", err)
+	}
+	if len(cfg.Listeners) != 2 || cfg.Listeners[0].Name != "users" || cfg.Listeners[1].Name != "connectors" {
+		t.Errorf("expected listeners [users connectors] after reload, got %+v", cfg.Listeners)
+	}
+}
+
+func TestLoadConfig_IncludeErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		main      string
+		fragments map[string]string
+		expected  string
+	}{
+		{
+			name: "conflicting global key",
+			main: `include: ["conf.d/*.yml"]
+splunk:
+  hec_url: "https://splunk1.example.com:8088/services/collector/raw"
+`,
+			fragments: map[string]string{
+				"a.yml": `splunk:
+  hec_url: "https://splunk2.example.com:8088/services/collector/raw"
+`,
+			},
+			expected: "conflicting values for splunk.hec_url in",
+		},
+		{
+			name: "conflicting list",
+			main: `include: ["conf.d/*.yml"]`,
+			fragments: map[string]string{
+				"a.yml": "splunk:\n  hec_targets:\n    - name: primary\n",
+				"b.yml": "splunk:\n  hec_targets:\n    - name: secondary\n",
+			},
+			expected: "conflicting values for splunk.hec_targets in",
+		},
+		{
+			name: "duplicate listener name",
+			main: `include: ["conf.d/*.yml"]`,
+			fragments: map[string]string{
+				"a.yml": listenerFragment("users", ":19041", "user-activity", "/tmp"),
+				"b.yml": listenerFragment("users", ":19042", "user-activity", "/tmp"),
+			},
+			expected: "listener users is defined in both",
+		},
+		{
+			name:      "nested include",
+			main:      `include: ["conf.d/*.yml"]`,
+			fragments: map[string]string{"a.yml": `include: ["other/*.yml"]`},
+			expected:  "include is only supported in the main configuration file",
+		},
+		{
+			name:     "missing file",
+			main:     `include: ["conf.d/users.yml"]`,
+			expected: "included config file not found",
+		},
+		{
+			name:      "unknown key in fragment",
+			main:      `include: ["conf.d/*.yml"]`,
+			fragments: map[string]string{"a.yml": "splunk:\n  hec_tokn: \"test-token\"\n"},
+			expected:  "a.yml: yaml: unmarshal errors:\n  line 2: field hec_tokn not found",
+		},
+		{
+			name:      "invalid fragment",
+			main:      `include: ["conf.d/*.yml"]`,
+			fragments: map[string]string{"a.yml": "listeners: \"users\"\n"},
+			expected:  "failed to parse included config file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			configFile := filepath.Join(tmpDir, "config.yml")
+			writeFile(t, configFile, tt.main)
+			for name, content := range tt.fragments {
+				writeFile(t, filepath.Join(tmpDir, "conf.d", name), content)
+			}
+
+			_, err := ValidateConfig(configFile, false)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_IncludeSkipsMainFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.yml")
+
+	// *.yml matches the main file itself, which must not be merged twice
+	writeFile(t, configFile, "include: [\"*.yml\"]\n"+listenerFragment("users", ":19043", "user-activity", tmpDir))
+	writeFile(t, filepath.Join(tmpDir, "status.yml"), listenerFragment("status", ":19044", "user-status", tmpDir))
+
+	cfg, err := ValidateConfig(configFile, false)
+	if err != nil {
+		t.Fatalf("ValidateConfig should succeed: %v", err)
+	}
+	if len(cfg.Listeners) != 2 {
+		t.Errorf("expected 2 listeners, got %d", len(cfg.Listeners))
+	}
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/scottbrown/relay/internal/filter"
+	"github.com/scottbrown/relay/internal/logtypes"
+	"github.com/scottbrown/relay/internal/redact"
+)
+
+// schemaEnums lists the allowed values of settings that take one of a fixed set of strings,
+// keyed by struct type and field name.
+var schemaEnums = map[string][]string{
+	"ListenerConfig.LogType":        logTypeNames(),
+	"SplunkConfig.Endpoint":         {"raw", "event"},
+	"HECTarget.Endpoint":            {"raw", "event"},
+	"RoutingConfig.Mode":            {string(RoutingModeAll), string(RoutingModePrimaryFailover), string(RoutingModeRoundRobin), string(RoutingModeRouteByField), string(RoutingModeWeighted), string(RoutingModeHash)},
+	"TLSConfig.ClientAuth":          {"none", "optional", "required"},
+	"SchemaValidationConfig.Action": {SchemaActionForward, SchemaActionQuarantine},
+	"RedactionRule.Action":          {redact.Drop, redact.Mask, redact.HMAC},
+	"FilterRule.Action":             {filter.Drop, filter.DropForward, filter.Sample, filter.Route},
+}
+
+// schemaRequired lists the settings that must be present, keyed by struct type.
+// Listeners are not required at the top level because they may come from included files.
+var schemaRequired = map[string][]string{
+	"ListenerConfig":  {"name", "listen_addr", "log_type", "output_dir", "file_prefix"},
+	"HECTarget":       {"name", "hec_url", "hec_token", "source_type"},
+	"RedactionRule":   {"path", "action"},
+	"FilterRule":      {"name", "match", "action"},
+	"FilterCondition": {"field"},
+	"FieldRoute":      {"values", "targets"},
+}
+
+// JSONSchema returns a JSON Schema (draft 2020-12) for the configuration file, generated
+// from the configuration structs. Like LoadConfig, it does not allow unknown keys.
+// It checks the structure of a file; the rules that span settings are checked by validate.
+func JSONSchema() ([]byte, error) {
+	g := schemaGenerator{defs: make(map[string]interface{})}
+	root := g.object(reflect.TypeOf(Config{}))
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["title"] = "Relay configuration"
+	root["$defs"] = g.defs
+	return json.MarshalIndent(root, "", "  ")
+}
+
+// schemaGenerator builds schemas for configuration types, collecting nested structs in defs.
+type schemaGenerator struct {
+	defs map[string]interface{}
+}
+
+// object returns the schema of a struct type, with one property per yaml tag.
+func (g *schemaGenerator) object(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		schema := g.schema(field.Type)
+		if enum, ok := schemaEnums[t.Name()+"."+field.Name]; ok {
+			schema["enum"] = enum
+		}
+		properties[name] = schema
+	}
+
+	object := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if required, ok := schemaRequired[t.Name()]; ok {
+		object["required"] = required
+	}
+	return object
+}
+
+// schema returns the schema of a field type. Structs are referenced from defs.
+func (g *schemaGenerator) schema(t reflect.Type) map[string]interface{} {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if _, ok := g.defs[t.Name()]; !ok {
+			g.defs[t.Name()] = nil // Reserve the name while the struct is generated
+			g.defs[t.Name()] = g.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	default:
+		return map[string]interface{}{"type": "string"}
+	}
+}
+
+// logTypeNames returns the names of the ZPA log types.
+func logTypeNames() []string {
+	var names []string
+	for _, lt := range logtypes.All() {
+		names = append(names, string(lt))
+	}
+	return names
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// schemaObject returns schema as an object, following a $ref into $defs.
+func schemaObject(t *testing.T, root map[string]interface{}, schema interface{}) map[string]interface{} {
+	t.Helper()
+	s, ok := schema.(map[string]interface{})
+	if !ok {
+		t.Fatalf("schema is not an object: %v", schema)
+	}
+	if ref, ok := s["$ref"].(string); ok {
+		defs := root["$defs"].(map[string]interface{})
+		return schemaObject(t, root, defs[strings.TrimPrefix(ref, "#/$defs/")])
+	}
+	return s
+}
+
+// checkKeys reports keys in doc that the schema does not define.
+func checkKeys(t *testing.T, root map[string]interface{}, schema interface{}, path string, doc interface{}) {
+	t.Helper()
+	s := schemaObject(t, root, schema)
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		properties, ok := s["properties"].(map[string]interface{})
+		if !ok {
+			if additional, ok := s["additionalProperties"].(map[string]interface{}); ok {
+				for key, value := range v {
+					checkKeys(t, root, additional, path+key+".", value)
+				}
+			}
+			return
+		}
+		for key, value := range v {
+			property, ok := properties[key]
+			if !ok {
+				t.Errorf("schema does not define %s%s", path, key)
+				continue
+			}
+			checkKeys(t, root, property, path+key+".", value)
+		}
+	case []interface{}:
+		for _, item := range v {
+			checkKeys(t, root, s["items"], path, item)
+		}
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	data, err := JSONSchema()
+	if err != nil {
+		t.Fatalf("JSONSchema() error = %v", err)
+	}
+	var root map[string]interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+
+	if root["additionalProperties"] != false {
+		t.Error("schema should not allow unknown top-level keys")
+	}
+
+	batch := schemaObject(t, root, map[string]interface{}{"$ref": "#/$defs/BatchConfig"})
+	properties := batch["properties"].(map[string]interface{})
+	if _, ok := properties["flush_interval_seconds"]; !ok {
+		t.Error("BatchConfig should define flush_interval_seconds")
+	}
+	if batch["additionalProperties"] != false {
+		t.Error("BatchConfig should not allow unknown keys")
+	}
+
+	listener := schemaObject(t, root, map[string]interface{}{"$ref": "#/$defs/ListenerConfig"})
+	required, _ := listener["required"].([]interface{})
+	if len(required) != 5 {
+		t.Errorf("ListenerConfig should require 5 settings, got %v", required)
+	}
+	logType := listener["properties"].(map[string]interface{})["log_type"].(map[string]interface{})
+	if enum, _ := logType["enum"].([]interface{}); len(enum) != 8 {
+		t.Errorf("log_type should allow the 8 ZPA log types, got %v", logType["enum"])
+	}
+
+	// Every key in the template is defined by the schema
+	var template map[string]interface{}
+	if err := yaml.Unmarshal([]byte(GetTemplate()), &template); err != nil {
+		t.Fatalf("failed to parse template: %v", err)
+	}
+	checkKeys(t, root, root, "", template)
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Secret references keep tokens and key paths out of the configuration file.
+// A hec_token or redaction.hmac_key can be given as:
+//   - ${NAME}: the value of environment variable NAME
+//   - file:/path: the contents of a file, without trailing newlines
+//   - credential:name: the contents of a systemd credential in $CREDENTIALS_DIRECTORY
+//
+// A tls.key_file can use ${NAME} and credential:name, which resolve to a path.
+// Any other value is used as is.
+const (
+	secretFilePrefix       = "file:"
+	secretCredentialPrefix = "credential:"
+)
+
+// envReference matches ${NAME} references. $NAME without braces is left alone, so
+// values that contain a dollar sign are not changed.
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// resolveSecrets replaces the secret references in cfg with the values they point to.
+// Files are read each time, so a reload picks up rotated secrets.
+// Errors name the setting and the reference, never a resolved value.
+func resolveSecrets(cfg *Config) error {
+	if err := resolveSplunkSecrets(cfg.Splunk); err != nil {
+		return fmt.Errorf("splunk.%w", err)
+	}
+	for i := range cfg.Listeners {
+		listener := &cfg.Listeners[i]
+		if err := resolveSplunkSecrets(listener.Splunk); err != nil {
+			return fmt.Errorf("listener %s: splunk.%w", listener.Name, err)
+		}
+		if listener.TLS != nil && listener.TLS.KeyFile != "" {
+			keyFile, err := resolveSecretPath(listener.TLS.KeyFile)
+			if err != nil {
+				return fmt.Errorf("listener %s: tls.key_file: %w", listener.Name, err)
+			}
+			listener.TLS.KeyFile = keyFile
+		}
+		if listener.Redaction != nil && listener.Redaction.HMACKey != "" {
+			key, err := resolveSecret(listener.Redaction.HMACKey)
+			if err != nil {
+				return fmt.Errorf("listener %s: redaction.hmac_key: %w", listener.Name, err)
+			}
+			listener.Redaction.HMACKey = key
+		}
+	}
+	return nil
+}
+
+// resolveSplunkSecrets resolves the HEC tokens of a Splunk section, which may be nil.
+func resolveSplunkSecrets(splunk *SplunkConfig) error {
+	if splunk == nil {
+		return nil
+	}
+	if splunk.HECToken != "" {
+		token, err := resolveSecret(splunk.HECToken)
+		if err != nil {
+			return fmt.Errorf("hec_token: %w", err)
+		}
+		splunk.HECToken = token
+	}
+	for i := range splunk.HECTargets {
+		target := &splunk.HECTargets[i]
+		if target.HECToken == "" {
+			continue
+		}
+		token, err := resolveSecret(target.HECToken)
+		if err != nil {
+			return fmt.Errorf("hec_targets[%s].hec_token: %w", target.Name, err)
+		}
+		target.HECToken = token
+	}
+	return nil
+}
+
+// resolveSecret returns the secret a value refers to, or the value itself if it is not a reference.
+func resolveSecret(value string) (string, error) {
+	var secret string
+	var err error
+	switch {
+	case strings.HasPrefix(value, secretFilePrefix):
+		var path string
+		if path, err = expandEnvReferences(strings.TrimPrefix(value, secretFilePrefix)); err == nil {
+			secret, err = readSecretFile(path)
+		}
+	case strings.HasPrefix(value, secretCredentialPrefix):
+		var path string
+		if path, err = credentialPath(strings.TrimPrefix(value, secretCredentialPrefix)); err == nil {
+			secret, err = readSecretFile(path)
+		}
+	default:
+		secret, err = expandEnvReferences(value)
+	}
+	if err != nil {
+		return "", err
+	}
+	if secret == "" {
+		return "", fmt.Errorf("%q resolved to an empty value", value)
+	}
+	return secret, nil
+}
+
+// resolveSecretPath returns the path a value refers to. Paths are read by the code that
+// uses them, so file: references are not accepted.
+func resolveSecretPath(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, secretFilePrefix):
+		return "", errors.New("file: references are only supported for tokens; give the path itself")
+	case strings.HasPrefix(value, secretCredentialPrefix):
+		return credentialPath(strings.TrimPrefix(value, secretCredentialPrefix))
+	default:
+		return expandEnvReferences(value)
+	}
+}
+
+// expandEnvReferences replaces ${NAME} references with environment variables.
+// An unset variable is an error, so a missing secret is never sent as an empty token.
+func expandEnvReferences(value string) (string, error) {
+	var missing []string
+	expanded := envReference.ReplaceAllStringFunc(value, func(ref string) string {
+		name := envReference.FindStringSubmatch(ref)[1]
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// credentialPath returns the path of a systemd credential (LoadCredential= or SetCredential=).
+func credentialPath(name string) (string, error) {
+	dir := os.Getenv("CREDENTIALS_DIRECTORY")
+	if dir == "" {
+		return "", fmt.Errorf("credential %q requested but CREDENTIALS_DIRECTORY is not set (is the relay running under systemd with LoadCredential=?)", name)
+	}
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid credential name %q", name)
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// readSecretFile returns the contents of a secret file without trailing newlines.
+func readSecretFile(path string) (string, error) {
+	// #nosec G304 -- the path is an operator-provided secret reference in the configuration file.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveSecret(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "hec_token"), []byte("file-token\n"), 0600); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "empty"), []byte("\n"), 0600); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+	t.Setenv("RELAY_TEST_TOKEN", "env-token")
+	t.Setenv("RELAY_TEST_SECRETS", dir)
+	t.Setenv("CREDENTIALS_DIRECTORY", dir)
+
+	tests := []struct {
+		name        string
+		value       string
+		expected    string
+		errContains string
+	}{
+		{name: "literal", value: "plain-token", expected: "plain-token"},
+		{name: "literal with dollar sign", value: "abc$DEF", expected: "abc$DEF"},
+		{name: "environment variable", value: "${RELAY_TEST_TOKEN}", expected: "env-token"},
+		{name: "embedded environment variable", value: "prefix-${RELAY_TEST_TOKEN}", expected: "prefix-env-token"},
+		{name: "file", value: "file:" + filepath.Join(dir, "hec_token"), expected: "file-token"},
+		{name: "file under environment variable", value: "file:${RELAY_TEST_SECRETS}/hec_token", expected: "file-token"},
+		{name: "systemd credential", value: "credential:hec_token", expected: "file-token"},
+		{name: "unset environment variable", value: "${RELAY_TEST_UNSET}", errContains: "environment variable RELAY_TEST_UNSET is not set"},
+		{name: "missing file", value: "file:" + filepath.Join(dir, "missing"), errContains: "failed to read secret file"},
+		{name: "empty file", value: "file:" + filepath.Join(dir, "empty"), errContains: "resolved to an empty value"},
+		{name: "credential outside directory", value: "credential:../hec_token", errContains: "invalid credential name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveSecret(tt.value)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("resolveSecret(%q) error = %v, want error containing %q", tt.value, err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveSecret(%q) error = %v", tt.value, err)
+			}
+			if got != tt.expected {
+				t.Errorf("resolveSecret(%q) = %q, want %q", tt.value, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestResolveSecretPath(t *testing.T) {
+	t.Setenv("CREDENTIALS_DIRECTORY", "/run/credentials/relay.service")
+	t.Setenv("RELAY_TEST_KEY", "/etc/relay/key.pem")
+
+	if got, err := resolveSecretPath("credential:tls.key"); err != nil || got != "/run/credentials/relay.service/tls.key" {
+		t.Errorf("resolveSecretPath(credential) = %q, %v", got, err)
+	}
+	if got, err := resolveSecretPath("${RELAY_TEST_KEY}"); err != nil || got != "/etc/relay/key.pem" {
+		t.Errorf("resolveSecretPath(env) = %q, %v", got, err)
+	}
+	if _, err := resolveSecretPath("file:/etc/relay/key-path"); err == nil {
+		t.Error("resolveSecretPath should reject file: references")
+	}
+
+	t.Setenv("CREDENTIALS_DIRECTORY", "")
+	if _, err := resolveSecretPath("credential:tls.key"); err == nil || !strings.Contains(err.Error(), "CREDENTIALS_DIRECTORY is not set") {
+		t.Errorf("expected error about CREDENTIALS_DIRECTORY, got %v", err)
+	}
+}
+
+func TestLoadConfig_SecretReferences(t *testing.T) {
+	tmpDir := t.TempDir()
+	secretFile := filepath.Join(tmpDir, "primary_token")
+	if err := os.WriteFile(secretFile, []byte("primary-secret\n"), 0600); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+	t.Setenv("RELAY_TEST_HEC_TOKEN", "global-secret")
+
+	configFile := filepath.Join(tmpDir, "test.yml")
+	content := fmt.Sprintf(`splunk:
+  hec_url: "https://splunk.example.com:8088/services/collector/raw"
+  hec_token: "${RELAY_TEST_HEC_TOKEN}"
+listeners:
+  - name: "test"
+    listen_addr: ":19034"
+    log_type: "user-activity"
+    output_dir: "%s/logs"
+    file_prefix: "zpa-test"
+    splunk:
+      source_type: "zpa:user:activity"
+  - name: "multi"
+    listen_addr: ":19035"
+    log_type: "user-status"
+    output_dir: "%s/logs"
+    file_prefix: "zpa-status"
+    splunk:
+      hec_targets:
+        - name: "primary"
+          hec_url: "https://splunk1.example.com:8088/services/collector/raw"
+          hec_token: "file:%s"
+          source_type: "zpa:user:status"
+`, tmpDir, tmpDir, secretFile)
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create config file: %v", err)
+	}
+
+	cfg, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig should succeed: %v", err)
+	}
+	if cfg.Splunk.HECToken != "global-secret" {
+		t.Errorf("global hec_token = %q, want the environment variable's value", cfg.Splunk.HECToken)
+	}
+	if token := cfg.Listeners[1].Splunk.HECTargets[0].HECToken; token != "primary-secret" {
+		t.Errorf("target hec_token = %q, want the secret file's contents", token)
+	}
+
+	// A rotated secret file is read again on reload
+	if err := os.WriteFile(secretFile, []byte("rotated-secret\n"), 0600); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+	cfg, err = ReloadConfig(configFile, []string{":19034", ":19035"})
+	if err != nil {
+		t.Fatalf("ReloadConfig should succeed: %v", err)
+	}
+	if token := cfg.Listeners[1].Splunk.HECTargets[0].HECToken; token != "rotated-secret" {
+		t.Errorf("target hec_token = %q after reload, want the rotated secret", token)
+	}
+
+	// Errors name the setting, not the secret
+	os.Unsetenv("RELAY_TEST_HEC_TOKEN") // Restored by t.Setenv above
+	_, err = LoadConfig(configFile)
+	if err == nil || !strings.Contains(err.Error(), "splunk.hec_token: environment variable RELAY_TEST_HEC_TOKEN is not set") {
+		t.Errorf("expected error naming the setting and variable, got %v", err)
+	}
+	if err != nil && strings.Contains(err.Error(), "rotated-secret") {
+		t.Errorf("error must not contain a resolved secret: %v", err)
+	}
+}
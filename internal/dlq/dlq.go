@@ -28,6 +28,7 @@ type Entry struct {
 //
 // Writer is safe for concurrent use by multiple goroutines.
 type Writer struct {
+	name    string // Listener name, used to label metrics
 	baseDir string
 	file    *os.File
 	curDay  string
@@ -47,6 +48,13 @@ func New(baseDir string) (*Writer, error) {
 	}, nil
 }
 
+// SetName sets the listener name used to label the Writer's metrics.
+func (w *Writer) SetName(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.name = name
+}
+
 // Write writes a failed forward entry to the DLQ with metadata.
 // The entry includes timestamp, connection ID, error message, and original data.
 func (w *Writer) Write(connID string, data []byte, err error) error {
@@ -89,7 +97,7 @@ func (w *Writer) Write(connID string, data []byte, err error) error {
 		return writeErr
 	}
 
-	metrics.LinesProcessed.Add("dlq", 1)
+	metrics.ListenerLinesProcessed.Add(1, w.name, "dlq")
 	slog.Debug("wrote to DLQ", "conn_id", connID, "error", err.Error())
 	return nil
 }
@@ -0,0 +1,343 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/scottbrown/relay/internal/atomicfile"
+	"github.com/scottbrown/relay/internal/circuitbreaker"
+	"github.com/scottbrown/relay/internal/metrics"
+)
+
+const (
+	// drainCheckpointFile records how far each DLQ file has been drained.
+	drainCheckpointFile = "drain-checkpoint.json"
+	// drainedDir receives DLQ files once every entry has been re-forwarded.
+	drainedDir = "drained"
+	// sealGrace is how long after midnight UTC a day's file is still treated as writable.
+	sealGrace = time.Minute
+)
+
+// DrainConfig holds configuration for a Drainer.
+type DrainConfig struct {
+	Name          string                      // Listener name used in logs and metrics
+	Dir           string                      // DLQ directory to drain
+	RateLimit     int                         // Maximum entries re-forwarded per second (default: 100)
+	CheckInterval time.Duration               // How often breaker state and backlog are checked (default: 10s)
+	State         func() circuitbreaker.State // Circuit breaker state of the listener's live forwarder
+	Paused        func() bool                 // Optional: reports whether forwarding is paused by an operator
+}
+
+// drainCheckpoint is persisted in the DLQ directory after every drained entry.
+// Offsets are keyed by file name without the .gz suffix so they survive retention compression.
+type drainCheckpoint struct {
+	Offsets map[string]int64 `json:"offsets"`
+}
+
+// Drainer re-forwards DLQ entries in the background once HEC has recovered.
+//
+// It polls the live forwarder's circuit breaker and drains while the breaker is closed,
+// so a drain starts as soon as the breaker moves from half-open back to closed. Entries are
+// sent in order at no more than RateLimit per second. The first failed send ends the pass
+// and the entry is retried on the next check. Offsets are checkpointed after every entry,
+// so a restart resumes where the previous process stopped (delivery is at-least-once).
+//
+// Files from previous days are moved to the drained/ subdirectory once fully drained.
+// Today's file is drained in place while the DLQ Writer keeps appending to it.
+type Drainer struct {
+	config         DrainConfig
+	sender         Sender
+	checkpointPath string
+	checkpoint     drainCheckpoint
+	lastState      circuitbreaker.State
+	now            func() time.Time
+	wg             sync.WaitGroup
+}
+
+// NewDrainer creates a Drainer that sends DLQ entries through sender.
+// The sender should not write to the DLQ itself, or failed drains would be duplicated.
+// Returns an error if the directory cannot be created or the checkpoint cannot be read.
+// A damaged checkpoint is logged and discarded, so the files are drained from their start.
+func NewDrainer(sender Sender, config DrainConfig) (*Drainer, error) {
+	if config.State == nil {
+		return nil, errors.New("drain requires a circuit breaker state function")
+	}
+	if config.RateLimit <= 0 {
+		config.RateLimit = 100
+	}
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = 10 * time.Second
+	}
+	if err := ensureDir(config.Dir); err != nil {
+		return nil, err
+	}
+
+	d := &Drainer{
+		config:         config,
+		sender:         sender,
+		checkpointPath: filepath.Join(config.Dir, drainCheckpointFile),
+		checkpoint:     drainCheckpoint{Offsets: make(map[string]int64)},
+		lastState:      circuitbreaker.StateClosed,
+		now:            time.Now,
+	}
+
+	// #nosec G304 -- checkpointPath is derived from the configured DLQ directory.
+	data, err := os.ReadFile(d.checkpointPath)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(data, &d.checkpoint); err != nil {
+			// Draining from the start may send entries twice, but loses none
+			slog.Warn("failed to parse drain checkpoint, draining files from the start", "listener", config.Name, "path", d.checkpointPath, "error", err)
+			d.checkpoint = drainCheckpoint{}
+		}
+		if d.checkpoint.Offsets == nil {
+			d.checkpoint.Offsets = make(map[string]int64)
+		}
+	}
+
+	return d, nil
+}
+
+// Start begins draining in a goroutine.
+// The backlog is checked immediately, then every CheckInterval until ctx is cancelled.
+func (d *Drainer) Start(ctx context.Context) {
+	slog.Info("starting DLQ drain worker",
+		"listener", d.config.Name,
+		"dir", d.config.Dir,
+		"rate_limit", d.config.RateLimit,
+		"check_interval", d.config.CheckInterval)
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+
+		ticker := time.NewTicker(d.config.CheckInterval)
+		defer ticker.Stop()
+
+		for {
+			d.drain(ctx)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				slog.Info("DLQ drain worker stopped", "listener", d.config.Name)
+				return
+			}
+		}
+	}()
+}
+
+// Wait blocks until the drain goroutine has exited after its context is cancelled.
+func (d *Drainer) Wait() {
+	d.wg.Wait()
+}
+
+// drain runs one pass over the DLQ directory if the breaker is closed.
+func (d *Drainer) drain(ctx context.Context) {
+	files, err := Files(d.config.Dir)
+	if err != nil {
+		slog.Error("failed to list DLQ files", "listener", d.config.Name, "error", err)
+		return
+	}
+	d.prune(files)
+	d.updateBacklog(files)
+
+	state := d.config.State()
+	if state != d.lastState {
+		if state == circuitbreaker.StateClosed {
+			slog.Info("circuit breaker closed, resuming DLQ drain", "listener", d.config.Name)
+		} else {
+			slog.Info("circuit breaker not closed, pausing DLQ drain", "listener", d.config.Name, "state", state.String())
+		}
+		d.lastState = state
+	}
+	if state != circuitbreaker.StateClosed {
+		return
+	}
+	if d.config.Paused != nil && d.config.Paused() {
+		return // Logged by the forwarder when paused and resumed
+	}
+
+	for _, path := range files {
+		done, err := d.drainFile(ctx, path)
+		if err != nil {
+			slog.Warn("DLQ drain paused", "listener", d.config.Name, "file", path, "error", err)
+			break
+		}
+		if done {
+			d.archive(path)
+		}
+	}
+	d.updateBacklog(nil)
+}
+
+// drainFile sends every remaining entry in a file.
+// It reports whether the file is complete and can be archived.
+func (d *Drainer) drainFile(ctx context.Context, path string) (bool, error) {
+	key := checkpointKey(path)
+	offset := d.checkpoint.Offsets[key]
+
+	reader, err := OpenReader(path)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = reader.Close() }()
+
+	if err := reader.Skip(offset); err != nil {
+		// The file was rewritten (e.g. by relay dlq replay); start it again from the top
+		slog.Warn("DLQ file shorter than drain checkpoint, restarting file", "listener", d.config.Name, "file", path, "offset", offset)
+		_ = reader.Close()
+		if reader, err = OpenReader(path); err != nil {
+			return false, err
+		}
+		offset = 0
+	}
+
+	interval := time.Second / time.Duration(d.config.RateLimit)
+	for {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		if d.config.State() != circuitbreaker.StateClosed {
+			return false, circuitbreaker.ErrCircuitOpen
+		}
+
+		entry, _, next, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		switch {
+		case errors.Is(err, ErrMalformedEntry):
+			// Malformed lines cannot be sent; they remain in the archived file for inspection
+			slog.Warn("skipping malformed DLQ entry", "listener", d.config.Name, "file", path, "error", err)
+			metrics.ListenerDLQDrained.Add(1, d.config.Name, "malformed")
+		case err != nil:
+			return false, err
+		default:
+			if sendErr := d.sender.Forward(entry.ConnID, []byte(entry.Data)); sendErr != nil {
+				metrics.ListenerDLQDrained.Add(1, d.config.Name, "failure")
+				return false, sendErr
+			}
+			metrics.ListenerDLQDrained.Add(1, d.config.Name, "success")
+		}
+
+		if !strings.HasSuffix(path, ".gz") {
+			metrics.DLQBacklogBytes.Add(d.config.Name, offset-next)
+		}
+		offset = next
+		d.checkpoint.Offsets[key] = offset
+		if err := d.saveCheckpoint(); err != nil {
+			return false, err
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+
+	return d.sealed(path), nil
+}
+
+// sealed reports whether the DLQ Writer has moved past the file's day.
+func (d *Drainer) sealed(path string) bool {
+	day, ok := FileDate(path)
+	if !ok {
+		return true
+	}
+	return d.now().UTC().After(day.AddDate(0, 0, 1).Add(sealGrace))
+}
+
+// archive moves a fully drained file into the drained/ subdirectory.
+func (d *Drainer) archive(path string) {
+	dir := DrainedDir(d.config.Dir)
+	if err := ensureDir(dir); err != nil {
+		slog.Error("failed to create drained directory", "listener", d.config.Name, "error", err)
+		return
+	}
+	if err := os.Rename(path, filepath.Join(dir, filepath.Base(path))); err != nil {
+		slog.Error("failed to archive drained DLQ file", "listener", d.config.Name, "file", path, "error", err)
+		return
+	}
+	delete(d.checkpoint.Offsets, checkpointKey(path))
+	if err := d.saveCheckpoint(); err != nil {
+		slog.Error("failed to save drain checkpoint", "listener", d.config.Name, "error", err)
+	}
+	slog.Info("drained DLQ file", "listener", d.config.Name, "file", path)
+}
+
+// prune removes checkpoint offsets for files that no longer exist, e.g. after retention cleanup.
+func (d *Drainer) prune(files []string) {
+	present := make(map[string]bool, len(files))
+	for _, f := range files {
+		present[checkpointKey(f)] = true
+	}
+	changed := false
+	for key := range d.checkpoint.Offsets {
+		if !present[key] {
+			delete(d.checkpoint.Offsets, key)
+			changed = true
+		}
+	}
+	if changed {
+		if err := d.saveCheckpoint(); err != nil {
+			slog.Error("failed to save drain checkpoint", "listener", d.config.Name, "error", err)
+		}
+	}
+}
+
+// updateBacklog publishes the number of files and bytes still to drain.
+// Compressed files are counted at their on-disk size. A nil files slice re-lists the directory.
+func (d *Drainer) updateBacklog(files []string) {
+	if files == nil {
+		var err error
+		if files, err = Files(d.config.Dir); err != nil {
+			return
+		}
+	}
+
+	var backlog int64
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			continue
+		}
+		if strings.HasSuffix(f, ".gz") {
+			backlog += info.Size()
+		} else if remaining := info.Size() - d.checkpoint.Offsets[checkpointKey(f)]; remaining > 0 {
+			backlog += remaining
+		}
+	}
+
+	metrics.SetMapInt(metrics.DLQBacklogFiles, d.config.Name, int64(len(files)))
+	metrics.SetMapInt(metrics.DLQBacklogBytes, d.config.Name, backlog)
+}
+
+// saveCheckpoint writes the checkpoint atomically via a temporary file and rename.
+func (d *Drainer) saveCheckpoint() error {
+	data, err := json.Marshal(d.checkpoint)
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(d.checkpointPath, data, 0600)
+}
+
+// DrainedDir returns the directory fully drained files are moved to for a DLQ directory.
+func DrainedDir(dir string) string {
+	return filepath.Join(dir, drainedDir)
+}
+
+func checkpointKey(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), ".gz")
+}
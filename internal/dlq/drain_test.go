@@ -0,0 +1,168 @@
+package dlq
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/scottbrown/relay/internal/circuitbreaker"
+)
+
+func newTestDrainer(t *testing.T, sender Sender, dir string, state *circuitbreaker.State) *Drainer {
+	t.Helper()
+	d, err := NewDrainer(sender, DrainConfig{
+		Name:      "test",
+		Dir:       dir,
+		RateLimit: 10000,
+		State:     func() circuitbreaker.State { return *state },
+	})
+	if err != nil {
+		t.Fatalf("NewDrainer() error = %v", err)
+	}
+	d.now = func() time.Time { return time.Date(2025, 1, 16, 12, 0, 0, 0, time.UTC) }
+	return d
+}
+
+func TestDrainer_DrainsAndArchivesPastFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dlq-2025-01-15.ndjson")
+	writeEntries(t, path, testEntries())
+
+	state := circuitbreaker.StateClosed
+	sender := &recordingSender{}
+	d := newTestDrainer(t, sender, dir, &state)
+
+	d.drain(context.Background())
+
+	if len(sender.sent) != 3 || sender.sent[2] != `{"n":3}` {
+		t.Errorf("sent = %v, want the three payloads in order", sender.sent)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("drained file should be moved out of the DLQ directory")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "drained", "dlq-2025-01-15.ndjson")); err != nil {
+		t.Errorf("drained file should be archived: %v", err)
+	}
+	if len(d.checkpoint.Offsets) != 0 {
+		t.Errorf("checkpoint offsets = %v, want empty after archive", d.checkpoint.Offsets)
+	}
+}
+
+func TestDrainer_WaitsWhileBreakerOpen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dlq-2025-01-15.ndjson")
+	writeEntries(t, path, testEntries())
+
+	state := circuitbreaker.StateOpen
+	sender := &recordingSender{}
+	d := newTestDrainer(t, sender, dir, &state)
+
+	d.drain(context.Background())
+	if len(sender.sent) != 0 {
+		t.Fatalf("sent %d entries while breaker open, want 0", len(sender.sent))
+	}
+
+	state = circuitbreaker.StateHalfOpen
+	d.drain(context.Background())
+	if len(sender.sent) != 0 {
+		t.Fatalf("sent %d entries while breaker half-open, want 0", len(sender.sent))
+	}
+
+	state = circuitbreaker.StateClosed
+	d.drain(context.Background())
+	if len(sender.sent) != 3 {
+		t.Errorf("sent %d entries after breaker closed, want 3", len(sender.sent))
+	}
+}
+
+func TestDrainer_WaitsWhilePaused(t *testing.T) {
+	dir := t.TempDir()
+	writeEntries(t, filepath.Join(dir, "dlq-2025-01-15.ndjson"), testEntries())
+
+	state := circuitbreaker.StateClosed
+	sender := &recordingSender{}
+	d := newTestDrainer(t, sender, dir, &state)
+	paused := true
+	d.config.Paused = func() bool { return paused }
+
+	d.drain(context.Background())
+	if len(sender.sent) != 0 {
+		t.Fatalf("sent %d entries while paused, want 0", len(sender.sent))
+	}
+
+	paused = false
+	d.drain(context.Background())
+	if len(sender.sent) != 3 {
+		t.Errorf("sent %d entries after resume, want 3", len(sender.sent))
+	}
+}
+
+func TestDrainer_FailureResumesFromCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dlq-2025-01-15.ndjson")
+	writeEntries(t, path, testEntries())
+
+	state := circuitbreaker.StateClosed
+	failing := &recordingSender{failFor: `"n":2`}
+	d := newTestDrainer(t, failing, dir, &state)
+
+	d.drain(context.Background())
+	if len(failing.sent) != 1 {
+		t.Fatalf("sent %d entries before failure, want 1", len(failing.sent))
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("DLQ file should remain after a failed drain: %v", err)
+	}
+
+	// A new drainer (as after a restart) resumes from the persisted offset
+	sender := &recordingSender{}
+	d = newTestDrainer(t, sender, dir, &state)
+	d.drain(context.Background())
+
+	if len(sender.sent) != 2 || sender.sent[0] != `{"n":2}` {
+		t.Errorf("sent = %v, want n=2 and n=3 only", sender.sent)
+	}
+}
+
+func TestDrainer_DamagedCheckpointDrainsFromStart(t *testing.T) {
+	dir := t.TempDir()
+	writeEntries(t, filepath.Join(dir, "dlq-2025-01-15.ndjson"), testEntries())
+	// A checkpoint cut short by a power failure
+	if err := os.WriteFile(filepath.Join(dir, drainCheckpointFile), []byte(`{"offsets":{"dlq-2025`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	state := circuitbreaker.StateClosed
+	sender := &recordingSender{}
+	d := newTestDrainer(t, sender, dir, &state)
+	d.drain(context.Background())
+
+	if len(sender.sent) != 3 {
+		t.Errorf("sent %d entries, want all 3 from the start of the file", len(sender.sent))
+	}
+}
+
+func TestDrainer_TodaysFileDrainedInPlace(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dlq-2025-01-16.ndjson")
+	writeEntries(t, path, testEntries()[:1])
+
+	state := circuitbreaker.StateClosed
+	sender := &recordingSender{}
+	d := newTestDrainer(t, sender, dir, &state)
+
+	d.drain(context.Background())
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("today's file should stay in place: %v", err)
+	}
+
+	// Entries appended by the DLQ Writer are picked up on the next pass
+	writeEntries(t, path, testEntries())
+	d.drain(context.Background())
+
+	if len(sender.sent) != 3 {
+		t.Errorf("sent %d entries, want 3 (1 + 2 appended): %v", len(sender.sent), sender.sent)
+	}
+}
@@ -0,0 +1,357 @@
+package dlq
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Sender delivers a replayed payload. forwarder.Forwarder satisfies this interface.
+type Sender interface {
+	Forward(connID string, data []byte) error
+}
+
+// Filter selects which DLQ entries are replayed.
+// Zero-valued fields match every entry.
+type Filter struct {
+	Since         time.Time // Only entries at or after this time
+	Until         time.Time // Only entries before this time
+	ConnID        string    // Only entries with this connection ID
+	ErrorContains string    // Only entries whose error contains this substring
+}
+
+// Matches reports whether the entry satisfies the filter.
+func (f Filter) Matches(e Entry) bool {
+	if f.ConnID != "" && e.ConnID != f.ConnID {
+		return false
+	}
+	if f.ErrorContains != "" && !strings.Contains(e.Error, f.ErrorContains) {
+		return false
+	}
+	if !f.Since.IsZero() || !f.Until.IsZero() {
+		ts, err := time.Parse(time.RFC3339, e.Timestamp)
+		if err != nil {
+			return false
+		}
+		if !f.Since.IsZero() && ts.Before(f.Since) {
+			return false
+		}
+		if !f.Until.IsZero() && !ts.Before(f.Until) {
+			return false
+		}
+	}
+	return true
+}
+
+// skipsDay reports whether no entry in a file for the given UTC day can match the filter.
+func (f Filter) skipsDay(day time.Time) bool {
+	if !f.Since.IsZero() && !day.AddDate(0, 0, 1).After(f.Since) {
+		return true
+	}
+	if !f.Until.IsZero() && !day.Before(f.Until) {
+		return true
+	}
+	return false
+}
+
+// ReplayStats summarises the outcome of replaying one or more DLQ files.
+type ReplayStats struct {
+	Replayed  int // Entries delivered and archived
+	Failed    int // Matching entries that could not be delivered and were kept
+	Skipped   int // Entries that did not match the filter and were kept
+	Malformed int // Lines that could not be decoded and were kept
+}
+
+func (s *ReplayStats) add(o ReplayStats) {
+	s.Replayed += o.Replayed
+	s.Failed += o.Failed
+	s.Skipped += o.Skipped
+	s.Malformed += o.Malformed
+}
+
+// replayProgress is the checkpoint persisted next to a DLQ file while it is being replayed.
+// Sizes of the output files are recorded so a resumed run can discard partial writes.
+type replayProgress struct {
+	Done          bool        `json:"done"` // All entries processed; only the final rewrite remains
+	Offset        int64       `json:"offset"`
+	RemainingSize int64       `json:"remaining_size"`
+	ArchiveSize   int64       `json:"archive_size"`
+	Stats         ReplayStats `json:"stats"`
+}
+
+// Replayer re-sends dead-lettered entries and removes delivered entries from their DLQ file.
+//
+// Progress is checkpointed after every entry in {file}.progress so an interrupted replay
+// resumes where it stopped. Delivered entries are appended to the same-named file in
+// ArchiveDir; entries that were not delivered are written back to the original file once
+// it has been fully processed. Delivery is at-least-once: an entry sent immediately before
+// a crash may be sent again on resume.
+type Replayer struct {
+	sender     Sender
+	filter     Filter
+	archiveDir string
+}
+
+// NewReplayer creates a Replayer that sends matching entries through sender.
+// Delivered entries are archived under archiveDir, which is created if needed.
+func NewReplayer(sender Sender, filter Filter, archiveDir string) (*Replayer, error) {
+	if err := ensureDir(archiveDir); err != nil {
+		return nil, err
+	}
+	return &Replayer{
+		sender:     sender,
+		filter:     filter,
+		archiveDir: archiveDir,
+	}, nil
+}
+
+// ReplayFiles replays each file in order, stopping at the first file-level error.
+// Files whose date falls outside the filter's time range are skipped without being opened.
+func (r *Replayer) ReplayFiles(ctx context.Context, files []string) (ReplayStats, error) {
+	var total ReplayStats
+	for _, path := range files {
+		if day, ok := FileDate(path); ok && r.filter.skipsDay(day) {
+			continue
+		}
+		stats, err := r.ReplayFile(ctx, path)
+		total.add(stats)
+		if err != nil {
+			return total, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return total, nil
+}
+
+// ReplayFile replays a single DLQ file, resuming from its progress file if one exists.
+// Cancelling ctx stops the replay after the current entry; progress is kept for the next run.
+func (r *Replayer) ReplayFile(ctx context.Context, path string) (ReplayStats, error) {
+	progressPath := path + ".progress"
+	remainingPath := path + ".remaining"
+	archivePath := filepath.Join(r.archiveDir, strings.TrimSuffix(filepath.Base(path), ".gz"))
+
+	progress, found, err := loadProgress(progressPath)
+	if err != nil {
+		return ReplayStats{}, err
+	}
+	if progress.Done {
+		return progress.Stats, r.complete(path, remainingPath, progressPath, progress)
+	}
+	if found {
+		slog.Info("resuming DLQ replay", "file", path, "offset", progress.Offset)
+	} else if info, err := os.Stat(archivePath); err == nil {
+		// Entries archived by earlier replays must survive the first checkpoint
+		progress.ArchiveSize = info.Size()
+	}
+
+	reader, err := OpenReader(path)
+	if err != nil {
+		return ReplayStats{}, err
+	}
+	defer reader.Close()
+
+	if err := reader.Skip(progress.Offset); err != nil {
+		return progress.Stats, err
+	}
+
+	remaining, err := openTruncated(remainingPath, progress.RemainingSize)
+	if err != nil {
+		return progress.Stats, err
+	}
+	defer remaining.Close()
+
+	archive, err := openTruncated(archivePath, progress.ArchiveSize)
+	if err != nil {
+		return progress.Stats, err
+	}
+	defer archive.Close()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return progress.Stats, err
+		}
+
+		entry, raw, offset, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var out *os.File
+		var size *int64
+		switch {
+		case errors.Is(err, ErrMalformedEntry):
+			slog.Warn("keeping malformed DLQ entry", "file", path, "error", err)
+			progress.Stats.Malformed++
+			out, size = remaining, &progress.RemainingSize
+		case err != nil:
+			return progress.Stats, err
+		case !r.filter.Matches(entry):
+			progress.Stats.Skipped++
+			out, size = remaining, &progress.RemainingSize
+		default:
+			if sendErr := r.sender.Forward(entry.ConnID, []byte(entry.Data)); sendErr != nil {
+				slog.Warn("DLQ replay failed", "conn_id", entry.ConnID, "error", sendErr)
+				progress.Stats.Failed++
+				out, size = remaining, &progress.RemainingSize
+			} else {
+				progress.Stats.Replayed++
+				out, size = archive, &progress.ArchiveSize
+			}
+		}
+
+		n, err := out.Write(append(raw, '\n'))
+		*size += int64(n)
+		if err != nil {
+			return progress.Stats, err
+		}
+
+		progress.Offset = offset
+		if err := saveProgress(progressPath, progress); err != nil {
+			return progress.Stats, err
+		}
+	}
+
+	if err := remaining.Close(); err != nil {
+		return progress.Stats, err
+	}
+	if err := archive.Sync(); err != nil {
+		return progress.Stats, err
+	}
+
+	progress.Done = true
+	if err := saveProgress(progressPath, progress); err != nil {
+		return progress.Stats, err
+	}
+	return progress.Stats, r.complete(path, remainingPath, progressPath, progress)
+}
+
+// complete rewrites the DLQ file and removes the progress file.
+// It is safe to call again if a previous run stopped part way through.
+func (r *Replayer) complete(path, remainingPath, progressPath string, progress replayProgress) error {
+	if err := r.finish(path, remainingPath, progress.RemainingSize); err != nil {
+		return err
+	}
+	if err := os.Remove(progressPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	slog.Info("replayed DLQ file",
+		"file", path,
+		"replayed", progress.Stats.Replayed,
+		"failed", progress.Stats.Failed,
+		"skipped", progress.Stats.Skipped,
+		"malformed", progress.Stats.Malformed)
+	return nil
+}
+
+// finish replaces the original DLQ file with the entries that were not delivered.
+// If every entry was delivered, the original file is removed.
+// A missing remaining file means a previous run already finished the rewrite.
+func (r *Replayer) finish(path, remainingPath string, remainingSize int64) error {
+	if _, err := os.Stat(remainingPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	if remainingSize == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return os.Remove(remainingPath)
+	}
+
+	if !strings.HasSuffix(path, ".gz") {
+		return os.Rename(remainingPath, path)
+	}
+
+	// Keep compressed files compressed
+	tmpPath := path + ".tmp"
+	if err := gzipFile(remainingPath, tmpPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	return os.Remove(remainingPath)
+}
+
+// openTruncated opens path for appending after truncating it to size.
+// This discards anything written after the last recorded checkpoint.
+func openTruncated(path string, size int64) (*os.File, error) {
+	// #nosec G304 -- path is derived from a DLQ file path chosen by the operator.
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Truncate(size); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(size, io.SeekStart); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// loadProgress reads the checkpoint at path. The boolean reports whether one existed.
+func loadProgress(path string) (replayProgress, bool, error) {
+	var p replayProgress
+	// #nosec G304 -- path is derived from a DLQ file path chosen by the operator.
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return p, false, nil
+	}
+	if err != nil {
+		return p, false, err
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return p, false, fmt.Errorf("failed to parse replay progress %s: %w", path, err)
+	}
+	return p, true, nil
+}
+
+// saveProgress writes the checkpoint atomically via a temporary file and rename.
+func saveProgress(path string, p replayProgress) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func gzipFile(src, dst string) error {
+	// #nosec G304 -- src is a replay working file next to the DLQ file.
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	// #nosec G304 -- dst is a replay working file next to the DLQ file.
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,327 @@
+package dlq
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordingSender implements Sender for testing
+type recordingSender struct {
+	sent    []string
+	failFor string // data containing this substring fails
+	onSend  func()
+}
+
+func (s *recordingSender) Forward(connID string, data []byte) error {
+	if s.onSend != nil {
+		s.onSend()
+	}
+	if s.failFor != "" && strings.Contains(string(data), s.failFor) {
+		return errors.New("send failed")
+	}
+	s.sent = append(s.sent, string(data))
+	return nil
+}
+
+func writeEntries(t *testing.T, path string, entries []Entry) {
+	t.Helper()
+	var b strings.Builder
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			t.Fatalf("failed to marshal entry: %v", err)
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0600); err != nil {
+		t.Fatalf("failed to write DLQ file: %v", err)
+	}
+}
+
+func readEntries(t *testing.T, path string) []Entry {
+	t.Helper()
+	r, err := OpenReader(path)
+	if err != nil {
+		t.Fatalf("OpenReader() error = %v", err)
+	}
+	defer r.Close()
+
+	var entries []Entry
+	for {
+		e, _, _, err := r.Next()
+		if err != nil {
+			break
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func testEntries() []Entry {
+	return []Entry{
+		{Timestamp: "2025-01-15T10:00:00Z", ConnID: "conn-a", Error: "hec send failed after retries", Data: `{"n":1}`},
+		{Timestamp: "2025-01-15T11:00:00Z", ConnID: "conn-b", Error: "circuit breaker is open", Data: `{"n":2}`},
+		{Timestamp: "2025-01-15T12:00:00Z", ConnID: "conn-a", Error: "circuit breaker is open", Data: `{"n":3}`},
+	}
+}
+
+func TestFiles_SortedWithCompressed(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"dlq-2025-01-16.ndjson", "dlq-2025-01-15.ndjson.gz", "other-2025-01-15.ndjson", "dlq-2025-01-16.ndjson.progress"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := Files(dir)
+	if err != nil {
+		t.Fatalf("Files() error = %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Files() returned %d files, want 2: %v", len(files), files)
+	}
+	if filepath.Base(files[0]) != "dlq-2025-01-15.ndjson.gz" {
+		t.Errorf("files[0] = %s, want dlq-2025-01-15.ndjson.gz", filepath.Base(files[0]))
+	}
+}
+
+func TestSize(t *testing.T) {
+	dir := t.TempDir()
+	for name, size := range map[string]int{"dlq-2025-01-15.ndjson.gz": 10, "dlq-2025-01-16.ndjson": 32, "dlq-2025-01-16.ndjson.progress": 5} {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, size), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	size, err := Size(dir)
+	if err != nil {
+		t.Fatalf("Size() error = %v", err)
+	}
+	if size != 42 {
+		t.Errorf("Size() = %d, want 42", size)
+	}
+}
+
+func TestFileDate(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+		ok   bool
+	}{
+		{"/var/dlq/dlq-2025-01-15.ndjson", "2025-01-15", true},
+		{"dlq-2025-01-15.ndjson.gz", "2025-01-15", true},
+		{"zpa-2025-01-15.ndjson", "", false},
+		{"dlq-latest.ndjson", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := FileDate(tt.path)
+		if ok != tt.ok {
+			t.Errorf("FileDate(%q) ok = %v, want %v", tt.path, ok, tt.ok)
+			continue
+		}
+		if ok && got.Format("2006-01-02") != tt.want {
+			t.Errorf("FileDate(%q) = %s, want %s", tt.path, got.Format("2006-01-02"), tt.want)
+		}
+	}
+}
+
+func TestReplayFile_AllDelivered(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dlq-2025-01-15.ndjson")
+	writeEntries(t, path, testEntries())
+
+	sender := &recordingSender{}
+	replayer, err := NewReplayer(sender, Filter{}, filepath.Join(dir, "replayed"))
+	if err != nil {
+		t.Fatalf("NewReplayer() error = %v", err)
+	}
+
+	stats, err := replayer.ReplayFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("ReplayFile() error = %v", err)
+	}
+
+	if stats.Replayed != 3 {
+		t.Errorf("Replayed = %d, want 3", stats.Replayed)
+	}
+	if len(sender.sent) != 3 || sender.sent[0] != `{"n":1}` {
+		t.Errorf("sent = %v, want the three original payloads", sender.sent)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("DLQ file should be removed once every entry is delivered")
+	}
+	if _, err := os.Stat(path + ".progress"); !os.IsNotExist(err) {
+		t.Error("progress file should be removed after completion")
+	}
+
+	archived := readEntries(t, filepath.Join(dir, "replayed", "dlq-2025-01-15.ndjson"))
+	if len(archived) != 3 {
+		t.Errorf("archived %d entries, want 3", len(archived))
+	}
+}
+
+func TestReplayFile_FilterKeepsUnmatched(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dlq-2025-01-15.ndjson")
+	writeEntries(t, path, testEntries())
+
+	sender := &recordingSender{}
+	replayer, err := NewReplayer(sender, Filter{ConnID: "conn-a", ErrorContains: "circuit"}, filepath.Join(dir, "replayed"))
+	if err != nil {
+		t.Fatalf("NewReplayer() error = %v", err)
+	}
+
+	stats, err := replayer.ReplayFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("ReplayFile() error = %v", err)
+	}
+
+	if stats.Replayed != 1 || stats.Skipped != 2 {
+		t.Errorf("stats = %+v, want 1 replayed and 2 skipped", stats)
+	}
+	if len(sender.sent) != 1 || sender.sent[0] != `{"n":3}` {
+		t.Errorf("sent = %v, want only {\"n\":3}", sender.sent)
+	}
+
+	kept := readEntries(t, path)
+	if len(kept) != 2 || kept[0].Data != `{"n":1}` || kept[1].Data != `{"n":2}` {
+		t.Errorf("kept entries = %+v, want n=1 and n=2", kept)
+	}
+}
+
+func TestReplayFile_FailedEntriesKept(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dlq-2025-01-15.ndjson")
+	writeEntries(t, path, testEntries())
+
+	sender := &recordingSender{failFor: `"n":2`}
+	replayer, err := NewReplayer(sender, Filter{}, filepath.Join(dir, "replayed"))
+	if err != nil {
+		t.Fatalf("NewReplayer() error = %v", err)
+	}
+
+	stats, err := replayer.ReplayFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("ReplayFile() error = %v", err)
+	}
+
+	if stats.Replayed != 2 || stats.Failed != 1 {
+		t.Errorf("stats = %+v, want 2 replayed and 1 failed", stats)
+	}
+
+	kept := readEntries(t, path)
+	if len(kept) != 1 || kept[0].Data != `{"n":2}` {
+		t.Errorf("kept entries = %+v, want only n=2", kept)
+	}
+}
+
+func TestReplayFile_ResumeAfterInterrupt(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dlq-2025-01-15.ndjson")
+	writeEntries(t, path, testEntries())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sender := &recordingSender{onSend: cancel}
+	replayer, err := NewReplayer(sender, Filter{}, filepath.Join(dir, "replayed"))
+	if err != nil {
+		t.Fatalf("NewReplayer() error = %v", err)
+	}
+
+	// First run is cancelled after the first entry is sent
+	if _, err := replayer.ReplayFile(ctx, path); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ReplayFile() error = %v, want context.Canceled", err)
+	}
+	if _, err := os.Stat(path + ".progress"); err != nil {
+		t.Fatalf("progress file should exist after interrupt: %v", err)
+	}
+
+	// Second run resumes without re-sending the first entry
+	sender.onSend = nil
+	stats, err := replayer.ReplayFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("ReplayFile() resume error = %v", err)
+	}
+
+	if len(sender.sent) != 3 {
+		t.Errorf("sent %d payloads across both runs, want 3: %v", len(sender.sent), sender.sent)
+	}
+	if stats.Replayed != 3 {
+		t.Errorf("Replayed = %d, want 3 (cumulative)", stats.Replayed)
+	}
+
+	archived := readEntries(t, filepath.Join(dir, "replayed", "dlq-2025-01-15.ndjson"))
+	if len(archived) != 3 {
+		t.Errorf("archived %d entries, want 3", len(archived))
+	}
+}
+
+func TestReplayFile_CompressedFile(t *testing.T) {
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "plain.ndjson")
+	writeEntries(t, plain, testEntries())
+	path := filepath.Join(dir, "dlq-2025-01-15.ndjson.gz")
+	if err := gzipFile(plain, path); err != nil {
+		t.Fatalf("gzipFile() error = %v", err)
+	}
+
+	sender := &recordingSender{failFor: `"n":1`}
+	replayer, err := NewReplayer(sender, Filter{}, filepath.Join(dir, "replayed"))
+	if err != nil {
+		t.Fatalf("NewReplayer() error = %v", err)
+	}
+
+	if _, err := replayer.ReplayFile(context.Background(), path); err != nil {
+		t.Fatalf("ReplayFile() error = %v", err)
+	}
+
+	// The rewritten file must still be gzip-compressed
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open rewritten file: %v", err)
+	}
+	defer f.Close()
+	if _, err := gzip.NewReader(f); err != nil {
+		t.Errorf("rewritten file is not gzip: %v", err)
+	}
+
+	kept := readEntries(t, path)
+	if len(kept) != 1 || kept[0].Data != `{"n":1}` {
+		t.Errorf("kept entries = %+v, want only n=1", kept)
+	}
+}
+
+func TestReplayFiles_SkipsDaysOutsideRange(t *testing.T) {
+	dir := t.TempDir()
+	older := filepath.Join(dir, "dlq-2025-01-14.ndjson")
+	newer := filepath.Join(dir, "dlq-2025-01-15.ndjson")
+	writeEntries(t, older, []Entry{{Timestamp: "2025-01-14T10:00:00Z", ConnID: "old", Error: "e", Data: `{"old":true}`}})
+	writeEntries(t, newer, testEntries())
+
+	sender := &recordingSender{}
+	since := time.Date(2025, 1, 15, 11, 30, 0, 0, time.UTC)
+	replayer, err := NewReplayer(sender, Filter{Since: since}, filepath.Join(dir, "replayed"))
+	if err != nil {
+		t.Fatalf("NewReplayer() error = %v", err)
+	}
+
+	stats, err := replayer.ReplayFiles(context.Background(), []string{older, newer})
+	if err != nil {
+		t.Fatalf("ReplayFiles() error = %v", err)
+	}
+
+	if stats.Replayed != 1 {
+		t.Errorf("Replayed = %d, want 1", stats.Replayed)
+	}
+	if _, err := os.Stat(older); err != nil {
+		t.Error("file outside the range should be left untouched")
+	}
+}
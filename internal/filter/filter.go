@@ -0,0 +1,255 @@
+// Package filter decides, by the values of their fields, which JSON log lines are stored and
+// forwarded. Rules match fields by equality, regular expression, numeric comparison or
+// existence, and drop, sample or route the lines they match.
+package filter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Filter actions.
+const (
+	Drop        = "drop"         // Neither store nor forward the line
+	DropForward = "drop_forward" // Store the line but do not forward it
+	Sample      = "sample"       // Store the line, and forward one in every SampleRate matching lines
+	Route       = "route"        // Store the line and forward it to Target only
+)
+
+// Condition tests the value of one field. Every test that is set must hold.
+type Condition struct {
+	Field          string   // Dotted path of object keys, such as Status or Policy.Name; "$." may prefix it
+	Equals         *string  // Value, compared as text with strings and as JSON with other values
+	Regex          string   // Regular expression the value must match
+	GreaterThan    *float64 // Numeric tests; numbers given as strings are also compared
+	GreaterOrEqual *float64
+	LessThan       *float64
+	LessOrEqual    *float64
+	Exists         *bool // Whether the field must be present (a null value is present)
+}
+
+// Rule applies an action to lines that meet all of its conditions.
+type Rule struct {
+	Name       string // Identifies the rule in metrics
+	Match      []Condition
+	Action     string // Drop, DropForward, Sample or Route
+	SampleRate int    // Forward one in every SampleRate matching lines (Sample)
+	Target     string // HEC target to forward to (Route)
+}
+
+// Decision is what to do with a line.
+type Decision struct {
+	Rule    string // Name of the rule that matched, or empty if none did
+	Action  string // Action of the rule that matched
+	Store   bool   // Write the line to local storage
+	Forward bool   // Forward the line to HEC
+	Target  string // Forward to this HEC target only, if set
+}
+
+// Filter evaluates a list of rules; the first rule a line matches decides what happens to it.
+//
+// Filter is safe for concurrent use by multiple goroutines.
+type Filter struct {
+	rules []*rule
+}
+
+// rule is a compiled Rule.
+type rule struct {
+	Rule
+	conditions []condition
+	matched    atomic.Uint64 // Lines matched, for sampling
+}
+
+// condition is a compiled Condition.
+type condition struct {
+	Condition
+	path  []string
+	regex *regexp.Regexp
+}
+
+// New compiles rules into a Filter. It returns nil if there are no rules.
+func New(rules []Rule) (*Filter, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	f := &Filter{}
+	names := make(map[string]bool)
+	for _, r := range rules {
+		if r.Name == "" {
+			return nil, errors.New("rule name is required")
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("rule %s: duplicate name", r.Name)
+		}
+		names[r.Name] = true
+
+		compiled, err := compile(r)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", r.Name, err)
+		}
+		f.rules = append(f.rules, compiled)
+	}
+	return f, nil
+}
+
+// compile checks a rule and compiles its conditions.
+func compile(r Rule) (*rule, error) {
+	switch r.Action {
+	case Drop, DropForward, Sample, Route:
+	default:
+		return nil, fmt.Errorf("action must be '%s', '%s', '%s' or '%s'", Drop, DropForward, Sample, Route)
+	}
+	if r.Action == Sample && r.SampleRate < 1 {
+		return nil, fmt.Errorf("action '%s' requires a sample_rate of at least 1", Sample)
+	}
+	if r.Action != Sample && r.SampleRate != 0 {
+		return nil, fmt.Errorf("sample_rate is only used with action '%s'", Sample)
+	}
+	if r.Action == Route && r.Target == "" {
+		return nil, fmt.Errorf("action '%s' requires a target", Route)
+	}
+	if r.Action != Route && r.Target != "" {
+		return nil, fmt.Errorf("target is only used with action '%s'", Route)
+	}
+	if len(r.Match) == 0 {
+		return nil, errors.New("match requires at least one condition")
+	}
+
+	compiled := &rule{Rule: r}
+	for _, c := range r.Match {
+		path := strings.Split(strings.TrimPrefix(c.Field, "$."), ".")
+		for _, key := range path {
+			if key == "" {
+				return nil, fmt.Errorf("field %q: empty key", c.Field)
+			}
+		}
+		if c.Equals == nil && c.Regex == "" && c.Exists == nil && c.GreaterThan == nil &&
+			c.GreaterOrEqual == nil && c.LessThan == nil && c.LessOrEqual == nil {
+			return nil, fmt.Errorf("field %s: condition has no test", c.Field)
+		}
+
+		cond := condition{Condition: c, path: path}
+		if c.Regex != "" {
+			re, err := regexp.Compile(c.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: invalid regex: %w", c.Field, err)
+			}
+			cond.regex = re
+		}
+		compiled.conditions = append(compiled.conditions, cond)
+	}
+	return compiled, nil
+}
+
+// Evaluate returns what to do with a line. Lines that match no rule, or are not JSON
+// objects, are stored and forwarded. A nil Filter stores and forwards every line.
+func (f *Filter) Evaluate(line []byte) Decision {
+	pass := Decision{Store: true, Forward: true}
+	if f == nil {
+		return pass
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	var fields map[string]any
+	if err := dec.Decode(&fields); err != nil || fields == nil {
+		return pass
+	}
+
+	for _, r := range f.rules {
+		if !r.matches(fields) {
+			continue
+		}
+		d := Decision{Rule: r.Name, Action: r.Action, Store: true}
+		switch r.Action {
+		case Drop:
+			d.Store = false
+		case Sample:
+			d.Forward = (r.matched.Add(1)-1)%uint64(r.SampleRate) == 0 // #nosec G115 -- SampleRate is checked to be positive
+		case Route:
+			d.Forward = true
+			d.Target = r.Target
+		}
+		return d
+	}
+	return pass
+}
+
+// matches reports whether the fields meet every condition of the rule.
+func (r *rule) matches(fields map[string]any) bool {
+	for i := range r.conditions {
+		if !r.conditions[i].matches(fields) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches reports whether the fields meet the condition.
+func (c *condition) matches(fields map[string]any) bool {
+	value, ok := lookup(fields, c.path)
+	if c.Exists != nil && *c.Exists != ok {
+		return false
+	}
+	if !ok {
+		// Only an existence test can hold for a missing field
+		return c.Equals == nil && c.Regex == "" && c.GreaterThan == nil &&
+			c.GreaterOrEqual == nil && c.LessThan == nil && c.LessOrEqual == nil
+	}
+
+	text := valueText(value)
+	if c.Equals != nil && text != *c.Equals {
+		return false
+	}
+	if c.regex != nil && !c.regex.MatchString(text) {
+		return false
+	}
+	if c.GreaterThan != nil || c.GreaterOrEqual != nil || c.LessThan != nil || c.LessOrEqual != nil {
+		n, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return false
+		}
+		if (c.GreaterThan != nil && n <= *c.GreaterThan) ||
+			(c.GreaterOrEqual != nil && n < *c.GreaterOrEqual) ||
+			(c.LessThan != nil && n >= *c.LessThan) ||
+			(c.LessOrEqual != nil && n > *c.LessOrEqual) {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup returns the value at path, and whether it is present.
+func lookup(fields map[string]any, path []string) (any, bool) {
+	var value any = fields
+	for _, key := range path {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// valueText returns the text of a string, or the compact JSON of any other value.
+func valueText(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		data, _ := json.Marshal(v) // Decoded JSON values always encode
+		return string(data)
+	}
+}
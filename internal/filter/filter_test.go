@@ -0,0 +1,136 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestEvaluate_Conditions(t *testing.T) {
+	line := `{"ConnectorStatus":"Active","CPUUtilization":"12","Memory":{"Used":83.5},"Enabled":true,"Idp":null}`
+
+	tests := []struct {
+		name      string
+		condition Condition
+		matches   bool
+	}{
+		{"equals string", Condition{Field: "ConnectorStatus", Equals: ptr("Active")}, true},
+		{"equals other string", Condition{Field: "ConnectorStatus", Equals: ptr("Inactive")}, false},
+		{"equals boolean", Condition{Field: "Enabled", Equals: ptr("true")}, true},
+		{"equals null", Condition{Field: "Idp", Equals: ptr("null")}, true},
+		{"regex", Condition{Field: "ConnectorStatus", Regex: "^Act"}, true},
+		{"regex no match", Condition{Field: "ConnectorStatus", Regex: "^In"}, false},
+		{"greater than number", Condition{Field: "Memory.Used", GreaterThan: ptr(80.0)}, true},
+		{"less than number", Condition{Field: "$.Memory.Used", LessThan: ptr(80.0)}, false},
+		{"numeric string", Condition{Field: "CPUUtilization", GreaterOrEqual: ptr(12.0), LessOrEqual: ptr(12.0)}, true},
+		{"not a number", Condition{Field: "ConnectorStatus", GreaterThan: ptr(0.0)}, false},
+		{"exists", Condition{Field: "Idp", Exists: ptr(true)}, true},
+		{"does not exist", Condition{Field: "SessionID", Exists: ptr(false)}, true},
+		{"exists fails", Condition{Field: "SessionID", Exists: ptr(true)}, false},
+		{"missing field", Condition{Field: "SessionID", Equals: ptr("")}, false},
+		{"path through a value", Condition{Field: "ConnectorStatus.Name", Exists: ptr(true)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := New([]Rule{{Name: "test", Match: []Condition{tt.condition}, Action: DropForward}})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			d := f.Evaluate([]byte(line))
+			if matched := d.Rule == "test"; matched != tt.matches {
+				t.Errorf("matched = %v, want %v", matched, tt.matches)
+			}
+		})
+	}
+}
+
+func TestEvaluate_Actions(t *testing.T) {
+	f, err := New([]Rule{
+		{Name: "drop-healthy", Match: []Condition{{Field: "Status", Equals: ptr("ok")}}, Action: Drop},
+		{Name: "keep-local", Match: []Condition{{Field: "Status", Equals: ptr("debug")}}, Action: DropForward},
+		{Name: "route-errors", Match: []Condition{{Field: "Status", Equals: ptr("error")}}, Action: Route, Target: "soc"},
+		{Name: "all", Match: []Condition{{Field: "Status", Exists: ptr(true)}}, Action: Drop},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		line     string
+		expected Decision
+	}{
+		{`{"Status":"ok"}`, Decision{Rule: "drop-healthy", Action: Drop}},
+		{`{"Status":"debug"}`, Decision{Rule: "keep-local", Action: DropForward, Store: true}},
+		{`{"Status":"error"}`, Decision{Rule: "route-errors", Action: Route, Store: true, Forward: true, Target: "soc"}},
+		{`{"Status":"warn"}`, Decision{Rule: "all", Action: Drop}},
+		{`{"Other":1}`, Decision{Store: true, Forward: true}},
+		{`not json`, Decision{Store: true, Forward: true}},
+	}
+	for _, tt := range tests {
+		if got := f.Evaluate([]byte(tt.line)); got != tt.expected {
+			t.Errorf("Evaluate(%s) = %+v, want %+v", tt.line, got, tt.expected)
+		}
+	}
+
+	var nilFilter *Filter
+	if got := nilFilter.Evaluate([]byte(`{}`)); !got.Store || !got.Forward {
+		t.Errorf("nil Filter should store and forward, got %+v", got)
+	}
+}
+
+func TestEvaluate_Sample(t *testing.T) {
+	f, _ := New([]Rule{{Name: "metrics", Match: []Condition{{Field: "CPU", Exists: ptr(true)}}, Action: Sample, SampleRate: 3}})
+
+	forwarded := 0
+	for i := 0; i < 9; i++ {
+		d := f.Evaluate([]byte(`{"CPU":1}`))
+		if !d.Store {
+			t.Fatal("sampled lines should be stored")
+		}
+		if d.Forward {
+			forwarded++
+		}
+	}
+	if forwarded != 3 {
+		t.Errorf("forwarded %d of 9 lines, want 3", forwarded)
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	match := []Condition{{Field: "Status", Exists: ptr(true)}}
+
+	tests := []struct {
+		name     string
+		rules    []Rule
+		expected string
+	}{
+		{"no name", []Rule{{Match: match, Action: Drop}}, "rule name is required"},
+		{"duplicate name", []Rule{{Name: "a", Match: match, Action: Drop}, {Name: "a", Match: match, Action: Drop}}, "rule a: duplicate name"},
+		{"unknown action", []Rule{{Name: "a", Match: match, Action: "keep"}}, "action must be"},
+		{"sample without rate", []Rule{{Name: "a", Match: match, Action: Sample}}, "requires a sample_rate"},
+		{"rate without sample", []Rule{{Name: "a", Match: match, Action: Drop, SampleRate: 2}}, "sample_rate is only used"},
+		{"route without target", []Rule{{Name: "a", Match: match, Action: Route}}, "requires a target"},
+		{"target without route", []Rule{{Name: "a", Match: match, Action: Drop, Target: "soc"}}, "target is only used"},
+		{"no conditions", []Rule{{Name: "a", Action: Drop}}, "match requires at least one condition"},
+		{"empty field", []Rule{{Name: "a", Match: []Condition{{Exists: ptr(true)}}, Action: Drop}}, "empty key"},
+		{"no test", []Rule{{Name: "a", Match: []Condition{{Field: "Status"}}, Action: Drop}}, "condition has no test"},
+		{"bad regex", []Rule{{Name: "a", Match: []Condition{{Field: "Status", Regex: "("}}, Action: Drop}}, "invalid regex"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.rules)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+
+	if f, err := New(nil); f != nil || err != nil {
+		t.Errorf("New(nil) = %v, %v, want nil, nil", f, err)
+	}
+}
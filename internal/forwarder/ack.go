@@ -0,0 +1,135 @@
+package forwarder
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/scottbrown/relay/internal/metrics"
+)
+
+// AckConfig holds configuration for HEC indexer acknowledgement (useACK).
+// When enabled, a request only counts as delivered once HEC reports its ackId as indexed.
+type AckConfig struct {
+	Enabled      bool
+	Timeout      time.Duration // Maximum wait for an acknowledgement before re-sending (default: 60s)
+	PollInterval time.Duration // Interval between ack status queries (default: 1s)
+}
+
+// errNoAckID is returned when HEC accepts a request without an ackId.
+// This happens when indexer acknowledgement is not enabled on the HEC token.
+var errNoAckID = errors.New("HEC response has no ackId (is indexer acknowledgement enabled on the token?)")
+
+// newChannel returns a random UUID to identify this forwarder's HEC ack channel.
+func newChannel() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40 // Version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// parseAckID extracts the ackId from a HEC data response.
+func parseAckID(body io.Reader) (uint64, error) {
+	var resp struct {
+		AckID *uint64 `json:"ackId"`
+	}
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return 0, fmt.Errorf("decode HEC response: %w", err)
+	}
+	if resp.AckID == nil {
+		return 0, errNoAckID
+	}
+	return *resp.AckID, nil
+}
+
+// getAckURL converts the collector URL to the ack endpoint URL.
+func (h *HEC) getAckURL() string {
+	return strings.Replace(h.getHealthURL(), "/services/collector/health", "/services/collector/ack", 1)
+}
+
+// waitForAck polls the ack endpoint until ackID is reported as indexed or the timeout expires.
+// Query errors are retried until the timeout, since HEC may be briefly busy.
+func (h *HEC) waitForAck(connID, token string, ackID uint64, cfg AckConfig) error {
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 60 * time.Second
+	}
+	pollInterval := cfg.PollInterval
+	if pollInterval == 0 {
+		pollInterval = time.Second
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		acked, err := h.queryAck(token, ackID)
+		if err != nil {
+			slog.Debug("HEC ack query failed", "conn_id", connID, "ack_id", ackID, "error", err)
+		} else if acked {
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("ackId %d not acknowledged within %s", ackID, timeout)
+		}
+		time.Sleep(min(pollInterval, remaining))
+	}
+}
+
+// queryAck asks HEC whether ackID has been indexed.
+func (h *HEC) queryAck(token string, ackID uint64) (bool, error) {
+	body := fmt.Sprintf(`{"acks":[%d]}`, ackID)
+	req, err := http.NewRequest("POST", h.getAckURL(), bytes.NewReader([]byte(body)))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", "Splunk "+token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Splunk-Request-Channel", h.channel)
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return false, errors.New("HEC ack query failed with status: " + resp.Status)
+	}
+
+	var status struct {
+		Acks map[string]bool `json:"acks"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return false, fmt.Errorf("decode HEC ack response: %w", err)
+	}
+	return status.Acks[strconv.FormatUint(ackID, 10)], nil
+}
+
+// awaitAck reads the ackId from a successful HEC response and waits for it to be indexed.
+// The response body is always closed.
+func (h *HEC) awaitAck(connID, token string, resp *http.Response, cfg AckConfig) error {
+	ackID, err := parseAckID(resp.Body)
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	if err == nil {
+		err = h.waitForAck(connID, token, ackID, cfg)
+	}
+
+	if err != nil {
+		metrics.TargetHecAcks.Add(1, h.config.Listener, h.targetName(), "failure")
+		return err
+	}
+	metrics.TargetHecAcks.Add(1, h.config.Listener, h.targetName(), "success")
+	return nil
+}
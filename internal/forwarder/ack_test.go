@@ -0,0 +1,112 @@
+package forwarder
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scottbrown/relay/internal/dlq"
+	"github.com/scottbrown/relay/internal/testutil/hecmock"
+)
+
+func TestNewChannel(t *testing.T) {
+	a, b := newChannel(), newChannel()
+	if len(a) != 36 || strings.Count(a, "-") != 4 {
+		t.Errorf("newChannel() = %q, want a UUID", a)
+	}
+	if a == b {
+		t.Errorf("newChannel() returned %q twice", a)
+	}
+}
+
+func TestForward_AckWaitsForAcknowledgement(t *testing.T) {
+	server := hecmock.NewMockHECServer("test-token")
+	defer server.Close()
+	server.EnableAck()
+	server.SetAckDelay(50 * time.Millisecond)
+
+	hec := New(Config{
+		URL:   server.URL + "/services/collector/raw",
+		Token: "test-token",
+		Ack:   AckConfig{Enabled: true, Timeout: 5 * time.Second, PollInterval: 10 * time.Millisecond},
+	})
+
+	start := time.Now()
+	if err := hec.Forward("conn-1", []byte(`{"n":1}`)); err != nil {
+		t.Fatalf("Forward() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Forward() returned after %s, before the data was acknowledged", elapsed)
+	}
+
+	requests := server.GetRequests()
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	if ch := requests[0].Headers.Get("X-Splunk-Request-Channel"); ch != hec.channel {
+		t.Errorf("request channel = %q, want %q", ch, hec.channel)
+	}
+	if server.AckQueryCount() < 2 {
+		t.Errorf("got %d ack queries, want polling until acknowledged", server.AckQueryCount())
+	}
+}
+
+func TestForward_AckTimeoutResendsThenDeadLetters(t *testing.T) {
+	server := hecmock.NewMockHECServer("test-token")
+	defer server.Close()
+	server.EnableAck()
+	server.SetAckWithheld(true)
+
+	dir := t.TempDir()
+	dlqWriter, err := dlq.New(dir)
+	if err != nil {
+		t.Fatalf("dlq.New() error = %v", err)
+	}
+	defer dlqWriter.Close()
+
+	hec := New(Config{
+		URL:   server.URL + "/services/collector/raw",
+		Token: "test-token",
+		DLQ:   dlqWriter,
+		Retry: RetryConfig{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+		Ack:   AckConfig{Enabled: true, Timeout: 50 * time.Millisecond, PollInterval: 10 * time.Millisecond},
+	})
+
+	if err := hec.Forward("conn-1", []byte(`{"n":1}`)); err == nil {
+		t.Fatal("Forward() should fail when the data is never acknowledged")
+	}
+
+	// Each attempt re-sends the data after the ack timeout
+	if got := server.RequestCount(); got != 2 {
+		t.Errorf("got %d requests, want 2", got)
+	}
+
+	content, err := os.ReadFile(dlqWriter.CurrentFile())
+	if err != nil {
+		t.Fatalf("failed to read DLQ file: %v", err)
+	}
+	if !strings.Contains(string(content), `{\"n\":1}`) {
+		t.Errorf("DLQ does not contain the unacknowledged data: %s", content)
+	}
+}
+
+func TestForward_AckMissingAckID(t *testing.T) {
+	// HEC accepts the data but the token does not have indexer acknowledgement enabled
+	server := hecmock.NewMockHECServer("test-token")
+	defer server.Close()
+
+	hec := New(Config{
+		URL:   server.URL + "/services/collector/raw",
+		Token: "test-token",
+		Retry: RetryConfig{MaxAttempts: 1},
+		Ack:   AckConfig{Enabled: true, Timeout: time.Second, PollInterval: 10 * time.Millisecond},
+	})
+
+	if err := hec.Forward("conn-1", []byte(`{"n":1}`)); err == nil {
+		t.Fatal("Forward() should fail without an ackId")
+	}
+	if server.AckQueryCount() != 0 {
+		t.Errorf("got %d ack queries, want none without an ackId", server.AckQueryCount())
+	}
+}
@@ -0,0 +1,95 @@
+package forwarder
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+const (
+	// EndpointRaw posts lines unchanged to the HEC raw endpoint (default).
+	EndpointRaw = "raw"
+	// EndpointEvent wraps each line in a HEC event envelope with per-event metadata.
+	EndpointEvent = "event"
+)
+
+// EventConfig holds metadata added to every event sent to the HEC event endpoint.
+type EventConfig struct {
+	Index  string            // Destination index (empty = token default)
+	Source string            // Event source
+	Fields map[string]string // Indexed fields added to every event
+}
+
+// hecEvent is the HEC event endpoint envelope.
+type hecEvent struct {
+	Time       float64           `json:"time,omitempty"`
+	Host       string            `json:"host,omitempty"`
+	Source     string            `json:"source,omitempty"`
+	SourceType string            `json:"sourcetype,omitempty"`
+	Index      string            `json:"index,omitempty"`
+	Event      json.RawMessage   `json:"event"`
+	Fields     map[string]string `json:"fields,omitempty"`
+}
+
+// logTimestampLayouts are the accepted formats of the ZPA LogTimestamp field.
+// LSS emits ANSI C format in UTC (e.g. "Fri May 31 17:35:42 2019").
+var logTimestampLayouts = []string{time.ANSIC, time.RFC3339Nano}
+
+// appendEvents wraps each newline-separated line of data in an event envelope and appends it to buf.
+// Events are concatenated, as the event endpoint expects for batches. Blank lines are skipped.
+func appendEvents(buf *bytes.Buffer, data []byte, host, sourceType string, ev EventConfig) error {
+	enc := json.NewEncoder(buf)
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		event := hecEvent{
+			Time:       eventTime(line),
+			Host:       host,
+			Source:     ev.Source,
+			SourceType: sourceType,
+			Index:      ev.Index,
+			Fields:     ev.Fields,
+		}
+		if json.Valid(line) {
+			event.Event = line
+		} else {
+			// Lines from the DLQ or older storage may not be JSON; send them as a string
+			quoted, err := json.Marshal(string(line))
+			if err != nil {
+				return err
+			}
+			event.Event = quoted
+		}
+		if err := enc.Encode(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eventTime returns the ZPA LogTimestamp of a line as epoch seconds, or 0 if absent or unparseable.
+// A zero time is omitted so HEC uses the time the event was received.
+func eventTime(line []byte) float64 {
+	var fields struct {
+		LogTimestamp string `json:"LogTimestamp"`
+	}
+	if err := json.Unmarshal(line, &fields); err != nil || fields.LogTimestamp == "" {
+		return 0
+	}
+	for _, layout := range logTimestampLayouts {
+		if t, err := time.Parse(layout, strings.TrimSpace(fields.LogTimestamp)); err == nil {
+			return float64(t.UnixMilli()) / 1000
+		}
+	}
+	return 0
+}
+
+// eventURL returns the event endpoint URL for a configured HEC URL.
+// A raw endpoint path is replaced so the same hec_url works in both modes.
+func eventURL(url string) string {
+	return strings.Replace(url, "/services/collector/raw", "/services/collector/event", 1)
+}
@@ -0,0 +1,147 @@
+package forwarder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func decodeEvents(t *testing.T, body []byte) []map[string]any {
+	t.Helper()
+	var events []map[string]any
+	dec := json.NewDecoder(bytes.NewReader(body))
+	for dec.More() {
+		var ev map[string]any
+		if err := dec.Decode(&ev); err != nil {
+			t.Fatalf("failed to decode event: %v", err)
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+func TestAppendEvents_Envelope(t *testing.T) {
+	var buf bytes.Buffer
+	line := []byte(`{"LogTimestamp":"Fri May 31 17:35:42 2019","Customer":"Acme"}`)
+	ev := EventConfig{Index: "zpa", Source: "user-activity", Fields: map[string]string{"env": "prod"}}
+
+	if err := appendEvents(&buf, line, "10.0.0.5", "zpa:user:activity", ev); err != nil {
+		t.Fatalf("appendEvents() error = %v", err)
+	}
+
+	events := decodeEvents(t, buf.Bytes())
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	got := events[0]
+	if got["time"] != float64(1559324142) {
+		t.Errorf("time = %v, want 1559324142", got["time"])
+	}
+	if got["host"] != "10.0.0.5" || got["source"] != "user-activity" || got["sourcetype"] != "zpa:user:activity" || got["index"] != "zpa" {
+		t.Errorf("unexpected metadata: %v", got)
+	}
+	if event, ok := got["event"].(map[string]any); !ok || event["Customer"] != "Acme" {
+		t.Errorf("event = %v, want the original JSON object", got["event"])
+	}
+	if fields, ok := got["fields"].(map[string]any); !ok || fields["env"] != "prod" {
+		t.Errorf("fields = %v, want env=prod", got["fields"])
+	}
+}
+
+func TestAppendEvents_OmitsMissingMetadata(t *testing.T) {
+	var buf bytes.Buffer
+	data := []byte("{\"LogTimestamp\":\"not a time\"}\n\nnot json")
+
+	if err := appendEvents(&buf, data, "", "", EventConfig{}); err != nil {
+		t.Fatalf("appendEvents() error = %v", err)
+	}
+
+	events := decodeEvents(t, buf.Bytes())
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2 (blank line skipped)", len(events))
+	}
+	for _, key := range []string{"time", "host", "index", "fields"} {
+		if _, ok := events[0][key]; ok {
+			t.Errorf("event should omit %q when not set: %v", key, events[0])
+		}
+	}
+	if events[1]["event"] != "not json" {
+		t.Errorf("non-JSON line should be sent as a string, got %v", events[1]["event"])
+	}
+}
+
+func TestEventURL(t *testing.T) {
+	tests := map[string]string{
+		"https://splunk:8088/services/collector/raw":   "https://splunk:8088/services/collector/event",
+		"https://splunk:8088/services/collector/event": "https://splunk:8088/services/collector/event",
+		"https://splunk:8088/services/collector":       "https://splunk:8088/services/collector",
+	}
+	for in, want := range tests {
+		if got := eventURL(in); got != want {
+			t.Errorf("eventURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestForward_EventEndpoint(t *testing.T) {
+	var mu sync.Mutex
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/services/collector/event" {
+			t.Errorf("path = %s, want /services/collector/event", r.URL.Path)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("content type = %q, want application/json", ct)
+		}
+		if st := r.URL.Query().Get("sourcetype"); st != "" {
+			t.Errorf("sourcetype query = %q, want it in the envelope instead", st)
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	hec := New(Config{
+		URL:        server.URL + "/services/collector/raw",
+		Token:      "test-token",
+		SourceType: "zpa:user:activity",
+		Endpoint:   EndpointEvent,
+		Event:      EventConfig{Source: "user-activity"},
+		Batch:      BatchConfig{Enabled: true, MaxSize: 2, MaxBytes: 1 << 20, FlushInterval: time.Minute},
+	})
+
+	if err := hec.ForwardFrom("conn-1", "10.0.0.1", []byte(`{"n":1}`)); err != nil {
+		t.Fatalf("ForwardFrom() error = %v", err)
+	}
+	if err := hec.ForwardFrom("conn-2", "10.0.0.2", []byte(`{"n":2}`)); err != nil {
+		t.Fatalf("ForwardFrom() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := hec.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(bodies) != 1 {
+		t.Fatalf("got %d requests, want one batch", len(bodies))
+	}
+	events := decodeEvents(t, bodies[0])
+	if len(events) != 2 || events[0]["host"] != "10.0.0.1" || events[1]["host"] != "10.0.0.2" {
+		t.Errorf("events = %v, want one per line with its own host", events)
+	}
+	if !strings.Contains(string(bodies[0]), `"source":"user-activity"`) {
+		t.Errorf("body should carry the source: %s", bodies[0])
+	}
+}
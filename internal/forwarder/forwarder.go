@@ -8,19 +8,25 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"net"
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/scottbrown/relay/internal/circuitbreaker"
 	"github.com/scottbrown/relay/internal/dlq"
 	"github.com/scottbrown/relay/internal/metrics"
+	"github.com/scottbrown/relay/internal/redact"
 )
 
+// DefaultClientTimeout is the HTTP client timeout for HEC requests when none is configured.
+const DefaultClientTimeout = 15 * time.Second
+
 // BatchConfig holds configuration for batching multiple log lines before forwarding.
 // When enabled, logs are accumulated and sent together to reduce network overhead.
 type BatchConfig struct {
@@ -51,21 +57,28 @@ type TransportConfig struct {
 
 // Config holds configuration for the Splunk HEC forwarder.
 type Config struct {
+	Name           string // Target name, used to label metrics (default: "default")
+	Listener       string // Listener name, used to label metrics
 	URL            string
 	Token          string
 	SourceType     string
 	UseGzip        bool
-	ClientTimeout  time.Duration   // HTTP client timeout for HEC requests
-	DLQ            *dlq.Writer     // Optional dead letter queue for failed forwards
-	Transport      TransportConfig // HTTP transport configuration for connection pooling
+	Endpoint       string           // HEC endpoint format: "raw" (default) or "event"
+	Event          EventConfig      // Event metadata (event endpoint only)
+	ClientTimeout  time.Duration    // HTTP client timeout for HEC requests
+	DLQ            *dlq.Writer      // Optional dead letter queue for failed forwards
+	Redactor       *redact.Redactor // Optional redaction applied to each line before it is sent or dead-lettered
+	Transport      TransportConfig  // HTTP transport configuration for connection pooling
 	Batch          BatchConfig
 	CircuitBreaker circuitbreaker.Config
 	Retry          RetryConfig
+	Ack            AckConfig // Indexer acknowledgement (useACK)
 }
 
 // batch holds the current batch state
 type batch struct {
 	lines [][]byte
+	hosts []string // Client host of each line (event endpoint only)
 	size  int
 	timer *time.Timer
 }
@@ -79,6 +92,10 @@ type HEC struct {
 	configMu       sync.RWMutex // Protects reloadable config fields
 	client         *http.Client
 	circuitBreaker *circuitbreaker.CircuitBreaker
+	channel        string        // HEC request channel (indexer acknowledgement only)
+	lastSuccess    atomic.Int64  // Time of the last successful request (Unix nanoseconds)
+	paused         atomic.Bool   // Set by Pause; nothing is sent until Resume
+	undelivered    atomic.Uint64 // Batched lines that reached neither HEC nor the DLQ
 
 	// Batch state (only used when batch.Enabled is true)
 	mu       sync.Mutex
@@ -143,7 +160,7 @@ func New(config Config) *HEC {
 	// Use configured timeout, default to 15 seconds if not set
 	clientTimeout := config.ClientTimeout
 	if clientTimeout == 0 {
-		clientTimeout = 15 * time.Second
+		clientTimeout = DefaultClientTimeout
 	}
 
 	h := &HEC{
@@ -151,6 +168,9 @@ func New(config Config) *HEC {
 		client:         newHTTPClient(clientTimeout, config.Transport),
 		circuitBreaker: circuitbreaker.New(config.CircuitBreaker),
 	}
+	if config.Ack.Enabled {
+		h.channel = newChannel()
+	}
 
 	// Initialize batch mode if enabled
 	if config.Batch.Enabled {
@@ -173,32 +193,46 @@ func New(config Config) *HEC {
 // If HEC URL or token is empty, this method returns nil (forwarding disabled).
 // The connID parameter is used for logging and correlation.
 func (h *HEC) Forward(connID string, data []byte) error {
+	return h.ForwardFrom(connID, "", data)
+}
+
+// ForwardFrom is Forward with the host the data was received from.
+// With the event endpoint, host is sent as each event's host field; the raw endpoint ignores it.
+func (h *HEC) ForwardFrom(connID, host string, data []byte) error {
 	h.configMu.RLock()
 	url := h.config.URL
 	token := h.config.Token
 	batchEnabled := h.config.Batch.Enabled
+	redactor := h.config.Redactor
 	h.configMu.RUnlock()
 
 	if url == "" || token == "" {
 		return nil // HEC forwarding disabled
 	}
 
+	// Redact before batching, so the DLQ receives lines as they were sent
+	data = redactor.Apply(data)
+
 	// If batching is enabled, add to batch
 	if batchEnabled {
-		return h.addToBatch(data)
+		return h.addToBatch(data, host)
 	}
 
 	// Otherwise send immediately
 	slog.Debug("forwarding to HEC", "conn_id", connID, "hec_url", url)
 
-	err := h.circuitBreaker.Call(func() error {
-		return h.sendWithRetry(connID, data)
-	})
+	body, err := h.encodeBody([][]byte{data}, []string{host})
+	if err != nil {
+		return err
+	}
+	err = h.send(connID, body)
 
 	// Write to DLQ if forwarding failed and DLQ is configured
 	if err != nil && h.config.DLQ != nil {
 		if dlqErr := h.config.DLQ.Write(connID, data, err); dlqErr != nil {
 			slog.Error("failed to write to DLQ", "conn_id", connID, "error", dlqErr)
+		} else {
+			err = fmt.Errorf("%w (%w)", err, ErrDeadLettered)
 		}
 	}
 
@@ -273,6 +307,41 @@ func (h *HEC) getHealthURL() string {
 	return baseURL + "/services/collector/health"
 }
 
+// encodeBody builds the request body for lines received from the matching hosts.
+// The DLQ always receives the original lines so replays are not wrapped twice.
+func (h *HEC) encodeBody(lines [][]byte, hosts []string) ([]byte, error) {
+	h.configMu.RLock()
+	endpoint := h.config.Endpoint
+	sourceType := h.config.SourceType
+	h.configMu.RUnlock()
+
+	if endpoint != EndpointEvent {
+		if len(lines) == 1 {
+			return lines[0], nil
+		}
+		return bytes.Join(lines, []byte("\n")), nil
+	}
+
+	var buf bytes.Buffer
+	for i, line := range lines {
+		if err := appendEvents(&buf, line, hosts[i], sourceType, h.config.Event); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// send sends an encoded body through the circuit breaker.
+// While paused it fails with ErrPaused without counting against the breaker.
+func (h *HEC) send(connID string, body []byte) error {
+	if h.paused.Load() {
+		return ErrPaused
+	}
+	return h.circuitBreaker.Call(func() error {
+		return h.sendWithRetry(connID, body)
+	})
+}
+
 func (h *HEC) sendWithRetry(connID string, data []byte) error {
 	// Read config with read lock
 	h.configMu.RLock()
@@ -281,8 +350,18 @@ func (h *HEC) sendWithRetry(connID string, data []byte) error {
 	token := h.config.Token
 	sourceType := h.config.SourceType
 	retryConfig := h.config.Retry
+	eventMode := h.config.Endpoint == EndpointEvent
+	ackConfig := h.config.Ack
 	h.configMu.RUnlock()
 
+	// The event endpoint takes the sourcetype from each event envelope
+	contentType := "text/plain"
+	if eventMode {
+		url = eventURL(url)
+		sourceType = ""
+		contentType = "application/json"
+	}
+
 	// Pre-compress data if gzip is enabled
 	var payloadData []byte
 	var contentEnc string
@@ -317,11 +396,14 @@ func (h *HEC) sendWithRetry(connID string, data []byte) error {
 		}
 
 		req.Header.Set("Authorization", "Splunk "+token)
-		req.Header.Set("Content-Type", "text/plain")
+		req.Header.Set("Content-Type", contentType)
 		req.Header.Set("X-Correlation-ID", connID)
 		if contentEnc != "" {
 			req.Header.Set("Content-Encoding", contentEnc)
 		}
+		if ackConfig.Enabled {
+			req.Header.Set("X-Splunk-Request-Channel", h.channel)
+		}
 
 		// Add sourcetype to query parameters if specified
 		if sourceType != "" {
@@ -330,15 +412,27 @@ func (h *HEC) sendWithRetry(connID string, data []byte) error {
 			req.URL.RawQuery = q.Encode()
 		}
 
+		start := time.Now()
 		resp, err := h.client.Do(req)
+		metrics.HecRequestDuration.Observe(time.Since(start).Seconds(), h.config.Listener, h.targetName())
 		if err == nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			if err := resp.Body.Close(); err != nil {
+			status := resp.StatusCode
+			if ackConfig.Enabled {
+				// A 2xx only means HEC received the data; it is delivered once indexed
+				err = h.awaitAck(connID, token, resp, ackConfig)
+				resp = nil
+			} else if err := resp.Body.Close(); err != nil {
 				// Log but don't fail on close error in success path
 			}
-			metrics.HecForwards.Add("success", 1)
-			metrics.HecBytesForwarded.Add(int64(len(data)))
-			slog.Debug("HEC forward succeeded", "conn_id", connID, "status", resp.StatusCode)
-			return nil
+			if err == nil {
+				h.lastSuccess.Store(time.Now().UnixNano())
+				metrics.TargetHecForwards.Add(1, h.config.Listener, h.targetName(), "success")
+				metrics.TargetHecBytesForwarded.Add(int64(len(data)), h.config.Listener, h.targetName())
+				slog.Debug("HEC forward succeeded", "conn_id", connID, "status", status)
+				return nil
+			}
+			// Not acknowledged in time: send the data again
+			slog.Warn("HEC request not acknowledged", "conn_id", connID, "attempt", i+1, "error", err)
 		}
 		if resp != nil {
 			// Drain and close response body to enable connection reuse
@@ -348,7 +442,7 @@ func (h *HEC) sendWithRetry(connID string, data []byte) error {
 
 		// Track retry attempts (don't count initial attempt)
 		if i > 0 {
-			metrics.HecRetries.Add(1)
+			metrics.TargetHecRetries.Add(1, h.config.Listener, h.targetName())
 		}
 
 		// Calculate backoff duration with exponential growth
@@ -358,10 +452,18 @@ func (h *HEC) sendWithRetry(connID string, data []byte) error {
 		}
 	}
 
-	metrics.HecForwards.Add("failure", 1)
+	metrics.TargetHecForwards.Add(1, h.config.Listener, h.targetName(), "failure")
 	return errors.New("hec send failed after retries")
 }
 
+// targetName returns the name used to label this forwarder's metrics.
+func (h *HEC) targetName() string {
+	if h.config.Name == "" {
+		return "default"
+	}
+	return h.config.Name
+}
+
 // calculateBackoff computes the backoff duration for a retry attempt using exponential backoff.
 // The backoff is capped at the configured maximum.
 func (h *HEC) calculateBackoff(attemptNumber int, cfg RetryConfig) time.Duration {
@@ -395,7 +497,7 @@ func (h *HEC) calculateBackoff(attemptNumber int, cfg RetryConfig) time.Duration
 }
 
 // addToBatch adds data to the current batch and triggers flush if needed
-func (h *HEC) addToBatch(data []byte) error {
+func (h *HEC) addToBatch(data []byte, host string) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -405,6 +507,7 @@ func (h *HEC) addToBatch(data []byte) error {
 
 	// Add to batch
 	h.batch.lines = append(h.batch.lines, dataCopy)
+	h.batch.hosts = append(h.batch.hosts, host)
 	h.batch.size += len(dataCopy)
 
 	// Check if we should flush
@@ -466,11 +569,13 @@ func (h *HEC) doFlush() {
 
 	// Collect lines to send
 	lines := h.batch.lines
+	hosts := h.batch.hosts
 	batchSize := len(lines)
 	batchBytes := h.batch.size
 
 	// Reset batch
 	h.batch.lines = make([][]byte, 0, h.config.Batch.MaxSize)
+	h.batch.hosts = nil
 	h.batch.size = 0
 	if h.batch.timer != nil {
 		h.batch.timer.Stop()
@@ -479,13 +584,16 @@ func (h *HEC) doFlush() {
 
 	h.mu.Unlock()
 
+	metrics.HecBatchLines.Observe(float64(batchSize), h.config.Listener, h.targetName())
+
 	// Combine lines with newlines
 	payload := bytes.Join(lines, []byte("\n"))
 
 	// Send batch
-	err := h.circuitBreaker.Call(func() error {
-		return h.sendWithRetry("batch", payload)
-	})
+	body, err := h.encodeBody(lines, hosts)
+	if err == nil {
+		err = h.send("batch", body)
+	}
 
 	if err != nil {
 		slog.Error("batch forward failed",
@@ -494,11 +602,17 @@ func (h *HEC) doFlush() {
 			"error", err)
 
 		// Write to DLQ if configured
+		delivered := false
 		if h.config.DLQ != nil {
 			if dlqErr := h.config.DLQ.Write("batch", payload, err); dlqErr != nil {
 				slog.Error("failed to write batch to DLQ", "error", dlqErr)
+			} else {
+				delivered = true
 			}
 		}
+		if !delivered {
+			h.undelivered.Add(uint64(batchSize)) // #nosec G115 -- a slice length is never negative
+		}
 	} else {
 		slog.Debug("batch forwarded",
 			"lines", batchSize,
@@ -506,6 +620,21 @@ func (h *HEC) doFlush() {
 	}
 }
 
+// Flush sends the current batch synchronously.
+// Failed batches are written to the DLQ if configured. If batching is disabled, this is a no-op.
+func (h *HEC) Flush() {
+	if !h.config.Batch.Enabled {
+		return
+	}
+	h.doFlush()
+}
+
+// Undelivered returns the number of batched lines that failed to reach HEC and were not
+// written to the DLQ.
+func (h *HEC) Undelivered() uint64 {
+	return h.undelivered.Load()
+}
+
 // Shutdown gracefully shuts down the forwarder, flushing any remaining batched data.
 // If batching is disabled, this method returns immediately.
 // The provided context controls the shutdown timeout.
@@ -534,6 +663,77 @@ func (h *HEC) Shutdown(ctx context.Context) error {
 	}
 }
 
+// CircuitState returns the current state of the forwarder's circuit breaker.
+func (h *HEC) CircuitState() circuitbreaker.State {
+	return h.circuitBreaker.GetState()
+}
+
+// Targets returns the state of the forwarder's single target.
+func (h *HEC) Targets() []TargetStatus {
+	status := TargetStatus{
+		Name:         h.targetName(),
+		CircuitState: h.CircuitState(),
+		Paused:       h.paused.Load(),
+	}
+	if ns := h.lastSuccess.Load(); ns != 0 {
+		status.LastSuccess = time.Unix(0, ns)
+	}
+	return []TargetStatus{status}
+}
+
+// Pause stops sending to HEC until Resume is called. target must be empty or this forwarder's name.
+func (h *HEC) Pause(target string) error {
+	if err := h.checkTarget(target); err != nil {
+		return err
+	}
+	if !h.paused.Swap(true) {
+		slog.Info("HEC forwarding paused", "listener", h.config.Listener, "target", h.targetName())
+	}
+	return nil
+}
+
+// Resume restarts sending to HEC after Pause. target must be empty or this forwarder's name.
+func (h *HEC) Resume(target string) error {
+	if err := h.checkTarget(target); err != nil {
+		return err
+	}
+	if h.paused.Swap(false) {
+		slog.Info("HEC forwarding resumed", "listener", h.config.Listener, "target", h.targetName())
+	}
+	return nil
+}
+
+// Paused reports whether forwarding is paused.
+func (h *HEC) Paused() bool {
+	return h.paused.Load()
+}
+
+// ResetCircuit closes the circuit breaker so the next forward is sent immediately.
+// target must be empty or this forwarder's name.
+func (h *HEC) ResetCircuit(target string) error {
+	if err := h.checkTarget(target); err != nil {
+		return err
+	}
+	h.circuitBreaker.Reset()
+	slog.Info("circuit breaker reset", "listener", h.config.Listener, "target", h.targetName())
+	return nil
+}
+
+// setRedactor sets the redaction applied to each line before it is sent.
+func (h *HEC) setRedactor(r *redact.Redactor) {
+	h.configMu.Lock()
+	defer h.configMu.Unlock()
+	h.config.Redactor = r
+}
+
+// checkTarget returns an error unless target is empty or names this forwarder.
+func (h *HEC) checkTarget(target string) error {
+	if target != "" && target != h.targetName() {
+		return fmt.Errorf("unknown HEC target %q", target)
+	}
+	return nil
+}
+
 // UpdateConfig updates the reloadable configuration parameters in a thread-safe manner.
 // Only safe parameters (token, sourcetype, gzip) are updated.
 // Parameters that require restart (URL, batching, circuit breaker) are not affected.
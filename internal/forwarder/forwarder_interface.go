@@ -3,6 +3,18 @@ package forwarder
 
 import (
 	"context"
+	"errors"
+	"time"
+
+	"github.com/scottbrown/relay/internal/circuitbreaker"
+)
+
+var (
+	// ErrPaused is returned when forwarding to a target has been paused by an operator.
+	ErrPaused = errors.New("HEC forwarding is paused")
+	// ErrDeadLettered is wrapped into the error of a forward that failed but whose data was
+	// written to the DLQ, so callers can tell the data is safe.
+	ErrDeadLettered = errors.New("written to DLQ")
 )
 
 // ReloadableConfig holds configuration parameters that can be safely reloaded at runtime.
@@ -11,6 +23,10 @@ type ReloadableConfig struct {
 	Token      string
 	SourceType string
 	UseGzip    bool
+
+	// Targets holds the settings of each target of a multi-target forwarder, by target name.
+	// Targets not listed use the settings above.
+	Targets map[string]ReloadableConfig
 }
 
 // Forwarder defines the interface for forwarding log data to one or more HEC endpoints.
@@ -35,3 +51,66 @@ type Forwarder interface {
 	// Parameters that require restart (URL, batching, circuit breaker) are not affected.
 	UpdateConfig(cfg ReloadableConfig)
 }
+
+// HostForwarder is implemented by forwarders that can record which client sent the data.
+// With the HEC event endpoint the host becomes each event's host field.
+type HostForwarder interface {
+	ForwardFrom(connID, host string, data []byte) error
+}
+
+// TargetForwarder is implemented by forwarders that can send data to one named HEC target,
+// whatever the routing mode. Filter rules use it to route lines to a particular target.
+type TargetForwarder interface {
+	ForwardTo(target, connID, host string, data []byte) error
+}
+
+// CircuitStater is implemented by forwarders that expose their circuit breaker state.
+// The DLQ drain worker uses it to wait until HEC has recovered.
+type CircuitStater interface {
+	CircuitState() circuitbreaker.State
+}
+
+// TargetStatus is the state of one HEC target, for health reporting.
+type TargetStatus struct {
+	Name         string
+	CircuitState circuitbreaker.State
+	LastSuccess  time.Time // Zero if no request has succeeded yet
+	Paused       bool
+}
+
+// TargetReporter is implemented by forwarders that report the state of each HEC target.
+// The HTTP health endpoints use it to show circuit breaker state and last success per target.
+type TargetReporter interface {
+	Targets() []TargetStatus
+}
+
+// Pauser is implemented by forwarders whose forwarding can be paused at runtime,
+// for example while Splunk is in maintenance. A paused target is not sent anything:
+// forwards fail with ErrPaused (and go to the DLQ if configured) while storage continues.
+// An empty target name applies to every target.
+type Pauser interface {
+	Pause(target string) error
+	Resume(target string) error
+	// Paused reports whether every target is paused.
+	Paused() bool
+}
+
+// CircuitResetter is implemented by forwarders whose circuit breakers can be closed manually.
+// An empty target name applies to every target.
+type CircuitResetter interface {
+	ResetCircuit(target string) error
+}
+
+// Flusher is implemented by forwarders that buffer data before sending.
+// Flush sends any buffered data and returns once it has reached HEC or the DLQ.
+type Flusher interface {
+	Flush()
+}
+
+// UndeliveredCounter is implemented by forwarders that send batches in the background.
+// Undelivered returns the number of batched lines that have failed to reach HEC and were not
+// written to the DLQ; Forward cannot report these because they are sent after it returns.
+// The count only grows, so a caller compares it before and after a Flush.
+type UndeliveredCounter interface {
+	Undelivered() uint64
+}
@@ -4,13 +4,21 @@ import (
 	"compress/gzip"
 	"context"
 	"crypto/tls"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/scottbrown/relay/internal/circuitbreaker"
+	"github.com/scottbrown/relay/internal/dlq"
+	"github.com/scottbrown/relay/internal/redact"
 )
 
 func TestNew(t *testing.T) {
@@ -26,7 +34,7 @@ func TestNew(t *testing.T) {
 		t.Fatal("New should return non-nil HEC")
 	}
 
-	if hec.config != config {
+	if !reflect.DeepEqual(hec.config, config) {
 		t.Error("config should be stored")
 	}
 
@@ -997,3 +1005,153 @@ func TestNewHTTPClient_CustomConfig(t *testing.T) {
 		t.Errorf("expected IdleConnTimeout=60s, got %v", transport.IdleConnTimeout)
 	}
 }
+
+func TestForward_Paused(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	dlqWriter, err := dlq.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("dlq.New() error = %v", err)
+	}
+	defer dlqWriter.Close()
+
+	hec := New(Config{
+		Name:           "primary",
+		URL:            server.URL,
+		Token:          "test-token",
+		DLQ:            dlqWriter,
+		CircuitBreaker: circuitbreaker.Config{FailureThreshold: 1, SuccessThreshold: 1, Timeout: time.Minute},
+	})
+
+	if err := hec.Pause("secondary"); err == nil {
+		t.Error("Pause() should reject an unknown target")
+	}
+	if err := hec.Pause("primary"); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if !hec.Paused() {
+		t.Fatal("Paused() = false after Pause()")
+	}
+
+	err = hec.Forward("conn-1", []byte(`{"n":1}`))
+	if !errors.Is(err, ErrPaused) {
+		t.Fatalf("Forward() error = %v, want ErrPaused", err)
+	}
+	if !errors.Is(err, ErrDeadLettered) {
+		t.Errorf("Forward() error = %v, want it marked as written to the DLQ", err)
+	}
+	if got := requests.Load(); got != 0 {
+		t.Errorf("got %d requests while paused, want 0", got)
+	}
+	content, err := os.ReadFile(dlqWriter.CurrentFile())
+	if err != nil {
+		t.Fatalf("failed to read DLQ file: %v", err)
+	}
+	if !strings.Contains(string(content), `{\"n\":1}`) {
+		t.Errorf("DLQ does not contain the paused data: %s", content)
+	}
+
+	// Pausing is not a HEC failure and must not open the circuit
+	if state := hec.Targets()[0]; state.CircuitState != circuitbreaker.StateClosed || !state.Paused {
+		t.Errorf("unexpected target status while paused: %+v", state)
+	}
+
+	if err := hec.Resume(""); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	if err := hec.Forward("conn-1", []byte(`{"n":2}`)); err != nil {
+		t.Fatalf("Forward() after Resume() error = %v", err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("got %d requests after resume, want 1", got)
+	}
+}
+
+func TestResetCircuit(t *testing.T) {
+	var fail atomic.Bool
+	fail.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	hec := New(Config{
+		URL:            server.URL,
+		Token:          "test-token",
+		Retry:          RetryConfig{MaxAttempts: 1},
+		CircuitBreaker: circuitbreaker.Config{FailureThreshold: 1, SuccessThreshold: 1, Timeout: time.Hour},
+	})
+
+	if err := hec.Forward("conn-1", []byte("data")); err == nil {
+		t.Fatal("Forward() should fail")
+	}
+	if state := hec.Targets()[0].CircuitState; state != circuitbreaker.StateOpen {
+		t.Fatalf("circuit state = %s, want open", state)
+	}
+
+	fail.Store(false)
+	if err := hec.ResetCircuit(""); err != nil {
+		t.Fatalf("ResetCircuit() error = %v", err)
+	}
+	if err := hec.Forward("conn-1", []byte("data")); err != nil {
+		t.Errorf("Forward() after ResetCircuit() error = %v", err)
+	}
+}
+
+func TestForward_Redactor(t *testing.T) {
+	var body atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body.Store(string(data))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	dlqWriter, err := dlq.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("dlq.New() error = %v", err)
+	}
+	defer dlqWriter.Close()
+
+	redactor, err := redact.New([]redact.Rule{{Path: "ClientPublicIP", Action: redact.Drop}}, nil)
+	if err != nil {
+		t.Fatalf("redact.New() error = %v", err)
+	}
+	hec := New(Config{
+		Name:           "primary",
+		URL:            server.URL,
+		Token:          "test-token",
+		DLQ:            dlqWriter,
+		Redactor:       redactor,
+		CircuitBreaker: circuitbreaker.DefaultConfig(),
+	})
+
+	if err := hec.Forward("conn-1", []byte(`{"User":"a","ClientPublicIP":"203.0.113.7"}`)); err != nil {
+		t.Fatalf("Forward() error = %v", err)
+	}
+	if got := body.Load(); got != `{"User":"a"}` {
+		t.Errorf("HEC received %v, want the redacted line", got)
+	}
+
+	// Dead-lettered lines are redacted as they would have been sent
+	_ = hec.Pause("")
+	if err := hec.Forward("conn-2", []byte(`{"User":"b","ClientPublicIP":"203.0.113.8"}`)); !errors.Is(err, ErrPaused) {
+		t.Fatalf("Forward() error = %v, want ErrPaused", err)
+	}
+	content, err := os.ReadFile(dlqWriter.CurrentFile())
+	if err != nil {
+		t.Fatalf("failed to read DLQ file: %v", err)
+	}
+	if strings.Contains(string(content), "203.0.113.8") || !strings.Contains(string(content), `{\"User\":\"b\"}`) {
+		t.Errorf("DLQ should contain the redacted line, got %s", content)
+	}
+}
@@ -1,16 +1,25 @@
 package forwarder
 
 import (
+	"bytes"
+	"cmp"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"log/slog"
+	"math"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/scottbrown/relay/internal/circuitbreaker"
 	"github.com/scottbrown/relay/internal/config"
+	"github.com/scottbrown/relay/internal/metrics"
+	"github.com/scottbrown/relay/internal/redact"
 )
 
 // MultiHEC manages multiple HEC forwarders with configurable routing.
@@ -18,35 +27,68 @@ import (
 // - All (broadcast): sends to all targets
 // - Primary-Failover: tries primary first, fails over to secondary
 // - Round-Robin: distributes logs across targets
+// - Route-By-Field: picks targets by the value of a field, failing over within each route
+// - Weighted: distributes logs across targets in proportion to their weights
+// - Hash: sends logs with the same value of a field to the same target
 type MultiHEC struct {
+	listener    string
 	targets     []*HEC
 	targetNames []string
 	mode        config.RoutingMode
 	mu          sync.RWMutex
-	rrCounter   uint64 // atomic counter for round-robin
+	rrCounter   uint64       // atomic counter for round-robin and weighted
+	fieldRoutes *fieldRoutes // Routes for route-by-field
+	weights     []int        // Weight of each target (weighted, hash)
+	schedule    []int        // One cycle of weighted round-robin, as target indexes
+	hashPath    []string     // Path of the field to hash (hash)
+}
+
+// fieldRoutes maps values of a field to the targets that receive them, as indexes in
+// failover order.
+type fieldRoutes struct {
+	path     []string
+	routes   map[string][]int
+	defaults []int
 }
 
 // NewMulti creates a new multi-target HEC forwarder with the given targets and routing mode.
-// Each target is initialized as a separate HEC forwarder instance.
-func NewMulti(targets []config.HECTarget, mode config.RoutingMode) (*MultiHEC, error) {
+// Each target is initialized as a separate HEC forwarder instance, recording metrics under
+// the listener and its target name.
+func NewMulti(listener string, targets []config.HECTarget, mode config.RoutingMode) (*MultiHEC, error) {
+	return NewMultiWithRouting(listener, targets, config.RoutingConfig{Mode: mode})
+}
+
+// NewMultiWithRouting is NewMulti with the whole routing configuration, which modes such as
+// route-by-field and hash need.
+func NewMultiWithRouting(listener string, targets []config.HECTarget, routing config.RoutingConfig) (*MultiHEC, error) {
 	if len(targets) == 0 {
 		return nil, errors.New("at least one HEC target is required")
 	}
 
 	// Default to "all" mode if not specified
+	mode := routing.Mode
 	if mode == "" {
 		mode = config.RoutingModeAll
 	}
 
 	hecInstances := make([]*HEC, 0, len(targets))
 	targetNames := make([]string, 0, len(targets))
+	weights := make([]int, 0, len(targets))
 
 	for _, target := range targets {
 		// Convert target config to HEC config
 		hecConfig := Config{
+			Name:       target.Name,
+			Listener:   listener,
 			URL:        target.HECURL,
 			Token:      target.HECToken,
 			SourceType: target.SourceType,
+			Endpoint:   target.Endpoint,
+			Event: EventConfig{
+				Index:  target.Index,
+				Source: target.Source,
+				Fields: target.Fields,
+			},
 		}
 
 		// Apply gzip setting
@@ -82,6 +124,29 @@ func NewMulti(targets []config.HECTarget, mode config.RoutingMode) (*MultiHEC, e
 		}
 		hecConfig.Batch = batchConfig
 
+		// Convert retry config
+		retryConfig := RetryConfig{
+			MaxAttempts:       5,
+			InitialBackoff:    250 * time.Millisecond,
+			BackoffMultiplier: 2.0,
+			MaxBackoff:        30 * time.Second,
+		}
+		if target.Retry != nil {
+			if target.Retry.MaxAttempts > 0 {
+				retryConfig.MaxAttempts = target.Retry.MaxAttempts
+			}
+			if target.Retry.InitialBackoffMS > 0 {
+				retryConfig.InitialBackoff = time.Duration(target.Retry.InitialBackoffMS) * time.Millisecond
+			}
+			if target.Retry.BackoffMultiplier > 0 {
+				retryConfig.BackoffMultiplier = target.Retry.BackoffMultiplier
+			}
+			if target.Retry.MaxBackoffSeconds > 0 {
+				retryConfig.MaxBackoff = time.Duration(target.Retry.MaxBackoffSeconds) * time.Second
+			}
+		}
+		hecConfig.Retry = retryConfig
+
 		// Convert circuit breaker config
 		cbConfig := circuitbreaker.DefaultConfig()
 		if target.CircuitBreaker != nil {
@@ -103,6 +168,24 @@ func NewMulti(targets []config.HECTarget, mode config.RoutingMode) (*MultiHEC, e
 		}
 		hecConfig.CircuitBreaker = cbConfig
 
+		// Convert indexer acknowledgement config
+		ackConfig := AckConfig{
+			Timeout:      60 * time.Second,
+			PollInterval: 1 * time.Second,
+		}
+		if target.Ack != nil {
+			if target.Ack.Enabled != nil {
+				ackConfig.Enabled = *target.Ack.Enabled
+			}
+			if target.Ack.Timeout > 0 {
+				ackConfig.Timeout = time.Duration(target.Ack.Timeout) * time.Second
+			}
+			if target.Ack.PollInterval > 0 {
+				ackConfig.PollInterval = time.Duration(target.Ack.PollInterval) * time.Second
+			}
+		}
+		hecConfig.Ack = ackConfig
+
 		// Convert transport config
 		transportConfig := TransportConfig{
 			MaxIdleConns:        100,
@@ -129,39 +212,171 @@ func NewMulti(targets []config.HECTarget, mode config.RoutingMode) (*MultiHEC, e
 		hec := New(hecConfig)
 		hecInstances = append(hecInstances, hec)
 		targetNames = append(targetNames, target.Name)
+
+		// A target without a weight takes an equal share
+		weight := target.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		weights = append(weights, weight)
 	}
 
-	return &MultiHEC{
+	m := &MultiHEC{
+		listener:    listener,
 		targets:     hecInstances,
 		targetNames: targetNames,
 		mode:        mode,
-	}, nil
+		weights:     weights,
+	}
+	switch mode {
+	case config.RoutingModeRouteByField:
+		routes, err := newFieldRoutes(routing, targetNames)
+		if err != nil {
+			return nil, err
+		}
+		m.fieldRoutes = routes
+	case config.RoutingModeWeighted:
+		m.schedule = weightedSchedule(weights)
+	case config.RoutingModeHash:
+		if routing.Field == "" {
+			return nil, fmt.Errorf("routing mode %s requires a field", config.RoutingModeHash)
+		}
+		m.hashPath = strings.Split(strings.TrimPrefix(routing.Field, "$."), ".")
+	}
+	return m, nil
+}
+
+// weightedSchedule returns one cycle of smooth weighted round-robin: each target appears in
+// proportion to its weight, spread as evenly as the weights allow.
+func weightedSchedule(weights []int) []int {
+	divisor := 0
+	for _, w := range weights {
+		divisor = gcd(divisor, w)
+	}
+	total := 0
+	for _, w := range weights {
+		total += w / divisor
+	}
+
+	current := make([]int, len(weights))
+	schedule := make([]int, 0, total)
+	for range total {
+		best := 0
+		for i, w := range weights {
+			current[i] += w / divisor
+			if current[i] > current[best] {
+				best = i
+			}
+		}
+		current[best] -= total
+		schedule = append(schedule, best)
+	}
+	return schedule
+}
+
+// gcd returns the greatest common divisor of a and b.
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// newFieldRoutes resolves the target names of route-by-field routing to indexes.
+func newFieldRoutes(routing config.RoutingConfig, targetNames []string) (*fieldRoutes, error) {
+	if routing.Field == "" || len(routing.Default) == 0 {
+		return nil, fmt.Errorf("routing mode %s requires a field and default targets", config.RoutingModeRouteByField)
+	}
+	indexes := func(names []string) ([]int, error) {
+		out := make([]int, 0, len(names))
+		for _, name := range names {
+			i := slices.Index(targetNames, name)
+			if i < 0 {
+				return nil, fmt.Errorf("unknown HEC target %q", name)
+			}
+			out = append(out, i)
+		}
+		return out, nil
+	}
+
+	r := &fieldRoutes{
+		path:   strings.Split(strings.TrimPrefix(routing.Field, "$."), "."),
+		routes: make(map[string][]int),
+	}
+	var err error
+	if r.defaults, err = indexes(routing.Default); err != nil {
+		return nil, err
+	}
+	for _, route := range routing.Routes {
+		targets, err := indexes(route.Targets)
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range route.Values {
+			r.routes[value] = targets
+		}
+	}
+	return r, nil
 }
 
 // Forward sends data to one or more HEC targets based on the configured routing mode.
 func (m *MultiHEC) Forward(connID string, data []byte) error {
+	return m.ForwardFrom(connID, "", data)
+}
+
+// ForwardFrom is Forward with the host the data was received from, passed on to each target.
+func (m *MultiHEC) ForwardFrom(connID, host string, data []byte) error {
 	switch m.mode {
 	case config.RoutingModeAll:
-		return m.forwardAll(connID, data)
+		return m.forwardAll(connID, host, data)
 	case config.RoutingModePrimaryFailover:
-		return m.forwardPrimaryFailover(connID, data)
+		return m.forwardPrimaryFailover(connID, host, data)
 	case config.RoutingModeRoundRobin:
-		return m.forwardRoundRobin(connID, data)
+		return m.forwardRoundRobin(connID, host, data)
+	case config.RoutingModeRouteByField:
+		return m.forwardByField(connID, host, data)
+	case config.RoutingModeWeighted:
+		return m.forwardWeighted(connID, host, data)
+	case config.RoutingModeHash:
+		return m.forwardHash(connID, host, data)
 	default:
 		return fmt.Errorf("unknown routing mode: %s", m.mode)
 	}
 }
 
+// ForwardTo sends data to the named target only, whatever the routing mode.
+func (m *MultiHEC) ForwardTo(target, connID, host string, data []byte) error {
+	for i, hec := range m.targets {
+		if m.targetNames[i] != target {
+			continue
+		}
+		if hec.Paused() {
+			return ErrPaused
+		}
+		if err := hec.ForwardFrom(connID, host, data); err != nil {
+			return fmt.Errorf("target %s: %w", target, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown HEC target %q", target)
+}
+
 // forwardAll sends data to all targets concurrently (broadcast mode)
-func (m *MultiHEC) forwardAll(connID string, data []byte) error {
+func (m *MultiHEC) forwardAll(connID, host string, data []byte) error {
 	var wg sync.WaitGroup
 	errCh := make(chan error, len(m.targets))
+	sent := 0
 
 	for i, target := range m.targets {
+		// A paused target misses broadcasts; they remain in local storage
+		if target.Paused() {
+			continue
+		}
+		sent++
 		wg.Add(1)
 		go func(hec *HEC, name string) {
 			defer wg.Done()
-			if err := hec.Forward(connID, data); err != nil {
+			if err := hec.ForwardFrom(connID, host, data); err != nil {
 				slog.Warn("HEC forward failed for target",
 					"target", name,
 					"conn_id", connID,
@@ -178,6 +393,10 @@ func (m *MultiHEC) forwardAll(connID string, data []byte) error {
 	wg.Wait()
 	close(errCh)
 
+	if sent == 0 {
+		return ErrPaused
+	}
+
 	// Collect all errors
 	var errs []error
 	for err := range errCh {
@@ -192,16 +411,88 @@ func (m *MultiHEC) forwardAll(connID string, data []byte) error {
 	return nil
 }
 
-// forwardPrimaryFailover tries primary target first, fails over to secondary targets on error
-func (m *MultiHEC) forwardPrimaryFailover(connID string, data []byte) error {
-	for i, target := range m.targets {
-		err := target.Forward(connID, data)
+// forwardPrimaryFailover tries primary target first, fails over to secondary targets on error.
+// Paused targets are skipped as if they had failed.
+func (m *MultiHEC) forwardPrimaryFailover(connID, host string, data []byte) error {
+	order := make([]int, len(m.targets))
+	for i := range order {
+		order[i] = i
+	}
+	return m.failover(connID, host, data, order)
+}
+
+// forwardByField sends data to the route for the value of the routing field, or to the default
+// route if the field is missing or no route has its value. Within a route each target fails
+// over to the next. Each line of a batch from tail mode or backfill is routed on its own.
+func (m *MultiHEC) forwardByField(connID, host string, data []byte) error {
+	return forwardLines(connID, host, data, m.fieldRoute, m.failover)
+}
+
+// fieldRoute returns the targets of the route for a line.
+func (m *MultiHEC) fieldRoute(line []byte) []int {
+	if value, ok := fieldValue(line, m.fieldRoutes.path); ok {
+		if targets, routed := m.fieldRoutes.routes[value]; routed {
+			return targets
+		}
+	}
+	return m.fieldRoutes.defaults
+}
+
+// forwardLines sends each line of newline-separated data to the targets route orders for it.
+// Lines with the same order are sent together with send, keeping their order, and the errors of
+// every group are returned joined. Data without a newline is sent as a single line.
+func forwardLines(connID, host string, data []byte, route func(line []byte) []int, send func(connID, host string, data []byte, order []int) error) error {
+	if bytes.IndexByte(bytes.TrimSpace(data), '\n') < 0 {
+		return send(connID, host, data, route(data))
+	}
+
+	type group struct {
+		order []int
+		lines [][]byte
+	}
+	var groups []*group
+	byOrder := make(map[string]*group)
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		order := route(line)
+		key := fmt.Sprint(order)
+		g, ok := byOrder[key]
+		if !ok {
+			g = &group{order: order}
+			byOrder[key] = g
+			groups = append(groups, g)
+		}
+		g.lines = append(g.lines, line)
+	}
+
+	var errs []error
+	for _, g := range groups {
+		if err := send(connID, host, bytes.Join(g.lines, []byte("\n")), g.order); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// failover tries the targets at the given indexes in order until one accepts the data.
+// Paused targets are skipped as if they had failed.
+func (m *MultiHEC) failover(connID, host string, data []byte, order []int) error {
+	attempted := false
+	for n, i := range order {
+		target := m.targets[i]
+		if target.Paused() {
+			continue
+		}
+		attempted = true
+		err := target.ForwardFrom(connID, host, data)
 		if err == nil {
-			if i > 0 {
+			if n > 0 {
 				slog.Info("failover successful",
 					"target", m.targetNames[i],
 					"conn_id", connID,
-					"attempt", i+1)
+					"attempt", n+1)
 			}
 			return nil
 		}
@@ -210,15 +501,19 @@ func (m *MultiHEC) forwardPrimaryFailover(connID string, data []byte) error {
 			"target", m.targetNames[i],
 			"conn_id", connID,
 			"error", err,
-			"attempt", i+1,
-			"remaining", len(m.targets)-i-1)
+			"attempt", n+1,
+			"remaining", len(order)-n-1)
 	}
 
-	return fmt.Errorf("all %d targets failed for primary-failover", len(m.targets))
+	if !attempted {
+		return ErrPaused
+	}
+	return fmt.Errorf("all %d targets failed for %s", len(order), m.mode)
 }
 
-// forwardRoundRobin distributes logs across targets in round-robin fashion
-func (m *MultiHEC) forwardRoundRobin(connID string, data []byte) error {
+// forwardRoundRobin distributes logs across targets in round-robin fashion.
+// A paused target's turn goes to the next target that is not paused.
+func (m *MultiHEC) forwardRoundRobin(connID, host string, data []byte) error {
 	// Atomically increment and get counter
 	count := atomic.AddUint64(&m.rrCounter, 1)
 	// Safe conversion: modulo ensures result fits in int since it's bounded by len(m.targets)
@@ -226,10 +521,17 @@ func (m *MultiHEC) forwardRoundRobin(connID string, data []byte) error {
 	// #nosec G115 -- Modulo operation guarantees result < numTargets, which is derived from len() (an int)
 	idx := int((count - 1) % numTargets)
 
+	for skipped := 0; m.targets[idx].Paused(); skipped++ {
+		if skipped == len(m.targets)-1 {
+			return ErrPaused
+		}
+		idx = (idx + 1) % len(m.targets)
+	}
+
 	target := m.targets[idx]
 	targetName := m.targetNames[idx]
 
-	err := target.Forward(connID, data)
+	err := target.ForwardFrom(connID, host, data)
 	if err != nil {
 		slog.Warn("HEC forward failed in round-robin",
 			"target", targetName,
@@ -244,6 +546,114 @@ func (m *MultiHEC) forwardRoundRobin(connID string, data []byte) error {
 	return nil
 }
 
+// forwardWeighted distributes logs across targets in proportion to their weights. When the
+// chosen target is unavailable, the line goes to the next target of the cycle, so the targets
+// that remain share its lines in roughly the proportion of their weights.
+func (m *MultiHEC) forwardWeighted(connID, host string, data []byte) error {
+	count := atomic.AddUint64(&m.rrCounter, 1)
+	// #nosec G115 -- Modulo operation guarantees result < len(m.schedule), which is an int
+	pos := int((count - 1) % uint64(len(m.schedule)))
+
+	order := make([]int, 0, len(m.targets))
+	for n := 0; n < len(m.schedule) && len(order) < len(m.targets); n++ {
+		if i := m.schedule[(pos+n)%len(m.schedule)]; !slices.Contains(order, i) {
+			order = append(order, i)
+		}
+	}
+	return m.forwardRanked(connID, host, data, order)
+}
+
+// forwardHash sends data to the target that ranks first for the value of the hash field, so
+// lines with the same value reach the same target. Lines without the field are ranked by
+// their content, which spreads them across the targets. Each line of a batch from tail mode or
+// backfill is hashed on its own.
+func (m *MultiHEC) forwardHash(connID, host string, data []byte) error {
+	return forwardLines(connID, host, data, m.hashRank, m.forwardRanked)
+}
+
+// hashRank returns the targets ranked for the hash field of a line, or for its content if the
+// line has no such field.
+func (m *MultiHEC) hashRank(line []byte) []int {
+	key, ok := fieldValue(line, m.hashPath)
+	if !ok {
+		key = string(line)
+	}
+	return m.rank(key)
+}
+
+// rank orders the targets for a key by weighted rendezvous hashing: each target scores the key
+// by a hash of the key and its name, scaled by its weight. A key keeps its first target while
+// that target is available; when it is not, only its keys move, spread over the other targets
+// in proportion to their weights, and they return when it recovers.
+func (m *MultiHEC) rank(key string) []int {
+	scores := make([]float64, len(m.targets))
+	order := make([]int, len(m.targets))
+	for i, name := range m.targetNames {
+		h := fnv.New64a()
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+		h.Write([]byte(key))
+		u := (float64(mix64(h.Sum64())>>11) + 0.5) / (1 << 53) // Uniform in (0, 1)
+		scores[i] = -float64(m.weights[i]) / math.Log(u)
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(scores[b], scores[a])
+	})
+	return order
+}
+
+// mix64 scrambles the bits of a hash, so keys that differ only slightly still score
+// independently.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// forwardRanked sends data to the first target in order that takes it, passing over targets
+// that are paused, whose circuit breaker is open, or that fail. Each line moved to a later
+// target is counted against the target it was moved from.
+func (m *MultiHEC) forwardRanked(connID, host string, data []byte, order []int) error {
+	attempted := false
+	for n, i := range order {
+		target := m.targets[i]
+		var reason string
+		switch {
+		case target.Paused():
+			reason = "paused"
+		case target.circuitBreaker.Rejecting():
+			reason = "circuit_open"
+		default:
+			attempted = true
+			err := target.ForwardFrom(connID, host, data)
+			if err == nil {
+				return nil
+			}
+			slog.Warn("HEC forward failed, trying next target",
+				"target", m.targetNames[i],
+				"conn_id", connID,
+				"error", err,
+				"remaining", len(order)-n-1)
+			reason = "failed"
+		}
+		if n < len(order)-1 {
+			metrics.TargetHecReroutes.Add(1, m.listener, m.targetNames[i], reason)
+		}
+	}
+
+	if !attempted {
+		if m.Paused() {
+			return ErrPaused
+		}
+		return fmt.Errorf("no target available for %s: circuit breakers open", m.mode)
+	}
+	return fmt.Errorf("all %d targets failed for %s", len(order), m.mode)
+}
+
 // HealthCheck verifies connectivity to all configured HEC targets.
 // Returns an error if any target fails the health check.
 func (m *MultiHEC) HealthCheck() error {
@@ -262,6 +672,91 @@ func (m *MultiHEC) HealthCheck() error {
 	return nil
 }
 
+// Targets returns the state of every target, in configuration order.
+func (m *MultiHEC) Targets() []TargetStatus {
+	statuses := make([]TargetStatus, 0, len(m.targets))
+	for _, target := range m.targets {
+		statuses = append(statuses, target.Targets()...)
+	}
+	return statuses
+}
+
+// Pause stops sending to the named target, or to every target if target is empty.
+func (m *MultiHEC) Pause(target string) error {
+	return m.each(target, func(hec *HEC) error { return hec.Pause("") })
+}
+
+// Resume restarts sending to the named target, or to every target if target is empty.
+func (m *MultiHEC) Resume(target string) error {
+	return m.each(target, func(hec *HEC) error { return hec.Resume("") })
+}
+
+// Paused reports whether every target is paused.
+func (m *MultiHEC) Paused() bool {
+	for _, target := range m.targets {
+		if !target.Paused() {
+			return false
+		}
+	}
+	return true
+}
+
+// ResetCircuit closes the circuit breaker of the named target, or of every target if target is empty.
+func (m *MultiHEC) ResetCircuit(target string) error {
+	return m.each(target, func(hec *HEC) error { return hec.ResetCircuit("") })
+}
+
+// SetRedactor sets the redaction applied to lines before they are sent to a target, such as
+// fields one Splunk tenant must not receive. It should be called before the first Forward.
+// An empty target name applies to every target.
+func (m *MultiHEC) SetRedactor(target string, r *redact.Redactor) error {
+	return m.each(target, func(hec *HEC) error {
+		hec.setRedactor(r)
+		return nil
+	})
+}
+
+// each calls f for the named target, or for every target if target is empty.
+// Returns an error if no target has that name.
+func (m *MultiHEC) each(target string, f func(*HEC) error) error {
+	found := false
+	for i, hec := range m.targets {
+		if target != "" && m.targetNames[i] != target {
+			continue
+		}
+		found = true
+		if err := f(hec); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return fmt.Errorf("unknown HEC target %q", target)
+	}
+	return nil
+}
+
+// Flush sends the current batch of every target synchronously.
+func (m *MultiHEC) Flush() {
+	var wg sync.WaitGroup
+	for _, target := range m.targets {
+		wg.Add(1)
+		go func(hec *HEC) {
+			defer wg.Done()
+			hec.Flush()
+		}(target)
+	}
+	wg.Wait()
+}
+
+// Undelivered returns the number of batched lines that failed to reach a target.
+func (m *MultiHEC) Undelivered() uint64 {
+	var total uint64
+	for _, target := range m.targets {
+		total += target.Undelivered()
+	}
+	return total
+}
+
 // Shutdown gracefully shuts down all HEC forwarders, flushing any remaining batched data.
 func (m *MultiHEC) Shutdown(ctx context.Context) error {
 	var wg sync.WaitGroup
@@ -294,6 +789,7 @@ func (m *MultiHEC) Shutdown(ctx context.Context) error {
 }
 
 // UpdateConfig updates the reloadable configuration parameters for all targets in a thread-safe manner.
+// Each target takes its settings from cfg.Targets, or from cfg if it is not listed there.
 // Only safe parameters (token, sourcetype, gzip) are updated.
 // Parameters that require restart (URL, batching, circuit breaker) are not affected.
 func (m *MultiHEC) UpdateConfig(cfg ReloadableConfig) {
@@ -302,7 +798,11 @@ func (m *MultiHEC) UpdateConfig(cfg ReloadableConfig) {
 
 	// Update all targets
 	for i, target := range m.targets {
-		target.UpdateConfig(cfg)
+		targetCfg, ok := cfg.Targets[m.targetNames[i]]
+		if !ok {
+			targetCfg = cfg
+		}
+		target.UpdateConfig(targetCfg)
 		slog.Debug("updated multi-target HEC configuration",
 			"target", m.targetNames[i])
 	}
@@ -310,3 +810,30 @@ func (m *MultiHEC) UpdateConfig(cfg ReloadableConfig) {
 	slog.Info("multi-target HEC configuration updated",
 		"targets", len(m.targets))
 }
+
+// fieldValue returns the value of the field at path in a JSON line: the text of a string, or
+// the compact JSON of any other value. It reports false if the field is missing or the line is
+// not a JSON object.
+func fieldValue(data []byte, path []string) (string, bool) {
+	value := json.RawMessage(data)
+	for _, key := range path {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(value, &object); err != nil {
+			return "", false
+		}
+		var ok bool
+		if value, ok = object[key]; !ok {
+			return "", false
+		}
+	}
+
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		return s, true
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, value); err != nil {
+		return "", false
+	}
+	return compact.String(), true
+}
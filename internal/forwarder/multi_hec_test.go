@@ -2,13 +2,24 @@ package forwarder
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/scottbrown/relay/internal/circuitbreaker"
 	"github.com/scottbrown/relay/internal/config"
+	"github.com/scottbrown/relay/internal/metrics"
+	"github.com/scottbrown/relay/internal/redact"
 )
 
 func TestNewMulti(t *testing.T) {
@@ -62,7 +73,7 @@ func TestNewMulti(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := NewMulti(tt.targets, tt.mode)
+			got, err := NewMulti("test", tt.targets, tt.mode)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewMulti() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -83,6 +94,27 @@ func TestNewMulti(t *testing.T) {
 	}
 }
 
+func TestNewMulti_Retry(t *testing.T) {
+	targets := []config.HECTarget{
+		{Name: "primary", HECURL: "http://localhost:8088", HECToken: "token1", Retry: &config.RetryConfig{MaxAttempts: 2, InitialBackoffMS: 100}},
+		{Name: "secondary", HECURL: "http://localhost:8089", HECToken: "token2"},
+	}
+
+	multi, err := NewMulti("test", targets, config.RoutingModeAll)
+	if err != nil {
+		t.Fatalf("NewMulti() failed: %v", err)
+	}
+
+	primary := multi.targets[0].config.Retry
+	if primary.MaxAttempts != 2 || primary.InitialBackoff != 100*time.Millisecond || primary.MaxBackoff != 30*time.Second {
+		t.Errorf("primary retry = %+v, want configured attempts and backoff with default maximum", primary)
+	}
+	secondary := multi.targets[1].config.Retry
+	if secondary.MaxAttempts != 5 || secondary.InitialBackoff != 250*time.Millisecond || secondary.BackoffMultiplier != 2.0 {
+		t.Errorf("secondary retry = %+v, want defaults", secondary)
+	}
+}
+
 func TestMultiHEC_ForwardAll(t *testing.T) {
 	var count1, count2 atomic.Int32
 
@@ -115,7 +147,7 @@ func TestMultiHEC_ForwardAll(t *testing.T) {
 		},
 	}
 
-	multi, err := NewMulti(targets, config.RoutingModeAll)
+	multi, err := NewMulti("test", targets, config.RoutingModeAll)
 	if err != nil {
 		t.Fatalf("NewMulti() failed: %v", err)
 	}
@@ -175,7 +207,7 @@ func TestMultiHEC_ForwardPrimaryFailover(t *testing.T) {
 		},
 	}
 
-	multi, err := NewMulti(targets, config.RoutingModePrimaryFailover)
+	multi, err := NewMulti("test", targets, config.RoutingModePrimaryFailover)
 	if err != nil {
 		t.Fatalf("NewMulti() failed: %v", err)
 	}
@@ -245,7 +277,7 @@ func TestMultiHEC_ForwardRoundRobin(t *testing.T) {
 		},
 	}
 
-	multi, err := NewMulti(targets, config.RoutingModeRoundRobin)
+	multi, err := NewMulti("test", targets, config.RoutingModeRoundRobin)
 	if err != nil {
 		t.Fatalf("NewMulti() failed: %v", err)
 	}
@@ -270,6 +302,296 @@ func TestMultiHEC_ForwardRoundRobin(t *testing.T) {
 	}
 }
 
+func TestMultiHEC_ForwardRouteByField(t *testing.T) {
+	names := []string{"acme-primary", "acme-dr", "shared"}
+	counts := make([]atomic.Int32, len(names))
+	var targets []config.HECTarget
+	for i, name := range names {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			counts[i].Add(1)
+			if i == 0 {
+				w.WriteHeader(http.StatusInternalServerError) // acme-primary is down
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+		targets = append(targets, config.HECTarget{
+			Name: name, HECURL: server.URL, HECToken: "token", SourceType: "test",
+			Retry: &config.RetryConfig{MaxAttempts: 1},
+		})
+	}
+
+	routing := config.RoutingConfig{
+		Mode:    config.RoutingModeRouteByField,
+		Field:   "Customer.Name",
+		Routes:  []config.FieldRoute{{Values: []string{"acme", "acme-eu"}, Targets: []string{"acme-primary", "acme-dr"}}},
+		Default: []string{"shared"},
+	}
+	multi, err := NewMultiWithRouting("test", targets, routing)
+	if err != nil {
+		t.Fatalf("NewMultiWithRouting() failed: %v", err)
+	}
+
+	tests := []struct {
+		line     string
+		expected [3]int32 // Cumulative requests per target
+	}{
+		{`{"Customer":{"Name":"acme"}}`, [3]int32{1, 1, 0}},    // Fails over within the route
+		{`{"Customer":{"Name":"acme-eu"}}`, [3]int32{2, 2, 0}}, // Another value of the same route
+		{`{"Customer":{"Name":"globex"}}`, [3]int32{2, 2, 1}},  // No route for the value
+		{`{"Customer":"acme"}`, [3]int32{2, 2, 2}},             // No field at the path
+		{`not json`, [3]int32{2, 2, 3}},
+	}
+	for _, tt := range tests {
+		if err := multi.Forward("test-conn", []byte(tt.line)); err != nil {
+			t.Errorf("Forward(%s) failed: %v", tt.line, err)
+		}
+		got := [3]int32{counts[0].Load(), counts[1].Load(), counts[2].Load()}
+		if got != tt.expected {
+			t.Errorf("after %s requests = %v, want %v", tt.line, got, tt.expected)
+		}
+	}
+
+	// A paused route fails like a route whose targets are all down
+	_ = multi.Pause("acme-dr")
+	if err := multi.Forward("test-conn", []byte(`{"Customer":{"Name":"acme"}}`)); err == nil {
+		t.Error("Forward() should fail when every target of the route is down or paused")
+	}
+
+	routing.Default = []string{"other"}
+	if _, err := NewMultiWithRouting("test", targets, routing); err == nil {
+		t.Error("NewMultiWithRouting() should reject an unknown default target")
+	}
+}
+
+func TestMultiHEC_ForwardRouteByField_Batch(t *testing.T) {
+	names := []string{"acme", "shared"}
+	var mu sync.Mutex
+	bodies := make([][]string, len(names))
+	var targets []config.HECTarget
+	for i, name := range names {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			bodies[i] = append(bodies[i], string(body))
+			mu.Unlock()
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+		targets = append(targets, config.HECTarget{Name: name, HECURL: server.URL, HECToken: "token", SourceType: "test"})
+	}
+
+	routing := config.RoutingConfig{
+		Mode:    config.RoutingModeRouteByField,
+		Field:   "Customer",
+		Routes:  []config.FieldRoute{{Values: []string{"acme"}, Targets: []string{"acme"}}},
+		Default: []string{"shared"},
+	}
+	multi, err := NewMultiWithRouting("test", targets, routing)
+	if err != nil {
+		t.Fatalf("NewMultiWithRouting() failed: %v", err)
+	}
+
+	// A batch from tail mode or backfill holds the lines of several tenants
+	batch := "{\"Customer\":\"acme\",\"n\":1}\n{\"Customer\":\"globex\",\"n\":2}\n{\"Customer\":\"acme\",\"n\":3}"
+	if err := multi.Forward("tail", []byte(batch)); err != nil {
+		t.Fatalf("Forward() failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"{\"Customer\":\"acme\",\"n\":1}\n{\"Customer\":\"acme\",\"n\":3}"}; !slices.Equal(bodies[0], want) {
+		t.Errorf("acme bodies = %q, want %q", bodies[0], want)
+	}
+	if want := []string{"{\"Customer\":\"globex\",\"n\":2}"}; !slices.Equal(bodies[1], want) {
+		t.Errorf("shared bodies = %q, want %q", bodies[1], want)
+	}
+}
+
+func TestMultiHEC_ForwardWeighted(t *testing.T) {
+	counts := make([]atomic.Int32, 3)
+	var targets []config.HECTarget
+	for i, weight := range []int{3, 1, 0} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			counts[i].Add(1)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+		targets = append(targets, config.HECTarget{
+			Name: fmt.Sprintf("target%d", i+1), HECURL: server.URL, HECToken: "token", SourceType: "test",
+			Weight: weight,
+		})
+	}
+
+	multi, err := NewMultiWithRouting("weighted-test", targets, config.RoutingConfig{Mode: config.RoutingModeWeighted})
+	if err != nil {
+		t.Fatalf("NewMultiWithRouting() failed: %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		if err := multi.Forward("test-conn", []byte(`{"test": "data"}`)); err != nil {
+			t.Fatalf("Forward() failed: %v", err)
+		}
+	}
+	// A target without a weight counts as weight 1
+	if got := [3]int32{counts[0].Load(), counts[1].Load(), counts[2].Load()}; got != [3]int32{6, 2, 2} {
+		t.Errorf("requests per target = %v, want [6 2 2]", got)
+	}
+
+	// A paused target's share moves to the others
+	_ = multi.Pause("target1")
+	for i := 0; i < 10; i++ {
+		if err := multi.Forward("test-conn", []byte(`{"test": "data"}`)); err != nil {
+			t.Fatalf("Forward() failed: %v", err)
+		}
+	}
+	if got := counts[0].Load(); got != 6 {
+		t.Errorf("paused target received %d requests, want 6", got)
+	}
+	if got := counts[1].Load() + counts[2].Load(); got != 14 {
+		t.Errorf("other targets received %d requests, want 14", got)
+	}
+	if got := metrics.TargetHecReroutes.Value("weighted-test", "target1", "paused"); got != 6 {
+		t.Errorf("reroutes from the paused target = %d, want 6", got)
+	}
+}
+
+func TestMultiHEC_ForwardHash(t *testing.T) {
+	const numTargets = 3
+	var mu sync.Mutex
+	received := make(map[string]int) // Target index by SessionID
+	moved := 0
+	var down atomic.Bool
+
+	var targets []config.HECTarget
+	for i := 0; i < numTargets; i++ {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if i == 0 && down.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			var line struct{ SessionID string }
+			_ = json.Unmarshal(body, &line)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if previous, ok := received[line.SessionID]; ok && previous != i {
+				if previous != 0 {
+					t.Errorf("session %s moved from target %d, which is up", line.SessionID, previous)
+				}
+				moved++
+			}
+			received[line.SessionID] = i
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+		targets = append(targets, config.HECTarget{
+			Name: fmt.Sprintf("target%d", i), HECURL: server.URL, HECToken: "token", SourceType: "test",
+			Retry:          &config.RetryConfig{MaxAttempts: 1},
+			CircuitBreaker: &config.CircuitBreakerConfig{FailureThreshold: 1, Timeout: 60},
+		})
+	}
+
+	routing := config.RoutingConfig{Mode: config.RoutingModeHash, Field: "SessionID"}
+	multi, err := NewMultiWithRouting("hash-test", targets, routing)
+	if err != nil {
+		t.Fatalf("NewMultiWithRouting() failed: %v", err)
+	}
+	forwardSessions := func() {
+		for i := 0; i < 60; i++ {
+			line := fmt.Sprintf(`{"SessionID":"session-%d"}`, i)
+			if err := multi.Forward("test-conn", []byte(line)); err != nil {
+				t.Fatalf("Forward(%s) failed: %v", line, err)
+			}
+		}
+	}
+
+	// Every session keeps its target, and each target gets some sessions
+	forwardSessions()
+	forwardSessions()
+	perTarget := make([]int, numTargets)
+	for _, i := range received {
+		perTarget[i]++
+	}
+	for i, n := range perTarget {
+		if n == 0 {
+			t.Errorf("target%d received no sessions: %v", i, perTarget)
+		}
+	}
+	if moved != 0 {
+		t.Fatalf("%d sessions changed target with every target up", moved)
+	}
+
+	// When target0 fails its sessions move, and only its sessions: the first line fails over,
+	// opening its circuit, and the rest pass it over
+	down.Store(true)
+	forwardSessions()
+	if moved != perTarget[0] {
+		t.Errorf("%d sessions moved, want the %d of target0", moved, perTarget[0])
+	}
+	if got := metrics.TargetHecReroutes.Value("hash-test", "target0", "failed"); got != 1 {
+		t.Errorf("reroutes after failure = %d, want 1", got)
+	}
+	if got := metrics.TargetHecReroutes.Value("hash-test", "target0", "circuit_open"); got != int64(perTarget[0]-1) {
+		t.Errorf("reroutes with the circuit open = %d, want %d", got, perTarget[0]-1)
+	}
+
+	routing.Field = ""
+	if _, err := NewMultiWithRouting("hash-test", targets, routing); err == nil {
+		t.Error("NewMultiWithRouting() should require a field for hash mode")
+	}
+}
+
+func TestMultiHEC_ForwardHash_Batch(t *testing.T) {
+	const numTargets = 3
+	var mu sync.Mutex
+	received := make(map[string]int) // Target index by SessionID
+	var targets []config.HECTarget
+	for i := 0; i < numTargets; i++ {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			defer mu.Unlock()
+			for _, line := range strings.Split(string(body), "\n") {
+				var event struct{ SessionID string }
+				_ = json.Unmarshal([]byte(line), &event)
+				received[event.SessionID] = i
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+		targets = append(targets, config.HECTarget{
+			Name: fmt.Sprintf("target%d", i), HECURL: server.URL, HECToken: "token", SourceType: "test",
+		})
+	}
+
+	multi, err := NewMultiWithRouting("hash-batch-test", targets, config.RoutingConfig{Mode: config.RoutingModeHash, Field: "SessionID"})
+	if err != nil {
+		t.Fatalf("NewMultiWithRouting() failed: %v", err)
+	}
+
+	// Each session reaches the same target in a batch from tail mode or backfill as on its own
+	var lines []string
+	for i := 0; i < 30; i++ {
+		line := fmt.Sprintf(`{"SessionID":"session-%d"}`, i)
+		lines = append(lines, line)
+		if err := multi.Forward("test-conn", []byte(line)); err != nil {
+			t.Fatalf("Forward(%s) failed: %v", line, err)
+		}
+	}
+	single := maps.Clone(received)
+	clear(received)
+
+	if err := multi.Forward("tail", []byte(strings.Join(lines, "\n"))); err != nil {
+		t.Fatalf("Forward() of a batch failed: %v", err)
+	}
+	if !maps.Equal(received, single) {
+		t.Errorf("targets of sessions in a batch = %v, want %v", received, single)
+	}
+}
+
 func TestMultiHEC_HealthCheck(t *testing.T) {
 	// Create a test server that responds to health checks
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -290,7 +612,7 @@ func TestMultiHEC_HealthCheck(t *testing.T) {
 		},
 	}
 
-	multi, err := NewMulti(targets, config.RoutingModeAll)
+	multi, err := NewMulti("test", targets, config.RoutingModeAll)
 	if err != nil {
 		t.Fatalf("NewMulti() failed: %v", err)
 	}
@@ -302,6 +624,239 @@ func TestMultiHEC_HealthCheck(t *testing.T) {
 	}
 }
 
+func TestMultiHEC_Targets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	targets := []config.HECTarget{
+		{Name: "target1", HECURL: server.URL, HECToken: "token1", SourceType: "test"},
+		{Name: "target2", HECURL: server.URL, HECToken: "token2", SourceType: "test"},
+	}
+
+	multi, err := NewMulti("test", targets, config.RoutingModeRoundRobin)
+	if err != nil {
+		t.Fatalf("NewMulti() failed: %v", err)
+	}
+
+	// Round robin sends the first line to target1 only
+	if err := multi.Forward("test-conn", []byte(`{"test": "data"}`)); err != nil {
+		t.Fatalf("Forward() failed: %v", err)
+	}
+
+	statuses := multi.Targets()
+	if len(statuses) != 2 {
+		t.Fatalf("Targets() returned %d statuses, want 2", len(statuses))
+	}
+	if statuses[0].Name != "target1" || statuses[1].Name != "target2" {
+		t.Errorf("Targets() names = %s, %s, want target1, target2", statuses[0].Name, statuses[1].Name)
+	}
+	if statuses[0].CircuitState != circuitbreaker.StateClosed {
+		t.Errorf("target1 circuit state = %s, want closed", statuses[0].CircuitState)
+	}
+	if statuses[0].LastSuccess.IsZero() {
+		t.Error("target1 should record its last success")
+	}
+	if !statuses[1].LastSuccess.IsZero() {
+		t.Error("target2 has not been sent anything and should have no last success")
+	}
+}
+
+func TestMultiHEC_PauseTarget(t *testing.T) {
+	var count1, count2 atomic.Int32
+	server1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count1.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server1.Close()
+	server2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count2.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server2.Close()
+
+	targets := []config.HECTarget{
+		{Name: "target1", HECURL: server1.URL, HECToken: "token1", SourceType: "test"},
+		{Name: "target2", HECURL: server2.URL, HECToken: "token2", SourceType: "test"},
+	}
+
+	multi, err := NewMulti("test", targets, config.RoutingModeRoundRobin)
+	if err != nil {
+		t.Fatalf("NewMulti() failed: %v", err)
+	}
+
+	if err := multi.Pause("target3"); err == nil {
+		t.Error("Pause() should reject an unknown target")
+	}
+	if err := multi.Pause("target1"); err != nil {
+		t.Fatalf("Pause() failed: %v", err)
+	}
+	if multi.Paused() {
+		t.Error("Paused() should be false while target2 is still forwarding")
+	}
+
+	// Round robin skips the paused target
+	data := []byte(`{"test": "data"}`)
+	for i := 0; i < 4; i++ {
+		if err := multi.Forward("test-conn", data); err != nil {
+			t.Errorf("Forward() failed on iteration %d: %v", i, err)
+		}
+	}
+	if count1.Load() != 0 || count2.Load() != 4 {
+		t.Errorf("requests = %d, %d, want 0, 4", count1.Load(), count2.Load())
+	}
+
+	statuses := multi.Targets()
+	if !statuses[0].Paused || statuses[1].Paused {
+		t.Errorf("Targets() paused = %v, %v, want true, false", statuses[0].Paused, statuses[1].Paused)
+	}
+
+	// With every target paused nothing is sent
+	if err := multi.Pause(""); err != nil {
+		t.Fatalf("Pause() failed: %v", err)
+	}
+	if !multi.Paused() {
+		t.Error("Paused() should be true when every target is paused")
+	}
+	if err := multi.Forward("test-conn", data); !errors.Is(err, ErrPaused) {
+		t.Errorf("Forward() error = %v, want ErrPaused", err)
+	}
+
+	if err := multi.Resume(""); err != nil {
+		t.Fatalf("Resume() failed: %v", err)
+	}
+	if multi.Paused() {
+		t.Error("Paused() should be false after Resume()")
+	}
+}
+
+func TestMultiHEC_ForwardTo(t *testing.T) {
+	var count1, count2 atomic.Int32
+	server1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count1.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server1.Close()
+	server2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count2.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server2.Close()
+
+	targets := []config.HECTarget{
+		{Name: "target1", HECURL: server1.URL, HECToken: "token1", SourceType: "test"},
+		{Name: "target2", HECURL: server2.URL, HECToken: "token2", SourceType: "test"},
+	}
+	multi, err := NewMulti("test", targets, config.RoutingModeAll)
+	if err != nil {
+		t.Fatalf("NewMulti() failed: %v", err)
+	}
+
+	// The named target receives the data whatever the routing mode
+	data := []byte(`{"test": "data"}`)
+	if err := multi.ForwardTo("target2", "test-conn", "", data); err != nil {
+		t.Fatalf("ForwardTo() failed: %v", err)
+	}
+	if count1.Load() != 0 || count2.Load() != 1 {
+		t.Errorf("requests = %d, %d, want 0, 1", count1.Load(), count2.Load())
+	}
+
+	if err := multi.ForwardTo("target3", "test-conn", "", data); err == nil {
+		t.Error("ForwardTo() should reject an unknown target")
+	}
+	_ = multi.Pause("target2")
+	if err := multi.ForwardTo("target2", "test-conn", "", data); !errors.Is(err, ErrPaused) {
+		t.Errorf("ForwardTo() error = %v, want ErrPaused", err)
+	}
+}
+
+func TestMultiHEC_SetRedactor(t *testing.T) {
+	var body1, body2 atomic.Value
+	server1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body1.Store(string(data))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server1.Close()
+	server2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body2.Store(string(data))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server2.Close()
+
+	targets := []config.HECTarget{
+		{Name: "internal", HECURL: server1.URL, HECToken: "token1", SourceType: "test"},
+		{Name: "tenant", HECURL: server2.URL, HECToken: "token2", SourceType: "test"},
+	}
+	multi, err := NewMulti("test", targets, config.RoutingModeAll)
+	if err != nil {
+		t.Fatalf("NewMulti() failed: %v", err)
+	}
+
+	redactor, _ := redact.New([]redact.Rule{{Path: "Username", Action: redact.Mask}}, nil)
+	if err := multi.SetRedactor("other", redactor); err == nil {
+		t.Error("SetRedactor() should reject an unknown target")
+	}
+	if err := multi.SetRedactor("tenant", redactor); err != nil {
+		t.Fatalf("SetRedactor() failed: %v", err)
+	}
+
+	if err := multi.Forward("test-conn", []byte(`{"Username":"jdoe"}`)); err != nil {
+		t.Fatalf("Forward() failed: %v", err)
+	}
+	if got := body1.Load(); got != `{"Username":"jdoe"}` {
+		t.Errorf("internal target received %v, want the line unchanged", got)
+	}
+	if got := body2.Load(); got != `{"Username":"[REDACTED]"}` {
+		t.Errorf("tenant target received %v, want the redacted line", got)
+	}
+}
+
+func TestMultiHEC_UpdateConfig(t *testing.T) {
+	var token1, token2 atomic.Value
+	server1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token1.Store(r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server1.Close()
+	server2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token2.Store(r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server2.Close()
+
+	targets := []config.HECTarget{
+		{Name: "target1", HECURL: server1.URL, HECToken: "token1", SourceType: "test"},
+		{Name: "target2", HECURL: server2.URL, HECToken: "token2", SourceType: "test"},
+	}
+
+	multi, err := NewMulti("test", targets, config.RoutingModeAll)
+	if err != nil {
+		t.Fatalf("NewMulti() failed: %v", err)
+	}
+
+	// Each target keeps its own token
+	multi.UpdateConfig(ReloadableConfig{
+		Token: "token1-rotated",
+		Targets: map[string]ReloadableConfig{
+			"target1": {Token: "token1-rotated", SourceType: "test"},
+			"target2": {Token: "token2-rotated", SourceType: "test"},
+		},
+	})
+
+	if err := multi.Forward("test-conn", []byte(`{"test": "data"}`)); err != nil {
+		t.Fatalf("Forward() failed: %v", err)
+	}
+	if got := token1.Load(); got != "Splunk token1-rotated" {
+		t.Errorf("target1 Authorization = %v, want Splunk token1-rotated", got)
+	}
+	if got := token2.Load(); got != "Splunk token2-rotated" {
+		t.Errorf("target2 Authorization = %v, want Splunk token2-rotated", got)
+	}
+}
+
 func TestMultiHEC_Shutdown(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -317,7 +872,7 @@ func TestMultiHEC_Shutdown(t *testing.T) {
 		},
 	}
 
-	multi, err := NewMulti(targets, config.RoutingModeAll)
+	multi, err := NewMulti("test", targets, config.RoutingModeAll)
 	if err != nil {
 		t.Fatalf("NewMulti() failed: %v", err)
 	}
@@ -348,7 +903,7 @@ func TestMultiHEC_DefaultRoutingMode(t *testing.T) {
 	}
 
 	// Create with empty routing mode (should default to "all")
-	multi, err := NewMulti(targets, "")
+	multi, err := NewMulti("test", targets, "")
 	if err != nil {
 		t.Fatalf("NewMulti() failed: %v", err)
 	}
@@ -357,3 +912,34 @@ func TestMultiHEC_DefaultRoutingMode(t *testing.T) {
 		t.Errorf("Default routing mode = %v, want %v", multi.mode, config.RoutingModeAll)
 	}
 }
+
+func TestMultiHEC_RecordsPerTargetMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	targets := []config.HECTarget{
+		{Name: "primary", HECURL: server.URL, HECToken: "token1"},
+		{Name: "secondary", HECURL: server.URL, HECToken: "token2"},
+	}
+	multi, err := NewMulti("metrics-test", targets, config.RoutingModeRoundRobin)
+	if err != nil {
+		t.Fatalf("NewMulti() failed: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := multi.Forward("test-conn", []byte(`{"test": "data"}`)); err != nil {
+			t.Fatalf("Forward() failed: %v", err)
+		}
+	}
+
+	primary := metrics.TargetHecForwards.Value("metrics-test", "primary", "success")
+	secondary := metrics.TargetHecForwards.Value("metrics-test", "secondary", "success")
+	if primary+secondary != 3 || primary == 0 || secondary == 0 {
+		t.Errorf("successes by target = primary %d, secondary %d; want 3 split across both", primary, secondary)
+	}
+	if got := metrics.TargetHecBytesForwarded.Value("metrics-test", "primary"); got != primary*16 {
+		t.Errorf("primary bytes = %d, want %d", got, primary*16)
+	}
+}
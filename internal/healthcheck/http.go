@@ -0,0 +1,262 @@
+package healthcheck
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/scottbrown/relay/internal/circuitbreaker"
+	"github.com/scottbrown/relay/internal/forwarder"
+)
+
+// Listener supplies the status of one listener to the HTTP health endpoints.
+// Nil functions are skipped: a listener without a forwarder has no Targets,
+// and one without a DLQ has no DLQBacklog.
+type Listener struct {
+	Name       string
+	LogType    string
+	Accepting  func() bool
+	LastEvent  func() time.Time // Zero if nothing has been received
+	Storage    func() error     // Returns an error if storage is not writable
+	DLQBacklog func() int64     // Bytes waiting in the DLQ
+	Targets    func() []forwarder.TargetStatus
+}
+
+// Readiness holds the conditions under which /readyz reports the relay as not ready.
+// A listener that is not accepting connections always fails readiness.
+type Readiness struct {
+	CircuitOpen        bool          // Fail while any HEC circuit breaker is open
+	StorageUnwritable  bool          // Fail while any storage directory is not writable
+	MaxDLQBacklogBytes int64         // Fail when a listener's DLQ backlog exceeds this size (0 = disabled)
+	MaxEventAge        time.Duration // Fail when a listener has received nothing for this long (0 = disabled)
+}
+
+// storageCheckInterval is how long the result of a storage probe is reused by /readyz.
+const storageCheckInterval = 10 * time.Second
+
+// Report is the JSON body returned by /healthz and /readyz.
+// /healthz reports only its status.
+type Report struct {
+	Status    string           `json:"status"` // "ok", "ready" or "not_ready"
+	Failures  []string         `json:"failures,omitempty"`
+	Listeners []ListenerReport `json:"listeners,omitempty"`
+}
+
+// ListenerReport is the status of one listener.
+type ListenerReport struct {
+	Name            string         `json:"name"`
+	LogType         string         `json:"log_type"`
+	Accepting       bool           `json:"accepting"`
+	LastEvent       *time.Time     `json:"last_event,omitempty"`
+	StorageWritable bool           `json:"storage_writable"`
+	StorageError    string         `json:"storage_error,omitempty"`
+	DLQBacklogBytes *int64         `json:"dlq_backlog_bytes,omitempty"`
+	Targets         []TargetReport `json:"targets,omitempty"`
+}
+
+// TargetReport is the status of one HEC target.
+type TargetReport struct {
+	Name         string     `json:"name"`
+	Paused       bool       `json:"paused"`
+	CircuitState string     `json:"circuit_state"`
+	LastSuccess  *time.Time `json:"last_success,omitempty"`
+}
+
+// HTTPServer serves /healthz (liveness) and /readyz (readiness) with per-component status.
+// /healthz returns 200 while the process is serving, without checking any component, so a
+// slow disk does not get the relay restarted. /readyz returns 503 when a readiness condition
+// fails, so load balancers stop sending traffic to this relay.
+type HTTPServer struct {
+	addr      string
+	listeners []Listener
+	mu        sync.RWMutex // Protects listeners
+	readiness Readiness
+	started   time.Time // Baseline for MaxEventAge before the first event
+	server    *http.Server
+	listener  net.Listener
+
+	storageMu      sync.Mutex // Serializes storage probes and protects storageResults
+	storageResults map[string]storageResult
+}
+
+// storageResult is the outcome of a listener's last storage probe.
+type storageResult struct {
+	err     error
+	checked time.Time
+}
+
+// NewHTTP creates an HTTP health server for the given listeners.
+// The server is not started until Start is called.
+func NewHTTP(addr string, listeners []Listener, readiness Readiness) *HTTPServer {
+	s := &HTTPServer{
+		addr:      addr,
+		listeners: listeners,
+		readiness: readiness,
+		started:   time.Now(),
+
+		storageResults: make(map[string]storageResult),
+	}
+	s.server = &http.Server{
+		Handler:           s.Handler(),
+		ReadTimeout:       5 * time.Second,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      10 * time.Second,
+		IdleTimeout:       30 * time.Second,
+	}
+	return s
+}
+
+// Handler returns the HTTP handler serving /healthz and /readyz.
+func (s *HTTPServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		writeReport(w, http.StatusOK, Report{Status: "ok"})
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, _ *http.Request) {
+		report := s.Check()
+		code := http.StatusOK
+		if report.Status != "ready" {
+			code = http.StatusServiceUnavailable
+		}
+		writeReport(w, code, report)
+	})
+	return mux
+}
+
+// Start starts serving in a background goroutine.
+// Returns an error if the listener cannot be created.
+func (s *HTTPServer) Start() error {
+	var err error
+	s.listener, err = net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := s.server.Serve(s.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("health HTTP server failed", "error", err)
+		}
+	}()
+	return nil
+}
+
+// Stop closes the server. It is safe to call Stop without Start.
+func (s *HTTPServer) Stop() error {
+	return s.server.Close()
+}
+
+// SetListeners replaces the listeners reported on, for example after a configuration reload.
+func (s *HTTPServer) SetListeners(listeners []Listener) {
+	s.mu.Lock()
+	s.listeners = listeners
+	s.mu.Unlock()
+
+	// A rebuilt listener may store elsewhere, so it is probed afresh
+	s.storageMu.Lock()
+	clear(s.storageResults)
+	s.storageMu.Unlock()
+}
+
+// Check collects the status of every listener and evaluates the readiness conditions.
+// Storage is probed at most once per storageCheckInterval for each listener.
+func (s *HTTPServer) Check() Report {
+	s.mu.RLock()
+	listeners := s.listeners
+	s.mu.RUnlock()
+
+	now := time.Now()
+	report := Report{Listeners: make([]ListenerReport, 0, len(listeners))}
+	fail := func(format string, args ...interface{}) {
+		report.Failures = append(report.Failures, fmt.Sprintf(format, args...))
+	}
+
+	for _, l := range listeners {
+		lr := ListenerReport{Name: l.Name, LogType: l.LogType, StorageWritable: true}
+
+		if l.Accepting != nil {
+			lr.Accepting = l.Accepting()
+		}
+		if !lr.Accepting {
+			fail("listener %s: not accepting connections", l.Name)
+		}
+
+		if l.LastEvent != nil {
+			last := l.LastEvent()
+			since := s.started
+			if !last.IsZero() {
+				lr.LastEvent = &last
+				since = last
+			}
+			if s.readiness.MaxEventAge > 0 && now.Sub(since) > s.readiness.MaxEventAge {
+				fail("listener %s: no events received for %s", l.Name, now.Sub(since).Round(time.Second))
+			}
+		}
+
+		if l.Storage != nil {
+			if err := s.storageStatus(l, now); err != nil {
+				lr.StorageWritable = false
+				lr.StorageError = err.Error()
+				if s.readiness.StorageUnwritable {
+					fail("listener %s: storage not writable: %v", l.Name, err)
+				}
+			}
+		}
+
+		if l.DLQBacklog != nil {
+			backlog := l.DLQBacklog()
+			lr.DLQBacklogBytes = &backlog
+			if s.readiness.MaxDLQBacklogBytes > 0 && backlog > s.readiness.MaxDLQBacklogBytes {
+				fail("listener %s: DLQ backlog %d bytes exceeds %d", l.Name, backlog, s.readiness.MaxDLQBacklogBytes)
+			}
+		}
+
+		if l.Targets != nil {
+			for _, t := range l.Targets() {
+				tr := TargetReport{Name: t.Name, Paused: t.Paused, CircuitState: t.CircuitState.String()}
+				if !t.LastSuccess.IsZero() {
+					last := t.LastSuccess
+					tr.LastSuccess = &last
+				}
+				if s.readiness.CircuitOpen && t.CircuitState == circuitbreaker.StateOpen {
+					fail("listener %s: circuit open for HEC target %s", l.Name, t.Name)
+				}
+				lr.Targets = append(lr.Targets, tr)
+			}
+		}
+
+		report.Listeners = append(report.Listeners, lr)
+	}
+
+	report.Status = "ready"
+	if len(report.Failures) > 0 {
+		report.Status = "not_ready"
+	}
+	return report
+}
+
+// storageStatus returns the result of a listener's storage probe, probing again only once the
+// previous result is older than storageCheckInterval. Probes run one at a time, so concurrent
+// requests do not pile up on a stalled disk.
+func (s *HTTPServer) storageStatus(l Listener, now time.Time) error {
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
+	if r, ok := s.storageResults[l.Name]; ok && now.Sub(r.checked) < storageCheckInterval {
+		return r.err
+	}
+	err := l.Storage()
+	s.storageResults[l.Name] = storageResult{err: err, checked: now}
+	return err
+}
+
+// writeReport writes a report as JSON with the given status code.
+func writeReport(w http.ResponseWriter, code int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(report) // Client disconnects are not actionable
+}
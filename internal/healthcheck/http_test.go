@@ -0,0 +1,265 @@
+package healthcheck
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scottbrown/relay/internal/circuitbreaker"
+	"github.com/scottbrown/relay/internal/forwarder"
+)
+
+// healthyListener returns a listener whose every component is healthy.
+func healthyListener(name string) Listener {
+	return Listener{
+		Name:       name,
+		LogType:    "user-activity",
+		Accepting:  func() bool { return true },
+		LastEvent:  func() time.Time { return time.Now() },
+		Storage:    func() error { return nil },
+		DLQBacklog: func() int64 { return 0 },
+		Targets: func() []forwarder.TargetStatus {
+			return []forwarder.TargetStatus{{Name: "primary", CircuitState: circuitbreaker.StateClosed, LastSuccess: time.Now()}}
+		},
+	}
+}
+
+// get requests path from srv and decodes the report.
+func get(t *testing.T, srv *HTTPServer, path string) (int, Report) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var report Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("%s returned invalid JSON: %v", path, err)
+	}
+	return rec.Code, report
+}
+
+func TestHTTPServer_Ready(t *testing.T) {
+	srv := NewHTTP(":0", []Listener{healthyListener("users")}, Readiness{CircuitOpen: true, StorageUnwritable: true})
+
+	code, report := get(t, srv, "/readyz")
+	if code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d (failures: %v)", code, report.Failures)
+	}
+	if report.Status != "ready" {
+		t.Errorf("expected status ready, got %q", report.Status)
+	}
+	if len(report.Listeners) != 1 {
+		t.Fatalf("expected 1 listener, got %d", len(report.Listeners))
+	}
+	l := report.Listeners[0]
+	if !l.Accepting || !l.StorageWritable || l.LastEvent == nil || l.DLQBacklogBytes == nil {
+		t.Errorf("unexpected listener report: %+v", l)
+	}
+	if len(l.Targets) != 1 || l.Targets[0].CircuitState != "closed" || l.Targets[0].LastSuccess == nil {
+		t.Errorf("unexpected target report: %+v", l.Targets)
+	}
+}
+
+func TestHTTPServer_ReadinessConditions(t *testing.T) {
+	tests := []struct {
+		name      string
+		modify    func(*Listener)
+		readiness Readiness
+		expected  string // Failure substring; empty if the relay should be ready
+	}{
+		{
+			name:     "not accepting",
+			modify:   func(l *Listener) { l.Accepting = func() bool { return false } },
+			expected: "not accepting connections",
+		},
+		{
+			name: "circuit open",
+			modify: func(l *Listener) {
+				l.Targets = func() []forwarder.TargetStatus {
+					return []forwarder.TargetStatus{{Name: "primary", CircuitState: circuitbreaker.StateOpen}}
+				}
+			},
+			readiness: Readiness{CircuitOpen: true},
+			expected:  "circuit open for HEC target primary",
+		},
+		{
+			name: "circuit open ignored",
+			modify: func(l *Listener) {
+				l.Targets = func() []forwarder.TargetStatus {
+					return []forwarder.TargetStatus{{Name: "primary", CircuitState: circuitbreaker.StateOpen}}
+				}
+			},
+		},
+		{
+			name: "circuit half-open",
+			modify: func(l *Listener) {
+				l.Targets = func() []forwarder.TargetStatus {
+					return []forwarder.TargetStatus{{Name: "primary", CircuitState: circuitbreaker.StateHalfOpen}}
+				}
+			},
+			readiness: Readiness{CircuitOpen: true},
+		},
+		{
+			name:      "storage unwritable",
+			modify:    func(l *Listener) { l.Storage = func() error { return errors.New("no space left on device") } },
+			readiness: Readiness{StorageUnwritable: true},
+			expected:  "storage not writable: no space left on device",
+		},
+		{
+			name:      "DLQ backlog too large",
+			modify:    func(l *Listener) { l.DLQBacklog = func() int64 { return 2048 } },
+			readiness: Readiness{MaxDLQBacklogBytes: 1024},
+			expected:  "DLQ backlog 2048 bytes exceeds 1024",
+		},
+		{
+			name:      "no recent events",
+			modify:    func(l *Listener) { l.LastEvent = func() time.Time { return time.Now().Add(-time.Hour) } },
+			readiness: Readiness{MaxEventAge: time.Minute},
+			expected:  "no events received for 1h0m0s",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := healthyListener("users")
+			tt.modify(&l)
+			srv := NewHTTP(":0", []Listener{l}, tt.readiness)
+
+			code, report := get(t, srv, "/readyz")
+			if tt.expected == "" {
+				if code != http.StatusOK {
+					t.Errorf("expected status 200, got %d (failures: %v)", code, report.Failures)
+				}
+				return
+			}
+			if code != http.StatusServiceUnavailable {
+				t.Errorf("expected status 503, got %d", code)
+			}
+			if report.Status != "not_ready" {
+				t.Errorf("expected status not_ready, got %q", report.Status)
+			}
+			if len(report.Failures) != 1 || !strings.Contains(report.Failures[0], tt.expected) {
+				t.Errorf("expected failure containing %q, got %v", tt.expected, report.Failures)
+			}
+		})
+	}
+}
+
+func TestHTTPServer_EventAgeBeforeFirstEvent(t *testing.T) {
+	l := healthyListener("users")
+	l.LastEvent = func() time.Time { return time.Time{} }
+	srv := NewHTTP(":0", []Listener{l}, Readiness{MaxEventAge: time.Minute})
+
+	// A relay that has just started is ready until MaxEventAge has passed
+	if code, report := get(t, srv, "/readyz"); code != http.StatusOK {
+		t.Errorf("expected status 200 after start, got %d (failures: %v)", code, report.Failures)
+	}
+
+	srv.started = time.Now().Add(-time.Hour)
+	if code, _ := get(t, srv, "/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503 with no events since start, got %d", code)
+	}
+}
+
+func TestHTTPServer_LivenessIgnoresReadiness(t *testing.T) {
+	l := healthyListener("users")
+	l.Accepting = func() bool { return false }
+	srv := NewHTTP(":0", []Listener{l}, Readiness{})
+
+	code, report := get(t, srv, "/healthz")
+	if code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", code)
+	}
+	if report.Status != "ok" || len(report.Failures) != 0 || len(report.Listeners) != 0 {
+		t.Errorf("unexpected liveness report: %+v", report)
+	}
+}
+
+func TestHTTPServer_LivenessSkipsComponents(t *testing.T) {
+	l := Listener{
+		Name:       "users",
+		Accepting:  func() bool { t.Error("liveness should not check accepting"); return true },
+		Storage:    func() error { t.Error("liveness should not probe storage"); return nil },
+		DLQBacklog: func() int64 { t.Error("liveness should not read the DLQ"); return 0 },
+	}
+	srv := NewHTTP(":0", []Listener{l}, Readiness{StorageUnwritable: true})
+
+	if code, _ := get(t, srv, "/healthz"); code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", code)
+	}
+}
+
+func TestHTTPServer_StorageProbeCached(t *testing.T) {
+	probes := 0
+	l := healthyListener("users")
+	l.Storage = func() error {
+		probes++
+		return errors.New("no space left on device")
+	}
+	srv := NewHTTP(":0", []Listener{l}, Readiness{StorageUnwritable: true})
+
+	for i := 0; i < 3; i++ {
+		if code, _ := get(t, srv, "/readyz"); code != http.StatusServiceUnavailable {
+			t.Errorf("expected status 503 while storage is unwritable, got %d", code)
+		}
+	}
+	if probes != 1 {
+		t.Errorf("storage probed %d times, want 1 within the check interval", probes)
+	}
+
+	// Replacing the listeners probes them afresh
+	srv.SetListeners([]Listener{l})
+	get(t, srv, "/readyz")
+	if probes != 2 {
+		t.Errorf("storage probed %d times after SetListeners, want 2", probes)
+	}
+}
+
+func TestHTTPServer_OptionalComponents(t *testing.T) {
+	srv := NewHTTP(":0", []Listener{{Name: "users", Accepting: func() bool { return true }}}, Readiness{CircuitOpen: true, StorageUnwritable: true})
+
+	code, report := get(t, srv, "/readyz")
+	if code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d (failures: %v)", code, report.Failures)
+	}
+	l := report.Listeners[0]
+	if l.DLQBacklogBytes != nil || l.Targets != nil || l.LastEvent != nil {
+		t.Errorf("expected unset components to be omitted: %+v", l)
+	}
+}
+
+func TestHTTPServer_SetListeners(t *testing.T) {
+	srv := NewHTTP(":0", []Listener{healthyListener("users")}, Readiness{})
+	srv.SetListeners([]Listener{healthyListener("users"), healthyListener("status")})
+
+	_, report := get(t, srv, "/readyz")
+	if len(report.Listeners) != 2 || report.Listeners[1].Name != "status" {
+		t.Errorf("expected the replaced listeners, got %+v", report.Listeners)
+	}
+}
+
+func TestHTTPServer_StartStop(t *testing.T) {
+	srv := NewHTTP("127.0.0.1:0", []Listener{healthyListener("users")}, Readiness{})
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	resp, err := http.Get("http://" + srv.listener.Addr().String() + "/healthz")
+	if err != nil {
+		t.Fatalf("failed to fetch /healthz: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+
+	if err := srv.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package logschema
+
+import "github.com/scottbrown/relay/internal/logtypes"
+
+// fields lists the fields of each log type and their JSON types, in the order of the LSS log
+// formats in spec/zpa-logs/*.spec.json. A %j value is a string, %d an integer, %f a number
+// and [%j(,)] an array. TestFields checks that the spec files and this table agree.
+var fields = map[logtypes.LogType][]Field{
+	logtypes.UserActivity: {
+		{"LogTimestamp", String},
+		{"Customer", String},
+		{"SessionID", String},
+		{"ConnectionID", String},
+		{"InternalReason", String},
+		{"ConnectionStatus", String},
+		{"IPProtocol", Integer},
+		{"DoubleEncryption", Integer},
+		{"Username", String},
+		{"ServicePort", Integer},
+		{"ClientPublicIP", String},
+		{"ClientPrivateIP", String},
+		{"ClientLatitude", Number},
+		{"ClientLongitude", Number},
+		{"ClientCountryCode", String},
+		{"ClientZEN", String},
+		{"Policy", String},
+		{"Connector", String},
+		{"ConnectorZEN", String},
+		{"ConnectorIP", String},
+		{"ConnectorPort", Integer},
+		{"Host", String},
+		{"Application", String},
+		{"AppGroup", String},
+		{"Server", String},
+		{"ServerIP", String},
+		{"ServerPort", Integer},
+		{"PolicyProcessingTime", Integer},
+		{"ServerSetupTime", Integer},
+		{"TimestampConnectionStart", String},
+		{"TimestampConnectionEnd", String},
+		{"TimestampCATx", String},
+		{"TimestampCARx", String},
+		{"TimestampAppLearnStart", String},
+		{"TimestampZENFirstRxClient", String},
+		{"TimestampZENFirstTxClient", String},
+		{"TimestampZENLastRxClient", String},
+		{"TimestampZENLastTxClient", String},
+		{"TimestampConnectorZENSetupComplete", String},
+		{"TimestampZENFirstRxConnector", String},
+		{"TimestampZENFirstTxConnector", String},
+		{"TimestampZENLastRxConnector", String},
+		{"TimestampZENLastTxConnector", String},
+		{"ZENTotalBytesRxClient", Integer},
+		{"ZENBytesRxClient", Integer},
+		{"ZENTotalBytesTxClient", Integer},
+		{"ZENBytesTxClient", Integer},
+		{"ZENTotalBytesRxConnector", Integer},
+		{"ZENBytesRxConnector", Integer},
+		{"ZENTotalBytesTxConnector", Integer},
+		{"ZENBytesTxConnector", Integer},
+		{"Idp", String},
+		{"ClientToClient", String},
+		{"ConnectorZENSetupTime", Integer},
+		{"ConnectionSetupTime", Integer},
+	},
+	logtypes.UserStatus: {
+		{"LogTimestamp", String},
+		{"Customer", String},
+		{"Username", String},
+		{"SessionID", String},
+		{"SessionStatus", String},
+		{"Version", String},
+		{"ZEN", String},
+		{"CertificateCN", String},
+		{"PrivateIP", String},
+		{"PublicIP", String},
+		{"Latitude", Number},
+		{"Longitude", Number},
+		{"CountryCode", String},
+		{"TimestampAuthentication", String},
+		{"TimestampUnAuthentication", String},
+		{"TotalBytesRx", Integer},
+		{"TotalBytesTx", Integer},
+		{"Idp", String},
+		{"Hostname", String},
+		{"Platform", String},
+		{"ClientType", String},
+		{"TrustedNetworks", Array},
+		{"TrustedNetworksNames", Array},
+		{"SAMLAttributes", String},
+		{"PosturesHit", Array},
+		{"PosturesMiss", Array},
+		{"ZENLatitude", Number},
+		{"ZENLongitude", Number},
+		{"ZENCountryCode", String},
+		{"FQDNRegistered", String},
+		{"FQDNRegisteredError", String},
+	},
+	logtypes.AppConnectorStatus: {
+		{"LogTimestamp", String},
+		{"Customer", String},
+		{"SessionID", String},
+		{"SessionType", String},
+		{"SessionStatus", String},
+		{"Version", String},
+		{"Platform", String},
+		{"ZEN", String},
+		{"Connector", String},
+		{"ConnectorGroup", String},
+		{"PrivateIP", String},
+		{"PublicIP", String},
+		{"Latitude", Number},
+		{"Longitude", Number},
+		{"CountryCode", String},
+		{"TimestampAuthentication", String},
+		{"TimestampUnAuthentication", String},
+		{"CPUUtilization", Integer},
+		{"MemUtilization", Integer},
+		{"ServiceCount", Integer},
+		{"InterfaceDefRoute", String},
+		{"DefRouteGW", String},
+		{"PrimaryDNSResolver", String},
+		{"HostStartTime", String},
+		{"ConnectorStartTime", String},
+		{"NumOfInterfaces", Integer},
+		{"BytesRxInterface", Integer},
+		{"PacketsRxInterface", Integer},
+		{"ErrorsRxInterface", Integer},
+		{"DiscardsRxInterface", Integer},
+		{"BytesTxInterface", Integer},
+		{"PacketsTxInterface", Integer},
+		{"ErrorsTxInterface", Integer},
+		{"DiscardsTxInterface", Integer},
+		{"TotalBytesRx", Integer},
+		{"TotalBytesTx", Integer},
+	},
+	logtypes.Audit: {
+		{"ModifiedTime", String},
+		{"CreationTime", String},
+		{"ModifiedBy", Integer},
+		{"RequestID", String},
+		{"SessionID", String},
+		{"AuditOldValue", String},
+		{"AuditNewValue", String},
+		{"AuditOperationType", String},
+		{"ObjectType", String},
+		{"ObjectName", String},
+		{"ObjectID", Integer},
+		{"CustomerID", Integer},
+		{"User", String},
+		{"ClientAuditUpdate", Integer},
+	},
+	logtypes.BrowserAccess: {
+		{"LogTimestamp", String},
+		{"ConnectionID", String},
+		{"Exporter", String},
+		{"TimestampRequestReceiveStart", String},
+		{"TimestampRequestReceiveHeaderFinish", String},
+		{"TimestampRequestReceiveFinish", String},
+		{"TimestampRequestTransmitStart", String},
+		{"TimestampRequestTransmitFinish", String},
+		{"TimestampResponseReceiveStart", String},
+		{"TimestampResponseReceiveFinish", String},
+		{"TimestampResponseTransmitStart", String},
+		{"TimestampResponseTransmitFinish", String},
+		{"TotalTimeRequestReceive", Integer},
+		{"TotalTimeRequestTransmit", Integer},
+		{"TotalTimeResponseReceive", Integer},
+		{"TotalTimeResponseTransmit", Integer},
+		{"TotalTimeConnectionSetup", Integer},
+		{"TotalTimeServerResponse", Integer},
+		{"Method", String},
+		{"Protocol", String},
+		{"Host", String},
+		{"URL", String},
+		{"UserAgent", String},
+		{"XFF", String},
+		{"NameID", String},
+		{"StatusCode", Integer},
+		{"RequestSize", Integer},
+		{"ResponseSize", Integer},
+		{"ApplicationPort", Integer},
+		{"ClientPublicIp", String},
+		{"ClientPublicPort", Integer},
+		{"ClientPrivateIp", String},
+		{"Customer", String},
+		{"ConnectionStatus", String},
+		{"ConnectionReason", String},
+		{"Origin", String},
+		{"CorsToken", String},
+	},
+}
@@ -0,0 +1,180 @@
+// Package logschema checks ZPA log lines against the fields and types of their LSS log format.
+// It detects schema drift, such as Zscaler adding, renaming or retyping fields, so drifted lines
+// can be counted and kept out of Splunk before they break searches and dashboards.
+package logschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/scottbrown/relay/internal/logtypes"
+)
+
+// Type is the JSON type of a field.
+type Type string
+
+const (
+	// String fields are JSON strings (%j in the LSS log format), including timestamps.
+	String Type = "string"
+	// Integer fields are JSON numbers without a fraction or exponent (%d).
+	Integer Type = "integer"
+	// Number fields are any JSON number (%f).
+	Number Type = "number"
+	// Array fields are JSON arrays ([%j(,)]).
+	Array Type = "array"
+)
+
+// Field is a field of a log type's format.
+type Field struct {
+	Name string
+	Type Type
+}
+
+// Kind describes how a line differs from its schema.
+type Kind string
+
+const (
+	// UnexpectedField is a field the log format does not define, such as a field Zscaler added.
+	UnexpectedField Kind = "unexpected_field"
+	// MissingField is a field of the log format the line does not have, such as a renamed field.
+	MissingField Kind = "missing_field"
+	// WrongType is a field whose value has a different JSON type than the log format defines.
+	WrongType Kind = "wrong_type"
+	// NotObject is a line that is valid JSON but not a JSON object.
+	NotObject Kind = "not_object"
+)
+
+// Problem is one difference between a line and its schema.
+type Problem struct {
+	Kind     Kind
+	Field    string // Empty for NotObject
+	Expected Type   // Set for WrongType
+	Actual   string // JSON type of the value, set for WrongType
+}
+
+// String returns a description of the problem for logs.
+func (p Problem) String() string {
+	switch p.Kind {
+	case UnexpectedField:
+		return "unexpected field " + p.Field
+	case MissingField:
+		return "missing field " + p.Field
+	case WrongType:
+		return fmt.Sprintf("field %s is %s, expected %s", p.Field, p.Actual, p.Expected)
+	default:
+		return "line is not a JSON object"
+	}
+}
+
+// Schema is the expected format of one log type.
+//
+// Schema is safe for concurrent use by multiple goroutines.
+type Schema struct {
+	logType logtypes.LogType
+	fields  map[string]Type
+	order   []string // Field names in log format order
+}
+
+// For returns the schema of a log type. It returns false for log types without an LSS
+// format in spec/zpa-logs, such as the metrics log types.
+func For(lt logtypes.LogType) (*Schema, bool) {
+	list, ok := fields[lt]
+	if !ok {
+		return nil, false
+	}
+
+	s := &Schema{
+		logType: lt,
+		fields:  make(map[string]Type, len(list)),
+		order:   make([]string, 0, len(list)),
+	}
+	for _, f := range list {
+		s.fields[f.Name] = f.Type
+		s.order = append(s.order, f.Name)
+	}
+	return s, true
+}
+
+// Supported returns the log types that have a schema.
+func Supported() []logtypes.LogType {
+	var supported []logtypes.LogType
+	for _, lt := range logtypes.All() {
+		if _, ok := fields[lt]; ok {
+			supported = append(supported, lt)
+		}
+	}
+	return supported
+}
+
+// LogType returns the log type the schema describes.
+func (s *Schema) LogType() logtypes.LogType {
+	return s.logType
+}
+
+// Check compares a line, which must be valid JSON, with the schema. It returns nil if the
+// line has exactly the schema's fields with the expected types. Missing and mistyped fields
+// are reported in log format order, followed by unexpected fields sorted by name.
+func (s *Schema) Check(line []byte) []Problem {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(line, &values); err != nil || values == nil {
+		return []Problem{{Kind: NotObject}}
+	}
+
+	var problems []Problem
+	for _, name := range s.order {
+		value, ok := values[name]
+		if !ok {
+			problems = append(problems, Problem{Kind: MissingField, Field: name})
+			continue
+		}
+		expected := s.fields[name]
+		if actual := typeOf(value); !matches(expected, actual) {
+			problems = append(problems, Problem{Kind: WrongType, Field: name, Expected: expected, Actual: actual})
+		}
+	}
+
+	var unexpected []string
+	for name := range values {
+		if _, ok := s.fields[name]; !ok {
+			unexpected = append(unexpected, name)
+		}
+	}
+	sort.Strings(unexpected)
+	for _, name := range unexpected {
+		problems = append(problems, Problem{Kind: UnexpectedField, Field: name})
+	}
+	return problems
+}
+
+// typeOf returns the JSON type of a valid JSON value. Numbers are reported as integer
+// when they have no fraction or exponent.
+func typeOf(value json.RawMessage) string {
+	value = bytes.TrimSpace(value)
+	if len(value) == 0 {
+		return "null"
+	}
+	switch value[0] {
+	case '"':
+		return string(String)
+	case '[':
+		return string(Array)
+	case '{':
+		return "object"
+	case 't', 'f':
+		return "boolean"
+	case 'n':
+		return "null"
+	}
+	if bytes.ContainsAny(value, ".eE") {
+		return string(Number)
+	}
+	return string(Integer)
+}
+
+// matches reports whether a value of the actual JSON type is valid for the expected type.
+// An integer is a valid number.
+func matches(expected Type, actual string) bool {
+	return actual == string(expected) || (expected == Number && actual == string(Integer))
+}
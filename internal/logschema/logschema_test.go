@@ -0,0 +1,202 @@
+package logschema
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/scottbrown/relay/internal/logtypes"
+	"github.com/scottbrown/relay/internal/testutil/fixtures"
+)
+
+// specField matches a field of an LSS log format, such as "Latitude": %f{Latitude}
+// or "TrustedNetworks": [%j(,){TrustedNetworks}].
+var specField = regexp.MustCompile(`"(\w+)":\s*(\[?)%([jdf])`)
+
+func TestFields(t *testing.T) {
+	specTypes := map[string]Type{"j": String, "d": Integer, "f": Number, "[j": Array}
+
+	for _, lt := range Supported() {
+		t.Run(string(lt), func(t *testing.T) {
+			// #nosec G304 -- SpecPath builds paths inside the repository's spec/ directory.
+			spec, err := os.ReadFile(fixtures.SpecPath(string(lt) + "-logs.spec.json"))
+			if err != nil {
+				t.Fatalf("failed to read spec file: %v", err)
+			}
+
+			var expected []Field
+			for _, m := range specField.FindAllStringSubmatch(string(spec), -1) {
+				expected = append(expected, Field{Name: m[1], Type: specTypes[m[2]+m[3]]})
+			}
+			if !reflect.DeepEqual(fields[lt], expected) {
+				t.Errorf("fields do not match the spec file:\n got: %v\nwant: %v", fields[lt], expected)
+			}
+		})
+	}
+}
+
+func TestSupported(t *testing.T) {
+	expected := []logtypes.LogType{
+		logtypes.UserActivity, logtypes.UserStatus, logtypes.AppConnectorStatus,
+		logtypes.BrowserAccess, logtypes.Audit,
+	}
+	if got := Supported(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Supported() = %v, want %v", got, expected)
+	}
+
+	if _, ok := For(logtypes.PSEMetrics); ok {
+		t.Error("For(pse-metrics) should report no schema")
+	}
+}
+
+// auditLine returns an audit log line with the given fields replaced or added.
+// An empty value removes the field.
+func auditLine(t *testing.T, changes map[string]string) []byte {
+	t.Helper()
+	values := map[string]string{
+		"ModifiedTime":       `"2021-11-17T04:29:38.000Z"`,
+		"CreationTime":       `"2021-11-17T04:29:38.000Z"`,
+		"ModifiedBy":         `12345678901234567`,
+		"RequestID":          `"11111111-1111-1111-1111-111111111111"`,
+		"SessionID":          `"1idn23nlfm2q1txa5h3r4mep6"`,
+		"AuditOldValue":      `""`,
+		"AuditNewValue":      `"{}"`,
+		"AuditOperationType": `"Create"`,
+		"ObjectType":         `"Server"`,
+		"ObjectName":         `"Some-Name"`,
+		"ObjectID":           `12345678901234567`,
+		"CustomerID":         `98765432109876543`,
+		"User":               `"zpaadmin@example.com"`,
+		"ClientAuditUpdate":  `0`,
+	}
+	for name, value := range changes {
+		if value == "" {
+			delete(values, name)
+			continue
+		}
+		values[name] = value
+	}
+
+	var b bytes.Buffer
+	b.WriteString("{")
+	first := true
+	for name, value := range values {
+		if !first {
+			b.WriteString(",")
+		}
+		first = false
+		b.WriteString(`"` + name + `":` + value)
+	}
+	b.WriteString("}")
+	return b.Bytes()
+}
+
+func TestCheck(t *testing.T) {
+	schema, ok := For(logtypes.Audit)
+	if !ok {
+		t.Fatal("For(audit) should return a schema")
+	}
+
+	tests := []struct {
+		name     string
+		line     []byte
+		expected []string
+	}{
+		{
+			name: "matching line",
+			line: auditLine(t, nil),
+		},
+		{
+			name:     "added field",
+			line:     auditLine(t, map[string]string{"TenantID": `"abc"`}),
+			expected: []string{"unexpected field TenantID"},
+		},
+		{
+			name:     "renamed field",
+			line:     auditLine(t, map[string]string{"User": "", "Username": `"zpaadmin@example.com"`}),
+			expected: []string{"missing field User", "unexpected field Username"},
+		},
+		{
+			name:     "string instead of integer",
+			line:     auditLine(t, map[string]string{"ObjectID": `"12345678901234567"`}),
+			expected: []string{"field ObjectID is string, expected integer"},
+		},
+		{
+			name:     "fraction for integer",
+			line:     auditLine(t, map[string]string{"ClientAuditUpdate": `1.5`}),
+			expected: []string{"field ClientAuditUpdate is number, expected integer"},
+		},
+		{
+			name:     "null value",
+			line:     auditLine(t, map[string]string{"ObjectName": `null`}),
+			expected: []string{"field ObjectName is null, expected string"},
+		},
+		{
+			name:     "not an object",
+			line:     []byte(`["ModifiedTime"]`),
+			expected: []string{"line is not a JSON object"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, p := range schema.Check(tt.line) {
+				got = append(got, p.String())
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Check() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCheck_NumberAcceptsInteger(t *testing.T) {
+	schema, _ := For(logtypes.UserStatus)
+	for _, p := range schema.Check([]byte(`{"Latitude":45,"Longitude":-119.5}`)) {
+		if p.Kind == WrongType {
+			t.Errorf("unexpected problem %v", p)
+		}
+	}
+}
+
+func TestCheck_Examples(t *testing.T) {
+	// The example lines in spec/zpa-logs were captured from older LSS formats than the
+	// spec files, so they show the kinds of drift the check is meant to catch.
+	expected := map[logtypes.LogType][]string{
+		logtypes.UserActivity:       {"missing field ClientToClient", "unexpected field AppLearnTime", "unexpected field CAProcessingTime"},
+		logtypes.AppConnectorStatus: nil,
+		logtypes.Audit:              nil,
+		logtypes.BrowserAccess:      {"missing field Origin", "missing field CorsToken"},
+		logtypes.UserStatus: {
+			"field TrustedNetworks is string, expected array",
+			"field TrustedNetworksNames is string, expected array",
+			"field PosturesHit is string, expected array",
+			"field PosturesMiss is string, expected array",
+			"missing field FQDNRegistered",
+			"missing field FQDNRegisteredError",
+		},
+	}
+
+	for _, lt := range Supported() {
+		t.Run(string(lt), func(t *testing.T) {
+			// #nosec G304 -- SpecPath builds paths inside the repository's spec/ directory.
+			example, err := os.ReadFile(fixtures.SpecPath(string(lt) + "-logs.example.json"))
+			if err != nil {
+				t.Fatalf("failed to read example file: %v", err)
+			}
+
+			schema, _ := For(lt)
+			var got []string
+			for _, p := range schema.Check([]byte(strings.TrimSpace(string(example)))) {
+				got = append(got, p.String())
+			}
+			if !reflect.DeepEqual(got, expected[lt]) {
+				t.Errorf("Check() = %v, want %v", got, expected[lt])
+			}
+		})
+	}
+}
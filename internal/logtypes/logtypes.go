@@ -27,6 +27,14 @@ const (
 	PSEMetrics LogType = "pse-metrics"
 )
 
+// All returns every defined ZPA log type.
+func All() []LogType {
+	return []LogType{
+		UserActivity, UserStatus, AppConnectorStatus, PSEStatus,
+		BrowserAccess, Audit, AppConnectorMetrics, PSEMetrics,
+	}
+}
+
 // IsValid returns true if the log type matches one of the defined ZPA log types.
 func (lt LogType) IsValid() bool {
 	switch lt {
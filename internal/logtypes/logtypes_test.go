@@ -85,3 +85,15 @@ func TestLogType_String(t *testing.T) {
 		t.Errorf("LogType.String() = %v, want %v", got, "user-activity")
 	}
 }
+
+func TestAll(t *testing.T) {
+	all := All()
+	if len(all) != 8 {
+		t.Errorf("expected 8 log types, got %d", len(all))
+	}
+	for _, lt := range all {
+		if !lt.IsValid() {
+			t.Errorf("All() returned invalid log type %q", lt)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"expvar"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Histogram counts observations in buckets, with one series per combination of label values.
+// It implements expvar.Var, so it is published in /debug/vars as well as /metrics.
+//
+// Histogram is safe for concurrent use by multiple goroutines.
+type Histogram struct {
+	labels  []string  // Label names, e.g. "listener" and "target"
+	buckets []float64 // Upper bounds, in increasing order
+
+	mu     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+// histogramSeries holds the observations for one combination of label values.
+type histogramSeries struct {
+	values []string
+	counts []uint64 // Per-bucket (non-cumulative) counts; the last entry is +Inf
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram creates a histogram and publishes it under name.
+func NewHistogram(name string, labels []string, buckets []float64) *Histogram {
+	h := &Histogram{
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*histogramSeries),
+	}
+	expvar.Publish(name, h)
+	return h
+}
+
+// Observe records a value for the given label values.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	if len(labelValues) != len(h.labels) {
+		panic("metrics: wrong number of label values")
+	}
+	key := seriesKey(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{
+			values: append([]string(nil), labelValues...),
+			counts: make([]uint64, len(h.buckets)+1),
+		}
+		h.series[key] = s
+	}
+	i := sort.SearchFloat64s(h.buckets, v) // First bucket with an upper bound >= v
+	s.counts[i]++
+	s.sum += v
+	s.count++
+}
+
+// histogramSnapshot is a point-in-time copy of one series, with cumulative bucket counts.
+type histogramSnapshot struct {
+	labelValues []string
+	cumulative  []uint64 // Cumulative count for each bucket; the last entry is +Inf
+	sum         float64
+	count       uint64
+}
+
+// snapshot returns a copy of every series, sorted by label values.
+func (h *Histogram) snapshot() []histogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	snaps := make([]histogramSnapshot, 0, len(h.series))
+	for _, s := range h.series {
+		cumulative := make([]uint64, len(s.counts))
+		var total uint64
+		for i, c := range s.counts {
+			total += c
+			cumulative[i] = total
+		}
+		snaps = append(snaps, histogramSnapshot{
+			labelValues: s.values,
+			cumulative:  cumulative,
+			sum:         s.sum,
+			count:       s.count,
+		})
+	}
+	sort.Slice(snaps, func(i, j int) bool {
+		return seriesKey(snaps[i].labelValues) < seriesKey(snaps[j].labelValues)
+	})
+	return snaps
+}
+
+// bucketLabel returns the "le" label for bucket i.
+func (h *Histogram) bucketLabel(i int) string {
+	if i == len(h.buckets) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(h.buckets[i], 'g', -1, 64)
+}
+
+// String returns the histogram as JSON objects nested by label, for expvar.
+func (h *Histogram) String() string {
+	type jsonSeries struct {
+		Count   uint64            `json:"count"`
+		Sum     float64           `json:"sum"`
+		Buckets map[string]uint64 `json:"buckets"`
+	}
+
+	out := make(map[string]interface{})
+	for _, s := range h.snapshot() {
+		buckets := make(map[string]uint64, len(s.cumulative))
+		for i, c := range s.cumulative {
+			buckets[h.bucketLabel(i)] = c
+		}
+		setNested(out, s.labelValues, jsonSeries{Count: s.count, Sum: s.sum, Buckets: buckets})
+	}
+	return marshalNested(out)
+}
@@ -11,6 +11,11 @@ var (
 	ConnectionsRejected = expvar.NewInt("connections_rejected")
 	ConnectionsActive   = expvar.NewInt("connections_active")
 	BytesReceived       = expvar.NewInt("bytes_received_total")
+	TLSClientAuth       = expvar.NewMap("tls_client_auth") // Client certificate checks by outcome (success, failure)
+
+	// TLS certificate metrics
+	TLSCertExpiry  = expvar.NewMap("tls_cert_expiry_seconds") // Listener certificate expiry (Unix timestamp), by listener
+	TLSCertReloads = expvar.NewMap("tls_cert_reloads")        // Certificate reloads by outcome (success, failure)
 
 	// Storage metrics
 	StorageWrites        = expvar.NewMap("storage_writes")
@@ -21,15 +26,97 @@ var (
 	HecForwards       = expvar.NewMap("hec_forwards")
 	HecBytesForwarded = expvar.NewInt("hec_bytes_forwarded")
 	HecRetries        = expvar.NewInt("hec_retries_total")
+	HecAcks           = expvar.NewMap("hec_acks")     // Indexer acknowledgements by outcome (success, failure)
+	HecReroutes       = expvar.NewMap("hec_reroutes") // Lines moved off their weighted or hash target, by reason (paused, circuit_open, failed)
+
+	// HEC forwarder histograms, by listener and target
+	HecRequestDuration = NewHistogram("hec_request_duration_seconds", []string{"listener", "target"},
+		[]float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}) // Duration of each HEC request attempt
+	HecBatchLines = NewHistogram("hec_batch_lines", []string{"listener", "target"},
+		[]float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500}) // Lines per flushed batch
 
 	// Processing metrics
 	LinesProcessed = expvar.NewMap("lines_processed")
+	SchemaDrift    = expvar.NewMap("schema_drift") // Lines that do not match their log type's schema, by kind of drift
+
+	// Quarantine metrics
+	QuarantineLines = expvar.NewMap("quarantine_lines")         // Quarantined lines by reason (invalid_json, oversized, schema_drift)
+	QuarantineBytes = expvar.NewInt("quarantine_bytes_written") // Bytes written to quarantine files
+
+	// Filter metrics
+	FilterHits = expvar.NewMap("filter_hits") // Lines matched by filter rules, by action (drop, drop_forward, sample, route)
+
+	// Forward queue metrics
+	QueueBytes        = expvar.NewMap("queue_bytes")              // Unacknowledged bytes, by listener
+	QueueBackpressure = expvar.NewInt("queue_backpressure_total") // Appends that blocked on a full queue
+
+	// Storage tailer metrics
+	TailBatches  = expvar.NewMap("tail_batches")   // Tail batches sent by outcome (success, failure)
+	TailLines    = expvar.NewMap("tail_lines")     // Lines forwarded from storage, by listener
+	TailLagBytes = expvar.NewMap("tail_lag_bytes") // Stored bytes not yet forwarded, by listener
+
+	// DLQ drain metrics
+	DLQDrained      = expvar.NewMap("dlq_drained")       // Drained entries by outcome (success, failure, malformed)
+	DLQBacklogBytes = expvar.NewMap("dlq_backlog_bytes") // Bytes still to drain, by listener
+	DLQBacklogFiles = expvar.NewMap("dlq_backlog_files") // DLQ files still to drain, by listener
+
+	// Per-listener breakdowns; each also adds to the process-wide metric above
+	ListenerConnectionsAccepted  = NewIntVec("connections_accepted_by_listener", ConnectionsAccepted, "listener")
+	ListenerConnectionsRejected  = NewIntVec("connections_rejected_by_listener", ConnectionsRejected, "listener")
+	ListenerConnectionsActive    = NewIntVec("connections_active_by_listener", ConnectionsActive, "listener")
+	ListenerBytesReceived        = NewIntVec("bytes_received_by_listener", BytesReceived, "listener")
+	ListenerTLSClientAuth        = NewIntVec("tls_client_auth_by_listener", TLSClientAuth, "listener", "outcome")
+	ListenerStorageWrites        = NewIntVec("storage_writes_by_listener", StorageWrites, "listener", "outcome")
+	ListenerStorageBytesWritten  = NewIntVec("storage_bytes_written_by_listener", StorageBytesWritten, "listener")
+	ListenerStorageFileRotations = NewIntVec("storage_file_rotations_by_listener", StorageFileRotations, "listener")
+	ListenerLinesProcessed       = NewIntVec("lines_processed_by_listener", LinesProcessed, "listener", "outcome")
+	ListenerSchemaDrift          = NewIntVec("schema_drift_by_listener", SchemaDrift, "listener", "kind")
+	ListenerQuarantineLines      = NewIntVec("quarantine_lines_by_listener", QuarantineLines, "listener", "reason")
+	ListenerQuarantineBytes      = NewIntVec("quarantine_bytes_written_by_listener", QuarantineBytes, "listener")
+	ListenerFilterHits           = NewIntVec("filter_hits_by_listener", FilterHits, "listener", "rule", "action")
+	ListenerQueueBackpressure    = NewIntVec("queue_backpressure_by_listener", QueueBackpressure, "listener")
+	ListenerTailBatches          = NewIntVec("tail_batches_by_listener", TailBatches, "listener", "outcome")
+	ListenerDLQDrained           = NewIntVec("dlq_drained_by_listener", DLQDrained, "listener", "outcome")
+
+	// Per-target HEC breakdowns; each also adds to the process-wide metric above
+	TargetHecForwards       = NewIntVec("hec_forwards_by_target", HecForwards, "listener", "target", "outcome")
+	TargetHecBytesForwarded = NewIntVec("hec_bytes_forwarded_by_target", HecBytesForwarded, "listener", "target")
+	TargetHecRetries        = NewIntVec("hec_retries_by_target", HecRetries, "listener", "target")
+	TargetHecAcks           = NewIntVec("hec_acks_by_target", HecAcks, "listener", "target", "outcome")
+	TargetHecReroutes       = NewIntVec("hec_reroutes_by_target", HecReroutes, "listener", "target", "reason")
 
 	// System metrics
 	StartTime = expvar.NewInt("start_time_seconds")
 	Version   = expvar.NewString("version_info")
+	Listeners = expvar.NewMap("listener_log_types") // Log type of each listener, by listener
 )
 
+// RegisterListener records a listener's log type.
+// Exporters use it to label per-listener metrics with the log type.
+func RegisterListener(name, logType string) {
+	v := new(expvar.String)
+	v.Set(logType)
+	Listeners.Set(name, v)
+}
+
+// UnregisterListener removes a listener's log type, for example when a reload removes the listener.
+// Its counters are kept so totals do not go backwards.
+func UnregisterListener(name string) {
+	Listeners.Delete(name)
+}
+
+// SetMapInt sets an integer value in an expvar map, creating the entry if needed.
+// It is used for gauges keyed by listener.
+func SetMapInt(m *expvar.Map, key string, value int64) {
+	if v, ok := m.Get(key).(*expvar.Int); ok {
+		v.Set(value)
+		return
+	}
+	v := new(expvar.Int)
+	v.Set(value)
+	m.Set(key, v)
+}
+
 // Init initialises system metrics that should be set once at startup.
 func Init(versionString string) {
 	StartTime.Set(time.Now().Unix())
@@ -70,6 +70,19 @@ func TestMetricsMap(t *testing.T) {
 	// This is tested in TestStartServer
 }
 
+func TestSetMapInt(t *testing.T) {
+	SetMapInt(DLQBacklogBytes, "test-listener", 42)
+	SetMapInt(DLQBacklogBytes, "test-listener", 7)
+
+	v, ok := DLQBacklogBytes.Get("test-listener").(interface{ Value() int64 })
+	if !ok {
+		t.Fatal("expected test-listener entry to be an integer")
+	}
+	if v.Value() != 7 {
+		t.Errorf("expected value 7, got %d", v.Value())
+	}
+}
+
 func TestStartServer(t *testing.T) {
 	// Use a unique port for testing
 	testAddr := ":19998"
@@ -0,0 +1,165 @@
+package metrics
+
+import (
+	"bufio"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Prometheus metric types.
+const (
+	promCounter   = "counter"
+	promGauge     = "gauge"
+	promHistogram = "histogram"
+)
+
+// Label names for expvar.Map keys.
+const (
+	labelOutcome  = "outcome"
+	labelListener = "listener"
+)
+
+// promMetric describes how an expvar metric is exposed in the Prometheus text format.
+type promMetric struct {
+	name  string // Prometheus name, without the relay_ prefix
+	help  string
+	kind  string // promCounter, promGauge or promHistogram
+	label string // Label for the keys of an expvar.Map ("" otherwise)
+	v     expvar.Var
+}
+
+// promMetrics lists every metric exposed at /metrics, in output order.
+// Every variable in this package must be listed here, directly or as the total of an IntVec;
+// a metric with a per-listener or per-target breakdown is exposed through its IntVec.
+var promMetrics = []promMetric{
+	{"connections_accepted_total", "Total connections accepted.", promCounter, "", ListenerConnectionsAccepted},
+	{"connections_rejected_total", "Total connections rejected by the ACL.", promCounter, "", ListenerConnectionsRejected},
+	{"connections_active", "Currently active connections.", promGauge, "", ListenerConnectionsActive},
+	{"bytes_received_total", "Total bytes received from clients.", promCounter, "", ListenerBytesReceived},
+	{"tls_client_auth_total", "Client certificate checks by outcome.", promCounter, "", ListenerTLSClientAuth},
+	{"tls_cert_expiry_timestamp_seconds", "Listener certificate expiry as a Unix timestamp.", promGauge, labelListener, TLSCertExpiry},
+	{"tls_cert_reloads_total", "Listener certificate reloads by outcome.", promCounter, labelOutcome, TLSCertReloads},
+	{"storage_writes_total", "Storage writes by outcome.", promCounter, "", ListenerStorageWrites},
+	{"storage_bytes_written_total", "Total bytes written to local storage.", promCounter, "", ListenerStorageBytesWritten},
+	{"storage_file_rotations_total", "Total daily file rotations.", promCounter, "", ListenerStorageFileRotations},
+	{"hec_forwards_total", "HEC forwards by outcome.", promCounter, "", TargetHecForwards},
+	{"hec_bytes_forwarded_total", "Total bytes forwarded to Splunk HEC.", promCounter, "", TargetHecBytesForwarded},
+	{"hec_retries_total", "Total HEC retry attempts.", promCounter, "", TargetHecRetries},
+	{"hec_acks_total", "HEC indexer acknowledgements by outcome.", promCounter, "", TargetHecAcks},
+	{"hec_reroutes_total", "Lines moved off their weighted or hash target, by reason.", promCounter, "", TargetHecReroutes},
+	{"hec_request_duration_seconds", "Duration of HEC request attempts.", promHistogram, "", HecRequestDuration},
+	{"hec_batch_lines", "Lines per flushed HEC batch.", promHistogram, "", HecBatchLines},
+	{"lines_processed_total", "Lines processed by outcome.", promCounter, "", ListenerLinesProcessed},
+	{"schema_drift_total", "Lines that do not match their log type's schema, by kind of drift.", promCounter, "", ListenerSchemaDrift},
+	{"quarantine_lines_total", "Quarantined lines by reason.", promCounter, "", ListenerQuarantineLines},
+	{"quarantine_bytes_written_total", "Total bytes written to quarantine files.", promCounter, "", ListenerQuarantineBytes},
+	{"filter_hits_total", "Lines matched by filter rules, by rule and action.", promCounter, "", ListenerFilterHits},
+	{"queue_bytes", "Forward queue bytes not yet delivered.", promGauge, labelListener, QueueBytes},
+	{"queue_backpressure_total", "Appends that blocked on a full forward queue.", promCounter, "", ListenerQueueBackpressure},
+	{"tail_batches_total", "Tail mode batches by outcome.", promCounter, "", ListenerTailBatches},
+	{"tail_lines_total", "Lines forwarded from storage in tail mode.", promCounter, labelListener, TailLines},
+	{"tail_lag_bytes", "Stored bytes not yet forwarded in tail mode.", promGauge, labelListener, TailLagBytes},
+	{"dlq_drained_total", "DLQ entries drained by outcome.", promCounter, "", ListenerDLQDrained},
+	{"dlq_backlog_bytes", "DLQ bytes still to drain.", promGauge, labelListener, DLQBacklogBytes},
+	{"dlq_backlog_files", "DLQ files still to drain.", promGauge, labelListener, DLQBacklogFiles},
+	{"start_time_seconds", "Service start time as a Unix timestamp.", promGauge, "", StartTime},
+}
+
+// PrometheusHandler returns an HTTP handler serving all metrics in the Prometheus text format.
+func PrometheusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = WritePrometheus(w) // Client disconnects are not actionable
+	})
+}
+
+// WritePrometheus writes all metrics in the Prometheus text format.
+// Per-listener series are also labelled with the listener's log type (see RegisterListener).
+func WritePrometheus(out io.Writer) error {
+	w := bufio.NewWriter(out)
+	logTypes := listenerLogTypes()
+
+	header := func(name, help, kind string) {
+		fmt.Fprintf(w, "# HELP relay_%s %s\n# TYPE relay_%s %s\n", name, help, name, kind)
+	}
+
+	header("build_info", "Relay version.", promGauge)
+	fmt.Fprintf(w, "relay_build_info{version=%s} 1\n", quoteLabel(Version.Value()))
+
+	header("listener_info", "Log type of each listener.", promGauge)
+	Listeners.Do(func(kv expvar.KeyValue) {
+		fmt.Fprintf(w, "relay_listener_info{listener=%s,log_type=%s} 1\n", quoteLabel(kv.Key), quoteLabel(logTypes[kv.Key]))
+	})
+
+	for _, m := range promMetrics {
+		header(m.name, m.help, m.kind)
+		switch v := m.v.(type) {
+		case *Histogram:
+			writeHistogram(w, m.name, v, logTypes)
+		case *IntVec:
+			v.do(func(labelValues []string, value int64) {
+				fmt.Fprintf(w, "relay_%s{%s} %d\n", m.name, formatLabels(v.labels, labelValues, logTypes), value)
+			})
+		case *expvar.Map:
+			v.Do(func(kv expvar.KeyValue) {
+				labels := formatLabels([]string{m.label}, []string{kv.Key}, logTypes)
+				fmt.Fprintf(w, "relay_%s{%s} %s\n", m.name, labels, kv.Value.String())
+			})
+		default:
+			fmt.Fprintf(w, "relay_%s %s\n", m.name, v.String())
+		}
+	}
+
+	return w.Flush()
+}
+
+// writeHistogram writes the bucket, sum and count series of a histogram.
+func writeHistogram(w io.Writer, name string, h *Histogram, logTypes map[string]string) {
+	for _, s := range h.snapshot() {
+		labels := formatLabels(h.labels, s.labelValues, logTypes)
+		for i, c := range s.cumulative {
+			fmt.Fprintf(w, "relay_%s_bucket{%s,le=%q} %d\n", name, labels, h.bucketLabel(i), c)
+		}
+		fmt.Fprintf(w, "relay_%s_sum{%s} %s\n", name, labels, strconv.FormatFloat(s.sum, 'g', -1, 64))
+		fmt.Fprintf(w, "relay_%s_count{%s} %d\n", name, labels, s.count)
+	}
+}
+
+// formatLabels returns the label pairs of a series. A listener label is followed by
+// the listener's log type, if registered.
+func formatLabels(names, values []string, logTypes map[string]string) string {
+	pairs := make([]string, 0, len(names)+1)
+	for i, name := range names {
+		pairs = append(pairs, name+"="+quoteLabel(values[i]))
+		if name != labelListener {
+			continue
+		}
+		if logType, ok := logTypes[values[i]]; ok {
+			pairs = append(pairs, "log_type="+quoteLabel(logType))
+		}
+	}
+	return strings.Join(pairs, ",")
+}
+
+// listenerLogTypes returns the registered log type of each listener.
+func listenerLogTypes() map[string]string {
+	logTypes := make(map[string]string)
+	Listeners.Do(func(kv expvar.KeyValue) {
+		if s, ok := kv.Value.(*expvar.String); ok {
+			logTypes[kv.Key] = s.Value()
+		}
+	})
+	return logTypes
+}
+
+// labelEscaper escapes label values as required by the text format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// quoteLabel returns a label value quoted and escaped for the text format.
+func quoteLabel(s string) string {
+	return `"` + labelEscaper.Replace(s) + `"`
+}
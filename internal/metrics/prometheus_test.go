@@ -0,0 +1,146 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistogram_Observe(t *testing.T) {
+	h := &Histogram{labels: []string{"target"}, buckets: []float64{1, 5}, series: make(map[string]*histogramSeries)}
+
+	h.Observe(0.5, "primary")
+	h.Observe(1, "primary") // Upper bounds are inclusive
+	h.Observe(3, "primary")
+	h.Observe(10, "primary")
+
+	snaps := h.snapshot()
+	if len(snaps) != 1 {
+		t.Fatalf("expected 1 series, got %d", len(snaps))
+	}
+	s := snaps[0]
+	want := []uint64{2, 3, 4}
+	for i, c := range s.cumulative {
+		if c != want[i] {
+			t.Errorf("bucket %s = %d, want %d", h.bucketLabel(i), c, want[i])
+		}
+	}
+	if s.count != 4 || s.sum != 14.5 {
+		t.Errorf("count = %d, sum = %v, want 4 and 14.5", s.count, s.sum)
+	}
+
+	var decoded map[string]struct {
+		Count   uint64            `json:"count"`
+		Buckets map[string]uint64 `json:"buckets"`
+	}
+	if err := json.Unmarshal([]byte(h.String()), &decoded); err != nil {
+		t.Fatalf("String() is not valid JSON: %v", err)
+	}
+	if decoded["primary"].Count != 4 || decoded["primary"].Buckets["+Inf"] != 4 {
+		t.Errorf("unexpected expvar output: %s", h.String())
+	}
+}
+
+func TestWritePrometheus(t *testing.T) {
+	RegisterListener("prom-test", "user-activity")
+	SetMapInt(QueueBytes, "prom-test", 512)
+	ListenerTLSClientAuth.Add(1, "prom-test", "success")
+	HecRequestDuration.Observe(0.02, "prom-test", "prom-target")
+
+	var buf bytes.Buffer
+	if err := WritePrometheus(&buf); err != nil {
+		t.Fatalf("WritePrometheus() error = %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"# TYPE relay_connections_accepted_total counter\n",
+		"# TYPE relay_queue_bytes gauge\n",
+		"# TYPE relay_hec_request_duration_seconds histogram\n",
+		`relay_listener_info{listener="prom-test",log_type="user-activity"} 1` + "\n",
+		`relay_queue_bytes{listener="prom-test",log_type="user-activity"} 512` + "\n",
+		`relay_tls_client_auth_total{listener="prom-test",log_type="user-activity",outcome="success"} 1` + "\n",
+		`relay_hec_request_duration_seconds_bucket{listener="prom-test",log_type="user-activity",target="prom-target",le="0.025"} 1` + "\n",
+		`relay_hec_request_duration_seconds_bucket{listener="prom-test",log_type="user-activity",target="prom-target",le="+Inf"} 1` + "\n",
+		`relay_hec_request_duration_seconds_count{listener="prom-test",log_type="user-activity",target="prom-target"} 1` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+
+	// Every sample line is "relay_name[{labels}] value"
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		value := line[strings.LastIndex(line, " ")+1:]
+		if _, err := strconv.ParseFloat(value, 64); !strings.HasPrefix(line, "relay_") || err != nil {
+			t.Errorf("malformed sample line %q", line)
+		}
+	}
+}
+
+func TestPromMetrics_CoverAllVariables(t *testing.T) {
+	listed := make(map[expvar.Var]bool)
+	for _, m := range promMetrics {
+		listed[m.v] = true
+		if vec, ok := m.v.(*IntVec); ok && vec.total != nil {
+			listed[vec.total] = true
+		}
+	}
+	// Exposed separately as relay_build_info and relay_listener_info
+	listed[Version] = true
+	listed[Listeners] = true
+
+	expvar.Do(func(kv expvar.KeyValue) {
+		if kv.Key == "cmdline" || kv.Key == "memstats" {
+			return // Published by the expvar package itself
+		}
+		if !listed[kv.Value] {
+			t.Errorf("expvar %q is not exposed at /metrics", kv.Key)
+		}
+	})
+}
+
+func TestQuoteLabel(t *testing.T) {
+	got := quoteLabel("a\"b\\c\nd")
+	want := `"a\"b\\c\nd"`
+	if got != want {
+		t.Errorf("quoteLabel() = %s, want %s", got, want)
+	}
+}
+
+func TestStartServer_Prometheus(t *testing.T) {
+	testAddr := ":19997"
+	if err := StartServer(testAddr); err != nil {
+		t.Fatalf("failed to start metrics server: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	resp, err := http.Get("http://localhost" + testAddr + "/metrics")
+	if err != nil {
+		t.Fatalf("failed to fetch metrics: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %v", err)
+	}
+	if !strings.Contains(string(body), "relay_connections_active ") {
+		t.Error("response does not contain relay_connections_active")
+	}
+}
@@ -8,7 +8,8 @@ import (
 )
 
 // StartServer starts the metrics HTTP server on the specified address.
-// It serves the standard expvar endpoint at /debug/vars.
+// It serves the standard expvar endpoint at /debug/vars and the Prometheus
+// text format at /metrics.
 // If addr is empty, the server is not started.
 func StartServer(addr string) error {
 	if addr == "" {
@@ -18,6 +19,7 @@ func StartServer(addr string) error {
 
 	mux := http.NewServeMux()
 	mux.Handle("/debug/vars", expvar.Handler())
+	mux.Handle("/metrics", PrometheusHandler())
 
 	// Create server with explicit timeouts to prevent resource exhaustion
 	server := &http.Server{
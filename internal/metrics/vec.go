@@ -0,0 +1,149 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// IntVec is an integer metric with one series per combination of label values,
+// such as HEC forwards by listener, target and outcome. It implements expvar.Var;
+// /debug/vars shows the series as objects nested by each label in turn.
+//
+// An IntVec may roll up into a process-wide total, so the metrics that predate
+// the breakdown keep their values: every Add is also added to the total.
+//
+// IntVec is safe for concurrent use by multiple goroutines.
+type IntVec struct {
+	labels []string
+	total  expvar.Var // Optional: *expvar.Int, or *expvar.Map keyed by the last label value
+
+	mu     sync.RWMutex
+	series map[string]*vecSeries
+}
+
+// vecSeries is the value for one combination of label values.
+type vecSeries struct {
+	values []string
+	v      expvar.Int
+}
+
+// NewIntVec creates an IntVec with the given labels and publishes it under name.
+// total may be nil.
+func NewIntVec(name string, total expvar.Var, labels ...string) *IntVec {
+	v := &IntVec{
+		labels: labels,
+		total:  total,
+		series: make(map[string]*vecSeries),
+	}
+	expvar.Publish(name, v)
+	return v
+}
+
+// Add adds delta to the series for the given label values, and to the total.
+func (v *IntVec) Add(delta int64, labelValues ...string) {
+	v.get(labelValues).v.Add(delta)
+
+	switch total := v.total.(type) {
+	case *expvar.Int:
+		total.Add(delta)
+	case *expvar.Map:
+		total.Add(labelValues[len(labelValues)-1], delta)
+	}
+}
+
+// Set sets the series for the given label values. It is used for gauges and does not
+// change the total.
+func (v *IntVec) Set(value int64, labelValues ...string) {
+	v.get(labelValues).v.Set(value)
+}
+
+// Value returns the value of the series for the given label values (0 if never recorded).
+func (v *IntVec) Value(labelValues ...string) int64 {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if s, ok := v.series[seriesKey(labelValues)]; ok {
+		return s.v.Value()
+	}
+	return 0
+}
+
+// get returns the series for the given label values, creating it if needed.
+func (v *IntVec) get(labelValues []string) *vecSeries {
+	if len(labelValues) != len(v.labels) {
+		panic("metrics: wrong number of label values")
+	}
+	key := seriesKey(labelValues)
+
+	v.mu.RLock()
+	s, ok := v.series[key]
+	v.mu.RUnlock()
+	if ok {
+		return s
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if s, ok := v.series[key]; ok {
+		return s
+	}
+	s = &vecSeries{values: append([]string(nil), labelValues...)}
+	v.series[key] = s
+	return s
+}
+
+// do calls f for each series, in label value order.
+func (v *IntVec) do(f func(labelValues []string, value int64)) {
+	v.mu.RLock()
+	series := make([]*vecSeries, 0, len(v.series))
+	for _, s := range v.series {
+		series = append(series, s)
+	}
+	v.mu.RUnlock()
+
+	sort.Slice(series, func(i, j int) bool {
+		return seriesKey(series[i].values) < seriesKey(series[j].values)
+	})
+	for _, s := range series {
+		f(s.values, s.v.Value())
+	}
+}
+
+// String returns the series as JSON objects nested by label, for expvar.
+func (v *IntVec) String() string {
+	out := make(map[string]interface{})
+	v.do(func(labelValues []string, value int64) {
+		setNested(out, labelValues, value)
+	})
+	return marshalNested(out)
+}
+
+// seriesKey joins label values into a map key.
+func seriesKey(labelValues []string) string {
+	return strings.Join(labelValues, "\xff")
+}
+
+// setNested stores value in out under one level of nesting per label value.
+func setNested(out map[string]interface{}, labelValues []string, value interface{}) {
+	m := out
+	for _, lv := range labelValues[:len(labelValues)-1] {
+		next, ok := m[lv].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			m[lv] = next
+		}
+		m = next
+	}
+	m[labelValues[len(labelValues)-1]] = value
+}
+
+// marshalNested encodes nested series as JSON.
+func marshalNested(out map[string]interface{}) string {
+	b, err := json.Marshal(out)
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+)
+
+func TestIntVec_AddRollsUpToTotal(t *testing.T) {
+	total := new(expvar.Map).Init()
+	v := &IntVec{labels: []string{"listener", "outcome"}, total: total, series: make(map[string]*vecSeries)}
+
+	v.Add(2, "user-activity", "success")
+	v.Add(1, "user-status", "success")
+	v.Add(1, "user-status", "failure")
+
+	if got := v.Value("user-activity", "success"); got != 2 {
+		t.Errorf("Value(user-activity, success) = %d, want 2", got)
+	}
+	if got := v.Value("audit", "success"); got != 0 {
+		t.Errorf("Value() of an unrecorded series = %d, want 0", got)
+	}
+	if got := total.Get("success").(*expvar.Int).Value(); got != 3 {
+		t.Errorf("total success = %d, want 3", got)
+	}
+	if got := total.Get("failure").(*expvar.Int).Value(); got != 1 {
+		t.Errorf("total failure = %d, want 1", got)
+	}
+}
+
+func TestIntVec_IntTotalAndSet(t *testing.T) {
+	total := new(expvar.Int)
+	v := &IntVec{labels: []string{"listener"}, total: total, series: make(map[string]*vecSeries)}
+
+	v.Add(1, "a")
+	v.Add(1, "b")
+	v.Add(-1, "a")
+	v.Set(10, "c") // Gauges set directly do not change the total
+
+	if got := total.Value(); got != 1 {
+		t.Errorf("total = %d, want 1", got)
+	}
+	if got := v.Value("c"); got != 10 {
+		t.Errorf("Value(c) = %d, want 10", got)
+	}
+}
+
+func TestIntVec_String(t *testing.T) {
+	v := &IntVec{labels: []string{"listener", "target", "outcome"}, series: make(map[string]*vecSeries)}
+	v.Add(5, "user-activity", "primary", "success")
+	v.Add(1, "user-activity", "secondary", "failure")
+
+	var decoded map[string]map[string]map[string]int64
+	if err := json.Unmarshal([]byte(v.String()), &decoded); err != nil {
+		t.Fatalf("String() is not valid JSON: %v (%s)", err, v.String())
+	}
+	if got := decoded["user-activity"]["primary"]["success"]; got != 5 {
+		t.Errorf("user-activity.primary.success = %d, want 5", got)
+	}
+	if got := decoded["user-activity"]["secondary"]["failure"]; got != 1 {
+		t.Errorf("user-activity.secondary.failure = %d, want 1", got)
+	}
+}
+
+func TestIntVec_WrongLabelCountPanics(t *testing.T) {
+	v := &IntVec{labels: []string{"listener"}, series: make(map[string]*vecSeries)}
+	defer func() {
+		if recover() == nil {
+			t.Error("Add() with the wrong number of label values should panic")
+		}
+	}()
+	v.Add(1, "a", "b")
+}
@@ -5,47 +5,109 @@ package processor
 import (
 	"bufio"
 	"bytes"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"io"
 )
 
+// ErrLineTooLong is the error ReadLineLimited returns for a line longer than its limit.
+// The error returned is a *LineTooLongError describing the line.
+var ErrLineTooLong = errors.New("line exceeds limit")
+
+// LineTooLongError describes a line rejected for exceeding the limit, so it can be
+// recorded without keeping the whole line. It matches ErrLineTooLong with errors.Is.
+type LineTooLongError struct {
+	Prefix []byte   // The first bytes of the line, up to the limit
+	Size   int64    // Length of the whole line, without its line ending
+	SHA256 [32]byte // Hash of the whole line, without its line ending
+}
+
+// Error returns the message of ErrLineTooLong.
+func (e *LineTooLongError) Error() string {
+	return ErrLineTooLong.Error()
+}
+
+// Is reports whether target is ErrLineTooLong.
+func (e *LineTooLongError) Is(target error) bool {
+	return target == ErrLineTooLong
+}
+
 // ReadLineLimited reads a line from the reader with a maximum byte limit.
 // If a line exceeds the limit, it drains the remaining data until a newline is found
-// and returns an error, ensuring the reader is positioned correctly for the next line.
+// and returns a *LineTooLongError, ensuring the reader is positioned correctly for the next line.
 // Returns the line without trailing newline characters on success.
 func ReadLineLimited(br *bufio.Reader, limit int) ([]byte, error) {
-	var buf bytes.Buffer
-
-	for {
-		b, err := br.ReadBytes('\n')
-		buf.Write(b)
-
-		if len(buf.Bytes()) > limit {
-			// Line exceeds limit - drain the rest until we find a newline
-			// to ensure the next read starts at the correct position
-			if err == nil || !errors.Is(err, io.EOF) {
-				// We have a complete chunk but haven't hit newline yet, keep draining
-				for !bytes.Contains(b, []byte{'\n'}) {
-					b, err = br.ReadBytes('\n')
-					if err != nil {
-						break
-					}
-				}
+	line, _, err := readLine(br, limit)
+	return line, err
+}
+
+// LineReader reads size-limited lines like ReadLineLimited and tracks the byte offset
+// at which each line starts in the stream.
+type LineReader struct {
+	br     *bufio.Reader
+	limit  int
+	offset int64 // Offset of the line last read
+	next   int64 // Offset of the next line
+}
+
+// NewLineReader returns a LineReader that reads lines of at most limit bytes from br.
+func NewLineReader(br *bufio.Reader, limit int) *LineReader {
+	return &LineReader{br: br, limit: limit}
+}
+
+// ReadLine reads the next line, with the same results as ReadLineLimited.
+func (r *LineReader) ReadLine() ([]byte, error) {
+	line, n, err := readLine(r.br, r.limit)
+	r.offset = r.next
+	r.next += n
+	return line, err
+}
+
+// Offset returns the byte offset in the stream of the line last read, including
+// a line that was rejected for exceeding the limit.
+func (r *LineReader) Offset() int64 {
+	return r.offset
+}
+
+// readLine reads a line and returns it with the number of bytes consumed from br.
+func readLine(br *bufio.Reader, limit int) ([]byte, int64, error) {
+	b, err := br.ReadBytes('\n')
+	n := int64(len(b))
+
+	if len(b) > limit {
+		tooLong := &LineTooLongError{}
+		h := sha256.New()
+		add := func(chunk []byte) {
+			if bytes.HasSuffix(chunk, []byte{'\n'}) {
+				chunk = bytes.TrimRight(chunk, "\r\n")
 			}
-			return nil, errors.New("line exceeds limit")
+			tooLong.Size += int64(len(chunk))
+			h.Write(chunk)
 		}
 
-		if err != nil {
-			if errors.Is(err, io.EOF) && buf.Len() > 0 {
-				return bytes.TrimRight(buf.Bytes(), "\r\n"), nil
-			}
-			return nil, err
+		add(b)
+		tooLong.Prefix = bytes.Clone(b[:limit])
+		// After a read error other than EOF, drain the rest of the line
+		// to ensure the next read starts at the correct position
+		if err != nil && !errors.Is(err, io.EOF) {
+			rest, _ := br.ReadBytes('\n')
+			n += int64(len(rest))
+			add(rest)
 		}
+		h.Sum(tooLong.SHA256[:0])
+		return nil, n, tooLong
+	}
 
-		// Got newline
-		return bytes.TrimRight(buf.Bytes(), "\r\n"), nil
+	if err != nil {
+		if errors.Is(err, io.EOF) && len(b) > 0 {
+			return bytes.TrimRight(b, "\r\n"), n, nil
+		}
+		return nil, n, err
 	}
+
+	// Got newline
+	return bytes.TrimRight(b, "\r\n"), n, nil
 }
 
 // IsValidJSON checks if the given byte slice contains valid JSON.
@@ -3,6 +3,7 @@ package processor
 import (
 	"bufio"
 	"bytes"
+	"crypto/sha256"
 	"errors"
 	"io"
 	"strings"
@@ -78,6 +79,65 @@ func TestReadLineLimited_ExceedsLimit(t *testing.T) {
 	}
 }
 
+func TestReadLineLimited_LineTooLongError(t *testing.T) {
+	input := "this line is too long for the limit\r\nnext\n"
+	br := bufio.NewReader(strings.NewReader(input))
+
+	_, err := ReadLineLimited(br, 10)
+	if !errors.Is(err, ErrLineTooLong) {
+		t.Fatalf("expected ErrLineTooLong, got %v", err)
+	}
+	var tooLong *LineTooLongError
+	if !errors.As(err, &tooLong) {
+		t.Fatalf("expected *LineTooLongError, got %T", err)
+	}
+
+	line := "this line is too long for the limit"
+	if string(tooLong.Prefix) != line[:10] {
+		t.Errorf("Prefix = %q, want %q", tooLong.Prefix, line[:10])
+	}
+	if tooLong.Size != int64(len(line)) {
+		t.Errorf("Size = %d, want %d", tooLong.Size, len(line))
+	}
+	if tooLong.SHA256 != sha256.Sum256([]byte(line)) {
+		t.Error("SHA256 should be the hash of the whole line without its line ending")
+	}
+
+	// The reader is positioned at the next line
+	result, err := ReadLineLimited(br, 10)
+	if err != nil || string(result) != "next" {
+		t.Errorf("expected next line, got %q, %v", result, err)
+	}
+}
+
+func TestLineReader_Offset(t *testing.T) {
+	input := "first\nthis line is too long\r\nthird\n"
+	r := NewLineReader(bufio.NewReader(strings.NewReader(input)), 10)
+
+	expected := []struct {
+		line   string
+		offset int64
+		err    error
+	}{
+		{"first", 0, nil},
+		{"", 6, ErrLineTooLong},
+		{"third", 29, nil},
+	}
+	for i, want := range expected {
+		line, err := r.ReadLine()
+		if string(line) != want.line || !errors.Is(err, want.err) {
+			t.Errorf("line %d: got %q, %v, want %q, %v", i+1, line, err, want.line, want.err)
+		}
+		if r.Offset() != want.offset {
+			t.Errorf("line %d: Offset() = %d, want %d", i+1, r.Offset(), want.offset)
+		}
+	}
+
+	if _, err := r.ReadLine(); err != io.EOF {
+		t.Errorf("expected EOF after all lines read, got %v", err)
+	}
+}
+
 func TestReadLineLimited_EOF(t *testing.T) {
 	input := ""
 	br := bufio.NewReader(strings.NewReader(input))
@@ -0,0 +1,218 @@
+// Package quarantine stores lines that are kept out of Splunk: lines that are not valid JSON,
+// lines longer than the listener's limit and lines that do not match their log type's schema.
+// Lines are written to NDJSON files with where they came from and why they were quarantined,
+// so they can be inspected, explained and, once the cause is fixed, replayed.
+package quarantine
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/scottbrown/relay/internal/metrics"
+)
+
+// DefaultMaxDataBytes is the number of bytes of each line kept when no limit is given.
+const DefaultMaxDataBytes = 64 << 10
+
+// Reason identifies why a line was quarantined.
+type Reason string
+
+const (
+	InvalidJSON Reason = "invalid_json" // The line is not valid JSON
+	Oversized   Reason = "oversized"    // The line is longer than the listener's max_line_bytes
+	SchemaDrift Reason = "schema_drift" // The line does not match its log type's schema
+)
+
+// Line describes a line to quarantine.
+type Line struct {
+	ConnID     string
+	ClientAddr string
+	Offset     int64  // Byte offset of the line in the connection's stream
+	Reason     Reason // Why the line was quarantined
+	Detail     string // Optional description of the problem, such as the drift found
+	Data       []byte // The line, or its first bytes when Size is larger
+	Size       int64  // Length of the whole line (0 = len(Data))
+	SHA256     []byte // Hash of the whole line (nil = hash of Data)
+}
+
+// Entry represents a quarantined line.
+// The line is kept in Data when it is valid UTF-8 and in DataBase64 otherwise,
+// so the original bytes can always be recovered.
+type Entry struct {
+	Timestamp  string `json:"timestamp"`             // ISO 8601 timestamp the line was quarantined
+	ConnID     string `json:"conn_id"`               // Connection ID for correlation
+	ClientAddr string `json:"client_addr"`           // Address of the client that sent the line
+	Offset     int64  `json:"offset"`                // Byte offset of the line in the connection's stream
+	Reason     Reason `json:"reason"`                // Why the line was quarantined
+	Detail     string `json:"detail,omitempty"`      // Description of the problem
+	Size       int64  `json:"size"`                  // Length of the original line in bytes
+	SHA256     string `json:"sha256"`                // Hex SHA-256 of the original line
+	Truncated  bool   `json:"truncated,omitempty"`   // Only the first bytes of the line are kept
+	Data       string `json:"data,omitempty"`        // Original log line, or its first bytes
+	DataBase64 string `json:"data_base64,omitempty"` // As Data, base64-encoded when not valid UTF-8
+}
+
+// Writer handles writing quarantined lines.
+// Files are rotated daily and named: quarantine-YYYY-MM-DD.ndjson.
+//
+// Writer is safe for concurrent use by multiple goroutines.
+type Writer struct {
+	name         string // Listener name, used to label metrics
+	baseDir      string
+	maxDataBytes int // Bytes of each line to keep
+	file         *os.File
+	curDay       string
+	mu           sync.Mutex
+}
+
+// New creates a new quarantine Writer for the given directory that keeps at most
+// maxDataBytes of each line (0 = DefaultMaxDataBytes). The directory is created if it does not exist.
+// Returns an error if the directory cannot be created.
+func New(baseDir string, maxDataBytes int) (*Writer, error) {
+	if maxDataBytes <= 0 {
+		maxDataBytes = DefaultMaxDataBytes
+	}
+	if err := os.MkdirAll(baseDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", baseDir, err)
+	}
+
+	return &Writer{
+		baseDir:      baseDir,
+		maxDataBytes: maxDataBytes,
+	}, nil
+}
+
+// SetName sets the listener name used to label the Writer's metrics.
+func (w *Writer) SetName(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.name = name
+}
+
+// Write writes a quarantined line with its origin and reason.
+func (w *Writer) Write(line Line) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := time.Now().UTC()
+	day := now.Format("2006-01-02")
+
+	if day != w.curDay {
+		if w.file != nil {
+			if err := w.file.Close(); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		w.file, err = w.openDayFile(day)
+		if err != nil {
+			return err
+		}
+		w.curDay = day
+	}
+
+	jsonData, err := json.Marshal(w.entry(now, line))
+	if err != nil {
+		return fmt.Errorf("failed to marshal quarantine entry: %w", err)
+	}
+
+	n, err := w.file.Write(append(jsonData, '\n'))
+	metrics.ListenerQuarantineBytes.Add(int64(n), w.name)
+	if err != nil {
+		return err
+	}
+
+	metrics.ListenerQuarantineLines.Add(1, w.name, string(line.Reason))
+	return nil
+}
+
+// entry builds the entry for a line, keeping at most maxDataBytes of it.
+func (w *Writer) entry(now time.Time, line Line) Entry {
+	size := line.Size
+	if size == 0 {
+		size = int64(len(line.Data))
+	}
+	hash := line.SHA256
+	if hash == nil {
+		sum := sha256.Sum256(line.Data)
+		hash = sum[:]
+	}
+
+	data := line.Data
+	if len(data) > w.maxDataBytes {
+		// Cut at a character boundary so a truncated text line is still kept as text
+		cut := w.maxDataBytes
+		for i := cut; i > 0 && i > cut-utf8.UTFMax; i-- {
+			if utf8.RuneStart(data[i]) {
+				cut = i
+				break
+			}
+		}
+		data = data[:cut]
+	}
+
+	entry := Entry{
+		Timestamp:  now.Format(time.RFC3339),
+		ConnID:     line.ConnID,
+		ClientAddr: line.ClientAddr,
+		Offset:     line.Offset,
+		Reason:     line.Reason,
+		Detail:     line.Detail,
+		Size:       size,
+		SHA256:     hex.EncodeToString(hash),
+		Truncated:  int64(len(data)) < size,
+	}
+	if utf8.Valid(data) {
+		entry.Data = string(data)
+	} else {
+		entry.DataBase64 = base64.StdEncoding.EncodeToString(data)
+	}
+	return entry
+}
+
+// Close closes the current day's file if open.
+// Returns an error if the file cannot be closed.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file != nil {
+		return w.file.Close()
+	}
+	return nil
+}
+
+// CurrentFile returns the path to the current day's quarantine file.
+// Returns empty string if no file is currently open.
+func (w *Writer) CurrentFile() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return ""
+	}
+	return w.file.Name()
+}
+
+// openDayFile opens or creates the quarantine file for the given day.
+func (w *Writer) openDayFile(day string) (*os.File, error) {
+	filename := filepath.Join(w.baseDir, fmt.Sprintf("quarantine-%s.ndjson", day))
+	// #nosec G304 -- baseDir is set during Writer construction from config.
+	// The day parameter is generated from time.Now() and used for daily rotation.
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("opened quarantine file", "path", filename)
+	return file, nil
+}
@@ -0,0 +1,149 @@
+package quarantine
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scottbrown/relay/internal/metrics"
+)
+
+// readEntries returns the entries in the writer's current file.
+func readEntries(t *testing.T, writer *Writer) []Entry {
+	t.Helper()
+	content, err := os.ReadFile(writer.CurrentFile())
+	if err != nil {
+		t.Fatalf("failed to read quarantine file: %v", err)
+	}
+
+	var entries []Entry
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		var entry Entry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("failed to parse quarantine entry: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestNew_CreateDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "subdir", "quarantine")
+
+	if _, err := New(dir, 0); err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		t.Error("directory was not created")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	writer, err := New(dir, 0)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer writer.Close()
+	writer.SetName("quarantine-test")
+
+	lines := []Line{
+		{ConnID: "conn-1", ClientAddr: "10.0.0.1:5000", Offset: 120, Reason: InvalidJSON, Data: []byte(`{"a":1`)},
+		{ConnID: "conn-2", ClientAddr: "10.0.0.2:5000", Reason: SchemaDrift, Detail: "missing field c", Data: []byte(`{"b":2}`)},
+	}
+	for _, line := range lines {
+		if err := writer.Write(line); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	expectedFile := filepath.Join(dir, "quarantine-"+time.Now().UTC().Format("2006-01-02")+".ndjson")
+	if writer.CurrentFile() != expectedFile {
+		t.Errorf("CurrentFile() = %s, want %s", writer.CurrentFile(), expectedFile)
+	}
+
+	entries := readEntries(t, writer)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+
+	sum := sha256.Sum256([]byte(`{"a":1`))
+	entry := entries[0]
+	if entry.ConnID != "conn-1" || entry.ClientAddr != "10.0.0.1:5000" || entry.Offset != 120 ||
+		entry.Reason != InvalidJSON || entry.Data != `{"a":1` {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if entry.Size != 6 || entry.SHA256 != hex.EncodeToString(sum[:]) || entry.Truncated {
+		t.Errorf("entry size = %d, sha256 = %s, truncated = %v", entry.Size, entry.SHA256, entry.Truncated)
+	}
+	if _, err := time.Parse(time.RFC3339, entry.Timestamp); err != nil {
+		t.Errorf("timestamp %q is not RFC 3339: %v", entry.Timestamp, err)
+	}
+	if entries[1].Reason != SchemaDrift || entries[1].Detail != "missing field c" {
+		t.Errorf("unexpected entry %+v", entries[1])
+	}
+
+	if got := metrics.ListenerQuarantineLines.Value("quarantine-test", string(InvalidJSON)); got != 1 {
+		t.Errorf("invalid_json lines = %d, want 1", got)
+	}
+	info, _ := os.Stat(expectedFile)
+	if got := metrics.ListenerQuarantineBytes.Value("quarantine-test"); got != info.Size() {
+		t.Errorf("quarantine bytes = %d, want %d", got, info.Size())
+	}
+}
+
+func TestWrite_Truncated(t *testing.T) {
+	writer, err := New(t.TempDir(), 8)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer writer.Close()
+
+	// An oversized line arrives as its first bytes with the size and hash of the whole line
+	hash := sha256.Sum256([]byte("whole line"))
+	lines := []Line{
+		{Reason: Oversized, Data: []byte("0123456789"), Size: 4096, SHA256: hash[:]},
+		{Reason: InvalidJSON, Data: []byte("1234567é")}, // é is 2 bytes, cut after 7
+	}
+	for _, line := range lines {
+		if err := writer.Write(line); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	entries := readEntries(t, writer)
+	if e := entries[0]; e.Data != "01234567" || e.Size != 4096 || !e.Truncated || e.SHA256 != hex.EncodeToString(hash[:]) {
+		t.Errorf("unexpected oversized entry %+v", e)
+	}
+	if e := entries[1]; e.Data != "1234567" || e.Size != 9 || !e.Truncated {
+		t.Errorf("truncated text should end at a character boundary, got %+v", e)
+	}
+}
+
+func TestWrite_Binary(t *testing.T) {
+	writer, err := New(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer writer.Close()
+
+	data := []byte{'{', 0xff, 0xfe, '}'}
+	if err := writer.Write(Line{Reason: InvalidJSON, Data: data}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	entry := readEntries(t, writer)[0]
+	if entry.Data != "" {
+		t.Errorf("Data = %q, want empty for invalid UTF-8", entry.Data)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(entry.DataBase64)
+	if err != nil || string(decoded) != string(data) {
+		t.Errorf("DataBase64 should hold the original bytes, got %q (%v)", decoded, err)
+	}
+}
@@ -0,0 +1,644 @@
+// Package queue implements a bounded, disk-backed write-ahead queue for forwarding.
+// Records are appended to segment files and removed only after the consumer
+// acknowledges them, so accepted lines survive crashes and restarts.
+package queue
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/scottbrown/relay/internal/atomicfile"
+	"github.com/scottbrown/relay/internal/metrics"
+)
+
+const (
+	// DefaultMaxBytes is the default limit of unacknowledged bytes before Append blocks (256 MiB).
+	DefaultMaxBytes int64 = 256 << 20
+	// DefaultSegmentBytes is the default size at which a new segment file is started (64 MiB).
+	DefaultSegmentBytes int64 = 64 << 20
+	// DefaultMaxLineBytes is the default size of the largest line a record holds (1 MiB).
+	DefaultMaxLineBytes = 1 << 20
+)
+
+const (
+	headerSize    = 8    // uint32 payload length + uint32 CRC-32 of the payload
+	fieldBytes    = 4096 // Room in a payload for the version, connection ID, host and target
+	recordVersion = 1
+	segmentSuffix = ".seg"
+	cursorFile    = "cursor.json"
+)
+
+var (
+	// ErrClosed is returned by Append and ReadBatch once the queue has been closed.
+	ErrClosed = errors.New("queue is closed")
+	// errCorrupt marks a record that failed its length or checksum validation.
+	errCorrupt = errors.New("corrupt queue record")
+)
+
+// Config holds configuration for a Queue.
+type Config struct {
+	Name         string        // Listener name used in logs and metrics
+	Dir          string        // Directory for segment and cursor files
+	MaxBytes     int64         // Maximum unacknowledged bytes before Append blocks (default: 256 MiB)
+	SegmentBytes int64         // Size at which a new segment file is started (default: 64 MiB)
+	SyncInterval time.Duration // How often appended data is fsynced (default: 1s)
+	MaxLineBytes int           // Largest line a record holds (default: 1 MiB)
+}
+
+// Record is a single queued line.
+type Record struct {
+	ConnID string   // Connection ID for correlation
+	Host   string   // Client address the line was received from
+	Target string   // HEC target a filter rule routed the line to, if any
+	Data   []byte   // Original log line
+	Next   Position // Position after the record, set by ReadBatch
+}
+
+// Position identifies a point in the queue: a segment and a byte offset within it.
+type Position struct {
+	Segment uint64 `json:"segment"`
+	Offset  int64  `json:"offset"`
+}
+
+type segment struct {
+	id   uint64
+	size int64
+}
+
+// Queue is a bounded write-ahead queue stored as numbered segment files.
+//
+// Producers call Append, which blocks while the unacknowledged backlog exceeds MaxBytes.
+// A single consumer calls ReadBatch and then Ack once the records have been handled.
+// The acknowledged position is persisted in cursor.json; on Open, everything after it
+// is delivered again, so delivery is at-least-once.
+//
+// Queue is safe for concurrent use by multiple producers and one consumer.
+type Queue struct {
+	config   Config
+	mu       sync.Mutex
+	cond     *sync.Cond
+	segments []segment // Ordered, contiguous segment IDs; the last one is being written
+	writer   *os.File
+	read     Position // Next position handed to the consumer
+	commit   Position // Last acknowledged position
+	closed   bool
+	stopSync chan struct{}
+	syncDone chan struct{}
+}
+
+// Open opens or creates the queue in cfg.Dir and recovers its state.
+// A partially written record at the end of the last segment (from a crash) is truncated.
+func Open(cfg Config) (*Queue, error) {
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultMaxBytes
+	}
+	if cfg.SegmentBytes <= 0 {
+		cfg.SegmentBytes = DefaultSegmentBytes
+	}
+	if cfg.SyncInterval <= 0 {
+		cfg.SyncInterval = time.Second
+	}
+	if cfg.MaxLineBytes <= 0 {
+		cfg.MaxLineBytes = DefaultMaxLineBytes
+	}
+	if err := os.MkdirAll(cfg.Dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create queue directory %s: %w", cfg.Dir, err)
+	}
+
+	q := &Queue{
+		config:   cfg,
+		stopSync: make(chan struct{}),
+		syncDone: make(chan struct{}),
+	}
+	q.cond = sync.NewCond(&q.mu)
+
+	if err := q.recover(); err != nil {
+		return nil, err
+	}
+
+	last := q.segments[len(q.segments)-1]
+	// #nosec G304 -- segment paths are built from the configured queue directory.
+	writer, err := os.OpenFile(q.segmentPath(last.id), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	q.writer = writer
+	q.read = q.commit
+	q.updateMetrics()
+
+	go q.syncLoop()
+
+	if used := q.usedLocked(); used > 0 {
+		slog.Info("recovered forward queue", "listener", cfg.Name, "dir", cfg.Dir, "pending_bytes", used)
+	}
+	return q, nil
+}
+
+// recover loads segments and the cursor from disk.
+func (q *Queue) recover() error {
+	ids, err := q.listSegments()
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		ids = []uint64{1}
+	}
+
+	for i, id := range ids {
+		size, err := q.segmentSize(id)
+		if err != nil {
+			return err
+		}
+		if i == len(ids)-1 {
+			if size, err = q.truncateTornTail(id, size); err != nil {
+				return err
+			}
+		}
+		q.segments = append(q.segments, segment{id: id, size: size})
+	}
+
+	// #nosec G304 -- cursor path is built from the configured queue directory.
+	data, err := os.ReadFile(filepath.Join(q.config.Dir, cursorFile))
+	switch {
+	case os.IsNotExist(err):
+		q.commit = Position{Segment: q.segments[0].id}
+	case err != nil:
+		return err
+	default:
+		if err := json.Unmarshal(data, &q.commit); err != nil {
+			// A damaged cursor replays the queue from the start: lines may be sent twice, not lost
+			slog.Warn("failed to parse queue cursor, forwarding from the start of the queue", "listener", q.config.Name, "error", err)
+			q.commit = Position{Segment: q.segments[0].id}
+		}
+	}
+
+	// Clamp the cursor to the segments that exist
+	first, last := q.segments[0], q.segments[len(q.segments)-1]
+	switch {
+	case q.commit.Segment < first.id:
+		q.commit = Position{Segment: first.id}
+	case q.commit.Segment > last.id:
+		q.commit = Position{Segment: last.id, Offset: last.size}
+	}
+	if seg := q.segments[q.commit.Segment-first.id]; q.commit.Offset > seg.size {
+		q.commit.Offset = seg.size
+	}
+
+	return q.removeSegmentsBefore(q.commit.Segment)
+}
+
+// truncateTornTail validates records in a segment and truncates it after the last good one.
+func (q *Queue) truncateTornTail(id uint64, size int64) (int64, error) {
+	path := q.segmentPath(id)
+	// #nosec G304 -- segment paths are built from the configured queue directory.
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var offset int64
+	for offset < size {
+		_, next, err := readRecord(f, offset, size, q.maxPayload())
+		if err != nil {
+			break
+		}
+		offset = next
+	}
+	if offset < size {
+		slog.Warn("truncating incomplete queue record", "listener", q.config.Name, "segment", path, "offset", offset, "size", size)
+		if err := f.Truncate(offset); err != nil {
+			return 0, err
+		}
+	}
+	return offset, nil
+}
+
+// Append adds a record to the queue.
+// It blocks while the queue is full, applying backpressure to the caller.
+// Returns ErrClosed if the queue is closed before the record is written, and an error
+// if the record is larger than a line of MaxLineBytes and its fields.
+func (q *Queue) Append(r Record) error {
+	buf := encodeRecord(r)
+	size := int64(len(buf))
+	if size-headerSize > q.maxPayload() {
+		return fmt.Errorf("queue record of %d bytes exceeds the maximum of %d", size-headerSize, q.maxPayload())
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.closed && q.usedLocked() > 0 && q.usedLocked()+size > q.config.MaxBytes {
+		metrics.ListenerQueueBackpressure.Add(1, q.config.Name)
+		slog.Debug("forward queue full, blocking producer", "listener", q.config.Name)
+		for !q.closed && q.usedLocked() > 0 && q.usedLocked()+size > q.config.MaxBytes {
+			q.cond.Wait()
+		}
+	}
+	if q.closed {
+		return ErrClosed
+	}
+
+	last := &q.segments[len(q.segments)-1]
+	if last.size > 0 && last.size+size > q.config.SegmentBytes {
+		if err := q.rotateLocked(); err != nil {
+			return err
+		}
+		last = &q.segments[len(q.segments)-1]
+	}
+
+	n, err := q.writer.Write(buf)
+	if err != nil {
+		// Drop the partial record so the segment stays readable
+		_ = q.writer.Truncate(last.size)
+		return fmt.Errorf("failed to append to queue: %w", err)
+	}
+	last.size += int64(n)
+
+	q.updateMetrics()
+	q.cond.Broadcast()
+	return nil
+}
+
+// ReadBatch returns up to max records following the last record handed out, and the
+// position after the last returned record. It blocks until at least one record is
+// available or wait has elapsed, in which case it returns no records.
+// Returns ErrClosed once the queue is closed.
+func (q *Queue) ReadBatch(max int, wait time.Duration) ([]Record, Position, error) {
+	deadline := time.Now().Add(wait)
+	timer := time.AfterFunc(wait, func() {
+		q.mu.Lock()
+		q.cond.Broadcast()
+		q.mu.Unlock()
+	})
+	defer timer.Stop()
+
+	q.mu.Lock()
+	for !q.closed && !q.hasUnreadLocked() {
+		if !time.Now().Before(deadline) {
+			pos := q.read
+			q.mu.Unlock()
+			return nil, pos, nil
+		}
+		q.cond.Wait()
+	}
+	if q.closed {
+		pos := q.read
+		q.mu.Unlock()
+		return nil, pos, ErrClosed
+	}
+
+	// Snapshot segment sizes; segments at or after the read position are never removed
+	// by anyone but the consumer, and only grow, so reading can happen without the lock.
+	pos := q.read
+	first := q.segments[0].id
+	sizes := make([]int64, len(q.segments))
+	for i, seg := range q.segments {
+		sizes[i] = seg.size
+	}
+	q.mu.Unlock()
+
+	var records []Record
+	var f *os.File
+	defer func() {
+		if f != nil {
+			_ = f.Close()
+		}
+	}()
+
+	for len(records) < max {
+		idx := int(pos.Segment - first)
+		if pos.Offset >= sizes[idx] {
+			if idx == len(sizes)-1 {
+				break
+			}
+			pos = Position{Segment: pos.Segment + 1}
+			if f != nil {
+				_ = f.Close()
+				f = nil
+			}
+			continue
+		}
+
+		if f == nil {
+			var err error
+			// #nosec G304 -- segment paths are built from the configured queue directory.
+			if f, err = os.Open(q.segmentPath(pos.Segment)); err != nil {
+				return records, q.setRead(pos), err
+			}
+		}
+
+		r, next, err := readRecord(f, pos.Offset, sizes[idx], q.maxPayload())
+		if err != nil {
+			// Skip the rest of a damaged segment rather than blocking the queue forever
+			slog.Error("skipping unreadable queue segment data", "listener", q.config.Name, "segment", q.segmentPath(pos.Segment), "offset", pos.Offset, "error", err)
+			pos.Offset = sizes[idx]
+			continue
+		}
+		pos.Offset = next
+		r.Next = pos
+		records = append(records, r)
+	}
+
+	return records, q.setRead(pos), nil
+}
+
+func (q *Queue) setRead(pos Position) Position {
+	q.mu.Lock()
+	q.read = pos
+	q.mu.Unlock()
+	return pos
+}
+
+// Ack acknowledges every record up to pos. Fully acknowledged segments are deleted
+// and the cursor is persisted, freeing space for blocked producers.
+func (q *Queue) Ack(pos Position) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if pos.Segment < q.commit.Segment || (pos.Segment == q.commit.Segment && pos.Offset <= q.commit.Offset) {
+		return nil
+	}
+	q.commit = pos
+
+	if err := q.saveCursorLocked(); err != nil {
+		return err
+	}
+	if err := q.removeSegmentsBefore(pos.Segment); err != nil {
+		slog.Warn("failed to remove acknowledged queue segment", "listener", q.config.Name, "error", err)
+	}
+
+	q.updateMetrics()
+	q.cond.Broadcast()
+	return nil
+}
+
+// Rewind makes the next ReadBatch start again after the last acknowledged record, so
+// records the consumer could not deliver are read again.
+func (q *Queue) Rewind() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.read = q.commit
+}
+
+// Done returns a channel that is closed when the queue is closed.
+func (q *Queue) Done() <-chan struct{} {
+	return q.stopSync
+}
+
+// Pending returns the number of unacknowledged bytes in the queue.
+func (q *Queue) Pending() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.usedLocked()
+}
+
+// Close flushes the queue to disk and unblocks any waiting producers and consumer.
+// Unacknowledged records remain on disk and are delivered after the next Open.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	q.cond.Broadcast()
+	q.mu.Unlock()
+
+	close(q.stopSync)
+	<-q.syncDone
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.writer.Sync(); err != nil {
+		_ = q.writer.Close()
+		return err
+	}
+	return q.writer.Close()
+}
+
+// syncLoop periodically fsyncs the active segment.
+func (q *Queue) syncLoop() {
+	defer close(q.syncDone)
+
+	ticker := time.NewTicker(q.config.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			q.mu.Lock()
+			if err := q.writer.Sync(); err != nil {
+				slog.Warn("failed to sync forward queue", "listener", q.config.Name, "error", err)
+			}
+			q.mu.Unlock()
+		case <-q.stopSync:
+			return
+		}
+	}
+}
+
+// rotateLocked starts a new segment. Callers must hold q.mu.
+func (q *Queue) rotateLocked() error {
+	if err := q.writer.Sync(); err != nil {
+		return err
+	}
+	if err := q.writer.Close(); err != nil {
+		return err
+	}
+
+	id := q.segments[len(q.segments)-1].id + 1
+	// #nosec G304 -- segment paths are built from the configured queue directory.
+	writer, err := os.OpenFile(q.segmentPath(id), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	q.writer = writer
+	q.segments = append(q.segments, segment{id: id})
+	return nil
+}
+
+// removeSegmentsBefore deletes segments with IDs lower than id. The active segment is never removed.
+func (q *Queue) removeSegmentsBefore(id uint64) error {
+	for len(q.segments) > 1 && q.segments[0].id < id {
+		if err := os.Remove(q.segmentPath(q.segments[0].id)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		q.segments = q.segments[1:]
+	}
+	return nil
+}
+
+func (q *Queue) saveCursorLocked() error {
+	data, err := json.Marshal(q.commit)
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(filepath.Join(q.config.Dir, cursorFile), data, 0600)
+}
+
+// hasUnreadLocked reports whether records exist after the read position. Callers must hold q.mu.
+func (q *Queue) hasUnreadLocked() bool {
+	last := q.segments[len(q.segments)-1]
+	if q.read.Segment < last.id {
+		return true
+	}
+	return q.read.Offset < last.size
+}
+
+// usedLocked returns the unacknowledged bytes. Callers must hold q.mu.
+func (q *Queue) usedLocked() int64 {
+	var used int64
+	for _, seg := range q.segments {
+		if seg.id >= q.commit.Segment {
+			used += seg.size
+		}
+	}
+	return used - q.commit.Offset
+}
+
+func (q *Queue) updateMetrics() {
+	metrics.SetMapInt(metrics.QueueBytes, q.config.Name, q.usedLocked())
+}
+
+// maxPayload returns the size of the largest record payload.
+func (q *Queue) maxPayload() int64 {
+	return int64(q.config.MaxLineBytes) + fieldBytes
+}
+
+func (q *Queue) segmentPath(id uint64) string {
+	return filepath.Join(q.config.Dir, fmt.Sprintf("%020d%s", id, segmentSuffix))
+}
+
+func (q *Queue) segmentSize(id uint64) (int64, error) {
+	info, err := os.Stat(q.segmentPath(id))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// listSegments returns the IDs of segment files in the queue directory, oldest first.
+func (q *Queue) listSegments() ([]uint64, error) {
+	entries, err := os.ReadDir(q.config.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var ids []uint64
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	// Segment IDs must be contiguous; keep only the newest contiguous run
+	for i := len(ids) - 1; i > 0; i-- {
+		if ids[i-1] != ids[i]-1 {
+			slog.Warn("ignoring queue segments before gap", "listener", q.config.Name, "segment", ids[i])
+			ids = ids[i:]
+			break
+		}
+	}
+	return ids, nil
+}
+
+// encodeRecord frames a record as header + payload.
+// Payload layout: version byte, then uvarint length-prefixed connID, host and target, then data.
+func encodeRecord(r Record) []byte {
+	payload := make([]byte, 0, 1+3*binary.MaxVarintLen64+len(r.ConnID)+len(r.Host)+len(r.Target)+len(r.Data))
+	payload = append(payload, recordVersion)
+	payload = binary.AppendUvarint(payload, uint64(len(r.ConnID)))
+	payload = append(payload, r.ConnID...)
+	payload = binary.AppendUvarint(payload, uint64(len(r.Host)))
+	payload = append(payload, r.Host...)
+	payload = binary.AppendUvarint(payload, uint64(len(r.Target)))
+	payload = append(payload, r.Target...)
+	payload = append(payload, r.Data...)
+
+	buf := make([]byte, headerSize, headerSize+len(payload))
+	// #nosec G115 -- a single log line is bounded by max_line_bytes, far below 4 GiB.
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+	return append(buf, payload...)
+}
+
+// readRecord reads the record at offset in a segment of size bytes and returns it with the
+// offset of the next record. A payload length beyond the end of the segment or above
+// maxPayload marks a torn or damaged header, and is rejected before anything is allocated.
+func readRecord(r io.ReaderAt, offset, size, maxPayload int64) (Record, int64, error) {
+	var header [headerSize]byte
+	if _, err := r.ReadAt(header[:], offset); err != nil {
+		return Record{}, offset, err
+	}
+	length := binary.BigEndian.Uint32(header[0:4])
+	sum := binary.BigEndian.Uint32(header[4:8])
+	if int64(length) > size-offset-headerSize || int64(length) > maxPayload {
+		return Record{}, offset, fmt.Errorf("%w at offset %d: length %d out of bounds", errCorrupt, offset, length)
+	}
+
+	payload := make([]byte, length)
+	if _, err := r.ReadAt(payload, offset+headerSize); err != nil {
+		return Record{}, offset, err
+	}
+	if crc32.ChecksumIEEE(payload) != sum {
+		return Record{}, offset, fmt.Errorf("%w at offset %d: checksum mismatch", errCorrupt, offset)
+	}
+
+	if len(payload) < 1 || payload[0] != recordVersion {
+		return Record{}, offset, fmt.Errorf("%w at offset %d: unsupported version", errCorrupt, offset)
+	}
+	rest := payload[1:]
+
+	connID, rest, ok := readField(rest)
+	if !ok {
+		return Record{}, offset, fmt.Errorf("%w at offset %d: bad connection ID length", errCorrupt, offset)
+	}
+	host, rest, ok := readField(rest)
+	if !ok {
+		return Record{}, offset, fmt.Errorf("%w at offset %d: bad host length", errCorrupt, offset)
+	}
+	target, rest, ok := readField(rest)
+	if !ok {
+		return Record{}, offset, fmt.Errorf("%w at offset %d: bad target length", errCorrupt, offset)
+	}
+
+	return Record{
+		ConnID: string(connID),
+		Host:   string(host),
+		Target: string(target),
+		Data:   rest,
+	}, offset + headerSize + int64(length), nil
+}
+
+// readField reads a uvarint length-prefixed field and returns it with the remaining bytes.
+func readField(b []byte) ([]byte, []byte, bool) {
+	n, size := binary.Uvarint(b)
+	if size <= 0 || uint64(len(b)-size) < n {
+		return nil, nil, false
+	}
+	// #nosec G115 -- n was checked against the buffer length above.
+	end := size + int(n)
+	return b[size:end], b[end:], true
+}
@@ -0,0 +1,301 @@
+package queue
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestQueue(t *testing.T, cfg Config) *Queue {
+	t.Helper()
+	q, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	return q
+}
+
+func appendN(t *testing.T, q *Queue, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := q.Append(Record{ConnID: "conn", Data: []byte(fmt.Sprintf(`{"n":%d}`, i))}); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+}
+
+func TestQueue_AppendReadAck(t *testing.T) {
+	q := openTestQueue(t, Config{Dir: t.TempDir()})
+	defer q.Close()
+
+	appendN(t, q, 3)
+
+	records, pos, err := q.ReadBatch(10, time.Second)
+	if err != nil {
+		t.Fatalf("ReadBatch() error = %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("ReadBatch() returned %d records, want 3", len(records))
+	}
+	if records[0].ConnID != "conn" || string(records[2].Data) != `{"n":2}` {
+		t.Errorf("unexpected records: %+v", records)
+	}
+
+	if err := q.Ack(pos); err != nil {
+		t.Fatalf("Ack() error = %v", err)
+	}
+	if p := q.Pending(); p != 0 {
+		t.Errorf("Pending() = %d, want 0 after ack", p)
+	}
+}
+
+func TestQueue_ReadBatchTimesOutWhenEmpty(t *testing.T) {
+	q := openTestQueue(t, Config{Dir: t.TempDir()})
+	defer q.Close()
+
+	start := time.Now()
+	records, _, err := q.ReadBatch(10, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("ReadBatch() error = %v", err)
+	}
+	if len(records) != 0 {
+		t.Errorf("ReadBatch() returned %d records, want 0", len(records))
+	}
+	if time.Since(start) < 50*time.Millisecond {
+		t.Error("ReadBatch() returned before the wait elapsed")
+	}
+}
+
+func TestQueue_UnackedRecordsSurviveReopen(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestQueue(t, Config{Dir: dir})
+	appendN(t, q, 4)
+
+	records, _, err := q.ReadBatch(2, time.Second)
+	if err != nil || len(records) != 2 {
+		t.Fatalf("ReadBatch() = %d records, %v", len(records), err)
+	}
+	_, pos, _ := q.ReadBatch(1, time.Second)
+	if err := q.Ack(pos); err != nil {
+		t.Fatalf("Ack() error = %v", err)
+	}
+	// The fourth record was never read, and nothing after pos was acknowledged
+	if err := q.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	q = openTestQueue(t, Config{Dir: dir})
+	defer q.Close()
+
+	records, _, err = q.ReadBatch(10, time.Second)
+	if err != nil {
+		t.Fatalf("ReadBatch() error = %v", err)
+	}
+	if len(records) != 1 || string(records[0].Data) != `{"n":3}` {
+		t.Errorf("records after reopen = %+v, want only n=3", records)
+	}
+}
+
+func TestQueue_DamagedCursorReplaysFromStart(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestQueue(t, Config{Dir: dir})
+	appendN(t, q, 3)
+	_, pos, err := q.ReadBatch(2, time.Second)
+	if err != nil {
+		t.Fatalf("ReadBatch() error = %v", err)
+	}
+	if err := q.Ack(pos); err != nil {
+		t.Fatalf("Ack() error = %v", err)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// A cursor cut short by a power failure
+	if err := os.WriteFile(filepath.Join(dir, cursorFile), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	q = openTestQueue(t, Config{Dir: dir})
+	defer q.Close()
+	records, _, err := q.ReadBatch(10, time.Second)
+	if err != nil {
+		t.Fatalf("ReadBatch() error = %v", err)
+	}
+	if len(records) != 3 {
+		t.Errorf("ReadBatch() after a damaged cursor returned %d records, want all 3", len(records))
+	}
+}
+
+func TestQueue_TornWriteTruncated(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestQueue(t, Config{Dir: dir})
+	appendN(t, q, 2)
+	if err := q.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// Simulate a crash part way through writing a third record
+	seg := filepath.Join(dir, fmt.Sprintf("%020d.seg", 1))
+	f, err := os.OpenFile(seg, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	partial := encodeRecord(Record{ConnID: "conn", Data: []byte(`{"n":2}`)})
+	if _, err := f.Write(partial[:len(partial)-3]); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	q = openTestQueue(t, Config{Dir: dir})
+	defer q.Close()
+
+	appendN(t, q, 1)
+	records, _, err := q.ReadBatch(10, time.Second)
+	if err != nil {
+		t.Fatalf("ReadBatch() error = %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("ReadBatch() returned %d records, want 3 (2 recovered + 1 new)", len(records))
+	}
+	if string(records[2].Data) != `{"n":0}` {
+		t.Errorf("record after truncated tail = %q, want {\"n\":0}", records[2].Data)
+	}
+}
+
+func TestQueue_RecordLengthBounded(t *testing.T) {
+	buf := encodeRecord(Record{ConnID: "conn", Data: []byte(`{"n":1}`)})
+	size := int64(len(buf))
+
+	// A torn header claiming 4 GiB, and a length that fits the segment but exceeds the limit
+	huge := append([]byte{}, buf...)
+	binary.BigEndian.PutUint32(huge[0:4], 0xFFFFFFFF)
+	if _, _, err := readRecord(bytes.NewReader(huge), 0, size, DefaultMaxLineBytes+fieldBytes); !errors.Is(err, errCorrupt) {
+		t.Errorf("readRecord() with a 4 GiB length error = %v, want errCorrupt", err)
+	}
+	if _, _, err := readRecord(bytes.NewReader(buf), 0, size, 4); !errors.Is(err, errCorrupt) {
+		t.Errorf("readRecord() above the maximum payload error = %v, want errCorrupt", err)
+	}
+
+	// Open truncates the damaged record instead of allocating for it
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("%020d.seg", 1)), append(buf, huge...), 0600); err != nil {
+		t.Fatal(err)
+	}
+	q := openTestQueue(t, Config{Dir: dir})
+	defer q.Close()
+	if got := q.Pending(); got != size {
+		t.Errorf("Pending() after recovery = %d, want %d", got, size)
+	}
+
+	if err := q.Append(Record{Data: make([]byte, DefaultMaxLineBytes+fieldBytes)}); err == nil {
+		t.Error("Append() should reject a record larger than a maximum line and its fields")
+	}
+}
+
+func TestQueue_SegmentsRotateAndAreRemoved(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestQueue(t, Config{Dir: dir, SegmentBytes: 64})
+	defer q.Close()
+
+	appendN(t, q, 10)
+
+	segments, _ := filepath.Glob(filepath.Join(dir, "*.seg"))
+	if len(segments) < 2 {
+		t.Fatalf("expected multiple segments, got %d", len(segments))
+	}
+
+	records, pos, err := q.ReadBatch(100, time.Second)
+	if err != nil || len(records) != 10 {
+		t.Fatalf("ReadBatch() = %d records, %v", len(records), err)
+	}
+	if err := q.Ack(pos); err != nil {
+		t.Fatalf("Ack() error = %v", err)
+	}
+
+	segments, _ = filepath.Glob(filepath.Join(dir, "*.seg"))
+	if len(segments) != 1 {
+		t.Errorf("expected acknowledged segments to be removed, %d remain", len(segments))
+	}
+}
+
+func TestQueue_AppendBlocksWhenFull(t *testing.T) {
+	q := openTestQueue(t, Config{Dir: t.TempDir(), MaxBytes: 64})
+	defer q.Close()
+
+	appendN(t, q, 2) // Fills the queue past MaxBytes
+
+	appended := make(chan error, 1)
+	go func() {
+		appended <- q.Append(Record{ConnID: "conn", Data: []byte(`{"blocked":true}`)})
+	}()
+
+	select {
+	case <-appended:
+		t.Fatal("Append() should block while the queue is full")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	_, pos, _ := q.ReadBatch(10, time.Second)
+	if err := q.Ack(pos); err != nil {
+		t.Fatalf("Ack() error = %v", err)
+	}
+
+	select {
+	case err := <-appended:
+		if err != nil {
+			t.Errorf("Append() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Append() should unblock once space is acknowledged")
+	}
+}
+
+func TestQueue_CloseUnblocks(t *testing.T) {
+	q := openTestQueue(t, Config{Dir: t.TempDir()})
+
+	read := make(chan error, 1)
+	go func() {
+		_, _, err := q.ReadBatch(10, time.Minute)
+		read <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	if err := q.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	select {
+	case err := <-read:
+		if !errors.Is(err, ErrClosed) {
+			t.Errorf("ReadBatch() error = %v, want ErrClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ReadBatch() should return when the queue is closed")
+	}
+
+	if err := q.Append(Record{Data: []byte("x")}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Append() after Close error = %v, want ErrClosed", err)
+	}
+}
+
+func TestQueue_RecordHostRoundTrip(t *testing.T) {
+	r := Record{ConnID: "conn", Host: "10.0.0.5", Target: "metrics", Data: []byte(`{"n":1}`)}
+	buf := encodeRecord(r)
+
+	got, next, err := readRecord(bytes.NewReader(buf), 0, int64(len(buf)), DefaultMaxLineBytes+fieldBytes)
+	if err != nil {
+		t.Fatalf("readRecord() error = %v", err)
+	}
+	if got.ConnID != r.ConnID || got.Host != r.Host || got.Target != r.Target || string(got.Data) != string(r.Data) {
+		t.Errorf("readRecord() = %+v, want %+v", got, r)
+	}
+	if next != int64(len(buf)) {
+		t.Errorf("next offset = %d, want %d", next, len(buf))
+	}
+}
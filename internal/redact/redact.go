@@ -0,0 +1,272 @@
+// Package redact removes or pseudonymises fields of JSON log lines before they are stored
+// or forwarded. Fields are named by dotted JSON paths and can be dropped, masked with a fixed
+// value, or replaced with a keyed HMAC pseudonym, so the same value always maps to the same
+// pseudonym without revealing it.
+package redact
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Redaction actions.
+const (
+	Drop = "drop" // Remove the field
+	Mask = "mask" // Replace the value with a fixed string
+	HMAC = "hmac" // Replace the value with a keyed HMAC-SHA256 pseudonym
+)
+
+// DefaultMask is the value a masked field is replaced with when the rule gives none.
+const DefaultMask = "[REDACTED]"
+
+// MinKeyBytes is the minimum length of the HMAC key.
+const MinKeyBytes = 16
+
+// pseudonymBytes is the number of bytes of the HMAC kept in a pseudonym (32 hex digits).
+const pseudonymBytes = 16
+
+// Rule redacts the value at a path.
+type Rule struct {
+	Path   string // Dotted path of object keys, such as Username or Policy.Name; "$." may prefix it
+	Action string // Drop, Mask or HMAC
+	Mask   string // Replacement for Mask (default: DefaultMask)
+}
+
+// Redactor applies a set of rules to JSON lines.
+//
+// Redactor is safe for concurrent use by multiple goroutines.
+type Redactor struct {
+	root *node
+	key  []byte
+}
+
+// node is a path segment of the compiled rules.
+type node struct {
+	rule     *Rule            // Rule for the value at this path, if any
+	children map[string]*node // Rules for fields inside the value
+}
+
+// New compiles rules into a Redactor. key is required by HMAC rules and must be at least
+// MinKeyBytes long. When several rules name the same path the last one applies, and a rule
+// for an object applies to the whole object, whatever rules name fields inside it.
+// It returns nil if there are no rules.
+func New(rules []Rule, key []byte) (*Redactor, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	r := &Redactor{root: &node{}, key: key}
+	for i := range rules {
+		rule := rules[i]
+		segments, err := parsePath(rule.Path)
+		if err != nil {
+			return nil, err
+		}
+		switch rule.Action {
+		case Drop, HMAC:
+			if rule.Mask != "" {
+				return nil, fmt.Errorf("path %s: mask is only used with action '%s'", rule.Path, Mask)
+			}
+		case Mask:
+			if rule.Mask == "" {
+				rule.Mask = DefaultMask
+			}
+		default:
+			return nil, fmt.Errorf("path %s: action must be '%s', '%s' or '%s'", rule.Path, Drop, Mask, HMAC)
+		}
+		if rule.Action == HMAC && len(key) < MinKeyBytes {
+			return nil, fmt.Errorf("path %s: action '%s' requires a key of at least %d bytes", rule.Path, HMAC, MinKeyBytes)
+		}
+
+		n := r.root
+		for _, segment := range segments {
+			child, ok := n.children[segment]
+			if !ok {
+				child = &node{}
+				if n.children == nil {
+					n.children = make(map[string]*node)
+				}
+				n.children[segment] = child
+			}
+			n = child
+		}
+		n.rule = &rule
+	}
+	return r, nil
+}
+
+// parsePath splits a dotted path into its keys.
+func parsePath(path string) ([]string, error) {
+	trimmed := strings.TrimPrefix(path, "$.")
+	if trimmed == "" {
+		return nil, errors.New("path is required")
+	}
+	segments := strings.Split(trimmed, ".")
+	for _, segment := range segments {
+		if segment == "" {
+			return nil, fmt.Errorf("path %s: empty key", path)
+		}
+	}
+	return segments, nil
+}
+
+// Apply returns the line with the rules applied. Arrays along a path are redacted element by
+// element; null values are not masked or pseudonymised. The line is returned unchanged if no
+// rule matches or it is not valid JSON. A nil Redactor returns the line unchanged.
+// Newline-separated lines, such as a batch from tail mode or backfill, are redacted one by one.
+func (r *Redactor) Apply(line []byte) []byte {
+	if r == nil {
+		return line
+	}
+	trimmed := bytes.TrimSpace(line)
+	if bytes.IndexByte(trimmed, '\n') >= 0 {
+		return r.applyLines(line)
+	}
+	if out, changed := r.rewrite(trimmed, r.root); changed {
+		return out
+	}
+	return line
+}
+
+// applyLines applies the rules to each line of newline-separated data.
+func (r *Redactor) applyLines(data []byte) []byte {
+	lines := bytes.Split(data, []byte("\n"))
+	changed := false
+	for i, line := range lines {
+		if out, ok := r.rewrite(bytes.TrimSpace(line), r.root); ok {
+			lines[i] = out
+			changed = true
+		}
+	}
+	if !changed {
+		return data
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
+
+// rewrite applies the rules below n to a JSON value. It reports whether the value changed.
+func (r *Redactor) rewrite(value []byte, n *node) ([]byte, bool) {
+	if len(value) == 0 {
+		return value, false
+	}
+	switch value[0] {
+	case '{':
+		return r.rewriteObject(value, n)
+	case '[':
+		return r.rewriteArray(value, n)
+	}
+	return value, false
+}
+
+// rewriteObject applies the rules below n to the fields of an object, keeping their order.
+func (r *Redactor) rewriteObject(value []byte, n *node) ([]byte, bool) {
+	dec := json.NewDecoder(bytes.NewReader(value))
+	if _, err := dec.Token(); err != nil {
+		return value, false
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(len(value))
+	buf.WriteByte('{')
+	changed := false
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return value, false
+		}
+		key, _ := tok.(string)
+		var field json.RawMessage
+		if err := dec.Decode(&field); err != nil {
+			return value, false
+		}
+
+		if child, ok := n.children[key]; ok {
+			if child.rule != nil {
+				if child.rule.Action == Drop {
+					changed = true
+					continue
+				}
+				if !bytes.Equal(field, []byte("null")) {
+					field = r.redact(field, child.rule)
+					changed = true
+				}
+			} else if out, ok := r.rewrite(field, child); ok {
+				field = out
+				changed = true
+			}
+		}
+
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.Write(quote(key))
+		buf.WriteByte(':')
+		buf.Write(field)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), changed
+}
+
+// rewriteArray applies the rules below n to each element of an array.
+func (r *Redactor) rewriteArray(value []byte, n *node) ([]byte, bool) {
+	var elements []json.RawMessage
+	if err := json.Unmarshal(value, &elements); err != nil {
+		return value, false
+	}
+
+	changed := false
+	out := make([][]byte, len(elements))
+	for i, element := range elements {
+		var ok bool
+		if out[i], ok = r.rewrite(element, n); ok {
+			changed = true
+		}
+	}
+	if !changed {
+		return value, false
+	}
+	return append(append([]byte{'['}, bytes.Join(out, []byte{','})...), ']'), true
+}
+
+// redact returns the replacement for a value under a Mask or HMAC rule.
+func (r *Redactor) redact(value json.RawMessage, rule *Rule) []byte {
+	if rule.Action == Mask {
+		return quote(rule.Mask)
+	}
+	return quote(r.pseudonym(value))
+}
+
+// pseudonym returns the hex HMAC of a value: of the text of a string, or the compact JSON of
+// anything else, so equal values get equal pseudonyms.
+func (r *Redactor) pseudonym(value json.RawMessage) string {
+	var text []byte
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		text = []byte(s)
+	} else {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, value); err != nil {
+			compact.Write(value)
+		}
+		text = compact.Bytes()
+	}
+
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write(text)
+	return hex.EncodeToString(mac.Sum(nil)[:pseudonymBytes])
+}
+
+// quote returns s as a JSON string, without escaping HTML characters.
+func quote(s string) []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s) // Encoding a string cannot fail
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'})
+}
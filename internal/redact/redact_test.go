@@ -0,0 +1,171 @@
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+// pseudonymOf returns the pseudonym New(…, testKey) gives a string value.
+func pseudonymOf(s string) string {
+	mac := hmac.New(sha256.New, testKey)
+	mac.Write([]byte(s))
+	return hex.EncodeToString(mac.Sum(nil)[:pseudonymBytes])
+}
+
+func TestApply(t *testing.T) {
+	line := `{"Username":"jdoe@example.com","ClientPublicIP":"203.0.113.7","ClientPrivateIP":"10.1.2.3",` +
+		`"Policy":{"Name":"Finance","ID":42},"Connectors":[{"IP":"10.0.0.1"},{"IP":"10.0.0.2"}],"Idp":null}`
+
+	tests := []struct {
+		name     string
+		rules    []Rule
+		expected string
+	}{
+		{
+			name:     "drop",
+			rules:    []Rule{{Path: "ClientPrivateIP", Action: Drop}},
+			expected: strings.Replace(line, `"ClientPrivateIP":"10.1.2.3",`, "", 1),
+		},
+		{
+			name:     "mask with default",
+			rules:    []Rule{{Path: "$.ClientPublicIP", Action: Mask}},
+			expected: strings.Replace(line, `"203.0.113.7"`, `"[REDACTED]"`, 1),
+		},
+		{
+			name:     "mask with value",
+			rules:    []Rule{{Path: "ClientPublicIP", Action: Mask, Mask: "0.0.0.0"}},
+			expected: strings.Replace(line, `"203.0.113.7"`, `"0.0.0.0"`, 1),
+		},
+		{
+			name:     "hmac",
+			rules:    []Rule{{Path: "Username", Action: HMAC}},
+			expected: strings.Replace(line, `"jdoe@example.com"`, `"`+pseudonymOf("jdoe@example.com")+`"`, 1),
+		},
+		{
+			name:     "nested field",
+			rules:    []Rule{{Path: "Policy.Name", Action: Mask}},
+			expected: strings.Replace(line, `"Finance"`, `"[REDACTED]"`, 1),
+		},
+		{
+			name:     "array elements",
+			rules:    []Rule{{Path: "Connectors.IP", Action: Drop}},
+			expected: strings.Replace(line, `[{"IP":"10.0.0.1"},{"IP":"10.0.0.2"}]`, `[{},{}]`, 1),
+		},
+		{
+			name:     "null left as is",
+			rules:    []Rule{{Path: "Idp", Action: HMAC}},
+			expected: line,
+		},
+		{
+			name:     "missing field",
+			rules:    []Rule{{Path: "SessionID", Action: Drop}},
+			expected: line,
+		},
+		{
+			name:     "last rule for a path applies",
+			rules:    []Rule{{Path: "Username", Action: HMAC}, {Path: "Username", Action: Drop}},
+			expected: strings.Replace(line, `"Username":"jdoe@example.com",`, "", 1),
+		},
+		{
+			name:     "object rule covers its fields",
+			rules:    []Rule{{Path: "Policy.Name", Action: Mask}, {Path: "Policy", Action: Drop}},
+			expected: strings.Replace(line, `"Policy":{"Name":"Finance","ID":42},`, "", 1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := New(tt.rules, testKey)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if got := string(r.Apply([]byte(line))); got != tt.expected {
+				t.Errorf("Apply() =\n%s\nwant\n%s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestApply_Pseudonyms(t *testing.T) {
+	r, _ := New([]Rule{{Path: "Username", Action: HMAC}, {Path: "CustomerID", Action: HMAC}}, testKey)
+
+	first := string(r.Apply([]byte(`{"Username":"jdoe@example.com","CustomerID":12345}`)))
+	second := string(r.Apply([]byte(`{"CustomerID": 12345, "Username": "jdoe@example.com"}`)))
+	if !strings.Contains(second, pseudonymOf("jdoe@example.com")) || !strings.Contains(first, pseudonymOf("12345")) {
+		t.Errorf("equal values should get equal pseudonyms:\n%s\n%s", first, second)
+	}
+
+	other, _ := New([]Rule{{Path: "Username", Action: HMAC}}, []byte("another key of sufficient length"))
+	if strings.Contains(string(other.Apply([]byte(`{"Username":"jdoe@example.com"}`))), pseudonymOf("jdoe@example.com")) {
+		t.Error("pseudonyms should depend on the key")
+	}
+}
+
+func TestApply_Lines(t *testing.T) {
+	r, _ := New([]Rule{{Path: "Username", Action: Mask}}, nil)
+
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{name: "every line", data: "{\"Username\":\"a\"}\n{\"Username\":\"b\"}", expected: "{\"Username\":\"[REDACTED]\"}\n{\"Username\":\"[REDACTED]\"}"},
+		{name: "first line unchanged", data: "{\"User\":\"a\"}\n{\"Username\":\"b\"}", expected: "{\"User\":\"a\"}\n{\"Username\":\"[REDACTED]\"}"},
+		{name: "no match", data: "{\"User\":\"a\"}\nnot json", expected: "{\"User\":\"a\"}\nnot json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(r.Apply([]byte(tt.data))); got != tt.expected {
+				t.Errorf("Apply() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestApply_Unchanged(t *testing.T) {
+	r, _ := New([]Rule{{Path: "Username", Action: Drop}}, nil)
+
+	for _, line := range []string{`not json`, `["Username"]`, `{"Username":`, `{"User":"x"}`} {
+		if got := string(r.Apply([]byte(line))); got != line {
+			t.Errorf("Apply(%q) = %q, want the line unchanged", line, got)
+		}
+	}
+
+	var nilRedactor *Redactor
+	if got := string(nilRedactor.Apply([]byte(`{"a":1}`))); got != `{"a":1}` {
+		t.Errorf("nil Redactor should return the line unchanged, got %q", got)
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    []Rule
+		key      []byte
+		expected string
+	}{
+		{"empty path", []Rule{{Path: "", Action: Drop}}, nil, "path is required"},
+		{"empty key", []Rule{{Path: "Policy..Name", Action: Drop}}, nil, "path Policy..Name: empty key"},
+		{"unknown action", []Rule{{Path: "Username", Action: "hash"}}, nil, "action must be 'drop', 'mask' or 'hmac'"},
+		{"mask with drop", []Rule{{Path: "Username", Action: Drop, Mask: "x"}}, nil, "mask is only used with action 'mask'"},
+		{"hmac without key", []Rule{{Path: "Username", Action: HMAC}}, []byte("short"), "requires a key of at least 16 bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.rules, tt.key)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+
+	if r, err := New(nil, nil); r != nil || err != nil {
+		t.Errorf("New(nil) = %v, %v, want nil, nil", r, err)
+	}
+}
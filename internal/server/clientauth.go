@@ -0,0 +1,146 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"log/slog"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/scottbrown/relay/internal/audit"
+	"github.com/scottbrown/relay/internal/metrics"
+)
+
+// Client certificate verification modes for TLS listeners.
+const (
+	ClientAuthNone     = "none"     // Do not request client certificates (default)
+	ClientAuthOptional = "optional" // Verify a client certificate if one is presented
+	ClientAuthRequired = "required" // Require a verified client certificate
+)
+
+// peerIdentity returns the identity of a verified client certificate:
+// its subject CN, or its first SAN if the CN is empty.
+func peerIdentity(cert *x509.Certificate) string {
+	for _, name := range certNames(cert) {
+		if name != "" {
+			return name
+		}
+	}
+	return cert.Subject.String()
+}
+
+// certNames returns the subject CN followed by all SANs (DNS, email, URI and IP) of a certificate.
+func certNames(cert *x509.Certificate) []string {
+	names := []string{cert.Subject.CommonName}
+	names = append(names, cert.DNSNames...)
+	names = append(names, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	return names
+}
+
+// clientAllowed reports whether the certificate's CN or any SAN is in the allow-list.
+// An empty allow-list allows every verified certificate.
+func clientAllowed(cert *x509.Certificate, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, name := range certNames(cert) {
+		if name != "" && slices.Contains(allowed, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientAuthMode returns the effective client certificate mode.
+func (s *Server) clientAuthMode() string {
+	if s.config.TLSClientAuth == "" {
+		return ClientAuthNone
+	}
+	return s.config.TLSClientAuth
+}
+
+// authenticate completes the TLS handshake of a client-authenticated listener and
+// checks the client certificate against the allow-list. It returns the verified
+// client identity ("" if no certificate was presented) and whether the client may stream.
+// Failures are logged and audited; the caller closes the connection.
+func (s *Server) authenticate(conn net.Conn, connID, clientAddr string) (string, bool) {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok || s.clientAuthMode() == ClientAuthNone {
+		return "", true
+	}
+
+	_ = tlsConn.SetDeadline(time.Now().Add(tlsHandshakeTimeout)) // Best effort
+	err := tlsConn.Handshake()
+	_ = tlsConn.SetDeadline(time.Time{})
+	if err != nil {
+		s.authFailed(connID, clientAddr, clientAddr, "handshake_failed", err.Error())
+		return "", false
+	}
+
+	certs := tlsConn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		// Only possible in optional mode; an allow-list still needs an identity to check
+		if len(s.config.TLSAllowedClients) > 0 {
+			s.authFailed(connID, clientAddr, clientAddr, "no_client_certificate", "client certificate required by allowed_clients")
+			return "", false
+		}
+		return "", true
+	}
+
+	identity := peerIdentity(certs[0])
+	if !clientAllowed(certs[0], s.config.TLSAllowedClients) {
+		s.authFailed(connID, clientAddr, identity, "not_allowed", "client certificate not in allowed_clients")
+		return "", false
+	}
+
+	metrics.ListenerTLSClientAuth.Add(1, s.metricName(), "success")
+	if s.auditLogger != nil {
+		_ = s.auditLogger.Log(audit.Event{
+			EventType:    audit.EventAuthSuccess,
+			Success:      true,
+			Actor:        identity,
+			Action:       "authenticate",
+			Result:       "verified",
+			ConnectionID: connID,
+			Details: map[string]interface{}{
+				"client_addr": clientAddr,
+				"issuer":      certs[0].Issuer.CommonName,
+				"serial":      certs[0].SerialNumber.String(),
+			},
+		})
+	}
+	return identity, true
+}
+
+// authFailed records a rejected client certificate.
+func (s *Server) authFailed(connID, clientAddr, actor, result, reason string) {
+	metrics.ListenerTLSClientAuth.Add(1, s.metricName(), "failure")
+	slog.Warn("client authentication failed",
+		"conn_id", connID,
+		"client_addr", clientAddr,
+		"client_identity", actor,
+		"result", result,
+		"reason", reason)
+
+	if s.auditLogger != nil {
+		_ = s.auditLogger.Log(audit.Event{
+			EventType:    audit.EventAuthFailure,
+			Success:      false,
+			Actor:        actor,
+			Action:       "authenticate",
+			Result:       result,
+			ConnectionID: connID,
+			Details: map[string]interface{}{
+				"client_addr": clientAddr,
+				"reason":      reason,
+			},
+		})
+	}
+}
@@ -0,0 +1,340 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scottbrown/relay/internal/acl"
+	"github.com/scottbrown/relay/internal/audit"
+	"github.com/scottbrown/relay/internal/forwarder"
+	"github.com/scottbrown/relay/internal/storage"
+)
+
+// testPKI is a throwaway CA that issues server and client certificates.
+type testPKI struct {
+	dir    string
+	caFile string
+	caCert *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+	serial int64
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate CA key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create CA certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse CA certificate: %v", err)
+	}
+
+	p := &testPKI{dir: t.TempDir(), caCert: cert, caKey: key, serial: 1}
+	p.caFile = filepath.Join(p.dir, "ca.crt")
+	writePEM(t, p.caFile, "CERTIFICATE", der)
+	return p
+}
+
+// issue creates a certificate signed by the CA.
+func (p *testPKI) issue(t *testing.T, template *x509.Certificate) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	p.serial++
+	template.SerialNumber = big.NewInt(p.serial)
+	if template.NotBefore.IsZero() {
+		template.NotBefore = time.Now().Add(-time.Minute)
+	}
+	if template.NotAfter.IsZero() {
+		template.NotAfter = time.Now().Add(time.Hour)
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	der, err := x509.CreateCertificate(rand.Reader, template, p.caCert, &key.PublicKey, p.caKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// serverFiles issues a server certificate for 127.0.0.1 and writes it and its key to files.
+func (p *testPKI) serverFiles(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+	certFile = filepath.Join(p.dir, "server.crt")
+	keyFile = filepath.Join(p.dir, "server.key")
+	p.writeServerPair(t, certFile, keyFile, time.Time{})
+	return certFile, keyFile
+}
+
+// writeServerPair issues a server certificate expiring at notAfter (zero = in an hour)
+// and writes it and its key to the given files.
+func (p *testPKI) writeServerPair(t *testing.T, certFile, keyFile string, notAfter time.Time) tls.Certificate {
+	t.Helper()
+	cert := p.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "relay"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		NotAfter:    notAfter,
+	})
+	keyDER, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	writePEM(t, certFile, "CERTIFICATE", cert.Certificate[0])
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return cert
+}
+
+// client issues a client certificate with the given CN.
+func (p *testPKI) client(t *testing.T, cn string) tls.Certificate {
+	t.Helper()
+	return p.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: cn},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+// startMTLSServer starts a TLS listener with client authentication and an audit log.
+func startMTLSServer(t *testing.T, pki *testPKI, clientAuth string, allowed []string) (*Server, *storage.Manager, string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	storageManager, err := storage.New(tmpDir, "zpa")
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	t.Cleanup(func() { storageManager.Close() })
+
+	aclList, err := acl.New("")
+	if err != nil {
+		t.Fatalf("failed to create ACL: %v", err)
+	}
+
+	auditFile := filepath.Join(tmpDir, "audit.log")
+	auditLogger, err := audit.New(audit.Config{Enabled: true, LogFile: auditFile, Format: "json"})
+	if err != nil {
+		t.Fatalf("failed to create audit logger: %v", err)
+	}
+	t.Cleanup(func() { auditLogger.Close() })
+
+	certFile, keyFile := pki.serverFiles(t)
+	srv, err := New(Config{
+		ListenAddr:        "127.0.0.1:0",
+		TLSCertFile:       certFile,
+		TLSKeyFile:        keyFile,
+		TLSClientCAFile:   pki.caFile,
+		TLSClientAuth:     clientAuth,
+		TLSAllowedClients: allowed,
+		MaxLineBytes:      1024,
+	}, aclList, storageManager, forwarder.New(forwarder.Config{}), auditLogger)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+
+	go func() { _ = srv.Start() }()
+	t.Cleanup(func() { _ = srv.Stop() })
+	time.Sleep(100 * time.Millisecond)
+
+	return srv, storageManager, auditFile
+}
+
+// sendOverTLS connects with the given client certificates and sends one line.
+// Errors are ignored: with TLS 1.3 a rejected client only sees the failure on a later read.
+func sendOverTLS(t *testing.T, srv *Server, pki *testPKI, certs []tls.Certificate, line string) {
+	t.Helper()
+	roots := x509.NewCertPool()
+	roots.AddCert(pki.caCert)
+	conn, err := tls.Dial("tcp", srv.listener.Addr().String(), &tls.Config{
+		RootCAs:      roots,
+		Certificates: certs,
+	})
+	if err != nil {
+		return
+	}
+	_, _ = conn.Write([]byte(line + "\n"))
+	_ = conn.Close()
+	time.Sleep(100 * time.Millisecond)
+}
+
+// auditEvents reads the audit log as a list of events.
+func auditEvents(t *testing.T, path string) []audit.Event {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	var events []audit.Event
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		var ev audit.Event
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatalf("failed to parse audit event %q: %v", line, err)
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+func findEvent(events []audit.Event, eventType audit.EventType) *audit.Event {
+	for i := range events {
+		if events[i].EventType == eventType {
+			return &events[i]
+		}
+	}
+	return nil
+}
+
+func TestServer_ClientAuthRequired(t *testing.T) {
+	pki := newTestPKI(t)
+	srv, storageManager, auditFile := startMTLSServer(t, pki, ClientAuthRequired, []string{"lss-1"})
+
+	sendOverTLS(t, srv, pki, []tls.Certificate{pki.client(t, "lss-1")}, `{"mtls":"ok"}`)
+
+	data, err := os.ReadFile(storageManager.CurrentFile())
+	if err != nil {
+		t.Fatalf("failed to read stored file: %v", err)
+	}
+	if !strings.Contains(string(data), `"mtls":"ok"`) {
+		t.Errorf("stored data = %q, want the line from the verified client", data)
+	}
+
+	events := auditEvents(t, auditFile)
+	success := findEvent(events, audit.EventAuthSuccess)
+	if success == nil || success.Actor != "lss-1" {
+		t.Fatalf("auth.success event = %+v, want actor lss-1", success)
+	}
+	accepted := findEvent(events, audit.EventConnectionAccepted)
+	if accepted == nil || accepted.Actor != "lss-1" {
+		t.Errorf("connection.accepted event = %+v, want actor lss-1", accepted)
+	}
+}
+
+func TestServer_ClientAuthRejectsMissingCertificate(t *testing.T) {
+	pki := newTestPKI(t)
+	srv, storageManager, auditFile := startMTLSServer(t, pki, ClientAuthRequired, nil)
+
+	sendOverTLS(t, srv, pki, nil, `{"mtls":"anonymous"}`)
+
+	if f := storageManager.CurrentFile(); f != "" {
+		t.Errorf("expected nothing stored from an unauthenticated client, got %s", f)
+	}
+	events := auditEvents(t, auditFile)
+	failure := findEvent(events, audit.EventAuthFailure)
+	if failure == nil || failure.Result != "handshake_failed" {
+		t.Fatalf("auth.failure event = %+v, want result handshake_failed", failure)
+	}
+	if findEvent(events, audit.EventConnectionAccepted) != nil {
+		t.Error("unexpected connection.accepted event for a rejected client")
+	}
+}
+
+func TestServer_ClientAuthAllowList(t *testing.T) {
+	pki := newTestPKI(t)
+	srv, storageManager, auditFile := startMTLSServer(t, pki, ClientAuthRequired, []string{"lss-1"})
+
+	sendOverTLS(t, srv, pki, []tls.Certificate{pki.client(t, "intruder")}, `{"mtls":"intruder"}`)
+
+	if f := storageManager.CurrentFile(); f != "" {
+		t.Errorf("expected nothing stored from a client outside the allow-list, got %s", f)
+	}
+	failure := findEvent(auditEvents(t, auditFile), audit.EventAuthFailure)
+	if failure == nil || failure.Result != "not_allowed" || failure.Actor != "intruder" {
+		t.Fatalf("auth.failure event = %+v, want result not_allowed for intruder", failure)
+	}
+}
+
+func TestServer_ClientAuthOptional(t *testing.T) {
+	pki := newTestPKI(t)
+	srv, storageManager, auditFile := startMTLSServer(t, pki, ClientAuthOptional, nil)
+
+	sendOverTLS(t, srv, pki, nil, `{"mtls":"optional"}`)
+
+	if storageManager.CurrentFile() == "" {
+		t.Fatal("expected a client without a certificate to be accepted in optional mode")
+	}
+	if findEvent(auditEvents(t, auditFile), audit.EventAuthFailure) != nil {
+		t.Error("unexpected auth.failure event in optional mode")
+	}
+}
+
+func TestClientAllowed(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://example.com/lss")
+	cert := &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "lss-1"},
+		DNSNames:       []string{"lss-1.example.com"},
+		EmailAddresses: []string{"lss@example.com"},
+		URIs:           []*url.URL{spiffe},
+		IPAddresses:    []net.IP{net.ParseIP("10.0.0.5")},
+	}
+
+	tests := []struct {
+		name    string
+		allowed []string
+		want    bool
+	}{
+		{"empty allow-list", nil, true},
+		{"common name", []string{"lss-1"}, true},
+		{"DNS SAN", []string{"lss-1.example.com"}, true},
+		{"email SAN", []string{"lss@example.com"}, true},
+		{"URI SAN", []string{"spiffe://example.com/lss"}, true},
+		{"IP SAN", []string{"10.0.0.5"}, true},
+		{"no match", []string{"lss-2", "lss-2.example.com"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clientAllowed(cert, tt.allowed); got != tt.want {
+				t.Errorf("clientAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPeerIdentity(t *testing.T) {
+	if got := peerIdentity(&x509.Certificate{Subject: pkix.Name{CommonName: "lss-1"}}); got != "lss-1" {
+		t.Errorf("peerIdentity() = %q, want the CN", got)
+	}
+	if got := peerIdentity(&x509.Certificate{DNSNames: []string{"lss-1.example.com"}}); got != "lss-1.example.com" {
+		t.Errorf("peerIdentity() = %q, want the first SAN when the CN is empty", got)
+	}
+}
@@ -7,29 +7,63 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/tls"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
 	"net"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/scottbrown/relay/internal/acl"
 	"github.com/scottbrown/relay/internal/audit"
+	"github.com/scottbrown/relay/internal/filter"
 	"github.com/scottbrown/relay/internal/forwarder"
+	"github.com/scottbrown/relay/internal/logschema"
 	"github.com/scottbrown/relay/internal/metrics"
 	"github.com/scottbrown/relay/internal/processor"
+	"github.com/scottbrown/relay/internal/quarantine"
+	"github.com/scottbrown/relay/internal/queue"
+	"github.com/scottbrown/relay/internal/redact"
 	"github.com/scottbrown/relay/internal/storage"
 )
 
+const (
+	// queueReadBatch is the maximum number of queued lines forwarded per acknowledgement.
+	queueReadBatch = 512
+	// queueCommitInterval is the maximum time forwarded lines wait before being acknowledged.
+	queueCommitInterval = time.Second
+	// queueRetryMinBackoff and queueRetryMaxBackoff bound the wait before queued lines that
+	// failed to reach HEC are forwarded again.
+	queueRetryMinBackoff = time.Second
+	queueRetryMaxBackoff = 30 * time.Second
+	// queuePausePollInterval is how often a paused forwarder is checked for resume.
+	queuePausePollInterval = time.Second
+)
+
 // Config holds server configuration including listen address and TLS settings.
 type Config struct {
-	ListenAddr   string
-	TLSCertFile  string
-	TLSKeyFile   string
-	MaxLineBytes int
-	ReadTimeout  time.Duration // Timeout for each read operation
-	IdleTimeout  time.Duration // Maximum idle time between reads
+	Name              string // Listener name, used as the key in per-listener metrics
+	ListenAddr        string
+	TLSCertFile       string
+	TLSKeyFile        string
+	TLSClientCAFile   string   // CA bundle for verifying client certificates
+	TLSClientAuth     string   // Client certificate mode: none (default), optional, required
+	TLSAllowedClients []string // Allowed client certificate CNs/SANs (empty = any verified client)
+	MaxLineBytes      int
+	ReadTimeout       time.Duration      // Timeout for each read operation
+	IdleTimeout       time.Duration      // Maximum idle time between reads
+	Queue             *queue.Queue       // Optional durable forward queue; closed by Shutdown
+	StoreOnly         bool               // Only store lines; a storage tailer forwards them to HEC
+	Quarantine        *quarantine.Writer // Optional: quarantine invalid JSON and oversized lines instead of dropping them
+	Schema            *logschema.Schema  // Optional: check lines against their log type's schema
+	SchemaQuarantine  bool               // Quarantine drifted lines instead of storing and forwarding them (requires Quarantine)
+	SchemaLogEvery    int                // Log the first drifted line and then one in every N (0 or 1 = every line)
+	Redactor          *redact.Redactor   // Optional: applied to each line before it is stored and forwarded
+	Filter            *filter.Filter     // Optional: drop, sample or route lines by their field values
 }
 
 // Server manages incoming TCP/TLS connections and coordinates log processing.
@@ -43,11 +77,43 @@ type Server struct {
 	aclMu       sync.RWMutex // Protects ACL for config reloads
 	storage     *storage.Manager
 	forwarder   forwarder.Forwarder
+	forwarderMu sync.RWMutex // Held while forwarding; SetForwarder waits for forwards in progress
 	auditLogger *audit.Logger
 	listener    net.Listener
-	connections sync.WaitGroup // Tracks active connections for graceful shutdown
-	shutdown    chan struct{}  // Signals when shutdown is initiated
-	shutdownMu  sync.Mutex     // Protects shutdown channel from double-close
+	connections sync.WaitGroup         // Tracks active connections for graceful shutdown
+	shutdown    chan struct{}          // Signals when shutdown is initiated
+	shutdownMu  sync.Mutex             // Protects shutdown channel from double-close
+	forwardDone chan struct{}          // Closed when the queue forward loop exits
+	certs       *certStore             // Reloadable TLS certificate (nil without TLS)
+	certsMu     sync.Mutex             // Protects certs
+	accepting   atomic.Bool            // Whether the accept loop is running
+	lastEvent   atomic.Int64           // Time the last line was received (Unix nanoseconds)
+	driftLines  atomic.Int64           // Lines that did not match the schema, for log sampling
+	active      map[string]*activeConn // Active connections by conn_id
+	activeMu    sync.Mutex             // Protects active
+
+	// Undelivered batched lines of the forwarder at the last queue acknowledgement, and whether
+	// a forwarder replaced since then lost any; protected by forwarderMu
+	undeliveredBase uint64
+	undeliveredLost bool
+}
+
+// ConnectionInfo describes an active client connection.
+type ConnectionInfo struct {
+	ConnID     string
+	ClientAddr string
+	Identity   string // Verified client certificate identity ("" without client auth)
+	Started    time.Time
+	Bytes      int64 // Bytes received, including invalid lines
+	Lines      int64 // Lines received, including invalid lines
+}
+
+// activeConn tracks the counters of an active connection.
+type activeConn struct {
+	info  ConnectionInfo // Bytes and Lines are kept in the counters below
+	conn  net.Conn
+	bytes atomic.Int64
+	lines atomic.Int64
 }
 
 // isTestMode checks if we're running in test or benchmark mode
@@ -77,36 +143,34 @@ func New(config Config, aclList *acl.List, storageManager *storage.Manager, fwd
 		forwarder:   fwd,
 		auditLogger: auditLog,
 		shutdown:    make(chan struct{}),
+		active:      make(map[string]*activeConn),
 	}, nil
 }
 
-// Start begins accepting connections on the configured listen address.
-// It blocks until an error occurs or Stop is called.
+// Listen creates the listener on the configured listen address without accepting
+// connections, so address and TLS errors can be reported before Start is called.
+// Start calls Listen itself if it has not been called.
 //
 // If TLS is configured (TLSCertFile and TLSKeyFile are set), connections are encrypted.
-// Each accepted connection is handled in a separate goroutine.
+// With TLSClientAuth set, clients are also authenticated by certificate.
 //
 // Returns an error if the listener cannot be created or TLS setup fails.
-func (s *Server) Start() error {
+func (s *Server) Listen() error {
 	var err error
 
 	if s.config.TLSCertFile != "" && s.config.TLSKeyFile != "" {
-		cert, err := tls.LoadX509KeyPair(s.config.TLSCertFile, s.config.TLSKeyFile)
+		tlsConfig, err := s.buildTLSConfig()
 		if err != nil {
 			return err
 		}
 
-		tlsConfig := &tls.Config{
-			Certificates: []tls.Certificate{cert},
-			MinVersion:   tls.VersionTLS12,
-		}
-
 		s.listener, err = tls.Listen("tcp", s.config.ListenAddr, tlsConfig)
 		if err != nil {
 			return err
 		}
+		go s.watchCertificate(s.certs)
 
-		slog.Info("server listening", "addr", s.config.ListenAddr, "tls_enabled", true)
+		slog.Info("server listening", "addr", s.config.ListenAddr, "tls_enabled", true, "client_auth", s.clientAuthMode())
 	} else {
 		s.listener, err = net.Listen("tcp", s.config.ListenAddr)
 		if err != nil {
@@ -115,10 +179,77 @@ func (s *Server) Start() error {
 
 		slog.Info("server listening", "addr", s.config.ListenAddr, "tls_enabled", false)
 	}
+	return nil
+}
 
+// Start begins accepting connections on the configured listen address.
+// It blocks until an error occurs or Stop is called.
+// Each accepted connection is handled in a separate goroutine.
+//
+// Returns an error if the listener cannot be created or TLS setup fails.
+func (s *Server) Start() error {
+	if s.listener == nil {
+		if err := s.Listen(); err != nil {
+			return err
+		}
+	}
+
+	if s.config.Queue != nil {
+		s.forwardDone = make(chan struct{})
+		go s.forwardLoop()
+	}
+
+	s.accepting.Store(true)
+	defer s.accepting.Store(false)
 	return s.acceptLoop()
 }
 
+// Accepting reports whether the server is accepting connections.
+// It is false before Start and once shutdown has begun.
+func (s *Server) Accepting() bool {
+	return s.accepting.Load()
+}
+
+// LastEvent returns the time the last line was received, or the zero time if none has been.
+func (s *Server) LastEvent() time.Time {
+	if ns := s.lastEvent.Load(); ns != 0 {
+		return time.Unix(0, ns)
+	}
+	return time.Time{}
+}
+
+// Connections returns the active client connections, oldest first.
+func (s *Server) Connections() []ConnectionInfo {
+	s.activeMu.Lock()
+	conns := make([]ConnectionInfo, 0, len(s.active))
+	for _, c := range s.active {
+		info := c.info
+		info.Bytes = c.bytes.Load()
+		info.Lines = c.lines.Load()
+		conns = append(conns, info)
+	}
+	s.activeMu.Unlock()
+
+	sort.Slice(conns, func(i, j int) bool {
+		return conns[i].Started.Before(conns[j].Started)
+	})
+	return conns
+}
+
+// track registers an active connection until the returned function is called.
+func (s *Server) track(info ConnectionInfo, conn net.Conn) (*activeConn, func()) {
+	c := &activeConn{info: info, conn: conn}
+	s.activeMu.Lock()
+	s.active[info.ConnID] = c
+	s.activeMu.Unlock()
+
+	return c, func() {
+		s.activeMu.Lock()
+		delete(s.active, info.ConnID)
+		s.activeMu.Unlock()
+	}
+}
+
 // Stop stops the server by closing the listener.
 // Active connections are not forcibly closed but will eventually terminate.
 // Deprecated: Use Shutdown instead for graceful connection handling.
@@ -132,7 +263,8 @@ func (s *Server) Stop() error {
 // Shutdown gracefully shuts down the server by first stopping new connections,
 // then waiting for active connections to complete or timeout.
 // It returns nil if all connections closed gracefully, or an error if the context
-// deadline is exceeded while waiting for connections to finish.
+// deadline is exceeded while waiting for connections to finish. Connections still
+// active at the deadline are closed.
 func (s *Server) Shutdown(ctx context.Context) error {
 	startTime := time.Now()
 	slog.Info("initiating graceful shutdown")
@@ -147,6 +279,7 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	default:
 		close(s.shutdown)
 		s.shutdownMu.Unlock()
+		s.accepting.Store(false)
 	}
 
 	// Close listener to stop accepting new connections
@@ -164,16 +297,226 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		close(done)
 	}()
 
+	var err error
 	select {
 	case <-done:
 		duration := time.Since(startTime)
 		slog.Info("all connections closed gracefully", "duration", duration.String())
-		return nil
 	case <-ctx.Done():
 		duration := time.Since(startTime)
-		slog.Warn("shutdown timeout: some connections still active", "duration", duration.String())
-		return fmt.Errorf("shutdown timeout after %v: some connections still active", duration)
+		slog.Warn("shutdown timeout: closing connections still active", "duration", duration.String())
+		err = fmt.Errorf("shutdown timeout after %v: some connections still active", duration)
+		s.closeConnections()
 	}
+
+	s.stopForwarding(ctx)
+	return err
+}
+
+// closeConnections closes every active connection, so clients reconnect elsewhere
+// instead of sending to a listener that has been shut down.
+func (s *Server) closeConnections() {
+	s.activeMu.Lock()
+	defer s.activeMu.Unlock()
+	for _, c := range s.active {
+		_ = c.conn.Close()
+	}
+}
+
+// stopForwarding waits for the forward queue to drain until ctx expires, then closes it.
+// Lines still queued remain on disk and are forwarded after the next start.
+func (s *Server) stopForwarding(ctx context.Context) {
+	q := s.config.Queue
+	if q == nil {
+		return
+	}
+
+	if s.forwardDone != nil {
+		ticker := time.NewTicker(50 * time.Millisecond)
+		defer ticker.Stop()
+	wait:
+		for q.Pending() > 0 {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				break wait
+			}
+		}
+	}
+
+	if err := q.Close(); err != nil {
+		slog.Warn("failed to close forward queue", "error", err)
+	}
+	if s.forwardDone != nil {
+		select {
+		case <-s.forwardDone:
+		case <-ctx.Done():
+		}
+	}
+
+	if pending := q.Pending(); pending > 0 {
+		slog.Info("forward queue retained for next start", "pending_bytes", pending)
+	}
+}
+
+// forwardLoop delivers queued lines to the forwarder and acknowledges them once they
+// have reached HEC or the DLQ. Batching forwarders are flushed before each acknowledgement
+// so a crash never loses lines that were only held in memory. A line that reaches neither,
+// or a batch that fails, stops the acknowledgements there: the queue is rewound and the
+// lines are forwarded again after a backoff. While forwarding is paused the lines are held
+// in the queue until it is resumed.
+func (s *Server) forwardLoop() {
+	defer close(s.forwardDone)
+
+	q := s.config.Queue
+	var pending int
+	var pendingPos queue.Position
+	var firstPending time.Time
+	var backoff time.Duration
+
+	s.forwarderMu.Lock()
+	s.undeliveredBase = undelivered(s.forwarder)
+	s.forwarderMu.Unlock()
+
+	// commit acknowledges the lines forwarded so far. It acknowledges nothing and returns
+	// false if batched lines failed to reach HEC since the last acknowledgement.
+	commit := func() bool {
+		// A forwarder replaced since the lines were forwarded has already been flushed
+		s.forwarderMu.RLock()
+		defer s.forwarderMu.RUnlock()
+		if f, ok := s.forwarder.(forwarder.Flusher); ok {
+			f.Flush()
+		}
+		count := undelivered(s.forwarder)
+		lost := s.undeliveredLost || count > s.undeliveredBase
+		s.undeliveredBase, s.undeliveredLost = count, false
+		pending = 0
+
+		if lost {
+			slog.Warn("batched lines failed to reach HEC, forwarding them again from the queue")
+			return false
+		}
+		if err := q.Ack(pendingPos); err != nil {
+			slog.Error("failed to acknowledge forward queue", "error", err)
+		}
+		return true
+	}
+
+	// retry rewinds the queue to the first unacknowledged line and waits before it is
+	// forwarded again, backing off while HEC stays unavailable.
+	retry := func() {
+		q.Rewind()
+		backoff = min(max(2*backoff, queueRetryMinBackoff), queueRetryMaxBackoff)
+		select {
+		case <-time.After(backoff):
+		case <-q.Done():
+		}
+	}
+
+	for {
+		wait := queueCommitInterval
+		if pending > 0 {
+			wait = max(queueCommitInterval-time.Since(firstPending), 0)
+		}
+
+		records, _, err := q.ReadBatch(queueReadBatch, wait)
+		failed, paused := false, false
+		for _, r := range records {
+			if s.paused() {
+				paused = true
+				break
+			}
+			if pending == 0 {
+				firstPending = time.Now()
+			}
+			if fwdErr := s.forward(r.ConnID, r.Host, r.Target, r.Data); fwdErr != nil && !errors.Is(fwdErr, forwarder.ErrDeadLettered) {
+				slog.Warn("HEC forward failed, keeping line in forward queue", "conn_id", r.ConnID, "error", fwdErr)
+				failed = true
+				break
+			}
+			pending++
+			pendingPos = r.Next
+		}
+
+		if paused {
+			if pending > 0 {
+				commit()
+			}
+			q.Rewind()
+			select {
+			case <-time.After(queuePausePollInterval):
+			case <-q.Done():
+			}
+			continue
+		}
+
+		if failed {
+			if pending > 0 {
+				commit()
+			}
+			retry()
+			continue
+		}
+
+		if pending > 0 && (err != nil || pending >= queueReadBatch || time.Since(firstPending) >= queueCommitInterval) {
+			if !commit() {
+				retry()
+				continue
+			}
+			backoff = 0
+		}
+
+		if errors.Is(err, queue.ErrClosed) {
+			return
+		}
+		if err != nil {
+			slog.Error("failed to read forward queue", "error", err)
+			time.Sleep(queueCommitInterval)
+		}
+	}
+}
+
+// paused reports whether forwarding has been paused on every HEC target.
+func (s *Server) paused() bool {
+	s.forwarderMu.RLock()
+	defer s.forwarderMu.RUnlock()
+	p, ok := s.forwarder.(forwarder.Pauser)
+	return ok && p.Paused()
+}
+
+// undelivered returns the number of batched lines a forwarder failed to deliver, or 0 if it
+// does not batch.
+func undelivered(fwd forwarder.Forwarder) uint64 {
+	if c, ok := fwd.(forwarder.UndeliveredCounter); ok {
+		return c.Undelivered()
+	}
+	return 0
+}
+
+// forward sends a line with the client host when the forwarder can use it.
+// A line routed by a filter rule is sent to its target only.
+func (s *Server) forward(connID, host, target string, data []byte) error {
+	s.forwarderMu.RLock()
+	defer s.forwarderMu.RUnlock()
+	if target != "" {
+		tf, ok := s.forwarder.(forwarder.TargetForwarder)
+		if !ok {
+			return fmt.Errorf("forwarder cannot send to HEC target %q", target)
+		}
+		return tf.ForwardTo(target, connID, host, data)
+	}
+	if hf, ok := s.forwarder.(forwarder.HostForwarder); ok {
+		return hf.ForwardFrom(connID, host, data)
+	}
+	return s.forwarder.Forward(connID, data)
+}
+
+// metricName returns the key used for this listener in per-listener metrics.
+func (s *Server) metricName() string {
+	if s.config.Name != "" {
+		return s.config.Name
+	}
+	return s.config.ListenAddr
 }
 
 func (s *Server) acceptLoop() error {
@@ -216,7 +559,7 @@ func (s *Server) acceptLoop() error {
 		s.aclMu.RUnlock()
 
 		if !allowed {
-			metrics.ConnectionsRejected.Add(1)
+			metrics.ListenerConnectionsRejected.Add(1, s.metricName())
 			slog.Warn("connection denied by ACL", "client_ip", ra.IP.String())
 
 			// Audit: Connection rejected by ACL
@@ -239,7 +582,7 @@ func (s *Server) acceptLoop() error {
 			continue
 		}
 
-		metrics.ConnectionsAccepted.Add(1)
+		metrics.ListenerConnectionsAccepted.Add(1, s.metricName())
 		go s.handleConnection(conn)
 	}
 }
@@ -251,8 +594,8 @@ func (s *Server) handleConnection(conn net.Conn) {
 	defer conn.Close()
 
 	// Track active connections
-	metrics.ConnectionsActive.Add(1)
-	defer metrics.ConnectionsActive.Add(-1)
+	metrics.ListenerConnectionsActive.Add(1, s.metricName())
+	defer metrics.ListenerConnectionsActive.Add(-1, s.metricName())
 
 	// Check if shutdown is in progress
 	select {
@@ -264,39 +607,71 @@ func (s *Server) handleConnection(conn net.Conn) {
 
 	connID := generateConnID()
 	clientAddr := conn.RemoteAddr().String()
+	clientHost, _, err := net.SplitHostPort(clientAddr)
+	if err != nil {
+		clientHost = clientAddr
+	}
 	connStartTime := time.Now()
-	slog.Info("connection accepted", "conn_id", connID, "client_addr", clientAddr)
+
+	identity, ok := s.authenticate(conn, connID, clientAddr)
+	if !ok {
+		return
+	}
+
+	// A verified client certificate identifies the client better than its address
+	actor := clientAddr
+	logAttrs := []any{"conn_id", connID, "client_addr", clientAddr}
+	var actorDetails map[string]interface{}
+	if identity != "" {
+		actor = identity
+		logAttrs = append(logAttrs, "client_identity", identity)
+		actorDetails = map[string]interface{}{"client_addr": clientAddr}
+	}
+	slog.Info("connection accepted", logAttrs...)
+
+	stats, untrack := s.track(ConnectionInfo{
+		ConnID:     connID,
+		ClientAddr: clientAddr,
+		Identity:   identity,
+		Started:    connStartTime,
+	}, conn)
+	defer untrack()
 
 	// Audit: Connection accepted
 	if s.auditLogger != nil {
 		_ = s.auditLogger.Log(audit.Event{
 			EventType:    audit.EventConnectionAccepted,
 			Success:      true,
-			Actor:        clientAddr,
+			Actor:        actor,
 			Action:       "connect",
 			Result:       "accepted",
+			Details:      actorDetails,
 			ConnectionID: connID,
 		})
 	}
 
-	br := bufio.NewReader(conn)
+	lines := processor.NewLineReader(bufio.NewReader(conn), s.config.MaxLineBytes)
 
 	defer func() {
 		duration := time.Since(connStartTime)
-		slog.Info("connection closed", "conn_id", connID, "client_addr", clientAddr, "duration", duration.String())
+		slog.Info("connection closed", append(logAttrs, "duration", duration.String())...)
 
 		// Audit: Connection closed
 		if s.auditLogger != nil {
+			details := map[string]interface{}{
+				"duration": duration.String(),
+			}
+			if identity != "" {
+				details["client_addr"] = clientAddr
+			}
 			_ = s.auditLogger.Log(audit.Event{
 				EventType:    audit.EventConnectionClosed,
 				Success:      true,
-				Actor:        clientAddr,
+				Actor:        actor,
 				Action:       "disconnect",
 				Result:       "closed",
 				ConnectionID: connID,
-				Details: map[string]interface{}{
-					"duration": duration.String(),
-				},
+				Details:      details,
 			})
 		}
 	}()
@@ -326,7 +701,7 @@ func (s *Server) handleConnection(conn net.Conn) {
 			}
 		}
 
-		line, err := processor.ReadLineLimited(br, s.config.MaxLineBytes)
+		line, err := lines.ReadLine()
 		if err != nil {
 			// Check for timeout errors
 			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
@@ -338,52 +713,192 @@ func (s *Server) handleConnection(conn net.Conn) {
 				slog.Debug("connection EOF", "conn_id", connID, "client_addr", clientAddr)
 				return
 			}
+			// Closed by Shutdown after the drain timeout
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			var tooLong *processor.LineTooLongError
+			if errors.As(err, &tooLong) {
+				s.rejectOversized(connID, clientAddr, lines.Offset(), tooLong)
+				continue
+			}
 			slog.Warn("read error", "conn_id", connID, "client_addr", clientAddr, "error", err)
 			continue
 		}
 
 		// Track bytes received
-		metrics.BytesReceived.Add(int64(len(line)))
+		metrics.ListenerBytesReceived.Add(int64(len(line)), s.metricName())
+		s.lastEvent.Store(time.Now().UnixNano())
+		stats.bytes.Add(int64(len(line)))
+		stats.lines.Add(1)
 
 		// Validate JSON
 		if !processor.IsValidJSON(line) {
-			metrics.LinesProcessed.Add("invalid", 1)
+			metrics.ListenerLinesProcessed.Add(1, s.metricName(), "invalid")
 			slog.Warn("invalid JSON", "conn_id", connID, "client_addr", clientAddr, "line", processor.Truncate(line, 200))
+			s.writeQuarantine(quarantine.Line{
+				ConnID:     connID,
+				ClientAddr: clientAddr,
+				Offset:     lines.Offset(),
+				Reason:     quarantine.InvalidJSON,
+				Data:       line,
+			})
+			continue
+		}
+
+		// Check for schema drift; quarantined lines are neither stored nor forwarded
+		if s.config.Schema != nil && s.checkSchema(connID, clientAddr, lines.Offset(), line) {
+			continue
+		}
+
+		metrics.ListenerLinesProcessed.Add(1, s.metricName(), "valid")
+
+		// Apply filter rules before redaction, so they match the values LSS sent
+		decision := s.config.Filter.Evaluate(line)
+		if decision.Rule != "" {
+			metrics.ListenerFilterHits.Add(1, s.metricName(), decision.Rule, decision.Action)
+		}
+		if !decision.Store {
 			continue
 		}
 
-		metrics.LinesProcessed.Add("valid", 1)
+		// Redact after the schema check, so drift is judged on the fields LSS sent
+		line = s.config.Redactor.Apply(line)
 
 		// Store locally
 		if err := s.storage.Write(connID, line); err != nil {
 			slog.Error("storage write failed", "conn_id", connID, "error", err)
 		}
 
+		// In tail mode the stored file is the source of truth for forwarding
+		if s.config.StoreOnly || !decision.Forward {
+			continue
+		}
+
+		// Queue for durable forwarding. Append blocks while the queue is full,
+		// which stops reading from the socket and slows the client down.
+		if s.config.Queue != nil {
+			err := s.config.Queue.Append(queue.Record{ConnID: connID, Host: clientHost, Target: decision.Target, Data: line})
+			if err == nil {
+				continue
+			}
+			slog.Error("forward queue append failed, forwarding directly", "conn_id", connID, "error", err)
+		}
+
 		// Forward to HEC asynchronously to avoid blocking the read loop
 		// Make a copy of the line to avoid data races
 		lineCopy := make([]byte, len(line))
 		copy(lineCopy, line)
-		go func(data []byte, id string) {
-			if err := s.forwarder.Forward(id, data); err != nil {
+		go func(data []byte, id, target string) {
+			if err := s.forward(id, clientHost, target, data); err != nil {
 				// Suppress HEC errors in test/benchmark mode to reduce noise
 				if !isTestMode() {
 					slog.Debug("HEC forward failed", "conn_id", id, "error", err)
 				}
 			}
-		}(lineCopy, connID)
+		}(lineCopy, connID, decision.Target)
+	}
+}
+
+// checkSchema checks a line against the listener's schema. Drift is counted by kind, and the
+// first drifted line and one in every SchemaLogEvery after it are logged. It returns true if
+// the line was quarantined.
+func (s *Server) checkSchema(connID, clientAddr string, offset int64, line []byte) bool {
+	problems := s.config.Schema.Check(line)
+	if len(problems) == 0 {
+		return false
+	}
+
+	counted := make(map[logschema.Kind]bool)
+	descriptions := make([]string, len(problems))
+	for i, p := range problems {
+		if !counted[p.Kind] {
+			counted[p.Kind] = true
+			metrics.ListenerSchemaDrift.Add(1, s.metricName(), string(p.Kind))
+		}
+		descriptions[i] = p.String()
+	}
+	reason := strings.Join(descriptions, "; ")
+
+	n := s.driftLines.Add(1)
+	if every := int64(s.config.SchemaLogEvery); every <= 1 || n%every == 1 {
+		slog.Warn("schema drift", "conn_id", connID, "client_addr", clientAddr,
+			"log_type", s.config.Schema.LogType(), "problems", reason, "drifted_lines", n,
+			"line", processor.Truncate(line, 200))
+	}
+
+	if !s.config.SchemaQuarantine {
+		return false
+	}
+	quarantined := s.writeQuarantine(quarantine.Line{
+		ConnID:     connID,
+		ClientAddr: clientAddr,
+		Offset:     offset,
+		Reason:     quarantine.SchemaDrift,
+		Detail:     reason,
+		Data:       line,
+	})
+	if quarantined {
+		metrics.ListenerLinesProcessed.Add(1, s.metricName(), "quarantined")
+	}
+	return quarantined
+}
+
+// rejectOversized counts and logs a line longer than MaxLineBytes, and quarantines its
+// first bytes and hash if a quarantine store is configured.
+func (s *Server) rejectOversized(connID, clientAddr string, offset int64, tooLong *processor.LineTooLongError) {
+	metrics.ListenerLinesProcessed.Add(1, s.metricName(), "oversized")
+	slog.Warn("oversized line", "conn_id", connID, "client_addr", clientAddr, "size", tooLong.Size,
+		"limit", s.config.MaxLineBytes, "line", processor.Truncate(tooLong.Prefix, 200))
+
+	s.writeQuarantine(quarantine.Line{
+		ConnID:     connID,
+		ClientAddr: clientAddr,
+		Offset:     offset,
+		Reason:     quarantine.Oversized,
+		Data:       tooLong.Prefix,
+		Size:       tooLong.Size,
+		SHA256:     tooLong.SHA256[:],
+	})
+}
+
+// writeQuarantine writes a line to the quarantine store, if one is configured.
+// It returns true if the line was written.
+func (s *Server) writeQuarantine(line quarantine.Line) bool {
+	if s.config.Quarantine == nil {
+		return false
+	}
+	if err := s.config.Quarantine.Write(line); err != nil {
+		slog.Error("quarantine write failed", "conn_id", line.ConnID, "reason", line.Reason, "error", err)
+		return false
 	}
+	return true
 }
 
 // ReloadableConfig holds configuration parameters that can be safely reloaded at runtime.
 type ReloadableConfig struct {
 	AllowedCIDRs    string
+	TLSCertFile     string // Certificate and key to reload (TLS listeners only)
+	TLSKeyFile      string
 	ForwarderConfig forwarder.ReloadableConfig
 }
 
 // UpdateConfig updates the reloadable configuration parameters in a thread-safe manner.
-// Only safe parameters (ACL CIDRs, HEC token, sourcetype, gzip) are updated.
-// Parameters that require restart (listen address, TLS, storage, max line bytes) are not affected.
+// Only safe parameters (ACL CIDRs, TLS certificate, HEC token, sourcetype, gzip) are updated.
+// The TLS certificate is re-read even if its paths are unchanged, so renewed files are picked up.
+// Parameters that require restart (listen address, enabling TLS, client authentication,
+// storage, max line bytes) are not affected.
 func (s *Server) UpdateConfig(cfg ReloadableConfig) error {
+	// Reload the TLS certificate first so an invalid pair leaves everything unchanged
+	s.certsMu.Lock()
+	certs := s.certs
+	s.certsMu.Unlock()
+	if certs != nil && cfg.TLSCertFile != "" {
+		if err := certs.Reload(cfg.TLSCertFile, cfg.TLSKeyFile); err != nil {
+			return fmt.Errorf("failed to reload TLS certificate: %w", err)
+		}
+	}
+
 	// Update ACL if CIDRs changed
 	if cfg.AllowedCIDRs != "" {
 		newACL, err := acl.New(cfg.AllowedCIDRs)
@@ -399,9 +914,32 @@ func (s *Server) UpdateConfig(cfg ReloadableConfig) error {
 	}
 
 	// Update forwarder configuration
+	s.forwarderMu.RLock()
 	if s.forwarder != nil {
 		s.forwarder.UpdateConfig(cfg.ForwarderConfig)
 	}
+	s.forwarderMu.RUnlock()
 
 	return nil
 }
+
+// SetForwarder replaces the forwarder lines are sent to, for example after a configuration
+// reload changed the HEC targets, and returns the previous one. It waits for forwards in
+// progress and flushes the previous forwarder's batch before returning, so the caller can
+// shut it down without losing lines.
+func (s *Server) SetForwarder(fwd forwarder.Forwarder) forwarder.Forwarder {
+	s.forwarderMu.Lock()
+	defer s.forwarderMu.Unlock()
+
+	old := s.forwarder
+	s.forwarder = fwd
+	if f, ok := old.(forwarder.Flusher); ok {
+		f.Flush()
+	}
+	// Lines the previous forwarder lost are forwarded again from the queue
+	if undelivered(old) > s.undeliveredBase {
+		s.undeliveredLost = true
+	}
+	s.undeliveredBase = undelivered(fwd)
+	return old
+}
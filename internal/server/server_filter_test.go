@@ -0,0 +1,71 @@
+package server
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scottbrown/relay/internal/acl"
+	"github.com/scottbrown/relay/internal/filter"
+	"github.com/scottbrown/relay/internal/metrics"
+	"github.com/scottbrown/relay/internal/storage"
+)
+
+// routingForwarder records lines forwarded to a named target as "target:line"
+type routingForwarder struct {
+	recordingForwarder
+}
+
+func (r *routingForwarder) ForwardTo(target, connID, host string, data []byte) error {
+	return r.Forward(connID, []byte(target+":"+string(data)))
+}
+
+func TestHandleConnection_Filters(t *testing.T) {
+	status := func(s string) []filter.Condition {
+		return []filter.Condition{{Field: "Status", Equals: &s}}
+	}
+	f, err := filter.New([]filter.Rule{
+		{Name: "drop-ok", Match: status("ok"), Action: filter.Drop},
+		{Name: "keep-debug", Match: status("debug"), Action: filter.DropForward},
+		{Name: "route-error", Match: status("error"), Action: filter.Route, Target: "soc"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create filter: %v", err)
+	}
+
+	aclList, _ := acl.New("")
+	storageManager, _ := storage.New(t.TempDir(), "zpa")
+	defer storageManager.Close()
+
+	fwd := &routingForwarder{}
+	config := Config{Name: "filters", MaxLineBytes: 4096, Filter: f}
+	server, err := New(config, aclList, storageManager, fwd, nil)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	lines := []string{`{"Status":"ok"}`, `{"Status":"debug"}`, `{"Status":"error"}`, `{"Status":"warn"}`}
+	server.handleConnection(newMockConn(strings.Join(lines, "\n")+"\n", "192.168.1.1:12345"))
+
+	if got := strings.Join(readStored(t, storageManager), "\n"); got != strings.Join(lines[1:], "\n") {
+		t.Errorf("stored:\n%s\nwant every line but the dropped one", got)
+	}
+
+	// Lines are forwarded asynchronously
+	var forwarded []string
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if forwarded, _ = fwd.snapshot(); len(forwarded) == 2 {
+			break
+		}
+	}
+	sort.Strings(forwarded)
+	if strings.Join(forwarded, " ") != `soc:{"Status":"error"} {"Status":"warn"}` {
+		t.Errorf("forwarded %q, want the routed and unmatched lines", forwarded)
+	}
+
+	for rule, action := range map[string]string{"drop-ok": filter.Drop, "keep-debug": filter.DropForward, "route-error": filter.Route} {
+		if got := metrics.ListenerFilterHits.Value("filters", rule, action); got != 1 {
+			t.Errorf("%s hits = %d, want 1", rule, got)
+		}
+	}
+}
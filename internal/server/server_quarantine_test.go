@@ -0,0 +1,91 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/scottbrown/relay/internal/acl"
+	"github.com/scottbrown/relay/internal/metrics"
+	"github.com/scottbrown/relay/internal/quarantine"
+	"github.com/scottbrown/relay/internal/storage"
+)
+
+// readQuarantined returns the entries the quarantine writer has written.
+func readQuarantined(t *testing.T, writer *quarantine.Writer) []quarantine.Entry {
+	t.Helper()
+	data, err := os.ReadFile(writer.CurrentFile())
+	if err != nil {
+		t.Fatalf("failed to read quarantine file: %v", err)
+	}
+
+	var entries []quarantine.Entry
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var entry quarantine.Entry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("failed to parse quarantine entry: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestHandleConnection_Quarantine(t *testing.T) {
+	name := "quarantine-invalid"
+	writer, err := quarantine.New(t.TempDir(), 16)
+	if err != nil {
+		t.Fatalf("failed to create quarantine writer: %v", err)
+	}
+	defer writer.Close()
+	writer.SetName(name)
+
+	aclList, _ := acl.New("")
+	storageManager, _ := storage.New(t.TempDir(), "zpa")
+	defer storageManager.Close()
+
+	config := Config{Name: name, MaxLineBytes: 64, Quarantine: writer}
+	server, err := New(config, aclList, storageManager, &mockForwarder{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+
+	oversized := `{"data":"` + strings.Repeat("x", 100) + `"}`
+	input := `{"ok":1}` + "\n" + `{"broken":` + "\n" + oversized + "\n" + `{"ok":2}` + "\n"
+	server.handleConnection(newMockConn(input, "192.168.1.1:12345"))
+
+	if got := readStored(t, storageManager); strings.Join(got, "\n") != `{"ok":1}`+"\n"+`{"ok":2}` {
+		t.Errorf("only valid lines should be stored, got %v", got)
+	}
+
+	entries := readQuarantined(t, writer)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 quarantine entries, got %d", len(entries))
+	}
+
+	invalid := entries[0]
+	if invalid.Reason != quarantine.InvalidJSON || invalid.Data != `{"broken":` || invalid.Offset != 9 ||
+		invalid.ClientAddr != "192.168.1.1:12345" || invalid.ConnID == "" {
+		t.Errorf("unexpected invalid JSON entry %+v", invalid)
+	}
+
+	sum := sha256.Sum256([]byte(oversized))
+	tooLong := entries[1]
+	if tooLong.Reason != quarantine.Oversized || tooLong.Offset != 20 || tooLong.Size != int64(len(oversized)) {
+		t.Errorf("unexpected oversized entry %+v", tooLong)
+	}
+	if tooLong.Data != oversized[:16] || !tooLong.Truncated || tooLong.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("oversized entry should keep the first bytes and the hash of the whole line, got %+v", tooLong)
+	}
+
+	for _, reason := range []quarantine.Reason{quarantine.InvalidJSON, quarantine.Oversized} {
+		if got := metrics.ListenerQuarantineLines.Value(name, string(reason)); got != 1 {
+			t.Errorf("%s quarantined lines = %d, want 1", reason, got)
+		}
+	}
+	if got := metrics.ListenerLinesProcessed.Value(name, "oversized"); got != 1 {
+		t.Errorf("oversized lines = %d, want 1", got)
+	}
+}
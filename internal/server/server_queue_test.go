@@ -0,0 +1,375 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/scottbrown/relay/internal/acl"
+	"github.com/scottbrown/relay/internal/dlq"
+	"github.com/scottbrown/relay/internal/forwarder"
+	"github.com/scottbrown/relay/internal/queue"
+	"github.com/scottbrown/relay/internal/storage"
+)
+
+// recordingForwarder records forwarded lines and flushes for testing
+type recordingForwarder struct {
+	mu      sync.Mutex
+	lines   []string
+	hosts   []string
+	flushes int
+}
+
+func (r *recordingForwarder) Forward(connID string, data []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines = append(r.lines, string(data))
+	return nil
+}
+
+func (r *recordingForwarder) ForwardFrom(connID, host string, data []byte) error {
+	r.mu.Lock()
+	r.hosts = append(r.hosts, host)
+	r.mu.Unlock()
+	return r.Forward(connID, data)
+}
+
+func (r *recordingForwarder) Flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.flushes++
+}
+
+func (r *recordingForwarder) HealthCheck() error                      { return nil }
+func (r *recordingForwarder) Shutdown(ctx context.Context) error      { return nil }
+func (r *recordingForwarder) UpdateConfig(forwarder.ReloadableConfig) {}
+
+func (r *recordingForwarder) snapshot() ([]string, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.lines...), r.flushes
+}
+
+func startQueuedServer(t *testing.T, q *queue.Queue, fwd forwarder.Forwarder) (*Server, string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to get available port: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	aclList, _ := acl.New("")
+	storageManager, _ := storage.New(t.TempDir(), "zpa")
+	t.Cleanup(func() { storageManager.Close() })
+
+	srv, err := New(Config{ListenAddr: addr, MaxLineBytes: 1024, Queue: q}, aclList, storageManager, fwd, nil)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	go func() { _ = srv.Start() }()
+	time.Sleep(100 * time.Millisecond)
+	return srv, addr
+}
+
+func TestServer_QueueForwardsAndAcknowledges(t *testing.T) {
+	q, err := queue.Open(queue.Config{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("queue.Open() error = %v", err)
+	}
+	fwd := &recordingForwarder{}
+	srv, addr := startQueuedServer(t, q, fwd)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	if _, err := conn.Write([]byte("{\"n\":1}\n{\"n\":2}\n")); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	conn.Close()
+
+	// Wait for the forward loop to deliver both lines
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if lines, _ := fwd.snapshot(); len(lines) == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	lines, flushes := fwd.snapshot()
+	if len(lines) != 2 || lines[0] != `{"n":1}` || lines[1] != `{"n":2}` {
+		t.Errorf("forwarded lines = %v, want n=1 then n=2", lines)
+	}
+	if flushes == 0 {
+		t.Error("forwarder should be flushed before lines are acknowledged")
+	}
+	if pending := q.Pending(); pending != 0 {
+		t.Errorf("queue pending = %d bytes, want 0", pending)
+	}
+}
+
+func TestServer_QueueDeliversBacklogOnStart(t *testing.T) {
+	dir := t.TempDir()
+
+	// Lines accepted by a previous run that were never acknowledged
+	q, err := queue.Open(queue.Config{Dir: dir})
+	if err != nil {
+		t.Fatalf("queue.Open() error = %v", err)
+	}
+	if err := q.Append(queue.Record{ConnID: "old-conn", Data: []byte(`{"backlog":true}`)}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	q, err = queue.Open(queue.Config{Dir: dir})
+	if err != nil {
+		t.Fatalf("queue.Open() error = %v", err)
+	}
+	fwd := &recordingForwarder{}
+	srv, _ := startQueuedServer(t, q, fwd)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	lines, _ := fwd.snapshot()
+	if len(lines) != 1 || lines[0] != `{"backlog":true}` {
+		t.Errorf("forwarded lines = %v, want the queued backlog", lines)
+	}
+}
+
+func TestServer_QueueKeepsLinesWhileHECIsDown(t *testing.T) {
+	for _, batching := range []bool{false, true} {
+		t.Run(fmt.Sprintf("batching %v", batching), func(t *testing.T) {
+			var up atomic.Bool
+			var delivered atomic.Int32
+			hec := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !up.Load() {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				delivered.Add(1)
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer hec.Close()
+
+			// No DLQ: a line that does not reach HEC has nowhere else to go
+			fwd := forwarder.New(forwarder.Config{
+				URL:   hec.URL,
+				Token: "token",
+				Retry: forwarder.RetryConfig{MaxAttempts: 1},
+				Batch: forwarder.BatchConfig{Enabled: batching, MaxSize: 10, MaxBytes: 1 << 20, FlushInterval: time.Minute},
+			})
+			q, err := queue.Open(queue.Config{Dir: t.TempDir()})
+			if err != nil {
+				t.Fatalf("queue.Open() error = %v", err)
+			}
+			srv, addr := startQueuedServer(t, q, fwd)
+
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Fatalf("failed to connect: %v", err)
+			}
+			if _, err := conn.Write([]byte("{\"n\":1}\n")); err != nil {
+				t.Fatalf("failed to write: %v", err)
+			}
+			conn.Close()
+
+			time.Sleep(1500 * time.Millisecond)
+			if pending := q.Pending(); pending == 0 {
+				t.Fatal("a line that failed to reach HEC should stay in the queue")
+			}
+
+			// Once HEC recovers the line is forwarded again and acknowledged
+			up.Store(true)
+			deadline := time.Now().Add(5 * time.Second)
+			for q.Pending() > 0 && time.Now().Before(deadline) {
+				time.Sleep(50 * time.Millisecond)
+			}
+			if pending := q.Pending(); pending != 0 {
+				t.Errorf("queue pending = %d bytes after HEC recovered, want 0", pending)
+			}
+			if got := delivered.Load(); got != 1 {
+				t.Errorf("HEC received %d requests after recovering, want 1", got)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = srv.Shutdown(ctx)
+			_ = fwd.Shutdown(ctx)
+		})
+	}
+}
+
+func TestServer_QueueHoldsLinesWhilePaused(t *testing.T) {
+	var delivered atomic.Int32
+	hec := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer hec.Close()
+
+	dlqDir := t.TempDir()
+	dlqWriter, err := dlq.New(dlqDir)
+	if err != nil {
+		t.Fatalf("dlq.New() error = %v", err)
+	}
+	defer dlqWriter.Close()
+
+	fwd := forwarder.New(forwarder.Config{URL: hec.URL, Token: "token", DLQ: dlqWriter})
+	if err := fwd.Pause(""); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	q, err := queue.Open(queue.Config{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("queue.Open() error = %v", err)
+	}
+	srv, addr := startQueuedServer(t, q, fwd)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	if _, err := conn.Write([]byte("{\"n\":1}\n")); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	conn.Close()
+
+	time.Sleep(1500 * time.Millisecond)
+	if pending := q.Pending(); pending == 0 {
+		t.Fatal("a line received while paused should stay in the queue")
+	}
+	if got := delivered.Load(); got != 0 {
+		t.Errorf("HEC received %d requests while paused, want 0", got)
+	}
+	if files, _ := dlq.Files(dlqDir); len(files) != 0 {
+		t.Errorf("DLQ files = %v while paused, want none", files)
+	}
+
+	// Once resumed the held line is forwarded and acknowledged
+	if err := fwd.Resume(""); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for q.Pending() > 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if pending := q.Pending(); pending != 0 {
+		t.Errorf("queue pending = %d bytes after resume, want 0", pending)
+	}
+	if got := delivered.Load(); got != 1 {
+		t.Errorf("HEC received %d requests after resume, want 1", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = srv.Shutdown(ctx)
+	_ = fwd.Shutdown(ctx)
+}
+
+func TestServer_StoreOnlySkipsForwarding(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to get available port: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	aclList, _ := acl.New("")
+	storageManager, _ := storage.New(t.TempDir(), "zpa")
+	defer storageManager.Close()
+
+	fwd := &recordingForwarder{}
+	srv, err := New(Config{ListenAddr: addr, MaxLineBytes: 1024, StoreOnly: true}, aclList, storageManager, fwd, nil)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	go func() { _ = srv.Start() }()
+	time.Sleep(100 * time.Millisecond)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	if _, err := conn.Write([]byte("{\"n\":1}\n")); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	conn.Close()
+
+	// Wait for the line to be stored
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) && storageManager.CurrentFile() == "" {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	if storageManager.CurrentFile() == "" {
+		t.Error("line should be written to storage")
+	}
+	if lines, _ := fwd.snapshot(); len(lines) != 0 {
+		t.Errorf("forwarded lines = %v, want none in store-only mode", lines)
+	}
+}
+
+func TestServer_QueuePassesClientHost(t *testing.T) {
+	q, err := queue.Open(queue.Config{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("queue.Open() error = %v", err)
+	}
+	fwd := &recordingForwarder{}
+	srv, addr := startQueuedServer(t, q, fwd)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	if _, err := conn.Write([]byte("{\"n\":1}\n")); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	conn.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if lines, _ := fwd.snapshot(); len(lines) == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	fwd.mu.Lock()
+	defer fwd.mu.Unlock()
+	if len(fwd.hosts) != 1 || fwd.hosts[0] != "127.0.0.1" {
+		t.Errorf("forwarded hosts = %v, want the client IP without port", fwd.hosts)
+	}
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/scottbrown/relay/internal/acl"
+	"github.com/scottbrown/relay/internal/redact"
+	"github.com/scottbrown/relay/internal/storage"
+)
+
+func TestHandleConnection_Redaction(t *testing.T) {
+	redactor, err := redact.New([]redact.Rule{
+		{Path: "ClientPrivateIP", Action: redact.Drop},
+		{Path: "Username", Action: redact.Mask},
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to create redactor: %v", err)
+	}
+
+	aclList, _ := acl.New("")
+	storageManager, _ := storage.New(t.TempDir(), "zpa")
+	defer storageManager.Close()
+
+	config := Config{Name: "redaction", MaxLineBytes: 4096, Redactor: redactor}
+	server, err := New(config, aclList, storageManager, &mockForwarder{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	server.handleConnection(newMockConn(`{"Username":"jdoe","ClientPrivateIP":"10.1.2.3","ConnectionStatus":"open"}`+"\n", "192.168.1.1:12345"))
+
+	stored := readStored(t, storageManager)
+	if len(stored) != 1 || stored[0] != `{"Username":"[REDACTED]","ConnectionStatus":"open"}` {
+		t.Errorf("stored %q, want the redacted line", stored)
+	}
+}
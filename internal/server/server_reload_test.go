@@ -242,3 +242,41 @@ func TestServer_UpdateConfig_NilForwarder(t *testing.T) {
 		t.Fatalf("failed to update config: %v", err)
 	}
 }
+
+func TestServer_SetForwarder(t *testing.T) {
+	aclList, _ := acl.New("")
+	storage, err := storage.New(t.TempDir(), "test")
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	oldFwd := &recordingForwarder{}
+	srv, err := New(Config{
+		ListenAddr:   "127.0.0.1:0",
+		MaxLineBytes: 1024,
+	}, aclList, storage, oldFwd, nil)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+
+	newFwd := &recordingForwarder{}
+	if got := srv.SetForwarder(newFwd); got != oldFwd {
+		t.Errorf("SetForwarder() returned %v, want the previous forwarder", got)
+	}
+
+	// The previous forwarder's batch is flushed before it is handed back
+	if _, flushes := oldFwd.snapshot(); flushes != 1 {
+		t.Errorf("previous forwarder flushed %d times, want 1", flushes)
+	}
+
+	if err := srv.forward("conn-1", "10.0.0.1", "", []byte(`{"test": "data"}`)); err != nil {
+		t.Fatalf("forward() error = %v", err)
+	}
+	if lines, _ := newFwd.snapshot(); len(lines) != 1 {
+		t.Errorf("new forwarder received %d lines, want 1", len(lines))
+	}
+	if lines, _ := oldFwd.snapshot(); len(lines) != 0 {
+		t.Errorf("previous forwarder received %d lines after the swap, want 0", len(lines))
+	}
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/scottbrown/relay/internal/acl"
+	"github.com/scottbrown/relay/internal/logschema"
+	"github.com/scottbrown/relay/internal/logtypes"
+	"github.com/scottbrown/relay/internal/metrics"
+	"github.com/scottbrown/relay/internal/quarantine"
+	"github.com/scottbrown/relay/internal/storage"
+	"github.com/scottbrown/relay/internal/testutil/fixtures"
+)
+
+// auditExample returns the example audit log line, which matches the audit schema.
+func auditExample(t *testing.T) string {
+	t.Helper()
+	data, err := os.ReadFile(fixtures.SpecPath("audit-logs.example.json"))
+	if err != nil {
+		t.Fatalf("failed to read example: %v", err)
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// readStored returns the lines the storage manager has written.
+func readStored(t *testing.T, storageManager *storage.Manager) []string {
+	t.Helper()
+	if storageManager.CurrentFile() == "" {
+		return nil
+	}
+	data, err := os.ReadFile(storageManager.CurrentFile())
+	if err != nil {
+		t.Fatalf("failed to read storage file: %v", err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestHandleConnection_SchemaDrift(t *testing.T) {
+	schema, _ := logschema.For(logtypes.Audit)
+	valid := auditExample(t)
+	drifted := strings.Replace(valid, `"User":`, `"UserName":`, 1)
+
+	tests := []struct {
+		name       string
+		quarantine bool
+		stored     []string
+	}{
+		{name: "forward", stored: []string{valid, drifted}},
+		{name: "quarantine", quarantine: true, stored: []string{valid}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := "schema-drift-" + tt.name
+			config := Config{Name: name, MaxLineBytes: 4096, Schema: schema}
+
+			var writer *quarantine.Writer
+			if tt.quarantine {
+				var err error
+				writer, err = quarantine.New(t.TempDir(), 0)
+				if err != nil {
+					t.Fatalf("failed to create quarantine writer: %v", err)
+				}
+				defer writer.Close()
+				writer.SetName(name)
+				config.Quarantine = writer
+				config.SchemaQuarantine = true
+			}
+
+			aclList, _ := acl.New("")
+			storageManager, _ := storage.New(t.TempDir(), "zpa")
+			defer storageManager.Close()
+
+			server, err := New(config, aclList, storageManager, &mockForwarder{}, nil)
+			if err != nil {
+				t.Fatalf("failed to create server: %v", err)
+			}
+			server.handleConnection(newMockConn(valid+"\n"+drifted+"\n", "192.168.1.1:12345"))
+
+			if got := readStored(t, storageManager); strings.Join(got, "\n") != strings.Join(tt.stored, "\n") {
+				t.Errorf("stored %d lines, want %d", len(got), len(tt.stored))
+			}
+			for _, kind := range []logschema.Kind{logschema.MissingField, logschema.UnexpectedField} {
+				if got := metrics.ListenerSchemaDrift.Value(name, string(kind)); got != 1 {
+					t.Errorf("%s drift = %d, want 1", kind, got)
+				}
+			}
+
+			if writer == nil {
+				return
+			}
+			entries := readQuarantined(t, writer)
+			if len(entries) != 1 || entries[0].Reason != quarantine.SchemaDrift ||
+				entries[0].Detail != "missing field User; unexpected field UserName" || entries[0].Data != drifted {
+				t.Errorf("quarantine entry should give the drift found, got %+v", entries)
+			}
+			if entries[0].Offset != int64(len(valid)+1) {
+				t.Errorf("offset = %d, want %d", entries[0].Offset, len(valid)+1)
+			}
+			if got := metrics.ListenerLinesProcessed.Value(name, "quarantined"); got != 1 {
+				t.Errorf("quarantined lines = %d, want 1", got)
+			}
+		})
+	}
+}
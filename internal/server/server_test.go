@@ -6,6 +6,7 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 
@@ -45,7 +46,7 @@ func TestNew(t *testing.T) {
 		t.Fatalf("New should succeed: %v", err)
 	}
 
-	if server.config != config {
+	if !reflect.DeepEqual(server.config, config) {
 		t.Error("config should be stored")
 	}
 
@@ -287,6 +288,9 @@ func TestHandleConnection_ValidJSON(t *testing.T) {
 	if !conn.closed {
 		t.Error("connection should be closed after handling")
 	}
+	if server.LastEvent().IsZero() {
+		t.Error("server should record the time of the last event")
+	}
 
 	// Verify data was written to storage
 	currentFile := storageManager.CurrentFile()
@@ -448,6 +452,10 @@ func TestShutdown_NoActiveConnections(t *testing.T) {
 	// Give server time to start
 	time.Sleep(100 * time.Millisecond)
 
+	if !server.Accepting() {
+		t.Error("server should report accepting once started")
+	}
+
 	// Shutdown with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -456,6 +464,9 @@ func TestShutdown_NoActiveConnections(t *testing.T) {
 	if err != nil {
 		t.Errorf("Shutdown should succeed with no active connections: %v", err)
 	}
+	if server.Accepting() {
+		t.Error("server should not report accepting after shutdown")
+	}
 
 	// Check if Start returned
 	select {
@@ -466,6 +477,55 @@ func TestShutdown_NoActiveConnections(t *testing.T) {
 	}
 }
 
+func TestConnections(t *testing.T) {
+	aclList, _ := acl.New("")
+	storageManager, _ := storage.New(t.TempDir(), "zpa")
+	defer storageManager.Close()
+
+	server, err := New(Config{MaxLineBytes: 1024}, aclList, storageManager, forwarder.New(forwarder.Config{}), nil)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+
+	client, conn := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		server.handleConnection(conn)
+		close(done)
+	}()
+
+	line := `{"test": "data"}`
+	if _, err := client.Write([]byte(line + "\n")); err != nil {
+		t.Fatalf("failed to write line: %v", err)
+	}
+
+	// The line is counted once it has been read
+	var conns []ConnectionInfo
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		conns = server.Connections()
+		if len(conns) == 1 && conns[0].Lines == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(conns) != 1 {
+		t.Fatalf("Connections() returned %d connections, want 1", len(conns))
+	}
+	if conns[0].ConnID == "" || conns[0].ClientAddr != "pipe" || conns[0].Started.IsZero() {
+		t.Errorf("unexpected connection info: %+v", conns[0])
+	}
+	if conns[0].Lines != 1 || conns[0].Bytes != int64(len(line)) {
+		t.Errorf("Connections() counted %d lines and %d bytes, want 1 and %d", conns[0].Lines, conns[0].Bytes, len(line))
+	}
+
+	client.Close()
+	<-done
+	if conns := server.Connections(); len(conns) != 0 {
+		t.Errorf("Connections() returned %d connections after close, want 0", len(conns))
+	}
+}
+
 func TestShutdown_WithActiveConnections(t *testing.T) {
 	// Use a random available port
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
@@ -567,6 +627,85 @@ func TestShutdown_Timeout(t *testing.T) {
 	server.connections.Done()
 }
 
+func TestShutdown_ClosesRemainingConnections(t *testing.T) {
+	aclList, _ := acl.New("")
+	storageManager, _ := storage.New(t.TempDir(), "zpa")
+	defer storageManager.Close()
+
+	server, err := New(Config{ListenAddr: "127.0.0.1:0", MaxLineBytes: 1024}, aclList, storageManager, forwarder.New(forwarder.Config{}), nil)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+
+	// Listen reports errors before Start and provides the address
+	if err := server.Listen(); err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.Start()
+	}()
+
+	// A client that never disconnects, like ZPA LSS
+	conn, err := net.Dial("tcp", server.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect to server: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(`{"test": "data"}` + "\n")); err != nil {
+		t.Fatalf("failed to write data: %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for len(server.Connections()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := server.Shutdown(ctx); err == nil {
+		t.Error("Shutdown should report the connection that did not close in time")
+	}
+
+	// The connection is closed by the server once the deadline has passed
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Error("connection should be closed by the server")
+	}
+	deadline = time.Now().Add(time.Second)
+	for len(server.Connections()) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := len(server.Connections()); n != 0 {
+		t.Errorf("got %d active connections after shutdown, want 0", n)
+	}
+
+	select {
+	case <-errCh:
+	case <-time.After(time.Second):
+		t.Error("server did not stop within timeout")
+	}
+}
+
+func TestListen_AddressInUse(t *testing.T) {
+	occupied, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to create test listener: %v", err)
+	}
+	defer occupied.Close()
+
+	aclList, _ := acl.New("")
+	storageManager, _ := storage.New(t.TempDir(), "zpa")
+	defer storageManager.Close()
+
+	server, err := New(Config{ListenAddr: occupied.Addr().String(), MaxLineBytes: 1024}, aclList, storageManager, forwarder.New(forwarder.Config{}), nil)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	if err := server.Listen(); err == nil {
+		t.Error("Listen() should fail for an address in use")
+	}
+}
+
 func TestShutdown_DoubleCall(t *testing.T) {
 	config := Config{ListenAddr: ":0", MaxLineBytes: 1024}
 	aclList, _ := acl.New("")
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/scottbrown/relay/internal/metrics"
+)
+
+const (
+	// tlsHandshakeTimeout bounds how long a client may take to complete the TLS handshake.
+	tlsHandshakeTimeout = 10 * time.Second
+	// certCheckInterval is how often the certificate files are checked for changes.
+	certCheckInterval = 30 * time.Second
+)
+
+// certStore holds the listener certificate and swaps it when the files change.
+// New connections get the current certificate; established connections are not affected.
+//
+// certStore is safe for concurrent use by multiple goroutines.
+type certStore struct {
+	name string // Listener name, used as the metric key
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	certFile string
+	keyFile  string
+	certMod  time.Time // Modification times of the loaded files
+	keyMod   time.Time
+}
+
+// newCertStore loads the key pair and returns a store serving it.
+func newCertStore(name, certFile, keyFile string) (*certStore, error) {
+	c := &certStore{name: name}
+	if err := c.load(certFile, keyFile, false); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// GetCertificate returns the current certificate. It is used as tls.Config.GetCertificate.
+func (c *certStore) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+// Reload re-reads the key pair from the given paths, which may differ from the current ones.
+// The current certificate is kept if the new pair is invalid.
+func (c *certStore) Reload(certFile, keyFile string) error {
+	if err := c.load(certFile, keyFile, true); err != nil {
+		metrics.TLSCertReloads.Add("failure", 1)
+		return err
+	}
+	metrics.TLSCertReloads.Add("success", 1)
+	slog.Info("TLS certificate reloaded", "listener", c.name, "cert_file", certFile, "not_after", c.notAfter().UTC().Format(time.RFC3339))
+	return nil
+}
+
+// checkFiles reloads the key pair if either file's modification time has changed.
+// A failed reload is retried on the next check, so a renewal caught halfway
+// (new certificate, old key) is picked up once both files are in place.
+func (c *certStore) checkFiles() {
+	c.mu.RLock()
+	certFile, keyFile := c.certFile, c.keyFile
+	certMod, keyMod := c.certMod, c.keyMod
+	c.mu.RUnlock()
+
+	certInfo, err := os.Stat(certFile)
+	if err != nil {
+		return
+	}
+	keyInfo, err := os.Stat(keyFile)
+	if err != nil {
+		return
+	}
+	if certInfo.ModTime().Equal(certMod) && keyInfo.ModTime().Equal(keyMod) {
+		return
+	}
+
+	if err := c.Reload(certFile, keyFile); err != nil {
+		slog.Warn("TLS certificate files changed but could not be loaded, keeping current certificate",
+			"listener", c.name,
+			"cert_file", certFile,
+			"error", err)
+	}
+}
+
+// load reads and validates a key pair and swaps it in.
+// When replacing a certificate, a pair that is expired or not yet valid is refused.
+func (c *certStore) load(certFile, keyFile string, replacing bool) error {
+	certInfo, err := os.Stat(certFile)
+	if err != nil {
+		return err
+	}
+	keyInfo, err := os.Stat(keyFile)
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	leaf := cert.Leaf
+	if leaf == nil {
+		if leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return err
+		}
+		cert.Leaf = leaf
+	}
+
+	now := time.Now()
+	if replacing && now.After(leaf.NotAfter) {
+		return fmt.Errorf("certificate expired at %s", leaf.NotAfter.UTC().Format(time.RFC3339))
+	}
+	if replacing && now.Before(leaf.NotBefore) {
+		return fmt.Errorf("certificate not valid until %s", leaf.NotBefore.UTC().Format(time.RFC3339))
+	}
+
+	c.mu.Lock()
+	c.cert = &cert
+	c.certFile, c.keyFile = certFile, keyFile
+	c.certMod, c.keyMod = certInfo.ModTime(), keyInfo.ModTime()
+	c.mu.Unlock()
+
+	metrics.SetMapInt(metrics.TLSCertExpiry, c.name, leaf.NotAfter.Unix())
+	return nil
+}
+
+// notAfter returns the expiry time of the current certificate.
+func (c *certStore) notAfter() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert.Leaf.NotAfter
+}
+
+// buildTLSConfig creates the listener TLS configuration, including client certificate verification.
+// The server certificate is served from the certificate store so it can be replaced at runtime.
+func (s *Server) buildTLSConfig() (*tls.Config, error) {
+	certs, err := newCertStore(s.metricName(), s.config.TLSCertFile, s.config.TLSKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		GetCertificate: certs.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+	}
+
+	switch s.config.TLSClientAuth {
+	case "", ClientAuthNone:
+	case ClientAuthOptional:
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequired:
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("invalid TLS client auth mode '%s'", s.config.TLSClientAuth)
+	}
+
+	if tlsConfig.ClientAuth != tls.NoClientCert {
+		// #nosec G304 -- CA bundle path comes from the operator's configuration
+		caPEM, err := os.ReadFile(s.config.TLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, errors.New("client CA file contains no PEM certificates")
+		}
+		tlsConfig.ClientCAs = pool
+	}
+
+	s.certsMu.Lock()
+	s.certs = certs
+	s.certsMu.Unlock()
+	return tlsConfig, nil
+}
+
+// watchCertificate reloads the certificate when its files change, until shutdown.
+func (s *Server) watchCertificate(certs *certStore) {
+	ticker := time.NewTicker(certCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			certs.checkFiles()
+		case <-s.shutdown:
+			return
+		}
+	}
+}
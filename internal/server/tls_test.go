@@ -0,0 +1,170 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"expvar"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/scottbrown/relay/internal/acl"
+	"github.com/scottbrown/relay/internal/metrics"
+	"github.com/scottbrown/relay/internal/storage"
+)
+
+// servedSerial returns the serial number of the certificate currently served by the store.
+func servedSerial(t *testing.T, c *certStore) string {
+	t.Helper()
+	cert, err := c.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("GetCertificate() error = %v", err)
+	}
+	return cert.Leaf.SerialNumber.String()
+}
+
+// touch moves a file's modification time forward so the change is always detected.
+func touch(t *testing.T, path string) {
+	t.Helper()
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatalf("failed to touch %s: %v", path, err)
+	}
+}
+
+func TestCertStore_ReloadsChangedFiles(t *testing.T) {
+	pki := newTestPKI(t)
+	certFile, keyFile := filepath.Join(pki.dir, "a.crt"), filepath.Join(pki.dir, "a.key")
+	first := pki.writeServerPair(t, certFile, keyFile, time.Time{})
+
+	store, err := newCertStore("reload-test", certFile, keyFile)
+	if err != nil {
+		t.Fatalf("newCertStore() error = %v", err)
+	}
+	if got := servedSerial(t, store); got != first.Leaf.SerialNumber.String() {
+		t.Fatalf("served serial = %s, want %s", got, first.Leaf.SerialNumber)
+	}
+
+	// Unchanged files are not reloaded
+	store.checkFiles()
+	if got := servedSerial(t, store); got != first.Leaf.SerialNumber.String() {
+		t.Fatalf("served serial = %s after a no-op check, want %s", got, first.Leaf.SerialNumber)
+	}
+
+	renewed := pki.writeServerPair(t, certFile, keyFile, time.Now().Add(48*time.Hour))
+	touch(t, certFile)
+	touch(t, keyFile)
+	store.checkFiles()
+
+	if got := servedSerial(t, store); got != renewed.Leaf.SerialNumber.String() {
+		t.Errorf("served serial = %s, want renewed %s", got, renewed.Leaf.SerialNumber)
+	}
+	expiry, ok := metrics.TLSCertExpiry.Get("reload-test").(*expvar.Int)
+	if !ok || expiry.Value() != renewed.Leaf.NotAfter.Unix() {
+		t.Errorf("tls_cert_expiry_seconds = %v, want %d", metrics.TLSCertExpiry.Get("reload-test"), renewed.Leaf.NotAfter.Unix())
+	}
+}
+
+func TestCertStore_KeepsCurrentOnMismatchedPair(t *testing.T) {
+	pki := newTestPKI(t)
+	certFile, keyFile := filepath.Join(pki.dir, "a.crt"), filepath.Join(pki.dir, "a.key")
+	first := pki.writeServerPair(t, certFile, keyFile, time.Time{})
+
+	store, err := newCertStore("mismatch-test", certFile, keyFile)
+	if err != nil {
+		t.Fatalf("newCertStore() error = %v", err)
+	}
+
+	// A renewal caught halfway: new certificate, old key
+	otherCert := filepath.Join(pki.dir, "b.crt")
+	pki.writeServerPair(t, otherCert, filepath.Join(pki.dir, "b.key"), time.Time{})
+	data, err := os.ReadFile(otherCert)
+	if err != nil {
+		t.Fatalf("failed to read certificate: %v", err)
+	}
+	if err := os.WriteFile(certFile, data, 0600); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+	touch(t, certFile)
+
+	if err := store.Reload(certFile, keyFile); err == nil {
+		t.Fatal("Reload() should fail for a certificate that does not match its key")
+	}
+	if got := servedSerial(t, store); got != first.Leaf.SerialNumber.String() {
+		t.Errorf("served serial = %s, want the current certificate %s", got, first.Leaf.SerialNumber)
+	}
+}
+
+func TestCertStore_RefusesExpiredCertificate(t *testing.T) {
+	pki := newTestPKI(t)
+	certFile, keyFile := filepath.Join(pki.dir, "a.crt"), filepath.Join(pki.dir, "a.key")
+	first := pki.writeServerPair(t, certFile, keyFile, time.Time{})
+
+	store, err := newCertStore("expired-test", certFile, keyFile)
+	if err != nil {
+		t.Fatalf("newCertStore() error = %v", err)
+	}
+
+	expiredCert, expiredKey := filepath.Join(pki.dir, "old.crt"), filepath.Join(pki.dir, "old.key")
+	pki.writeServerPair(t, expiredCert, expiredKey, time.Now().Add(-30*time.Second))
+	if err := store.Reload(expiredCert, expiredKey); err == nil {
+		t.Fatal("Reload() should refuse an expired certificate")
+	}
+	if got := servedSerial(t, store); got != first.Leaf.SerialNumber.String() {
+		t.Errorf("served serial = %s, want the current certificate %s", got, first.Leaf.SerialNumber)
+	}
+}
+
+func TestServer_UpdateConfig_ReloadsCertificate(t *testing.T) {
+	pki := newTestPKI(t)
+	certFile, keyFile := pki.serverFiles(t)
+
+	storageManager, err := storage.New(t.TempDir(), "zpa")
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer storageManager.Close()
+	aclList, err := acl.New("")
+	if err != nil {
+		t.Fatalf("failed to create ACL: %v", err)
+	}
+
+	srv, err := New(Config{
+		Name:         "user-activity",
+		ListenAddr:   "127.0.0.1:0",
+		TLSCertFile:  certFile,
+		TLSKeyFile:   keyFile,
+		MaxLineBytes: 1024,
+	}, aclList, storageManager, &mockForwarder{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	go func() { _ = srv.Start() }()
+	defer srv.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	newCert, newKey := filepath.Join(pki.dir, "renewed.crt"), filepath.Join(pki.dir, "renewed.key")
+	renewed := pki.writeServerPair(t, newCert, newKey, time.Time{})
+	if err := srv.UpdateConfig(ReloadableConfig{TLSCertFile: newCert, TLSKeyFile: newKey}); err != nil {
+		t.Fatalf("UpdateConfig() error = %v", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(pki.caCert)
+	conn, err := tls.Dial("tcp", srv.listener.Addr().String(), &tls.Config{RootCAs: roots})
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	served := conn.ConnectionState().PeerCertificates[0]
+	if served.SerialNumber.Cmp(renewed.Leaf.SerialNumber) != 0 {
+		t.Errorf("served serial = %s, want renewed %s", served.SerialNumber, renewed.Leaf.SerialNumber)
+	}
+
+	// An invalid pair is rejected and the renewed certificate stays in place
+	if err := srv.UpdateConfig(ReloadableConfig{TLSCertFile: newCert, TLSKeyFile: keyFile}); err == nil {
+		t.Error("UpdateConfig() should fail for a mismatched certificate and key")
+	}
+}
@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DayFiles returns the stored files for a file prefix, oldest first.
+// Files compressed by retention (.ndjson.gz) are included. If both forms of a day exist,
+// e.g. while compression is in progress, only the uncompressed file is returned.
+func DayFiles(dir, filePrefix string) ([]string, error) {
+	var files []string
+	for _, pattern := range []string{filePrefix + "-????-??-??.ndjson", filePrefix + "-????-??-??.ndjson.gz"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Slice(files, func(i, j int) bool { return FileKey(files[i]) < FileKey(files[j]) })
+
+	out := make([]string, 0, len(files))
+	for _, f := range files {
+		if n := len(out); n > 0 && FileKey(out[n-1]) == FileKey(f) {
+			if strings.HasSuffix(out[n-1], ".gz") {
+				out[n-1] = f
+			}
+			continue
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+// FileKey returns the base name of a stored file without the .gz suffix,
+// so a day's file is identified the same way before and after compression.
+func FileKey(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), ".gz")
+}
+
+// FileDay returns the UTC day a stored file was written for.
+func FileDay(path string) (time.Time, bool) {
+	base := strings.TrimSuffix(FileKey(path), ".ndjson")
+	if len(base) < len("2006-01-02") {
+		return time.Time{}, false
+	}
+	day, err := time.Parse("2006-01-02", base[len(base)-len("2006-01-02"):])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return day, true
+}
+
+// LineReader reads complete lines from a stored file, which may still be growing.
+// A trailing line without a newline is held back until it is completed.
+type LineReader struct {
+	path    string
+	file    *os.File
+	gz      *gzip.Reader
+	br      *bufio.Reader
+	offset  int64 // Uncompressed offset after the last complete line returned
+	partial []byte
+}
+
+// OpenLineReader opens a stored file and positions it at an uncompressed byte offset.
+// Compressed files are decompressed and skipped forward to the offset.
+func OpenLineReader(path string, offset int64) (*LineReader, error) {
+	// #nosec G304 -- path is a stored log file found in the configured output directory.
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &LineReader{path: path, file: f, offset: offset}
+	if strings.HasSuffix(path, ".gz") {
+		if r.gz, err = gzip.NewReader(f); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		r.br = bufio.NewReaderSize(r.gz, 64*1024)
+		if _, err := io.CopyN(io.Discard, r.br, offset); err != nil {
+			_ = r.Close()
+			return nil, fmt.Errorf("failed to skip to offset %d: %w", offset, err)
+		}
+		return r, nil
+	}
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	r.br = bufio.NewReaderSize(f, 64*1024)
+	return r, nil
+}
+
+// Next returns the next complete line without its newline, or io.EOF.
+func (r *LineReader) Next() ([]byte, error) {
+	chunk, err := r.br.ReadBytes('\n')
+	r.partial = append(r.partial, chunk...)
+	if err != nil {
+		return nil, err
+	}
+	line := r.partial
+	r.partial = nil
+	r.offset += int64(len(line))
+	return bytes.TrimRight(line, "\r\n"), nil
+}
+
+// Path returns the file being read.
+func (r *LineReader) Path() string {
+	return r.path
+}
+
+// Offset returns the uncompressed offset after the last complete line returned by Next.
+func (r *LineReader) Offset() int64 {
+	return r.offset
+}
+
+// Close closes the underlying file.
+func (r *LineReader) Close() error {
+	if r.gz != nil {
+		_ = r.gz.Close()
+	}
+	return r.file.Close()
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDayFiles_PrefersUncompressed(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"zpa-2025-01-11.ndjson", "zpa-2025-01-10.ndjson.gz", "zpa-2025-01-10.ndjson", "dlq-2025-01-10.ndjson"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := DayFiles(dir, "zpa")
+	if err != nil {
+		t.Fatalf("DayFiles() error = %v", err)
+	}
+	if len(files) != 2 || filepath.Base(files[0]) != "zpa-2025-01-10.ndjson" || filepath.Base(files[1]) != "zpa-2025-01-11.ndjson" {
+		t.Errorf("DayFiles() = %v", files)
+	}
+}
+
+func TestFileDay(t *testing.T) {
+	day, ok := FileDay("/logs/zpa-user-activity-2025-01-15.ndjson.gz")
+	if !ok || day.Format("2006-01-02") != "2025-01-15" {
+		t.Errorf("FileDay() = %v, %v, want 2025-01-15", day, ok)
+	}
+	if _, ok := FileDay("/logs/notes.txt"); ok {
+		t.Error("FileDay() should reject names without a date")
+	}
+}
+
+func TestLineReader_HoldsBackPartialLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zpa-2025-01-15.ndjson")
+	if err := os.WriteFile(path, []byte("{\"n\":1}\n{\"n\""), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := OpenLineReader(path, 0)
+	if err != nil {
+		t.Fatalf("OpenLineReader() error = %v", err)
+	}
+	defer r.Close()
+
+	line, err := r.Next()
+	if err != nil || string(line) != `{"n":1}` {
+		t.Fatalf("Next() = %q, %v", line, err)
+	}
+	if _, err := r.Next(); !errors.Is(err, io.EOF) {
+		t.Fatalf("Next() on partial line error = %v, want io.EOF", err)
+	}
+	if r.Offset() != 8 {
+		t.Errorf("Offset() = %d, want 8", r.Offset())
+	}
+
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString(":2}\n")
+	f.Close()
+
+	line, err = r.Next()
+	if err != nil || string(line) != `{"n":2}` {
+		t.Errorf("Next() after append = %q, %v", line, err)
+	}
+}
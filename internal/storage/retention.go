@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -23,7 +24,8 @@ type RetentionPolicy struct {
 // RetentionWorker periodically cleans up old log files based on retention policy.
 type RetentionWorker struct {
 	policy      RetentionPolicy
-	directories []string // Directories to monitor (output dir, dlq dir, etc.)
+	directories []string     // Directories to monitor (output dir, dlq dir, etc.)
+	mu          sync.RWMutex // Protects directories
 }
 
 // NewRetentionWorker creates a new retention worker for the specified directories.
@@ -67,8 +69,20 @@ func (w *RetentionWorker) Start(ctx context.Context) {
 	}()
 }
 
+// SetDirectories replaces the monitored directories, for example after a configuration reload.
+// It takes effect from the next cleanup.
+func (w *RetentionWorker) SetDirectories(directories ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.directories = directories
+}
+
 // cleanup scans all monitored directories and cleans up old files.
 func (w *RetentionWorker) cleanup() {
+	w.mu.RLock()
+	directories := w.directories
+	w.mu.RUnlock()
+
 	slog.Debug("starting retention cleanup")
 
 	deleteCutoff := time.Now().AddDate(0, 0, -w.policy.MaxAge)
@@ -81,7 +95,7 @@ func (w *RetentionWorker) cleanup() {
 	totalCompressed := 0
 	var totalBytesFreed int64
 
-	for _, dir := range w.directories {
+	for _, dir := range directories {
 		deleted, compressed, bytesFreed := w.cleanupDirectory(dir, deleteCutoff, compressCutoff)
 		totalDeleted += deleted
 		totalCompressed += compressed
@@ -18,6 +18,7 @@ import (
 //
 // Manager is safe for concurrent use by multiple goroutines.
 type Manager struct {
+	name       string // Listener name, used to label metrics (default: filePrefix)
 	baseDir    string
 	filePrefix string
 	file       *os.File
@@ -34,11 +35,19 @@ func New(baseDir, filePrefix string) (*Manager, error) {
 	}
 
 	return &Manager{
+		name:       filePrefix,
 		baseDir:    baseDir,
 		filePrefix: filePrefix,
 	}, nil
 }
 
+// SetName sets the listener name used to label the Manager's metrics.
+func (m *Manager) SetName(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.name = name
+}
+
 // Write writes data to the current day's file, rotating if the date has changed.
 // Data is appended with a newline character.
 // The connID parameter is used for logging and correlation only.
@@ -62,16 +71,16 @@ func (m *Manager) Write(connID string, data []byte) error {
 		}
 
 		m.curDay = day
-		metrics.StorageFileRotations.Add(1)
+		metrics.ListenerStorageFileRotations.Add(1, m.name)
 	}
 
 	n, err := m.file.Write(append(data, '\n'))
 	if err == nil {
-		metrics.StorageWrites.Add("success", 1)
-		metrics.StorageBytesWritten.Add(int64(n))
+		metrics.ListenerStorageWrites.Add(1, m.name, "success")
+		metrics.ListenerStorageBytesWritten.Add(int64(n), m.name)
 		slog.Debug("stored line", "conn_id", connID, "bytes", n)
 	} else {
-		metrics.StorageWrites.Add("failure", 1)
+		metrics.ListenerStorageWrites.Add(1, m.name, "failure")
 	}
 	return err
 }
@@ -106,6 +115,26 @@ func (m *Manager) CurrentFile() string {
 	return filepath.Join(m.baseDir, m.filePrefix+"-"+m.curDay+".ndjson")
 }
 
+// CheckWritable verifies that a file can be created and written in the storage directory.
+// It is used by the readiness endpoint to detect a full or read-only disk.
+func (m *Manager) CheckWritable() error {
+	f, err := os.CreateTemp(m.baseDir, ".relay-probe-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write([]byte{'\n'}); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 func (m *Manager) openDayFile(day string) (*os.File, error) {
 	path := filepath.Join(m.baseDir, m.filePrefix+"-"+day+".ndjson")
 	// #nosec G304 -- baseDir and filePrefix are set during Manager construction from config.
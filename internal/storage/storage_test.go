@@ -7,6 +7,8 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/scottbrown/relay/internal/metrics"
 )
 
 func TestNew_Success(t *testing.T) {
@@ -414,3 +416,50 @@ func TestConcurrentWrites(t *testing.T) {
 	expectedWrites := numGoroutines * writesPerGoroutine
 	t.Logf("Successfully wrote %d lines from %d concurrent goroutines", expectedWrites, numGoroutines)
 }
+
+func TestWrite_RecordsListenerMetrics(t *testing.T) {
+	manager, err := New(t.TempDir(), "zpa")
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer manager.Close()
+	manager.SetName("storage-metrics-test")
+
+	if err := manager.Write("test-conn-id", []byte(`{"test": "data"}`)); err != nil {
+		t.Fatalf("Write should succeed: %v", err)
+	}
+
+	if got := metrics.ListenerStorageWrites.Value("storage-metrics-test", "success"); got != 1 {
+		t.Errorf("storage writes for listener = %d, want 1", got)
+	}
+	if got := metrics.ListenerStorageBytesWritten.Value("storage-metrics-test"); got != 17 {
+		t.Errorf("storage bytes for listener = %d, want 17", got)
+	}
+}
+
+func TestCheckWritable(t *testing.T) {
+	dir := t.TempDir()
+	manager, err := New(dir, "zpa")
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer manager.Close()
+
+	if err := manager.CheckWritable(); err != nil {
+		t.Fatalf("CheckWritable() error = %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("CheckWritable() left %d files behind", len(entries))
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.CheckWritable(); err == nil {
+		t.Error("CheckWritable() should fail when the directory is missing")
+	}
+}
@@ -0,0 +1,321 @@
+// Package tailer forwards log lines by reading the daily NDJSON files written by storage.
+// It follows UTC rotation and checkpoints a byte offset after every successful HEC batch,
+// so forwarding resumes exactly where it stopped after a restart or outage.
+package tailer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/scottbrown/relay/internal/atomicfile"
+	"github.com/scottbrown/relay/internal/metrics"
+	"github.com/scottbrown/relay/internal/storage"
+)
+
+const (
+	// DefaultBatchMaxLines is the default maximum number of lines per HEC request.
+	DefaultBatchMaxLines = 500
+	// DefaultBatchMaxBytes is the default maximum size of a HEC request (1 MiB).
+	DefaultBatchMaxBytes = 1 << 20
+	// DefaultPollInterval is the default time between checks for new lines when caught up.
+	DefaultPollInterval = time.Second
+)
+
+// Sender delivers a batch of newline-separated lines. forwarder.Forwarder satisfies this interface.
+type Sender interface {
+	Forward(connID string, data []byte) error
+}
+
+// Config holds configuration for a Tailer.
+type Config struct {
+	Name           string        // Listener name used in logs and metrics
+	Dir            string        // Storage directory containing the daily files
+	FilePrefix     string        // Storage file prefix ({prefix}-YYYY-MM-DD.ndjson)
+	CheckpointPath string        // Checkpoint file (default: {Dir}/{FilePrefix}.tail-checkpoint.json)
+	BatchMaxLines  int           // Maximum lines per HEC request (default: 500)
+	BatchMaxBytes  int           // Maximum bytes per HEC request (default: 1 MiB)
+	PollInterval   time.Duration // How often to check for new data when caught up (default: 1s)
+	MaxBackoff     time.Duration // Maximum wait between failed sends (default: 30s)
+}
+
+// Checkpoint records the file being read and the offset after the last forwarded line.
+// File names are stored without the .gz suffix so they survive retention compression.
+type Checkpoint struct {
+	File   string `json:"file"`
+	Offset int64  `json:"offset"`
+}
+
+// Tailer reads stored log files in date order and forwards them in batches.
+//
+// A batch is retried until it succeeds and the checkpoint only moves forward after a
+// successful send, so no line is skipped and nothing is written to the DLQ. Lines in a
+// batch that was sent just before a crash are sent again on restart (at-least-once).
+type Tailer struct {
+	config     Config
+	sender     Sender
+	checkpoint Checkpoint
+	reader     *storage.LineReader
+
+	// Batch that was read but not yet delivered; retried as-is after a failure
+	pending    [][]byte
+	pendingEnd int64
+
+	wg sync.WaitGroup
+}
+
+// New creates a Tailer and loads its checkpoint.
+// Without a checkpoint, tailing starts at the end of the newest file so lines that
+// were already forwarded before tail mode was enabled are not sent again. With a damaged
+// checkpoint, tailing starts at the beginning of the oldest file so no line is skipped.
+func New(sender Sender, config Config) (*Tailer, error) {
+	if config.CheckpointPath == "" {
+		config.CheckpointPath = filepath.Join(config.Dir, config.FilePrefix+".tail-checkpoint.json")
+	}
+	if config.BatchMaxLines <= 0 {
+		config.BatchMaxLines = DefaultBatchMaxLines
+	}
+	if config.BatchMaxBytes <= 0 {
+		config.BatchMaxBytes = DefaultBatchMaxBytes
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultPollInterval
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = 30 * time.Second
+	}
+
+	t := &Tailer{
+		config: config,
+		sender: sender,
+	}
+
+	// #nosec G304 -- CheckpointPath is derived from the configured output directory.
+	data, err := os.ReadFile(config.CheckpointPath)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &t.checkpoint); err != nil {
+			slog.Warn("failed to parse tail checkpoint, starting at beginning of oldest file",
+				"listener", config.Name, "path", config.CheckpointPath, "error", err)
+			t.checkpoint = Checkpoint{}
+		}
+	case os.IsNotExist(err):
+		files, err := storage.DayFiles(t.config.Dir, t.config.FilePrefix)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) > 0 {
+			newest := files[len(files)-1]
+			info, err := os.Stat(newest)
+			if err != nil {
+				return nil, err
+			}
+			t.checkpoint = Checkpoint{File: storage.FileKey(newest)}
+			if !strings.HasSuffix(newest, ".gz") {
+				t.checkpoint.Offset = info.Size()
+			}
+			slog.Info("no tail checkpoint, starting at end of newest file",
+				"listener", config.Name, "file", newest, "offset", t.checkpoint.Offset)
+		}
+	default:
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Checkpoint returns the current checkpoint.
+func (t *Tailer) Checkpoint() Checkpoint {
+	return t.checkpoint
+}
+
+// Start begins tailing in a goroutine until ctx is cancelled.
+func (t *Tailer) Start(ctx context.Context) {
+	slog.Info("starting storage tailer",
+		"listener", t.config.Name,
+		"dir", t.config.Dir,
+		"file", t.checkpoint.File,
+		"offset", t.checkpoint.Offset)
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		defer t.closeReader()
+
+		backoff := t.config.PollInterval
+		for ctx.Err() == nil {
+			progressed, err := t.step()
+			var wait time.Duration
+			switch {
+			case err != nil:
+				slog.Warn("tail forward failed, retrying", "listener", t.config.Name, "file", t.checkpoint.File, "error", err, "retry_in", backoff.String())
+				wait = backoff
+				backoff = min(backoff*2, t.config.MaxBackoff)
+			case progressed:
+				backoff = t.config.PollInterval
+				continue
+			default:
+				wait = t.config.PollInterval
+			}
+
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+			}
+		}
+		slog.Info("storage tailer stopped", "listener", t.config.Name, "file", t.checkpoint.File, "offset", t.checkpoint.Offset)
+	}()
+}
+
+// Wait blocks until the tailer goroutine has exited after its context is cancelled.
+func (t *Tailer) Wait() {
+	t.wg.Wait()
+}
+
+// step sends one batch or moves to the next file.
+// It reports whether any progress was made; false with a nil error means caught up.
+func (t *Tailer) step() (bool, error) {
+	if len(t.pending) == 0 {
+		// List files before reading: if a newer file already exists, the current one is
+		// final and reaching its end means it is safe to move on.
+		files, err := storage.DayFiles(t.config.Dir, t.config.FilePrefix)
+		if err != nil {
+			return false, err
+		}
+		current, next := t.locate(files)
+		if current == "" {
+			return false, nil
+		}
+
+		if err := t.fillBatch(current); err != nil {
+			return false, err
+		}
+		if len(t.pending) == 0 {
+			t.updateLag(files)
+			if next == "" {
+				return false, nil
+			}
+			slog.Info("tail moving to next file", "listener", t.config.Name, "from", current, "to", next)
+			t.closeReader()
+			t.checkpoint = Checkpoint{File: storage.FileKey(next)}
+			return true, t.saveCheckpoint()
+		}
+	}
+
+	payload := bytes.Join(t.pending, []byte("\n"))
+	if err := t.sender.Forward("tail", payload); err != nil {
+		metrics.ListenerTailBatches.Add(1, t.config.Name, "failure")
+		return false, err
+	}
+	metrics.ListenerTailBatches.Add(1, t.config.Name, "success")
+	metrics.TailLines.Add(t.config.Name, int64(len(t.pending)))
+
+	t.checkpoint.Offset = t.pendingEnd
+	t.pending = nil
+	return true, t.saveCheckpoint()
+}
+
+// locate returns the file at the checkpoint and the file after it.
+// If the checkpoint file no longer exists (e.g. removed by retention), the next newer file is used.
+func (t *Tailer) locate(files []string) (current, next string) {
+	for i, f := range files {
+		key := storage.FileKey(f)
+		if t.checkpoint.File != "" && key < t.checkpoint.File {
+			continue
+		}
+		if t.checkpoint.File != "" && key != t.checkpoint.File {
+			slog.Warn("tail checkpoint file missing, skipping to next file",
+				"listener", t.config.Name, "missing", t.checkpoint.File, "next", f)
+			t.checkpoint = Checkpoint{File: key}
+		} else if t.checkpoint.File == "" {
+			t.checkpoint = Checkpoint{File: key}
+		}
+		if i+1 < len(files) {
+			next = files[i+1]
+		}
+		return f, next
+	}
+	return "", ""
+}
+
+// fillBatch reads complete lines from path into the pending batch.
+func (t *Tailer) fillBatch(path string) error {
+	if t.reader == nil || t.reader.Path() != path {
+		t.closeReader()
+		r, err := storage.OpenLineReader(path, t.checkpoint.Offset)
+		if err != nil {
+			return err
+		}
+		t.reader = r
+	}
+
+	size := 0
+	for len(t.pending) < t.config.BatchMaxLines && size < t.config.BatchMaxBytes {
+		line, err := t.reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.closeReader()
+			return err
+		}
+		if len(line) == 0 {
+			continue
+		}
+		t.pending = append(t.pending, line)
+		size += len(line)
+	}
+	t.pendingEnd = t.reader.Offset()
+	if len(t.pending) == 0 && t.pendingEnd != t.checkpoint.Offset {
+		// Only blank lines were read; record the progress
+		t.checkpoint.Offset = t.pendingEnd
+		return t.saveCheckpoint()
+	}
+	return nil
+}
+
+func (t *Tailer) closeReader() {
+	if t.reader != nil {
+		_ = t.reader.Close()
+		t.reader = nil
+	}
+}
+
+// updateLag publishes the bytes stored but not yet forwarded.
+// Compressed files are counted at their on-disk size.
+func (t *Tailer) updateLag(files []string) {
+	var lag int64
+	for _, f := range files {
+		key := storage.FileKey(f)
+		if key < t.checkpoint.File {
+			continue
+		}
+		info, err := os.Stat(f)
+		if err != nil {
+			continue
+		}
+		if key == t.checkpoint.File && !strings.HasSuffix(f, ".gz") {
+			lag += max(info.Size()-t.checkpoint.Offset, 0)
+		} else {
+			lag += info.Size()
+		}
+	}
+	metrics.SetMapInt(metrics.TailLagBytes, t.config.Name, lag)
+}
+
+// saveCheckpoint writes the checkpoint atomically via a temporary file and rename.
+func (t *Tailer) saveCheckpoint() error {
+	data, err := json.Marshal(t.checkpoint)
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(t.config.CheckpointPath, data, 0600)
+}
@@ -0,0 +1,304 @@
+package tailer
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/scottbrown/relay/internal/forwarder"
+	"github.com/scottbrown/relay/internal/redact"
+)
+
+// mockSender records payloads and fails while fail is set
+type mockSender struct {
+	mu       sync.Mutex
+	payloads []string
+	fail     bool
+}
+
+func (m *mockSender) Forward(connID string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.fail {
+		return errors.New("hec unavailable")
+	}
+	m.payloads = append(m.payloads, string(data))
+	return nil
+}
+
+func (m *mockSender) setFail(fail bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fail = fail
+}
+
+func (m *mockSender) snapshot() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.payloads...)
+}
+
+func appendFile(t *testing.T, path, data string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestTailer(t *testing.T, sender Sender, dir string) *Tailer {
+	t.Helper()
+	tl, err := New(sender, Config{Name: "test", Dir: dir, FilePrefix: "zpa"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(tl.closeReader)
+	return tl
+}
+
+// drain steps until the tailer reports it is caught up
+func drain(t *testing.T, tl *Tailer) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		progressed, err := tl.step()
+		if err != nil {
+			t.Fatalf("step() error = %v", err)
+		}
+		if !progressed {
+			return
+		}
+	}
+	t.Fatal("tailer did not catch up")
+}
+
+func TestTailer_ForwardsAndCheckpoints(t *testing.T) {
+	dir := t.TempDir()
+	sender := &mockSender{}
+	tl := newTestTailer(t, sender, dir)
+
+	path := filepath.Join(dir, "zpa-2025-01-15.ndjson")
+	appendFile(t, path, "{\"n\":1}\n{\"n\":2}\n")
+	drain(t, tl)
+
+	payloads := sender.snapshot()
+	if len(payloads) != 1 || payloads[0] != "{\"n\":1}\n{\"n\":2}" {
+		t.Fatalf("payloads = %q, want one batch of two lines", payloads)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "zpa.tail-checkpoint.json"))
+	if err != nil {
+		t.Fatalf("checkpoint not written: %v", err)
+	}
+	if string(data) != `{"file":"zpa-2025-01-15.ndjson","offset":16}` {
+		t.Errorf("checkpoint = %s", data)
+	}
+}
+
+func TestTailer_RedactsEveryLineOfBatch(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	hec := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer hec.Close()
+
+	redactor, err := redact.New([]redact.Rule{{Path: "Username", Action: redact.Mask}}, nil)
+	if err != nil {
+		t.Fatalf("redact.New() error = %v", err)
+	}
+	fwd := forwarder.New(forwarder.Config{URL: hec.URL, Token: "token", Redactor: redactor})
+
+	dir := t.TempDir()
+	tl := newTestTailer(t, fwd, dir)
+	appendFile(t, filepath.Join(dir, "zpa-2025-01-15.ndjson"), "{\"User\":\"a\"}\n{\"Username\":\"b\"}\n{\"Username\":\"c\"}\n")
+	drain(t, tl)
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := "{\"User\":\"a\"}\n{\"Username\":\"[REDACTED]\"}\n{\"Username\":\"[REDACTED]\"}"
+	if len(bodies) != 1 || bodies[0] != want {
+		t.Errorf("HEC bodies = %q, want one batch %q", bodies, want)
+	}
+}
+
+func TestTailer_HoldsBackPartialLine(t *testing.T) {
+	dir := t.TempDir()
+	sender := &mockSender{}
+	tl := newTestTailer(t, sender, dir)
+
+	path := filepath.Join(dir, "zpa-2025-01-15.ndjson")
+	appendFile(t, path, "{\"n\":1}\n{\"n\"")
+	drain(t, tl)
+	if cp := tl.Checkpoint(); cp.Offset != 8 {
+		t.Errorf("checkpoint offset = %d, want 8 (partial line not consumed)", cp.Offset)
+	}
+
+	appendFile(t, path, ":2}\n")
+	drain(t, tl)
+
+	payloads := sender.snapshot()
+	if len(payloads) != 2 || payloads[1] != `{"n":2}` {
+		t.Errorf("payloads = %q, want the completed line in a second batch", payloads)
+	}
+}
+
+func TestTailer_RetriesFailedBatch(t *testing.T) {
+	dir := t.TempDir()
+	sender := &mockSender{fail: true}
+	tl := newTestTailer(t, sender, dir)
+
+	appendFile(t, filepath.Join(dir, "zpa-2025-01-15.ndjson"), "{\"n\":1}\n")
+	if _, err := tl.step(); err == nil {
+		t.Fatal("step() should return the send error")
+	}
+	if cp := tl.Checkpoint(); cp.Offset != 0 {
+		t.Errorf("checkpoint offset = %d after failure, want 0", cp.Offset)
+	}
+
+	sender.setFail(false)
+	drain(t, tl)
+	if payloads := sender.snapshot(); len(payloads) != 1 || payloads[0] != `{"n":1}` {
+		t.Errorf("payloads = %q, want the retried batch", payloads)
+	}
+	if cp := tl.Checkpoint(); cp.Offset != 8 {
+		t.Errorf("checkpoint offset = %d, want 8", cp.Offset)
+	}
+}
+
+func TestTailer_FollowsRotation(t *testing.T) {
+	dir := t.TempDir()
+	sender := &mockSender{}
+	tl := newTestTailer(t, sender, dir)
+
+	appendFile(t, filepath.Join(dir, "zpa-2025-01-15.ndjson"), "{\"day\":15}\n")
+	appendFile(t, filepath.Join(dir, "zpa-2025-01-16.ndjson"), "{\"day\":16}\n")
+	drain(t, tl)
+
+	payloads := sender.snapshot()
+	if len(payloads) != 2 || payloads[0] != `{"day":15}` || payloads[1] != `{"day":16}` {
+		t.Errorf("payloads = %q, want day 15 then day 16", payloads)
+	}
+	if cp := tl.Checkpoint(); cp.File != "zpa-2025-01-16.ndjson" {
+		t.Errorf("checkpoint file = %s, want the newest day", cp.File)
+	}
+}
+
+func TestTailer_ResumesFromCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "zpa-2025-01-15.ndjson")
+
+	first := &mockSender{}
+	tl := newTestTailer(t, first, dir)
+	appendFile(t, path, "{\"n\":1}\n")
+	drain(t, tl)
+
+	appendFile(t, path, "{\"n\":2}\n")
+
+	second := &mockSender{}
+	tl = newTestTailer(t, second, dir)
+	drain(t, tl)
+
+	if payloads := second.snapshot(); len(payloads) != 1 || payloads[0] != `{"n":2}` {
+		t.Errorf("payloads after restart = %q, want only the new line", payloads)
+	}
+}
+
+func TestTailer_StartsAtEndWithoutCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "zpa-2025-01-15.ndjson")
+	appendFile(t, path, "{\"already\":\"forwarded\"}\n")
+
+	sender := &mockSender{}
+	tl := newTestTailer(t, sender, dir)
+	appendFile(t, path, "{\"n\":1}\n")
+	drain(t, tl)
+
+	if payloads := sender.snapshot(); len(payloads) != 1 || payloads[0] != `{"n":1}` {
+		t.Errorf("payloads = %q, want only lines written after start", payloads)
+	}
+}
+
+func TestTailer_DamagedCheckpointStartsAtOldestFile(t *testing.T) {
+	dir := t.TempDir()
+	appendFile(t, filepath.Join(dir, "zpa-2025-01-14.ndjson"), "{\"n\":1}\n")
+	appendFile(t, filepath.Join(dir, "zpa-2025-01-15.ndjson"), "{\"n\":2}\n")
+	// A checkpoint cut short by a power failure
+	if err := os.WriteFile(filepath.Join(dir, "zpa.tail-checkpoint.json"), []byte(`{"file":"zpa-20`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	sender := &mockSender{}
+	tl := newTestTailer(t, sender, dir)
+	drain(t, tl)
+
+	payloads := sender.snapshot()
+	if len(payloads) != 2 || payloads[0] != `{"n":1}` || payloads[1] != `{"n":2}` {
+		t.Errorf("payloads = %q, want every line from the oldest file", payloads)
+	}
+}
+
+func TestTailer_ReadsCompressedFile(t *testing.T) {
+	dir := t.TempDir()
+	sender := &mockSender{}
+	tl := newTestTailer(t, sender, dir)
+
+	// A day that retention compressed before the tailer reached it
+	f, err := os.Create(filepath.Join(dir, "zpa-2025-01-14.ndjson.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	if _, err := gz.Write([]byte("{\"day\":14}\n")); err != nil {
+		t.Fatal(err)
+	}
+	gz.Close()
+	f.Close()
+	appendFile(t, filepath.Join(dir, "zpa-2025-01-15.ndjson"), "{\"day\":15}\n")
+
+	drain(t, tl)
+
+	payloads := sender.snapshot()
+	if len(payloads) != 2 || payloads[0] != `{"day":14}` {
+		t.Errorf("payloads = %q, want the compressed day first", payloads)
+	}
+}
+
+func TestTailer_StartAndWait(t *testing.T) {
+	dir := t.TempDir()
+	sender := &mockSender{}
+	tl, err := New(sender, Config{Name: "test", Dir: dir, FilePrefix: "zpa", PollInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tl.Start(ctx)
+	appendFile(t, filepath.Join(dir, "zpa-2025-01-15.ndjson"), "{\"n\":1}\n")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) && len(sender.snapshot()) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	tl.Wait()
+
+	if payloads := sender.snapshot(); len(payloads) != 1 {
+		t.Errorf("payloads = %q, want one batch", payloads)
+	}
+}
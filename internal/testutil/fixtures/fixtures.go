@@ -44,6 +44,17 @@ func LoadFixtureBytes(t *testing.T, name string) []byte {
 // FixturePath returns the absolute path to a fixture file.
 // The name parameter should be the filename without the path (e.g., "valid-user-activity.ndjson").
 func FixturePath(name string) string {
+	return filepath.Join(projectRoot(), "spec", "fixtures", name)
+}
+
+// SpecPath returns the absolute path to a ZPA LSS specification file.
+// The name parameter should be the filename without the path (e.g., "audit-logs.spec.json").
+func SpecPath(name string) string {
+	return filepath.Join(projectRoot(), "spec", "zpa-logs", name)
+}
+
+// projectRoot returns the directory containing go.mod.
+func projectRoot() string {
 	// Find the project root by looking for go.mod
 	dir, err := os.Getwd()
 	if err != nil {
@@ -62,5 +73,5 @@ func FixturePath(name string) string {
 		dir = parent
 	}
 
-	return filepath.Join(dir, "spec", "fixtures", name)
+	return dir
 }
@@ -52,6 +52,15 @@ func TestFixturePath(t *testing.T) {
 	}
 }
 
+func TestSpecPath(t *testing.T) {
+	path := SpecPath("audit-logs.spec.json")
+
+	expectedSuffix := filepath.Join("spec", "zpa-logs", "audit-logs.spec.json")
+	if !filepath.IsAbs(path) || !endsWithPath(path, expectedSuffix) {
+		t.Errorf("Path %s is not an absolute path ending with %s", path, expectedSuffix)
+	}
+}
+
 func TestLoadFixtureMalformed(t *testing.T) {
 	lines := LoadFixture(t, "malformed-json.ndjson")
 
@@ -9,6 +9,7 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -60,16 +61,30 @@ type MockHECServer struct {
 	mu       sync.Mutex
 	requests []RecordedRequest
 
+	// Indexer acknowledgement emulation
+	ackEnabled  bool
+	ackDelay    time.Duration
+	ackWithheld bool
+	ackQueries  int
+	channels    map[string]*ackChannel
+
 	// Observability
 	verbose bool
 }
 
+// ackChannel tracks the ackIds issued on one X-Splunk-Request-Channel.
+type ackChannel struct {
+	nextID  uint64
+	pending map[uint64]time.Time // ackId -> time it becomes acknowledged
+}
+
 // NewMockHECServer creates a new mock HEC server with the specified authorisation token.
 func NewMockHECServer(token string) *MockHECServer {
 	m := &MockHECServer{
 		Token:        token,
 		responseMode: ResponseOK,
 		requests:     make([]RecordedRequest, 0),
+		channels:     make(map[string]*ackChannel),
 	}
 
 	m.Server = httptest.NewServer(http.HandlerFunc(m.handler))
@@ -140,8 +155,14 @@ func (m *MockHECServer) handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Handle ack status endpoint
+	if r.URL.Path == "/services/collector/ack" {
+		m.handleAck(w, r)
+		return
+	}
+
 	// Validate path
-	if r.URL.Path != "/services/collector/raw" {
+	if r.URL.Path != "/services/collector/raw" && r.URL.Path != "/services/collector/event" {
 		http.Error(w, "Not found", http.StatusNotFound)
 		return
 	}
@@ -158,6 +179,14 @@ func (m *MockHECServer) handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// With indexer acknowledgement every data request must name a channel
+	channel := r.Header.Get("X-Splunk-Request-Channel")
+	if m.isAckEnabled() && channel == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"text":"Data channel is missing","code":10}`)
+		return
+	}
+
 	// Read body
 	var bodyReader io.Reader = r.Body
 	compressed := false
@@ -210,6 +239,10 @@ func (m *MockHECServer) handler(w http.ResponseWriter, r *http.Request) {
 	case ResponseOK:
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
+		if ackID, ok := m.issueAck(channel); ok {
+			fmt.Fprintf(w, `{"text":"Success","code":0,"ackId":%d}`, ackID)
+			return
+		}
 		fmt.Fprintf(w, `{"text":"Success","code":0}`)
 	case ResponseBadRequest:
 		w.WriteHeader(http.StatusBadRequest)
@@ -229,6 +262,124 @@ func (m *MockHECServer) handler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleAck answers ack status queries for the request's channel.
+// Each ackId is reported as acknowledged once, after which it is forgotten, as Splunk does.
+func (m *MockHECServer) handleAck(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Splunk "+m.Token {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if !m.isAckEnabled() {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"text":"ACK is disabled","code":14}`)
+		return
+	}
+	channel := r.Header.Get("X-Splunk-Request-Channel")
+	if channel == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"text":"Data channel is missing","code":10}`)
+		return
+	}
+
+	var query struct {
+		Acks []uint64 `json:"acks"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"text":"Invalid data format","code":6}`)
+		return
+	}
+
+	m.mu.Lock()
+	m.ackQueries++
+	status := make(map[string]bool, len(query.Acks))
+	ch := m.channels[channel]
+	for _, id := range query.Acks {
+		acked := false
+		if ch != nil && !m.ackWithheld {
+			if readyAt, ok := ch.pending[id]; ok && !time.Now().Before(readyAt) {
+				acked = true
+				delete(ch.pending, id)
+			}
+		}
+		status[strconv.FormatUint(id, 10)] = acked
+	}
+	m.mu.Unlock()
+
+	m.logEvent("ack_query", map[string]interface{}{
+		"channel": channel,
+		"acks":    status,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"acks": status})
+}
+
+// issueAck assigns the next ackId on channel if indexer acknowledgement is enabled.
+func (m *MockHECServer) issueAck(channel string) (uint64, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.ackEnabled {
+		return 0, false
+	}
+
+	ch, ok := m.channels[channel]
+	if !ok {
+		ch = &ackChannel{pending: make(map[uint64]time.Time)}
+		m.channels[channel] = ch
+	}
+	id := ch.nextID
+	ch.nextID++
+	ch.pending[id] = time.Now().Add(m.ackDelay)
+	return id, true
+}
+
+// EnableAck turns on indexer acknowledgement emulation.
+// Data requests then require an X-Splunk-Request-Channel header and return an ackId,
+// which can be queried at /services/collector/ack.
+func (m *MockHECServer) EnableAck() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ackEnabled = true
+	m.logEvent("ack_enabled", nil)
+}
+
+// SetAckDelay sets how long after a request its ackId is reported as acknowledged.
+func (m *MockHECServer) SetAckDelay(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ackDelay = d
+	m.logEvent("ack_delay_changed", map[string]interface{}{
+		"delay_ms": d.Milliseconds(),
+	})
+}
+
+// SetAckWithheld makes ack queries report every ackId as not acknowledged,
+// simulating data lost by the indexers after HEC accepted it.
+func (m *MockHECServer) SetAckWithheld(withheld bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ackWithheld = withheld
+	m.logEvent("ack_withheld_changed", map[string]interface{}{
+		"withheld": withheld,
+	})
+}
+
+// AckQueryCount returns the number of ack status queries received.
+func (m *MockHECServer) AckQueryCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ackQueries
+}
+
+// isAckEnabled safely reports whether indexer acknowledgement emulation is on.
+func (m *MockHECServer) isAckEnabled() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ackEnabled
+}
+
 // SetResponse sets the response mode for subsequent requests.
 func (m *MockHECServer) SetResponse(mode ResponseMode) {
 	m.mu.Lock()
@@ -274,6 +425,11 @@ func (m *MockHECServer) Reset() {
 	m.requests = make([]RecordedRequest, 0)
 	m.responseMode = ResponseOK
 	m.delay = 0
+	m.ackEnabled = false
+	m.ackDelay = 0
+	m.ackWithheld = false
+	m.ackQueries = 0
+	m.channels = make(map[string]*ackChannel)
 	m.logEvent("reset", nil)
 }
 
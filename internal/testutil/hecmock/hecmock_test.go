@@ -3,6 +3,7 @@ package hecmock
 import (
 	"bytes"
 	"compress/gzip"
+	"encoding/json"
 	"net/http"
 	"strings"
 	"testing"
@@ -320,6 +321,116 @@ func TestMockHECServer_MissingAuth(t *testing.T) {
 	}
 }
 
+// postWithChannel sends an authorised POST to path with the given ack channel header.
+func postWithChannel(t *testing.T, server *MockHECServer, path, channel, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Splunk test-token")
+	if channel != "" {
+		req.Header.Set("X-Splunk-Request-Channel", channel)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	return resp
+}
+
+func TestMockHECServer_AckChannel(t *testing.T) {
+	server := NewMockHECServer("test-token")
+	defer server.Close()
+	server.EnableAck()
+
+	resp := postWithChannel(t, server, "/services/collector/raw", "chan-1", "line 1")
+	var data struct {
+		AckID *uint64 `json:"ackId"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	resp.Body.Close()
+	if data.AckID == nil || *data.AckID != 0 {
+		t.Fatalf("Expected ackId 0, got %v", data.AckID)
+	}
+
+	var status struct {
+		Acks map[string]bool `json:"acks"`
+	}
+	resp = postWithChannel(t, server, "/services/collector/ack", "chan-1", `{"acks":[0]}`)
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("Failed to decode ack response: %v", err)
+	}
+	resp.Body.Close()
+	if !status.Acks["0"] {
+		t.Errorf("Expected ackId 0 to be acknowledged, got %v", status.Acks)
+	}
+
+	// An ackId is only reported once, and never on another channel
+	resp = postWithChannel(t, server, "/services/collector/ack", "chan-2", `{"acks":[0]}`)
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("Failed to decode ack response: %v", err)
+	}
+	resp.Body.Close()
+	if status.Acks["0"] {
+		t.Error("Expected ackId 0 not to be acknowledged on another channel")
+	}
+
+	if server.AckQueryCount() != 2 {
+		t.Errorf("Expected 2 ack queries, got %d", server.AckQueryCount())
+	}
+}
+
+func TestMockHECServer_AckWithheld(t *testing.T) {
+	server := NewMockHECServer("test-token")
+	defer server.Close()
+	server.EnableAck()
+	server.SetAckWithheld(true)
+
+	resp := postWithChannel(t, server, "/services/collector/raw", "chan-1", "line 1")
+	resp.Body.Close()
+
+	var status struct {
+		Acks map[string]bool `json:"acks"`
+	}
+	resp = postWithChannel(t, server, "/services/collector/ack", "chan-1", `{"acks":[0]}`)
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("Failed to decode ack response: %v", err)
+	}
+	resp.Body.Close()
+	if status.Acks["0"] {
+		t.Error("Expected withheld ackId not to be acknowledged")
+	}
+}
+
+func TestMockHECServer_AckRequiresChannel(t *testing.T) {
+	server := NewMockHECServer("test-token")
+	defer server.Close()
+	server.EnableAck()
+
+	resp := postWithChannel(t, server, "/services/collector/raw", "", "line 1")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without a channel, got %d", resp.StatusCode)
+	}
+	if server.RequestCount() != 0 {
+		t.Errorf("Expected rejected request not to be recorded, got %d", server.RequestCount())
+	}
+}
+
+func TestMockHECServer_AckDisabled(t *testing.T) {
+	server := NewMockHECServer("test-token")
+	defer server.Close()
+
+	resp := postWithChannel(t, server, "/services/collector/ack", "chan-1", `{"acks":[0]}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 with ack disabled, got %d", resp.StatusCode)
+	}
+}
+
 // String returns the string representation of ResponseMode for testing
 func (r ResponseMode) String() string {
 	switch r {